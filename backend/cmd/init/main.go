@@ -57,9 +57,428 @@ CREATE TABLE IF NOT EXISTS object_history (
     changed_at TIMESTAMP DEFAULT NOW()
 );
 
+ALTER TABLE water_objects ADD COLUMN IF NOT EXISTS source_crs VARCHAR(32);
+ALTER TABLE water_objects ADD COLUMN IF NOT EXISTS region VARCHAR(64);
+ALTER TABLE water_objects ADD COLUMN IF NOT EXISTS submitted_at TIMESTAMP;
+ALTER TABLE water_objects ADD COLUMN IF NOT EXISTS reviewed_at TIMESTAMP;
+CREATE INDEX IF NOT EXISTS idx_water_objects_reviewed_by ON water_objects(reviewed_by);
+
+-- Accounts that existed before email verification count as verified
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'users' AND column_name = 'email_verified_at') THEN
+        ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP DEFAULT NOW();
+        ALTER TABLE users ALTER COLUMN email_verified_at DROP DEFAULT;
+    END IF;
+END $$;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deactivated_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS failed_login_count INT NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locked_until TIMESTAMP;
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip VARCHAR(64) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP,
+    revoked_reason VARCHAR(50)
+);
+
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS impersonator_id INT REFERENCES users(id);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    token_hash CHAR(64) PRIMARY KEY,
+    session_id UUID NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS user_identities (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    issuer VARCHAR(255) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (issuer, subject)
+);
+
+CREATE UNLOGGED TABLE IF NOT EXISTS rate_limit_buckets (
+    key VARCHAR(512) PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    allowed BOOLEAN NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS permission_grants (
+    id SERIAL PRIMARY KEY,
+    permission VARCHAR(64) NOT NULL,
+    role VARCHAR(50),
+    user_id INT REFERENCES users(id) ON DELETE CASCADE,
+    region VARCHAR(64),
+    object_type VARCHAR(50),
+    created_by INT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK ((role IS NULL) <> (user_id IS NULL))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_permission_grants_unique ON permission_grants (
+    permission, COALESCE(role, ''), COALESCE(user_id, 0), COALESCE(region, ''), COALESCE(object_type, '')
+);
+
+-- Default grants reproduce the original expert and admin roles
+INSERT INTO permission_grants (permission, role)
+SELECT p, r FROM (VALUES
+    ('objects.edit', 'expert'),
+    ('objects.edit', 'admin'),
+    ('objects.review', 'admin'),
+    ('users.manage', 'admin'),
+    ('permissions.manage', 'admin')
+) AS defaults(p, r)
+WHERE NOT EXISTS (SELECT 1 FROM permission_grants);
+
+CREATE TABLE IF NOT EXISTS organizations (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) UNIQUE NOT NULL,
+    description TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS organization_members (
+    organization_id INT NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(16) NOT NULL DEFAULT 'member',
+    joined_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (organization_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_organization_members_user ON organization_members(user_id);
+
+ALTER TABLE water_objects ADD COLUMN IF NOT EXISTS organization_id INT REFERENCES organizations(id);
+CREATE INDEX IF NOT EXISTS idx_water_objects_organization ON water_objects(organization_id, status);
+
+-- The audit log is append-only: every row carries the hash of the previous
+-- one, and the trigger refuses updates, deletes and truncation
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    action VARCHAR(64) NOT NULL,
+    actor_id INT REFERENCES users(id),
+    target_type VARCHAR(64) NOT NULL DEFAULT '',
+    target_id VARCHAR(255) NOT NULL DEFAULT '',
+    ip VARCHAR(64) NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    before_value JSON,
+    after_value JSON,
+    created_at TIMESTAMPTZ NOT NULL,
+    prev_hash VARCHAR(64) NOT NULL,
+    hash CHAR(64) NOT NULL
+);
+
+ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS impersonator_id INT REFERENCES users(id);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_action ON audit_log(action, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log(actor_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_target ON audit_log(target_type, target_id);
+
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_log_append_only ON audit_log;
+CREATE TRIGGER audit_log_append_only
+    BEFORE UPDATE OR DELETE OR TRUNCATE ON audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
+
+INSERT INTO permission_grants (permission, role)
+SELECT 'audit.read', 'admin'
+WHERE NOT EXISTS (SELECT 1 FROM permission_grants WHERE permission = 'audit.read');
+
+CREATE TABLE IF NOT EXISTS user_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    purpose VARCHAR(32) NOT NULL,
+    token_hash CHAR(64) UNIQUE NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS user_two_factor (
+    user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret VARCHAR(64) NOT NULL,
+    confirmed_at TIMESTAMP,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS user_recovery_codes (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash CHAR(64) NOT NULL,
+    used_at TIMESTAMP,
+    UNIQUE (user_id, code_hash)
+);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(16) NOT NULL,
+    key_hash CHAR(64) UNIQUE NOT NULL,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    last_used_ip VARCHAR(64),
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    event VARCHAR(64) NOT NULL,
+    level VARCHAR(16) NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    message TEXT NOT NULL,
+    object_id INT REFERENCES water_objects(id) ON DELETE SET NULL,
+    read_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    event VARCHAR(64) NOT NULL,
+    enabled BOOLEAN NOT NULL,
+    PRIMARY KEY (user_id, event)
+);
+
+CREATE TABLE IF NOT EXISTS events (
+    id BIGSERIAL PRIMARY KEY,
+    type VARCHAR(64) NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS webhooks (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    url TEXT NOT NULL,
+    events TEXT[] NOT NULL DEFAULT '{}',
+    secret VARCHAR(128) NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by INT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Deliveries outlive the events they carry, which are pruned
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id BIGSERIAL PRIMARY KEY,
+    webhook_id INT NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    event_id BIGINT,
+    event_type VARCHAR(64) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    locked_until TIMESTAMP,
+    last_status_code INT,
+    last_error TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    delivered_at TIMESTAMP
+);
+
+INSERT INTO permission_grants (permission, role)
+SELECT 'webhooks.manage', 'admin'
+WHERE NOT EXISTS (SELECT 1 FROM permission_grants WHERE permission = 'webhooks.manage');
+
+-- Review comments follow the object across versions via canonical_id
+CREATE TABLE IF NOT EXISTS review_comments (
+    id SERIAL PRIMARY KEY,
+    canonical_id VARCHAR(255) NOT NULL,
+    object_id INT REFERENCES water_objects(id) ON DELETE SET NULL,
+    object_version INT NOT NULL,
+    parent_id INT REFERENCES review_comments(id),
+    author_id INT REFERENCES users(id),
+    body TEXT NOT NULL,
+    anchor JSONB,
+    resolved_at TIMESTAMP,
+    resolved_by INT REFERENCES users(id),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- A claim keeps other reviewers off a pending object until it expires
+CREATE TABLE IF NOT EXISTS review_claims (
+    object_id INT PRIMARY KEY REFERENCES water_objects(id) ON DELETE CASCADE,
+    reviewer_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    claimed_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS review_approvals (
+    object_id INT NOT NULL REFERENCES water_objects(id) ON DELETE CASCADE,
+    reviewer_id INT NOT NULL REFERENCES users(id),
+    senior BOOLEAN NOT NULL DEFAULT FALSE,
+    note TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (object_id, reviewer_id)
+);
+
+CREATE TABLE IF NOT EXISTS review_quorum_rules (
+    id SERIAL PRIMARY KEY,
+    object_type VARCHAR(50),
+    region VARCHAR(64),
+    approvals INT NOT NULL DEFAULT 1 CHECK (approvals >= 1),
+    senior_approvals INT NOT NULL DEFAULT 0 CHECK (senior_approvals >= 0),
+    senior_override BOOLEAN NOT NULL DEFAULT FALSE,
+    created_by INT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_review_quorum_rules_scope ON review_quorum_rules (
+    COALESCE(object_type, ''), COALESCE(region, '')
+);
+
+INSERT INTO permission_grants (permission, role)
+SELECT 'quorum.manage', 'admin'
+WHERE NOT EXISTS (SELECT 1 FROM permission_grants WHERE permission = 'quorum.manage');
+
+-- Versions of an object share its canonical_id; a merge folds the versions
+-- of one object into another's
+ALTER TABLE water_objects DROP CONSTRAINT IF EXISTS water_objects_canonical_id_key;
+
+-- Likely duplicates found when an object was submitted for review
+CREATE TABLE IF NOT EXISTS duplicate_matches (
+    object_id INT NOT NULL REFERENCES water_objects(id) ON DELETE CASCADE,
+    candidate_id INT NOT NULL REFERENCES water_objects(id) ON DELETE CASCADE,
+    method VARCHAR(32) NOT NULL,
+    spatial_score FLOAT NOT NULL,
+    name_similarity FLOAT NOT NULL,
+    score FLOAT NOT NULL,
+    distance_m FLOAT,
+    detected_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (object_id, candidate_id)
+);
+
+-- Bounds of a GeoJSON geometry, taken over every position in its
+-- coordinates, so that duplicate checks only compare nearby objects
+CREATE OR REPLACE FUNCTION geometry_extent(g JSONB) RETURNS box
+LANGUAGE sql IMMUTABLE STRICT AS $$
+    SELECT box(
+        point(MIN((c->>0)::float8), MIN((c->>1)::float8)),
+        point(MAX((c->>0)::float8), MAX((c->>1)::float8))
+    )
+    FROM jsonb_path_query(g, 'strict $.coordinates.** ? (@.type() == "array" && @[0].type() == "number")') AS c
+$$;
+
+-- Canonical ids merged into another object, kept so old links resolve
+CREATE TABLE IF NOT EXISTS object_merges (
+    source_canonical_id VARCHAR(255) PRIMARY KEY,
+    target_canonical_id VARCHAR(255) NOT NULL,
+    merged_by INT REFERENCES users(id) ON DELETE SET NULL,
+    merged_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO permission_grants (permission, role)
+SELECT 'objects.merge', 'admin'
+WHERE NOT EXISTS (SELECT 1 FROM permission_grants WHERE permission = 'objects.merge');
+
+-- Data-quality rules an object broke when it was last checked
+CREATE TABLE IF NOT EXISTS quality_violations (
+    id SERIAL PRIMARY KEY,
+    object_id INT NOT NULL REFERENCES water_objects(id) ON DELETE CASCADE,
+    rule_id VARCHAR(64) NOT NULL,
+    severity VARCHAR(16) NOT NULL,
+    field VARCHAR(64) NOT NULL,
+    message TEXT NOT NULL,
+    checked_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO permission_grants (permission, role)
+SELECT 'quality.manage', 'admin'
+WHERE NOT EXISTS (SELECT 1 FROM permission_grants WHERE permission = 'quality.manage');
+
+ALTER TABLE water_objects ADD COLUMN IF NOT EXISTS historical_notes TEXT;
+
+-- Bibliographic entries, shared between the objects that cite them
+CREATE TABLE IF NOT EXISTS sources (
+    id SERIAL PRIMARY KEY,
+    title TEXT NOT NULL,
+    authors TEXT,
+    publisher TEXT,
+    year INT NOT NULL,
+    url TEXT,
+    doi VARCHAR(255),
+    licence VARCHAR(128) NOT NULL,
+    created_by INT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- A source cited by an object version, as a whole or for one measured field
+CREATE TABLE IF NOT EXISTS object_sources (
+    id SERIAL PRIMARY KEY,
+    object_id INT NOT NULL REFERENCES water_objects(id) ON DELETE CASCADE,
+    source_id INT NOT NULL REFERENCES sources(id),
+    field VARCHAR(64),
+    locator TEXT,
+    created_by INT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_object_sources_unique ON object_sources (
+    object_id, source_id, COALESCE(field, '')
+);
+
+-- Photos and documents of an object version; the file itself lives in the
+-- blob store under its SHA-256, shared by every row attaching it
+CREATE TABLE IF NOT EXISTS attachments (
+    id SERIAL PRIMARY KEY,
+    object_id INT NOT NULL REFERENCES water_objects(id) ON DELETE CASCADE,
+    filename VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    kind VARCHAR(20) NOT NULL,
+    size BIGINT NOT NULL,
+    sha256 CHAR(64) NOT NULL,
+    has_thumbnail BOOLEAN NOT NULL DEFAULT FALSE,
+    caption TEXT,
+    latitude DOUBLE PRECISION,
+    longitude DOUBLE PRECISION,
+    uploaded_by INT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (object_id, sha256)
+);
+
 CREATE INDEX IF NOT EXISTS idx_water_objects_status ON water_objects(status);
 CREATE INDEX IF NOT EXISTS idx_water_objects_type ON water_objects(object_type);
+CREATE INDEX IF NOT EXISTS idx_water_objects_region ON water_objects(region);
 CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
+CREATE INDEX IF NOT EXISTS idx_sessions_user ON sessions(user_id) WHERE revoked_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_session ON refresh_tokens(session_id);
+CREATE INDEX IF NOT EXISTS idx_api_keys_user ON api_keys(user_id);
+CREATE INDEX IF NOT EXISTS idx_notifications_user ON notifications(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_events_created ON events(created_at);
+CREATE INDEX IF NOT EXISTS idx_review_comments_canonical ON review_comments(canonical_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_review_comments_parent ON review_comments(parent_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON webhook_deliveries(webhook_id, id);
+CREATE INDEX IF NOT EXISTS idx_notifications_unread ON notifications(user_id) WHERE read_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_water_objects_version ON water_objects(canonical_id, version);
+CREATE INDEX IF NOT EXISTS idx_duplicate_matches_candidate ON duplicate_matches(candidate_id);
+CREATE INDEX IF NOT EXISTS idx_water_objects_extent ON water_objects USING gist (geometry_extent(geometry))
+    WHERE status IN ('published', 'pending');
+CREATE INDEX IF NOT EXISTS idx_object_merges_target ON object_merges(target_canonical_id);
+CREATE INDEX IF NOT EXISTS idx_quality_violations_object ON quality_violations(object_id);
+CREATE INDEX IF NOT EXISTS idx_quality_violations_rule ON quality_violations(rule_id, severity);
+CREATE INDEX IF NOT EXISTS idx_attachments_sha256 ON attachments(sha256);
+CREATE INDEX IF NOT EXISTS idx_object_sources_source ON object_sources(source_id);
 `
 
 func main() {
@@ -149,7 +568,7 @@ func main() {
 
 	inserted := 0
 	for _, u := range users {
-		query := `INSERT INTO users (name, email, password, role, created_at) VALUES ($1, $2, $3, $4, $5)`
+		query := `INSERT INTO users (name, email, password, role, email_verified_at, created_at) VALUES ($1, $2, $3, $4, $5, $5)`
 		_, err := pool.Exec(ctx, query, u.Name, u.Email, password, u.Role, time.Now())
 		if err != nil {
 			log.Printf("Failed to insert %s: %v", u.Email, err)
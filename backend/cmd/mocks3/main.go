@@ -0,0 +1,101 @@
+// Command mocks3 is a minimal S3-compatible object store for local
+// development and manual testing of the s3 blob driver. It serves PUT, GET,
+// HEAD and DELETE on path-style object URLs, keeping objects on disk, and
+// checks request signatures when credentials are configured.
+//
+//	MOCK_S3_ACCESS_KEY=dev MOCK_S3_SECRET_KEY=devsecret go run ./cmd/mocks3/
+//	BLOB_DRIVER=s3 S3_ENDPOINT=http://localhost:9200 S3_BUCKET=watermap \
+//	  S3_ACCESS_KEY=dev S3_SECRET_KEY=devsecret go run ./cmd/server/
+package main
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"watermap/internal/infrastructure/blob"
+)
+
+func main() {
+	port := getEnv("MOCK_S3_PORT", "9200")
+	region := getEnv("MOCK_S3_REGION", "us-east-1")
+	accessKey := os.Getenv("MOCK_S3_ACCESS_KEY")
+	secretKey := os.Getenv("MOCK_S3_SECRET_KEY")
+
+	store, err := blob.NewLocalStore(getEnv("MOCK_S3_DIR", "mocks3-data"))
+	if err != nil {
+		log.Fatalf("Failed to create storage: %v", err)
+	}
+
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if accessKey != "" && !blob.Verify(r, accessKey, secretKey, region, 15*time.Minute) {
+			log.Printf("%s %s: invalid signature", r.Method, r.URL.Path)
+			http.Error(w, "SignatureDoesNotMatch", http.StatusForbidden)
+			return
+		}
+
+		// Path-style addressing: /bucket/key; buckets are just directories
+		key := strings.TrimPrefix(r.URL.Path, "/")
+		if _, object, ok := strings.Cut(key, "/"); !ok || object == "" {
+			http.Error(w, "InvalidRequest", http.StatusBadRequest)
+			return
+		}
+
+		ctx := r.Context()
+		switch r.Method {
+		case http.MethodPut:
+			if r.ContentLength < 0 {
+				http.Error(w, "MissingContentLength", http.StatusLengthRequired)
+				return
+			}
+			if err := store.Put(ctx, key, r.Body, r.ContentLength, r.Header.Get("Content-Type")); err != nil {
+				log.Printf("PUT %s: %v", key, err)
+				http.Error(w, "InternalError", http.StatusInternalServerError)
+				return
+			}
+			log.Printf("PUT %s (%d bytes)", key, r.ContentLength)
+		case http.MethodGet, http.MethodHead:
+			body, err := store.Get(ctx, key)
+			if err != nil {
+				if err == blob.ErrNotFound {
+					http.Error(w, "NoSuchKey", http.StatusNotFound)
+					return
+				}
+				log.Printf("GET %s: %v", key, err)
+				http.Error(w, "InternalError", http.StatusInternalServerError)
+				return
+			}
+			defer body.Close()
+			if r.Method == http.MethodGet {
+				io.Copy(w, body)
+			}
+		case http.MethodDelete:
+			if err := store.Delete(ctx, key); err != nil {
+				log.Printf("DELETE %s: %v", key, err)
+				http.Error(w, "InternalError", http.StatusInternalServerError)
+				return
+			}
+			log.Printf("DELETE %s", key)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, "MethodNotAllowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	signatures := "unchecked (MOCK_S3_ACCESS_KEY not set)"
+	if accessKey != "" {
+		signatures = "checked"
+	}
+	log.Printf("Mock S3 listening on :%s, signatures %s", port, signatures)
+	log.Fatal(http.ListenAndServe(":"+port, nil))
+}
+
+func getEnv(key, fallback string) string {
+	if val := os.Getenv(key); val != "" {
+		return val
+	}
+	return fallback
+}
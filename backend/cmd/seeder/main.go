@@ -99,7 +99,7 @@ func main() {
 	// Insert Loop
 	count := 0
 	for _, u := range users {
-		query := `INSERT INTO users (name, email, password, role, created_at) VALUES ($1, $2, $3, $4, $5)`
+		query := `INSERT INTO users (name, email, password, role, email_verified_at, created_at) VALUES ($1, $2, $3, $4, $5, $5)`
 		_, err := pool.Exec(ctx, query, u.Name, u.Email, password, u.Role, time.Now())
 		if err != nil {
 			log.Printf("Failed to insert %s: %v", u.Email, err)
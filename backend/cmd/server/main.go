@@ -14,9 +14,19 @@ import (
 	"watermap/internal/adapter/handler"
 	"watermap/internal/adapter/middleware"
 	"watermap/internal/adapter/repository/postgres"
+	"watermap/internal/domain/entity"
+	"watermap/internal/infrastructure/blob"
 	"watermap/internal/infrastructure/config"
 	"watermap/internal/infrastructure/database"
+	"watermap/internal/infrastructure/duplicate"
+	"watermap/internal/infrastructure/eventbus"
+	"watermap/internal/infrastructure/geometry"
+	"watermap/internal/infrastructure/mailer"
+	"watermap/internal/infrastructure/oidc"
+	"watermap/internal/infrastructure/quality"
+	"watermap/internal/infrastructure/ratelimit"
 	"watermap/internal/infrastructure/validator"
+	"watermap/internal/infrastructure/webhook"
 )
 
 func main() {
@@ -35,21 +45,134 @@ func main() {
 	// Initialize repositories
 	userRepo := postgres.NewUserRepo(pool)
 	waterObjectRepo := postgres.NewWaterObjectRepo(pool)
+	sessionRepo := postgres.NewSessionRepo(pool)
+	apiKeyRepo := postgres.NewAPIKeyRepo(pool)
+	identityRepo := postgres.NewUserIdentityRepo(pool)
+	twoFactorRepo := postgres.NewTwoFactorRepo(pool)
+	userTokenRepo := postgres.NewUserTokenRepo(pool)
+	permRepo := postgres.NewPermissionRepo(pool)
+	orgRepo := postgres.NewOrganizationRepo(pool)
+	auditRepo := postgres.NewAuditLogRepo(pool)
+	statsRepo := postgres.NewStatsRepo(pool)
+	notificationRepo := postgres.NewNotificationRepo(pool)
+	eventRepo := postgres.NewEventRepo(pool)
+	webhookRepo := postgres.NewWebhookRepo(pool)
+	commentRepo := postgres.NewCommentRepo(pool)
+	reviewRepo := postgres.NewReviewRepo(pool)
+	duplicateRepo := postgres.NewDuplicateRepo(pool)
+	qualityRepo := postgres.NewQualityRepo(pool)
+	sourceRepo := postgres.NewSourceRepo(pool)
+	attachmentRepo := postgres.NewAttachmentRepo(pool)
+
+	mail, err := mailer.New(mailer.Config{
+		Driver:   cfg.MailDriver,
+		From:     cfg.MailFrom,
+		Host:     cfg.SMTPHost,
+		Port:     cfg.SMTPPort,
+		Username: cfg.SMTPUsername,
+		Password: cfg.SMTPPassword,
+		Dir:      cfg.MailDir,
+	})
+	if err != nil {
+		log.Fatalf("Failed to configure mailer: %v", err)
+	}
+
+	blobs, err := blob.New(blob.Config{
+		Driver:    cfg.BlobDriver,
+		Dir:       cfg.BlobDir,
+		Endpoint:  cfg.S3Endpoint,
+		Region:    cfg.S3Region,
+		Bucket:    cfg.S3Bucket,
+		AccessKey: cfg.S3AccessKey,
+		SecretKey: cfg.S3SecretKey,
+	})
+	if err != nil {
+		log.Fatalf("Failed to configure blob storage: %v", err)
+	}
 
 	// Initialize validators
 	geomValidator := validator.NewGeometryValidator()
+	simplifier := geometry.NewSimplifier(cfg.SimplifyCacheSize)
+	qualityRules, err := quality.Load(cfg.QualityRules)
+	if err != nil {
+		log.Fatalf("Failed to load quality rules: %v", err)
+	}
 
 	// Initialize middleware
-	authMiddleware := middleware.NewAuthMiddleware(cfg.JWTSecret, userRepo)
+	authMiddleware := middleware.NewAuthMiddleware(cfg.JWTSecret, userRepo, sessionRepo, apiKeyRepo, permRepo)
+
+	var limitStore ratelimit.Store
+	switch cfg.RateLimitBackend {
+	case "postgres":
+		store := ratelimit.NewPostgresStore(pool)
+		go store.Prune(ctx, 10*time.Minute, time.Hour)
+		limitStore = store
+	case "memory":
+		limitStore = ratelimit.NewMemoryStore()
+	default:
+		log.Fatalf("Unknown RATE_LIMIT_BACKEND %q", cfg.RateLimitBackend)
+	}
+	rateLimiter := middleware.NewRateLimiter(limitStore)
+	authLimit := mustParseLimit("RATE_LIMIT_AUTH", cfg.RateLimitAuth)
+	authAccountLimit := mustParseLimit("RATE_LIMIT_AUTH_ACCOUNT", cfg.RateLimitAuthAccount)
+	publicLimit := mustParseLimit("RATE_LIMIT_PUBLIC", cfg.RateLimitPublic)
+	apiLimit := mustParseLimit("RATE_LIMIT_API", cfg.RateLimitAPI)
 
 	// Initialize handlers
-	authHandler := handler.NewAuthHandler(userRepo, cfg.JWTSecret)
-	waterObjectHandler := handler.NewWaterObjectHandler(waterObjectRepo, geomValidator)
-	adminHandler := handler.NewAdminHandler(waterObjectRepo, userRepo)
+	auditor := handler.NewAuditor(auditRepo)
+	eventBus := eventbus.New(eventRepo)
+	go eventBus.Run(ctx)
+	go eventBus.Prune(ctx, 10*time.Minute, cfg.EventRetention)
+	go webhook.NewDispatcher(webhookRepo).Run(ctx, cfg.WebhookPollInterval)
+	notifier := handler.NewNotifier(notificationRepo, permRepo, eventBus)
+	workflow := entity.NewWorkflow()
+	workflow.OnTransition(notifier.Transitioned)
+	duplicateChecker := handler.NewDuplicateChecker(waterObjectRepo, duplicateRepo, duplicate.NewDetector(cfg.DuplicateLineBuffer, cfg.DuplicateSpringRadius))
+	workflow.OnTransition(duplicateChecker.Transitioned)
+	qualitySweeper := quality.NewSweeper(waterObjectRepo, qualityRepo, qualityRules)
+	go qualitySweeper.RunNightly(ctx, cfg.QualitySweepHour)
+	provenance := handler.NewProvenance(waterObjectRepo, sourceRepo)
+	files := handler.NewAttachments(attachmentRepo, blobs, cfg.AttachmentMaxSize, cfg.ThumbnailSize)
+	authHandler := handler.NewAuthHandler(userRepo, sessionRepo, twoFactorRepo, userTokenRepo, mail, auditor, cfg.JWTSecret, cfg.ClientURL, cfg.AccessTokenTTL, cfg.RefreshTokenTTL, cfg.SessionMaxLifetime)
+	waterObjectHandler := handler.NewWaterObjectHandler(waterObjectRepo, orgRepo, geomValidator, handler.NewQualityChecker(qualityRules, qualityRepo), provenance, files, simplifier, workflow, auditor, notifier)
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyRepo, auditor)
+
+	// Single sign-on is optional and only wired up when an issuer is configured
+	var oidcHandler *handler.OIDCHandler
+	if cfg.OIDCIssuerURL != "" {
+		provider := oidc.NewProvider(oidc.Config{
+			IssuerURL:    cfg.OIDCIssuerURL,
+			ClientID:     cfg.OIDCClientID,
+			ClientSecret: cfg.OIDCClientSecret,
+			RedirectURL:  cfg.OIDCRedirectURL,
+		}, nil)
+		oidcHandler = handler.NewOIDCHandler(
+			authHandler, provider, identityRepo, notifier,
+			cfg.OIDCRoleClaim, handler.ParseRoleMapping(cfg.OIDCRoleMapping), cfg.OIDCSyncRoles,
+			cfg.ClientURL,
+		)
+		log.Printf("OpenID Connect login enabled for %s", cfg.OIDCIssuerURL)
+	}
+	adminHandler := handler.NewAdminHandler(authHandler, waterObjectRepo, userRepo, sessionRepo, permRepo, reviewRepo, orgRepo, duplicateChecker, provenance, files, workflow, auditor, notifier, cfg.ReviewClaimTTL)
+	permissionHandler := handler.NewPermissionHandler(permRepo, userRepo, auditor)
+	organizationHandler := handler.NewOrganizationHandler(orgRepo, userRepo, waterObjectRepo, auditor)
+	auditHandler := handler.NewAuditHandler(auditRepo, auditor)
+	statsHandler := handler.NewStatsHandler(statsRepo, cfg.StatsCacheTTL)
+	notificationHandler := handler.NewNotificationHandler(notificationRepo)
+	eventHandler := handler.NewEventHandler(eventBus)
+	webhookHandler := handler.NewWebhookHandler(webhookRepo, auditor)
+	qualityHandler := handler.NewQualityHandler(qualityRepo, qualityRules, qualitySweeper, auditor)
+	commentHandler := handler.NewCommentHandler(commentRepo, waterObjectRepo, orgRepo, notifier)
+	sourceHandler := handler.NewSourceHandler(sourceRepo, waterObjectRepo, orgRepo)
+	attachmentHandler := handler.NewAttachmentHandler(files, waterObjectRepo, orgRepo)
 
 	// Create Gin router
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.New()
+	// Forwarded client addresses are only believed from configured proxies
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
 	r.Use(gin.Recovery())
 	r.Use(gin.Logger())
 
@@ -57,7 +180,7 @@ func main() {
 	r.Use(func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", cfg.ClientURL)
 		c.Header("Access-Control-Allow-Credentials", "true")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key")
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
 
 		if c.Request.Method == "OPTIONS" {
@@ -78,42 +201,235 @@ func main() {
 	{
 		// Auth routes
 		auth := api.Group("/auth")
+		auth.Use(rateLimiter.PerIP("auth", authLimit))
 		{
-			auth.POST("/register", authHandler.Register)
-			auth.POST("/login", authHandler.Login)
+			// Credential endpoints are also limited per targeted account
+			perAccount := rateLimiter.PerAccount("auth", authAccountLimit)
+			auth.POST("/register", perAccount, authHandler.Register)
+			auth.POST("/login", perAccount, authHandler.Login)
+			auth.POST("/login/2fa", rateLimiter.PerChallenge("auth", authAccountLimit, authHandler.ChallengeSubject), authHandler.LoginTwoFactor)
+			auth.POST("/login/2fa/enroll", authHandler.EnrollWithChallenge)
+			auth.POST("/refresh", authHandler.Refresh)
 			auth.POST("/logout", authHandler.Logout)
+			auth.POST("/verify-email", authHandler.VerifyEmail)
+			auth.POST("/verify-email/resend", perAccount, authHandler.ResendVerification)
+			auth.POST("/forgot-password", perAccount, authHandler.ForgotPassword)
+			auth.POST("/reset-password", authHandler.ResetPassword)
+			auth.POST("/accept-invite", authHandler.AcceptInvite)
+			auth.POST("/impersonation/end", authMiddleware.Protect(), authHandler.EndImpersonation)
+			auth.POST("/logout-all", authMiddleware.Protect(), authMiddleware.RequireInteractive(), authHandler.LogoutAll)
+			auth.GET("/sessions", authMiddleware.Protect(), authMiddleware.RequireInteractive(), authHandler.GetSessions)
 			auth.GET("/me", authMiddleware.Protect(), authHandler.Me)
+
+			account := auth.Group("", authMiddleware.Protect(), authMiddleware.RequireInteractive())
+			{
+				account.POST("/change-password", authHandler.ChangePassword)
+				account.POST("/account/deactivate", authHandler.DeactivateAccount)
+				account.DELETE("/account", authHandler.DeleteAccount)
+			}
+
+			twoFactor := auth.Group("/2fa", authMiddleware.Protect(), authMiddleware.RequireInteractive())
+			{
+				twoFactor.GET("", authHandler.TwoFactorStatus)
+				twoFactor.POST("/enroll", authHandler.EnrollTwoFactor)
+				twoFactor.POST("/confirm", authHandler.ConfirmTwoFactor)
+				twoFactor.POST("/disable", authHandler.DisableTwoFactor)
+				twoFactor.POST("/recovery-codes", authHandler.RegenerateRecoveryCodes)
+			}
+
+			if oidcHandler != nil {
+				auth.GET("/oidc/login", oidcHandler.Login)
+				auth.GET("/oidc/callback", oidcHandler.Callback)
+			}
+		}
+
+		// Personal API keys (managed from a browser session only)
+		apiKeys := api.Group("/api-keys")
+		apiKeys.Use(authMiddleware.Protect(), authMiddleware.RequireInteractive(), rateLimiter.PerAccount("api", apiLimit))
+		{
+			apiKeys.GET("", apiKeyHandler.List)
+			apiKeys.POST("", apiKeyHandler.Create)
+			apiKeys.GET("/:id", apiKeyHandler.Get)
+			apiKeys.DELETE("/:id", apiKeyHandler.Revoke)
 		}
 
 		// Water objects routes
 		waterObjects := api.Group("/water-objects")
 		{
 			// Public routes
-			waterObjects.GET("", waterObjectHandler.GetPublished)
-			waterObjects.GET("/:canonicalId", waterObjectHandler.GetByCanonicalID)
+			public := rateLimiter.PerIP("public", publicLimit)
+			waterObjects.GET("", public, authMiddleware.Identify(), waterObjectHandler.GetPublished)
+			waterObjects.GET("/:canonicalId", public, waterObjectHandler.GetByCanonicalID)
 
 			// Expert routes (requires expert or admin role)
 			expert := waterObjects.Group("")
-			expert.Use(authMiddleware.Protect(), authMiddleware.RequireExpert())
+			expert.Use(authMiddleware.Protect(), rateLimiter.PerAccount("api", apiLimit), authMiddleware.RequirePermission(entity.PermObjectsEdit), authMiddleware.RequireScope(entity.ScopeWriteDrafts))
 			{
 				expert.GET("/my/drafts", waterObjectHandler.GetMyDrafts)
 				expert.POST("", waterObjectHandler.Create)
 				expert.PUT("/:id", waterObjectHandler.Update)
 				expert.POST("/:id/submit", waterObjectHandler.SubmitForReview)
 				expert.DELETE("/:id", waterObjectHandler.Delete)
+				expert.POST("/:id/sources", sourceHandler.Cite)
+				expert.DELETE("/:id/sources/:citationId", sourceHandler.Uncite)
+				expert.POST("/:id/attachments", attachmentHandler.Upload)
+				expert.DELETE("/:id/attachments/:attachmentId", attachmentHandler.Delete)
+				expert.GET("/my/attachments/:id", attachmentHandler.DraftDownload)
+				expert.GET("/my/attachments/:id/thumbnail", attachmentHandler.DraftThumbnail)
 			}
 		}
 
+		// Files of published objects
+		attachments := api.Group("/attachments")
+		{
+			public := rateLimiter.PerIP("public", publicLimit)
+			attachments.GET("/:id", public, attachmentHandler.Download)
+			attachments.GET("/:id/thumbnail", public, attachmentHandler.Thumbnail)
+		}
+
+		// Bibliography shared by objects and their measurements
+		sources := api.Group("/sources")
+		sources.Use(authMiddleware.Protect(), rateLimiter.PerAccount("api", apiLimit), authMiddleware.RequireScope(entity.ScopeWriteDrafts, entity.ScopeReview))
+		{
+			sources.GET("", sourceHandler.List)
+			sources.POST("", authMiddleware.RequirePermission(entity.PermObjectsEdit), sourceHandler.Create)
+			sources.GET("/:id", sourceHandler.Get)
+			sources.PUT("/:id", sourceHandler.Update)
+		}
+
+		// Review discussion, open to reviewers and the submission's authors
+		comments := api.Group("/comments")
+		comments.Use(authMiddleware.Protect(), rateLimiter.PerAccount("api", apiLimit), authMiddleware.RequireScope(entity.ScopeWriteDrafts, entity.ScopeReview))
+		{
+			comments.GET("", commentHandler.List)
+			comments.POST("", commentHandler.Create)
+			comments.PUT("/:id", commentHandler.Update)
+			comments.DELETE("/:id", commentHandler.Delete)
+			comments.POST("/:id/resolve", commentHandler.Resolve)
+			comments.DELETE("/:id/resolve", commentHandler.Reopen)
+		}
+
+		// Organization workspaces, visible to their members
+		organizations := api.Group("/organizations")
+		organizations.Use(authMiddleware.Protect(), rateLimiter.PerAccount("api", apiLimit), authMiddleware.RequireScope(entity.ScopeWriteDrafts))
+		{
+			organizations.GET("", organizationHandler.Mine)
+			organizations.GET("/:id", organizationHandler.Get)
+			organizations.GET("/:id/submissions", organizationHandler.Submissions)
+
+			members := organizations.Group("/:id/members", authMiddleware.RequireInteractive())
+			{
+				members.POST("", organizationHandler.AddMember)
+				members.PUT("/:userId", organizationHandler.UpdateMember)
+				members.DELETE("/:userId", organizationHandler.RemoveMember)
+			}
+		}
+
+		// Live workflow events, filtered by the user's permissions
+		api.GET("/events", authMiddleware.Protect(), authMiddleware.RequireScope(entity.ScopeWriteDrafts), eventHandler.Stream)
+
+		// Notification routes (the signed-in user's own inbox)
+		notifications := api.Group("/notifications")
+		notifications.Use(authMiddleware.Protect(), rateLimiter.PerAccount("api", apiLimit), authMiddleware.RequireScope(entity.ScopeWriteDrafts))
+		{
+			notifications.GET("", notificationHandler.List)
+			notifications.GET("/unread-count", notificationHandler.UnreadCount)
+			notifications.POST("/read-all", notificationHandler.MarkAllRead)
+			notifications.POST("/:id/read", notificationHandler.MarkRead)
+			notifications.DELETE("/:id", notificationHandler.Delete)
+			notifications.GET("/preferences", notificationHandler.GetPreferences)
+			notifications.PUT("/preferences", notificationHandler.UpdatePreferences)
+		}
+
 		// Admin routes
 		admin := api.Group("/admin")
-		admin.Use(authMiddleware.Protect(), authMiddleware.RequireAdmin())
+		admin.Use(authMiddleware.Protect(), rateLimiter.PerAccount("api", apiLimit))
 		{
-			admin.GET("/pending", adminHandler.GetPending)
-			admin.GET("/pending/:id/diff", adminHandler.GetDiff)
-			admin.POST("/approve/:id", adminHandler.Approve)
-			admin.POST("/reject/:id", adminHandler.Reject)
-			admin.GET("/users", adminHandler.GetUsers)
-			admin.PUT("/users/:id/role", adminHandler.UpdateUserRole)
+			review := admin.Group("")
+			review.Use(authMiddleware.RequirePermission(entity.PermObjectsReview), authMiddleware.RequireScope(entity.ScopeReview))
+			{
+				review.GET("/stats", statsHandler.Get)
+				review.GET("/pending", adminHandler.GetPending)
+				review.GET("/pending/:id/diff", adminHandler.GetDiff)
+				review.POST("/approve/:id", adminHandler.Approve)
+				review.POST("/reject/:id", adminHandler.Reject)
+				review.POST("/request-changes/:id", adminHandler.RequestChanges)
+				review.POST("/batch/approve", adminHandler.BatchApprove)
+				review.POST("/batch/reject", adminHandler.BatchReject)
+				review.POST("/claim/:id", adminHandler.Claim)
+				review.DELETE("/claim/:id", adminHandler.Unclaim)
+				review.POST("/objects/:id/unpublish", adminHandler.Unpublish)
+				review.POST("/objects/:id/archive", adminHandler.Archive)
+				review.POST("/objects/:id/restore", adminHandler.Restore)
+				review.POST("/objects/:id/merge", authMiddleware.RequirePermission(entity.PermObjectsMerge), authMiddleware.RequireScope(entity.ScopeAdmin), adminHandler.Merge)
+				review.POST("/pending/:id/duplicates", adminHandler.CheckDuplicates)
+				review.GET("/attachments/:id", attachmentHandler.ReviewDownload)
+				review.GET("/attachments/:id/thumbnail", attachmentHandler.ReviewThumbnail)
+				review.GET("/quality/violations", qualityHandler.Report)
+				review.GET("/quality/rules", qualityHandler.Rules)
+				review.POST("/quality/sweep", authMiddleware.RequirePermission(entity.PermQualityManage), authMiddleware.RequireScope(entity.ScopeAdmin), qualityHandler.Sweep)
+			}
+
+			quorum := admin.Group("/quorum-rules")
+			quorum.Use(authMiddleware.RequirePermission(entity.PermQuorumManage), authMiddleware.RequireScope(entity.ScopeAdmin))
+			{
+				quorum.GET("", adminHandler.ListQuorumRules)
+				quorum.POST("", adminHandler.CreateQuorumRule)
+				quorum.DELETE("/:id", adminHandler.DeleteQuorumRule)
+			}
+
+			users := admin.Group("/users")
+			users.Use(authMiddleware.RequirePermission(entity.PermUsersManage), authMiddleware.RequireScope(entity.ScopeAdmin))
+			{
+				users.GET("", adminHandler.GetUsers)
+				users.POST("", adminHandler.CreateUser)
+				users.DELETE("/:id", adminHandler.DeleteUser)
+				users.PUT("/:id/role", adminHandler.UpdateUserRole)
+				users.POST("/:id/revoke-sessions", adminHandler.RevokeUserSessions)
+				users.POST("/:id/invite", adminHandler.ResendInvite)
+				users.POST("/:id/deactivate", adminHandler.DeactivateUser)
+				users.POST("/:id/reactivate", adminHandler.ReactivateUser)
+				users.POST("/:id/impersonate", authMiddleware.RequireInteractive(), adminHandler.Impersonate)
+			}
+
+			orgs := admin.Group("/organizations")
+			orgs.Use(authMiddleware.RequirePermission(entity.PermUsersManage), authMiddleware.RequireScope(entity.ScopeAdmin))
+			{
+				orgs.GET("", organizationHandler.List)
+				orgs.POST("", organizationHandler.Create)
+				orgs.PUT("/:id", organizationHandler.Update)
+			}
+
+			webhooks := admin.Group("/webhooks")
+			webhooks.Use(authMiddleware.RequirePermission(entity.PermWebhooksManage), authMiddleware.RequireScope(entity.ScopeAdmin))
+			{
+				webhooks.GET("", webhookHandler.List)
+				webhooks.POST("", webhookHandler.Create)
+				webhooks.GET("/:id", webhookHandler.Get)
+				webhooks.PUT("/:id", webhookHandler.Update)
+				webhooks.DELETE("/:id", webhookHandler.Delete)
+				webhooks.POST("/:id/ping", webhookHandler.Ping)
+				webhooks.GET("/:id/deliveries", webhookHandler.Deliveries)
+				webhooks.POST("/:id/deliveries/:deliveryId/redeliver", webhookHandler.Redeliver)
+			}
+
+			audit := admin.Group("/audit")
+			audit.Use(authMiddleware.RequirePermission(entity.PermAuditRead), authMiddleware.RequireScope(entity.ScopeAdmin))
+			{
+				audit.GET("", auditHandler.List)
+				audit.GET("/export", auditHandler.Export)
+				audit.GET("/verify", auditHandler.Verify)
+			}
+
+			permissions := admin.Group("/permissions")
+			permissions.Use(authMiddleware.RequirePermission(entity.PermPermissionsManage), authMiddleware.RequireScope(entity.ScopeAdmin))
+			{
+				permissions.GET("", permissionHandler.List)
+				permissions.GET("/catalog", permissionHandler.Catalog)
+				permissions.GET("/users/:id", permissionHandler.ForUser)
+				permissions.POST("", permissionHandler.Create)
+				permissions.DELETE("/:id", permissionHandler.Delete)
+			}
 		}
 	}
 
@@ -149,3 +465,11 @@ func main() {
 
 	log.Println("Server stopped")
 }
+
+func mustParseLimit(name, value string) ratelimit.Limit {
+	limit, err := ratelimit.ParseLimit(value)
+	if err != nil {
+		log.Fatalf("Invalid %s: %v", name, err)
+	}
+	return limit
+}
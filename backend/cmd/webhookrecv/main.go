@@ -0,0 +1,66 @@
+// Command webhookrecv is a local webhook receiver for development and
+// manual testing of webhook deliveries. It verifies each request's
+// signature, logs the event and answers with the configured status, so
+// retries can be exercised by making it fail.
+//
+//	WEBHOOK_SECRET=whsec_... go run ./cmd/webhookrecv/
+//	POST /api/admin/webhooks {"name": "local", "url": "http://localhost:9100/"}
+//	POST /api/admin/webhooks/:id/ping
+package main
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"watermap/internal/infrastructure/webhook"
+)
+
+func main() {
+	port := getEnv("WEBHOOK_PORT", "9100")
+	secret := os.Getenv("WEBHOOK_SECRET")
+	status, err := strconv.Atoi(getEnv("WEBHOOK_STATUS", "204"))
+	if err != nil {
+		log.Fatalf("Invalid WEBHOOK_STATUS: %v", err)
+	}
+
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		if err != nil {
+			http.Error(w, "failed to read body", http.StatusBadRequest)
+			return
+		}
+
+		verified := "unchecked (WEBHOOK_SECRET not set)"
+		if secret != "" {
+			if !webhook.Verify(secret, r.Header.Get(webhook.HeaderTimestamp), body, r.Header.Get(webhook.HeaderSignature), 5*time.Minute) {
+				log.Printf("delivery %s: invalid signature", r.Header.Get(webhook.HeaderDelivery))
+				http.Error(w, "invalid signature", http.StatusUnauthorized)
+				return
+			}
+			verified = "valid"
+		}
+
+		log.Printf("delivery %s event %s, signature %s: %s",
+			r.Header.Get(webhook.HeaderDelivery), r.Header.Get(webhook.HeaderEvent), verified, body)
+		w.WriteHeader(status)
+	})
+
+	log.Printf("Webhook receiver listening on :%s, answering %d", port, status)
+	log.Fatal(http.ListenAndServe(":"+port, nil))
+}
+
+func getEnv(key, fallback string) string {
+	if val := os.Getenv(key); val != "" {
+		return val
+	}
+	return fallback
+}
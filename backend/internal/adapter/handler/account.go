@@ -0,0 +1,549 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+
+	"watermap/internal/adapter/middleware"
+	"watermap/internal/domain/entity"
+	"watermap/internal/infrastructure/mailer"
+)
+
+const mailTimeout = 30 * time.Second
+
+// issueUserToken replaces any outstanding token of the purpose with a new
+// one and returns the secret to put in the emailed link
+func (h *AuthHandler) issueUserToken(ctx context.Context, userID int64, purpose entity.UserTokenPurpose) (string, error) {
+	if err := h.tokenRepo.DeleteForUser(ctx, userID, purpose); err != nil {
+		return "", err
+	}
+
+	secret, secretHash, err := newRefreshToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = h.tokenRepo.Create(ctx, &entity.UserToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: secretHash,
+		ExpiresAt: time.Now().Add(purpose.TTL()),
+	})
+	if err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// sendMail delivers in the background so response times do not reveal
+// whether an address belongs to an account
+func (h *AuthHandler) sendMail(msg mailer.Message) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mailTimeout)
+		defer cancel()
+
+		if err := h.mailer.Send(ctx, msg); err != nil {
+			log.Printf("send %q to %s: %v", msg.Subject, msg.To, err)
+		}
+	}()
+}
+
+func (h *AuthHandler) sendVerification(c *gin.Context, user *entity.User) error {
+	secret, err := h.issueUserToken(c.Request.Context(), user.ID, entity.TokenPurposeVerifyEmail)
+	if err != nil {
+		return err
+	}
+
+	h.sendMail(mailer.Message{
+		To:      user.Email,
+		Subject: "Confirm your WaterMap email address",
+		Body: fmt.Sprintf("Hello %s,\n\nConfirm your email address to activate your account:\n\n%s/verify-email?token=%s\n\nThe link is valid for %d hours.\n",
+			user.Name, h.clientURL, url.QueryEscape(secret), int(entity.TokenPurposeVerifyEmail.TTL().Hours())),
+	})
+	return nil
+}
+
+// sendInvite emails a link for an account created by an administrator to
+// choose its password, and returns the link so it can also be shared directly
+func (h *AuthHandler) sendInvite(c *gin.Context, user *entity.User) (string, error) {
+	secret, err := h.issueUserToken(c.Request.Context(), user.ID, entity.TokenPurposeInvite)
+	if err != nil {
+		return "", err
+	}
+
+	link := fmt.Sprintf("%s/accept-invite?token=%s", h.clientURL, url.QueryEscape(secret))
+	h.sendMail(mailer.Message{
+		To:      user.Email,
+		Subject: "You have been invited to WaterMap",
+		Body: fmt.Sprintf("Hello %s,\n\nAn administrator created a WaterMap account for you. Choose your password here:\n\n%s\n\nThe link is valid for %d days.\n",
+			user.Name, link, int(entity.TokenPurposeInvite.TTL().Hours()/24)),
+	})
+	return link, nil
+}
+
+// reauthWindow is how recently an account without a password must have
+// signed in to confirm a destructive change by its session
+const reauthWindow = 5 * time.Minute
+
+// confirmIdentity checks that the current user proved who they are just
+// now, and responds itself when they did not. Accounts with a password give
+// it; SSO-only accounts need a session from a sign-in within reauthWindow
+// or a current two-factor code.
+func (h *AuthHandler) confirmIdentity(c *gin.Context, user *entity.User, req *ConfirmPasswordRequest) bool {
+	if user.Password != "" {
+		if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)) != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error":   "invalid_credentials",
+				"message": "password is incorrect",
+			})
+			return false
+		}
+		return true
+	}
+
+	ctx := c.Request.Context()
+	if req.Code == "" {
+		// An administrator's impersonation session is never proof
+		if _, impersonated := c.Get("impersonator_id"); !impersonated {
+			if id, err := uuid.Parse(c.GetString("session_id")); err == nil {
+				session, err := h.sessionRepo.GetByID(ctx, id)
+				if err == nil && time.Since(session.CreatedAt) < reauthWindow {
+					return true
+				}
+			}
+		}
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "reauthentication_required",
+			"message": "sign in again or enter a two-factor code to confirm",
+		})
+		return false
+	}
+
+	if wait := user.LockedFor(time.Now()); wait > 0 {
+		middleware.TooManyRequests(c, wait, "account_locked", accountLockedMessage)
+		return false
+	}
+	tf, err := h.twoFactorRepo.Get(ctx, user.ID)
+	if err != nil && err != entity.ErrNotFound {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "verify_failed",
+			"message": err.Error(),
+		})
+		return false
+	}
+	if !tf.IsEnabled() {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "not_enabled",
+			"message": entity.ErrTwoFactorNotActive.Error(),
+		})
+		return false
+	}
+	if err := h.verifyCode(c, tf, req.Code); err != nil {
+		if err == entity.ErrInvalidOTP {
+			h.recordLoginFailure(c, user, "totp")
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error":   "invalid_code",
+				"message": err.Error(),
+			})
+			return false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "verify_failed",
+			"message": err.Error(),
+		})
+		return false
+	}
+	return true
+}
+
+type TokenRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type EmailRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=6"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
+}
+
+// ConfirmPasswordRequest confirms a destructive account change. Accounts
+// without a password send a two-factor code or sign in again instead.
+type ConfirmPasswordRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// VerifyEmail consumes an email verification token
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	var req TokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	token, err := h.tokenRepo.Consume(c.Request.Context(), entity.TokenPurposeVerifyEmail, hashToken(req.Token))
+	if err != nil {
+		if err == entity.ErrInvalidToken {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid_token",
+				"message": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "verify_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if err := h.userRepo.MarkEmailVerified(c.Request.Context(), token.UserID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "verify_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "email verified"})
+}
+
+// ResendVerification sends a new verification link. The response is the
+// same whether or not the address belongs to an unverified account.
+func (h *AuthHandler) ResendVerification(c *gin.Context) {
+	var req EmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	user, err := h.userRepo.GetByEmail(c.Request.Context(), req.Email)
+	if err == nil && user.IsActive() && !user.IsEmailVerified() {
+		if err := h.sendVerification(c, user); err != nil {
+			log.Printf("resend verification for user %d: %v", user.ID, err)
+		}
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "if the address belongs to an unverified account, a new link has been sent",
+	})
+}
+
+// ForgotPassword emails a password reset link. The response is the same
+// whether or not the address belongs to an account.
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req EmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, err := h.userRepo.GetByEmail(ctx, req.Email)
+	if err == nil && user.IsActive() {
+		secret, err := h.issueUserToken(ctx, user.ID, entity.TokenPurposePasswordReset)
+		if err != nil {
+			log.Printf("issue password reset for user %d: %v", user.ID, err)
+		} else {
+			h.sendMail(mailer.Message{
+				To:      user.Email,
+				Subject: "Reset your WaterMap password",
+				Body: fmt.Sprintf("Hello %s,\n\nSomeone asked to reset the password for your account. If it was you, choose a new password here:\n\n%s/reset-password?token=%s\n\nThe link is valid for %d minutes. If you did not ask for this, ignore this email.\n",
+					user.Name, h.clientURL, url.QueryEscape(secret), int(entity.TokenPurposePasswordReset.TTL().Minutes())),
+			})
+		}
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "if the address belongs to an account, a reset link has been sent",
+	})
+}
+
+// AcceptInvite sets the first password of an invited account. Following
+// the emailed link also verifies the address.
+func (h *AuthHandler) AcceptInvite(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	token, err := h.tokenRepo.Consume(ctx, entity.TokenPurposeInvite, hashToken(req.Token))
+	if err != nil {
+		if err == entity.ErrInvalidToken {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid_token",
+				"message": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "accept_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	user, err := h.userRepo.GetByID(ctx, token.UserID)
+	if err != nil || !user.IsActive() {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_token",
+			"message": entity.ErrInvalidToken.Error(),
+		})
+		return
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "hash_failed",
+			"message": "failed to hash password",
+		})
+		return
+	}
+	if err := h.userRepo.UpdatePassword(ctx, user.ID, string(hashed)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "accept_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+	if err := h.userRepo.MarkEmailVerified(ctx, user.ID); err != nil {
+		log.Printf("mark email verified for user %d: %v", user.ID, err)
+	}
+
+	event := auditUser(entity.AuditInviteAccepted, user.ID)
+	event.ActorID = &user.ID
+	h.audit.Record(c, event, nil, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "account ready; sign in with your new password"})
+}
+
+// ResetPassword sets a new password with a reset token, lifts any lockout
+// and signs the user out everywhere
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	token, err := h.tokenRepo.Consume(ctx, entity.TokenPurposePasswordReset, hashToken(req.Token))
+	if err != nil {
+		if err == entity.ErrInvalidToken {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid_token",
+				"message": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "reset_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	user, err := h.userRepo.GetByID(ctx, token.UserID)
+	if err != nil || !user.IsActive() {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_token",
+			"message": entity.ErrInvalidToken.Error(),
+		})
+		return
+	}
+
+	if !h.setPassword(c, user, req.Password) {
+		return
+	}
+
+	// Following the emailed link proves control of the address too
+	if err := h.userRepo.MarkEmailVerified(ctx, user.ID); err != nil {
+		log.Printf("mark email verified for user %d: %v", user.ID, err)
+	}
+
+	h.clearCookies(c)
+	c.JSON(http.StatusOK, gin.H{"message": "password has been reset"})
+}
+
+// setPassword stores the new password, revokes all sessions and notifies
+// the user. It writes the error response and returns false on failure.
+func (h *AuthHandler) setPassword(c *gin.Context, user *entity.User, password string) bool {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "hash_failed",
+			"message": "failed to hash password",
+		})
+		return false
+	}
+
+	ctx := c.Request.Context()
+	if err := h.userRepo.UpdatePassword(ctx, user.ID, string(hashed)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "update_failed",
+			"message": err.Error(),
+		})
+		return false
+	}
+
+	revoked, err := h.sessionRepo.RevokeAllForUser(ctx, user.ID, entity.RevokeReasonPassword)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "update_failed",
+			"message": err.Error(),
+		})
+		return false
+	}
+
+	// A reset arrives without a session, so the actor is set explicitly
+	event := auditUser(entity.AuditPasswordChanged, user.ID)
+	event.ActorID = &user.ID
+	h.audit.Record(c, event, nil, gin.H{"sessions_revoked": revoked})
+
+	if err := h.tokenRepo.DeleteForUser(ctx, user.ID, entity.TokenPurposePasswordReset); err != nil {
+		log.Printf("clear reset tokens for user %d: %v", user.ID, err)
+	}
+
+	h.sendMail(mailer.Message{
+		To:      user.Email,
+		Subject: "Your WaterMap password was changed",
+		Body: fmt.Sprintf("Hello %s,\n\nThe password for your account was changed and all sessions were signed out. If this was not you, reset your password at %s/forgot-password and contact an administrator.\n",
+			user.Name, h.clientURL),
+	})
+	return true
+}
+
+// ChangePassword replaces the current user's password. Other sessions are
+// signed out; the caller gets a fresh session.
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	user := c.MustGet("user").(*entity.User)
+	if user.Password == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "no_local_password",
+			"message": "this account signs in with single sign-on; use password reset to set a password",
+		})
+		return
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.CurrentPassword)) != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "invalid_credentials",
+			"message": "current password is incorrect",
+		})
+		return
+	}
+
+	if !h.setPassword(c, user, req.NewPassword) {
+		return
+	}
+
+	token, err := h.startSession(c, user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "token_failed",
+			"message": "failed to generate token",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "password changed",
+		"access_token": token,
+		"expires_in":   int(h.accessTTL.Seconds()),
+	})
+}
+
+// DeactivateAccount disables sign-in for the current user. An
+// administrator can reactivate the account.
+func (h *AuthHandler) DeactivateAccount(c *gin.Context) {
+	var req ConfirmPasswordRequest
+	c.ShouldBindJSON(&req)
+
+	user := c.MustGet("user").(*entity.User)
+	if !h.confirmIdentity(c, user, &req) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.userRepo.Deactivate(ctx, user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "deactivate_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if _, err := h.sessionRepo.RevokeAllForUser(ctx, user.ID, entity.RevokeReasonDeactivate); err != nil {
+		log.Printf("revoke sessions of deactivated user %d: %v", user.ID, err)
+	}
+	h.audit.Record(c, auditUser(entity.AuditAccountDeactivated, user.ID), nil, nil)
+
+	h.clearCookies(c)
+	c.JSON(http.StatusOK, gin.H{"message": "account deactivated"})
+}
+
+// DeleteAccount permanently removes the current user's personal data.
+// Contributed water objects and their history are kept.
+func (h *AuthHandler) DeleteAccount(c *gin.Context) {
+	var req ConfirmPasswordRequest
+	c.ShouldBindJSON(&req)
+
+	user := c.MustGet("user").(*entity.User)
+	if !h.confirmIdentity(c, user, &req) {
+		return
+	}
+
+	if err := h.userRepo.Anonymize(c.Request.Context(), user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "delete_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+	h.audit.Record(c, auditUser(entity.AuditAccountDeleted, user.ID), nil, nil)
+
+	h.clearCookies(c)
+	c.JSON(http.StatusOK, gin.H{"message": "account deleted"})
+}
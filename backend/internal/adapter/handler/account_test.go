@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+
+	"watermap/internal/domain/entity"
+	"watermap/internal/domain/repository"
+	"watermap/internal/infrastructure/totp"
+)
+
+// sessionAt holds one session signed in at a fixed time
+type sessionAt struct {
+	repository.SessionRepository
+	session *entity.Session
+}
+
+func (r *sessionAt) GetByID(_ context.Context, id uuid.UUID) (*entity.Session, error) {
+	if r.session == nil || r.session.ID != id {
+		return nil, entity.ErrNotFound
+	}
+	return r.session, nil
+}
+
+// enrolledRepo is a confirmed enrolment on top of stepRepo
+type enrolledRepo struct {
+	stepRepo
+	tf *entity.TwoFactor
+}
+
+func (r *enrolledRepo) Get(context.Context, int64) (*entity.TwoFactor, error) {
+	if r.tf == nil {
+		return nil, entity.ErrNotFound
+	}
+	return r.tf, nil
+}
+
+// failureCounter counts failed sign-ins without locking
+type failureCounter struct {
+	repository.UserRepository
+	failures int
+}
+
+func (r *failureCounter) RecordLoginFailure(context.Context, int64) (int, error) {
+	r.failures++
+	return r.failures, nil
+}
+
+// discardLog accepts audit events and drops them
+type discardLog struct {
+	repository.AuditLogRepository
+}
+
+func (discardLog) Append(context.Context, *entity.AuditEvent) error { return nil }
+
+func TestConfirmIdentity(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := totp.CodeAt(secret, totp.Step(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	confirmed := time.Now().Add(-24 * time.Hour)
+
+	withPassword := &entity.User{ID: 1, Password: string(hash)}
+	sso := &entity.User{ID: 2}
+	fresh := time.Now().Add(-time.Minute)
+	stale := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name         string
+		user         *entity.User
+		signedIn     time.Time
+		impersonated bool
+		enrolled     bool
+		req          ConfirmPasswordRequest
+		want         int
+	}{
+		{"right password", withPassword, stale, false, false, ConfirmPasswordRequest{Password: "correct horse"}, http.StatusOK},
+		{"wrong password", withPassword, fresh, false, false, ConfirmPasswordRequest{Password: "battery"}, http.StatusUnauthorized},
+		{"password account ignores a fresh session", withPassword, fresh, false, true, ConfirmPasswordRequest{Code: code}, http.StatusUnauthorized},
+		{"sso with a fresh sign-in", sso, fresh, false, false, ConfirmPasswordRequest{}, http.StatusOK},
+		{"sso with a stale sign-in", sso, stale, false, false, ConfirmPasswordRequest{}, http.StatusForbidden},
+		{"sso impersonated", sso, fresh, true, false, ConfirmPasswordRequest{}, http.StatusForbidden},
+		{"sso with a stale sign-in and a code", sso, stale, false, true, ConfirmPasswordRequest{Code: code}, http.StatusOK},
+		{"sso with a wrong code", sso, stale, false, true, ConfirmPasswordRequest{Code: "000000"}, http.StatusUnauthorized},
+		{"sso with a code but no enrolment", sso, stale, false, false, ConfirmPasswordRequest{Code: code}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := &entity.Session{ID: uuid.New(), UserID: tt.user.ID, CreatedAt: tt.signedIn}
+			twoFactor := &enrolledRepo{}
+			if tt.enrolled {
+				twoFactor.tf = &entity.TwoFactor{UserID: tt.user.ID, Secret: secret, ConfirmedAt: &confirmed}
+			}
+			h := &AuthHandler{
+				userRepo:      &failureCounter{},
+				sessionRepo:   &sessionAt{session: session},
+				twoFactorRepo: twoFactor,
+				audit:         NewAuditor(discardLog{}),
+			}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodDelete, "/api/auth/account", nil)
+			c.Set("session_id", session.ID.String())
+			if tt.impersonated {
+				c.Set("impersonator_id", int64(99))
+			}
+
+			ok := h.confirmIdentity(c, tt.user, &tt.req)
+			if ok != (tt.want == http.StatusOK) {
+				t.Fatalf("confirmIdentity = %v, want %v", ok, tt.want == http.StatusOK)
+			}
+			if !ok && w.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+}
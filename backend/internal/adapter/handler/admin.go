@@ -1,8 +1,13 @@
 package handler
 
 import (
+	"errors"
+	"io"
+	"log"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
@@ -11,20 +16,88 @@ import (
 )
 
 type AdminHandler struct {
+	auth            *AuthHandler
 	waterObjectRepo repository.WaterObjectRepository
 	userRepo        repository.UserRepository
+	sessionRepo     repository.SessionRepository
+	permRepo        repository.PermissionRepository
+	reviewRepo      repository.ReviewRepository
+	orgRepo         repository.OrganizationRepository
+	duplicates      *DuplicateChecker
+	provenance      *Provenance
+	files           *Attachments
+	workflow        *entity.Workflow
+	audit           *Auditor
+	notify          *Notifier
+	claimTTL        time.Duration
 }
 
-func NewAdminHandler(waterObjectRepo repository.WaterObjectRepository, userRepo repository.UserRepository) *AdminHandler {
+func NewAdminHandler(auth *AuthHandler, waterObjectRepo repository.WaterObjectRepository, userRepo repository.UserRepository, sessionRepo repository.SessionRepository, permRepo repository.PermissionRepository, reviewRepo repository.ReviewRepository, orgRepo repository.OrganizationRepository, duplicates *DuplicateChecker, provenance *Provenance, files *Attachments, workflow *entity.Workflow, auditor *Auditor, notifier *Notifier, claimTTL time.Duration) *AdminHandler {
 	return &AdminHandler{
+		auth:            auth,
 		waterObjectRepo: waterObjectRepo,
 		userRepo:        userRepo,
+		sessionRepo:     sessionRepo,
+		permRepo:        permRepo,
+		reviewRepo:      reviewRepo,
+		orgRepo:         orgRepo,
+		duplicates:      duplicates,
+		provenance:      provenance,
+		files:           files,
+		workflow:        workflow,
+		audit:           auditor,
+		notify:          notifier,
+		claimTTL:        claimTTL,
 	}
 }
 
-// GetPending returns all pending submissions
+// pendingReview is a pending object with its claim, approval progress and
+// likely duplicates
+type pendingReview struct {
+	*entity.WaterObject
+	Claim      *entity.ReviewClaim      `json:"claim,omitempty"`
+	Quorum     entity.QuorumProgress    `json:"quorum"`
+	Duplicates []*entity.DuplicateMatch `json:"duplicates,omitempty"`
+}
+
+// GetPending returns all pending submissions. ?claim=mine keeps the ones
+// claimed by the current reviewer and ?claim=none the unclaimed ones.
 func (h *AdminHandler) GetPending(c *gin.Context) {
-	pending, err := h.waterObjectRepo.GetPending(c.Request.Context())
+	ctx := c.Request.Context()
+	pending, err := h.waterObjectRepo.GetPending(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "fetch_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	claims, err := h.reviewRepo.ActiveClaims(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "fetch_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+	approvals, err := h.reviewRepo.PendingApprovals(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "fetch_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+	rules, err := h.reviewRepo.ListQuorumRules(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "fetch_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+	duplicates, err := h.duplicates.repo.ListPending(ctx)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "fetch_failed",
@@ -33,10 +106,48 @@ func (h *AdminHandler) GetPending(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"pending": pending})
+	claimOf := make(map[int64]*entity.ReviewClaim, len(claims))
+	for _, claim := range claims {
+		claimOf[claim.ObjectID] = claim
+	}
+	approvalsOf := make(map[int64][]*entity.ReviewApproval)
+	for _, a := range approvals {
+		approvalsOf[a.ObjectID] = append(approvalsOf[a.ObjectID], a)
+	}
+	duplicatesOf := make(map[int64][]*entity.DuplicateMatch)
+	for _, m := range duplicates {
+		duplicatesOf[m.ObjectID] = append(duplicatesOf[m.ObjectID], m)
+	}
+
+	// Reviewers only see submissions within their scope
+	perms := permissionsFrom(c)
+	userID := c.GetInt64("user_id")
+	filter := c.Query("claim")
+	visible := make([]pendingReview, 0, len(pending))
+	for _, obj := range pending {
+		if !perms.Allows(entity.PermObjectsReview, obj) {
+			continue
+		}
+		claim := claimOf[obj.ID]
+		if filter == "mine" && (claim == nil || claim.ReviewerID != userID) {
+			continue
+		}
+		if filter == "none" && claim != nil {
+			continue
+		}
+		visible = append(visible, pendingReview{
+			WaterObject: obj,
+			Claim:       claim,
+			Quorum:      entity.QuorumFor(rules, obj).Progress(approvalsOf[obj.ID]),
+			Duplicates:  duplicatesOf[obj.ID],
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"pending": visible})
 }
 
-// GetDiff returns the pending object and its published version for comparison
+// GetDiff returns the pending object and its published version for
+// comparison, with its approvals and likely duplicates
 func (h *AdminHandler) GetDiff(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
@@ -47,19 +158,8 @@ func (h *AdminHandler) GetDiff(c *gin.Context) {
 		return
 	}
 
-	pending, err := h.waterObjectRepo.GetByID(c.Request.Context(), id)
-	if err != nil {
-		if err == entity.ErrNotFound {
-			c.JSON(http.StatusNotFound, gin.H{
-				"error":   "not_found",
-				"message": "pending object not found",
-			})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "fetch_failed",
-			"message": err.Error(),
-		})
+	pending, ok := objectAllowed(c, h.waterObjectRepo, id, entity.PermObjectsReview)
+	if !ok {
 		return
 	}
 
@@ -70,13 +170,44 @@ func (h *AdminHandler) GetDiff(c *gin.Context) {
 		entity.StatusPublished,
 	)
 
+	approvals, err := h.reviewRepo.ListApprovals(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "fetch_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	duplicates, err := h.duplicates.repo.ListForObject(c.Request.Context(), id)
+	if err == nil {
+		err = h.provenance.Attach(c.Request.Context(), pending, published)
+	}
+	if err == nil {
+		err = h.files.Attach(c.Request.Context(), pending, published)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "fetch_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"pending":   pending,
-		"published": published,
+		"pending":    pending,
+		"published":  published,
+		"approvals":  approvals,
+		"duplicates": duplicates,
 	})
 }
 
-// Approve publishes a pending object
+type ApproveRequest struct {
+	Note *string `json:"note"`
+}
+
+// Approve records the reviewer's approval of a pending object and
+// publishes it once the quorum rule for the object is met
 func (h *AdminHandler) Approve(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
@@ -87,32 +218,123 @@ func (h *AdminHandler) Approve(c *gin.Context) {
 		return
 	}
 
+	// The note is optional, and so is the body
+	var req ApproveRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	result, failed := h.approve(c, id, req.Note)
+	if failed != nil {
+		failed.respond(c)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// approve records an approval of one object, publishing it when the quorum
+// is met
+func (h *AdminHandler) approve(c *gin.Context, id int64, note *string) (gin.H, *requestError) {
+	obj, failed := findObject(c, h.waterObjectRepo, id, entity.PermObjectsReview)
+	if failed != nil {
+		return nil, failed
+	}
+	if failed := checkTransition(c, h.workflow, entity.TransitionApprove, obj, isAuthor(c, h.orgRepo, obj)); failed != nil {
+		return nil, failed
+	}
+	if failed := h.checkClaim(c, id); failed != nil {
+		return nil, failed
+	}
+	if failed := h.provenance.check(c.Request.Context(), obj); failed != nil {
+		return nil, failed
+	}
+
+	ctx := c.Request.Context()
 	reviewerID := c.GetInt64("user_id")
 
-	if err := h.waterObjectRepo.Approve(c.Request.Context(), id, reviewerID); err != nil {
-		if err == entity.ErrNotFound {
-			c.JSON(http.StatusNotFound, gin.H{
-				"error":   "not_found",
-				"message": "pending object not found",
-			})
-			return
+	rules, err := h.reviewRepo.ListQuorumRules(ctx)
+	if err != nil {
+		return nil, &requestError{http.StatusInternalServerError, gin.H{
+			"error":   "approve_failed",
+			"message": err.Error(),
+		}}
+	}
+
+	var quorum entity.QuorumProgress
+	_, published, err := h.reviewRepo.AddApproval(ctx, &entity.ReviewApproval{
+		ObjectID:   id,
+		ReviewerID: reviewerID,
+		Senior:     permissionsFrom(c).Allows(entity.PermObjectsReviewSenior, obj),
+		Note:       note,
+	}, func(approvals []*entity.ReviewApproval) bool {
+		quorum = entity.QuorumFor(rules, obj).Progress(approvals)
+		return quorum.Met
+	})
+	if err != nil {
+		switch err {
+		case entity.ErrNotFound:
+			return nil, transitionConflict(entity.TransitionApprove)
+		case entity.ErrAlreadyApproved:
+			return nil, &requestError{http.StatusConflict, gin.H{
+				"error":   "already_approved",
+				"message": err.Error(),
+			}}
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{
+		return nil, &requestError{http.StatusInternalServerError, gin.H{
 			"error":   "approve_failed",
 			"message": err.Error(),
-		})
-		return
+		}}
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "object approved and published"})
+	if !published {
+		// Hand the object on to the next reviewer
+		if err := h.reviewRepo.Unclaim(ctx, id, reviewerID); err != nil && err != entity.ErrNotFound {
+			log.Printf("release claim on %d: %v", id, err)
+		}
+
+		h.audit.Record(c, auditObject(entity.AuditApprovalRecorded, id), nil, gin.H{"quorum": quorum, "note": note})
+
+		return gin.H{
+			"message":   "approval recorded; more approvals are needed to publish",
+			"published": false,
+			"quorum":    quorum,
+		}, nil
+	}
+
+	h.audit.Record(c, auditObject(entity.AuditObjectApproved, id),
+		gin.H{"status": obj.Status}, gin.H{"status": entity.StatusPublished, "canonical_id": obj.CanonicalID, "quorum": quorum})
+	h.workflow.Completed(ctx, entity.TransitionApprove, obj, reviewerID, "")
+	h.notify.SubmissionApproved(c, obj)
+
+	return gin.H{
+		"message":   "object approved and published",
+		"published": true,
+		"quorum":    quorum,
+	}, nil
 }
 
 type RejectRequest struct {
 	Reason string `json:"reason" binding:"required"`
 }
 
-// Reject rejects a pending object
+// Reject turns a pending object down for good, for data that is wrong
+// rather than in need of fixes
 func (h *AdminHandler) Reject(c *gin.Context) {
+	h.sendBack(c, entity.TransitionReject)
+}
+
+// RequestChanges returns a pending object to its authors with a note on
+// what to fix; they can edit and resubmit it
+func (h *AdminHandler) RequestChanges(c *gin.Context) {
+	h.sendBack(c, entity.TransitionRequestChanges)
+}
+
+// sendBack ends the review of a pending object without publishing it
+func (h *AdminHandler) sendBack(c *gin.Context, t entity.Transition) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -131,29 +353,101 @@ func (h *AdminHandler) Reject(c *gin.Context) {
 		return
 	}
 
+	result, failed := h.decline(c, id, t, req.Reason)
+	if failed != nil {
+		failed.respond(c)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// decline rejects one object or requests changes to it
+func (h *AdminHandler) decline(c *gin.Context, id int64, t entity.Transition, reason string) (gin.H, *requestError) {
+	obj, failed := findObject(c, h.waterObjectRepo, id, entity.PermObjectsReview)
+	if failed != nil {
+		return nil, failed
+	}
+	if failed := checkTransition(c, h.workflow, t, obj, isAuthor(c, h.orgRepo, obj)); failed != nil {
+		return nil, failed
+	}
+	if failed := h.checkClaim(c, id); failed != nil {
+		return nil, failed
+	}
+
 	reviewerID := c.GetInt64("user_id")
 
-	if err := h.waterObjectRepo.Reject(c.Request.Context(), id, reviewerID, req.Reason); err != nil {
+	send := h.waterObjectRepo.Reject
+	if t == entity.TransitionRequestChanges {
+		send = h.waterObjectRepo.RequestChanges
+	}
+	if err := send(c.Request.Context(), id, reviewerID, reason); err != nil {
 		if err == entity.ErrNotFound {
-			c.JSON(http.StatusNotFound, gin.H{
-				"error":   "not_found",
-				"message": "pending object not found",
-			})
-			return
+			return nil, transitionConflict(t)
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{
+		return nil, &requestError{http.StatusInternalServerError, gin.H{
 			"error":   "reject_failed",
 			"message": err.Error(),
-		})
-		return
+		}}
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "object rejected"})
+	h.workflow.Completed(c.Request.Context(), t, obj, reviewerID, reason)
+
+	if t == entity.TransitionRequestChanges {
+		h.audit.Record(c, auditObject(entity.AuditChangesRequested, id),
+			gin.H{"status": obj.Status}, gin.H{"status": t.Target(), "reason": reason})
+		h.notify.ChangesRequested(c, obj, reason)
+
+		return gin.H{"message": "changes requested"}, nil
+	}
+
+	h.audit.Record(c, auditObject(entity.AuditObjectRejected, id),
+		gin.H{"status": obj.Status}, gin.H{"status": entity.StatusRejected, "reason": reason})
+	h.notify.SubmissionRejected(c, obj, reason)
+
+	return gin.H{"message": "object rejected"}, nil
 }
 
-// GetUsers returns all users
+const (
+	defaultUserPageSize = 50
+	maxUserPageSize     = 200
+)
+
+// GetUsers returns a page of users matching the optional q (name or email),
+// role and status (active, deactivated, deleted, invited) filters
 func (h *AdminHandler) GetUsers(c *gin.Context) {
-	users, err := h.userRepo.GetAll(c.Request.Context())
+	filter := &repository.UserFilter{
+		Query:  strings.TrimSpace(c.Query("q")),
+		Limit:  defaultUserPageSize,
+		Status: c.Query("status"),
+	}
+
+	if raw := c.Query("role"); raw != "" {
+		filter.Role = entity.UserRole(raw)
+		if !filter.Role.IsValid() {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "validation_error",
+				"message": "invalid role",
+			})
+			return
+		}
+	}
+	switch filter.Status {
+	case "", repository.UserStatusActive, repository.UserStatusDeactivated, repository.UserStatusDeleted, repository.UserStatusInvited:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": "status must be active, deactivated, deleted or invited",
+		})
+		return
+	}
+	if limit, err := strconv.Atoi(c.Query("limit")); err == nil && limit > 0 {
+		filter.Limit = min(limit, maxUserPageSize)
+	}
+	if offset, err := strconv.Atoi(c.Query("offset")); err == nil && offset > 0 {
+		filter.Offset = offset
+	}
+
+	users, total, err := h.userRepo.Search(c.Request.Context(), filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "fetch_failed",
@@ -162,7 +456,12 @@ func (h *AdminHandler) GetUsers(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"users": users})
+	c.JSON(http.StatusOK, gin.H{
+		"users":  users,
+		"total":  total,
+		"limit":  filter.Limit,
+		"offset": filter.Offset,
+	})
 }
 
 type UpdateRoleRequest struct {
@@ -198,6 +497,22 @@ func (h *AdminHandler) UpdateUserRole(c *gin.Context) {
 		return
 	}
 
+	user, err := h.userRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		if err == entity.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"message": "user not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "fetch_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
 	if err := h.userRepo.UpdateRole(c.Request.Context(), id, role); err != nil {
 		if err == entity.ErrNotFound {
 			c.JSON(http.StatusNotFound, gin.H{
@@ -213,5 +528,56 @@ func (h *AdminHandler) UpdateUserRole(c *gin.Context) {
 		return
 	}
 
+	h.audit.Record(c, auditUser(entity.AuditRoleChanged, id), gin.H{"role": user.Role}, gin.H{"role": role})
+	if user.Role != role {
+		h.notify.RoleChanged(c, id, user.Role, role)
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "role updated"})
 }
+
+// RevokeUserSessions signs a user out of every device
+func (h *AdminHandler) RevokeUserSessions(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_id",
+			"message": "invalid user id",
+		})
+		return
+	}
+
+	if _, err := h.userRepo.GetByID(c.Request.Context(), id); err != nil {
+		if err == entity.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"message": "user not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "fetch_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	revoked, err := h.sessionRepo.RevokeAllForUser(c.Request.Context(), id, entity.RevokeReasonAdmin)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "revoke_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.audit.Record(c, auditUser(entity.AuditSessionsRevoked, id), nil, gin.H{
+		"reason":  entity.RevokeReasonAdmin,
+		"revoked": revoked,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "sessions revoked",
+		"revoked": revoked,
+	})
+}
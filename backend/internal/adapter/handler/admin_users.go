@@ -0,0 +1,349 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"watermap/internal/domain/entity"
+)
+
+type CreateUserRequest struct {
+	Name  string `json:"name" binding:"required,max=255"`
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role"`
+}
+
+// CreateUser creates an account without a password and invites its owner
+// by email to choose one
+func (h *AdminHandler) CreateUser(c *gin.Context) {
+	var req CreateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	role := entity.RoleUser
+	if req.Role != "" {
+		role = entity.UserRole(req.Role)
+		if !role.IsValid() {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "validation_error",
+				"message": "invalid role",
+			})
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	if existing, _ := h.userRepo.GetByEmail(ctx, req.Email); existing != nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "email_exists",
+			"message": "user with this email already exists",
+		})
+		return
+	}
+
+	user, err := h.userRepo.Create(ctx, &entity.User{
+		Name:  req.Name,
+		Email: req.Email,
+		Role:  role,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "create_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	link, err := h.auth.sendInvite(c, user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "invite_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.audit.Record(c, auditUser(entity.AuditUserCreated, user.ID), nil, gin.H{
+		"name":  user.Name,
+		"email": user.Email,
+		"role":  user.Role,
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"user":       user,
+		"invite_url": link,
+		"expires_in": int(entity.TokenPurposeInvite.TTL().Seconds()),
+	})
+}
+
+// ResendInvite issues a fresh invite link, invalidating the previous one
+func (h *AdminHandler) ResendInvite(c *gin.Context) {
+	user, ok := h.loadUser(c)
+	if !ok {
+		return
+	}
+
+	if user.Password != "" || user.IsEmailVerified() || !user.IsActive() {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "not_invited",
+			"message": "the account has already been set up or is closed",
+		})
+		return
+	}
+
+	link, err := h.auth.sendInvite(c, user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "invite_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.audit.Record(c, auditUser(entity.AuditInviteResent, user.ID), nil, nil)
+
+	c.JSON(http.StatusOK, gin.H{
+		"invite_url": link,
+		"expires_in": int(entity.TokenPurposeInvite.TTL().Seconds()),
+	})
+}
+
+// DeactivateUser disables sign-in for an account and ends its sessions
+func (h *AdminHandler) DeactivateUser(c *gin.Context) {
+	user, ok := h.loadOtherUser(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.userRepo.Deactivate(ctx, user.ID); err != nil {
+		if err == entity.ErrNotFound {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "not_active",
+				"message": "user is already deactivated or deleted",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "deactivate_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	revoked, err := h.sessionRepo.RevokeAllForUser(ctx, user.ID, entity.RevokeReasonAdmin)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "revoke_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.audit.Record(c, auditUser(entity.AuditUserDeactivated, user.ID), gin.H{"active": true}, gin.H{
+		"active":           false,
+		"sessions_revoked": revoked,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "user deactivated"})
+}
+
+// ReactivateUser lifts a deactivation. Deleted accounts stay closed.
+func (h *AdminHandler) ReactivateUser(c *gin.Context) {
+	user, ok := h.loadUser(c)
+	if !ok {
+		return
+	}
+
+	if err := h.userRepo.Reactivate(c.Request.Context(), user.ID); err != nil {
+		if err == entity.ErrNotFound {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "not_deactivated",
+				"message": "user is not deactivated or has been deleted",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "reactivate_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.audit.Record(c, auditUser(entity.AuditUserReactivated, user.ID), gin.H{"active": false}, gin.H{"active": true})
+
+	c.JSON(http.StatusOK, gin.H{"message": "user reactivated"})
+}
+
+// DeleteUser soft-deletes an account. The user can no longer sign in, but
+// the row and name remain so authored water objects keep their attribution.
+func (h *AdminHandler) DeleteUser(c *gin.Context) {
+	user, ok := h.loadOtherUser(c)
+	if !ok {
+		return
+	}
+
+	if err := h.userRepo.SoftDelete(c.Request.Context(), user.ID); err != nil {
+		if err == entity.ErrNotFound {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "already_deleted",
+				"message": "user has already been deleted",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "delete_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.audit.Record(c, auditUser(entity.AuditUserDeleted, user.ID), gin.H{
+		"name":  user.Name,
+		"email": user.Email,
+		"role":  user.Role,
+	}, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "user deleted"})
+}
+
+type ImpersonateRequest struct {
+	Reason          string `json:"reason" binding:"required,max=500"`
+	DurationMinutes int    `json:"duration_minutes"`
+}
+
+// Impersonate issues a short-lived access token for acting as another user,
+// for reproducing problems they report. Only users whose permissions the
+// administrator already holds can be impersonated.
+func (h *AdminHandler) Impersonate(c *gin.Context) {
+	var req ImpersonateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": "a reason is required",
+		})
+		return
+	}
+
+	ttl := entity.DefaultImpersonation
+	if req.DurationMinutes != 0 {
+		ttl = time.Duration(req.DurationMinutes) * time.Minute
+		if ttl <= 0 || ttl > entity.MaxImpersonation {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "validation_error",
+				"message": "duration_minutes must be between 1 and " + strconv.Itoa(int(entity.MaxImpersonation.Minutes())),
+			})
+			return
+		}
+	}
+
+	target, ok := h.loadOtherUser(c)
+	if !ok {
+		return
+	}
+	if !target.IsActive() {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "account_inactive",
+			"message": "inactive accounts cannot be impersonated",
+		})
+		return
+	}
+
+	targetPerms, err := h.permRepo.ForUser(c.Request.Context(), target.ID, target.Role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "fetch_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+	// Scoped grants only cover their region or type, so an admin limited to
+	// one region cannot borrow the powers of a user holding another
+	own := permissionsFrom(c)
+	for _, grant := range targetPerms {
+		if !own.Covers(grant) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "forbidden",
+				"message": "cannot impersonate a user holding permissions you do not have",
+			})
+			return
+		}
+	}
+
+	adminID := c.GetInt64("user_id")
+	session, token, err := h.auth.startImpersonation(c, target, adminID, ttl)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "token_failed",
+			"message": "failed to start impersonation",
+		})
+		return
+	}
+
+	h.audit.Record(c, auditUser(entity.AuditImpersonateStart, target.ID), nil, gin.H{
+		"reason":     req.Reason,
+		"session_id": session.ID,
+		"expires_at": session.ExpiresAt,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"access_token": token,
+		"expires_in":   int(ttl.Seconds()),
+		"expires_at":   session.ExpiresAt,
+		"user":         target,
+	})
+}
+
+// loadUser reads the :id parameter and fetches the user
+func (h *AdminHandler) loadUser(c *gin.Context) (*entity.User, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_id",
+			"message": "invalid user id",
+		})
+		return nil, false
+	}
+
+	user, err := h.userRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		if err == entity.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"message": "user not found",
+			})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "fetch_failed",
+			"message": err.Error(),
+		})
+		return nil, false
+	}
+	return user, true
+}
+
+// loadOtherUser is loadUser for actions an administrator may not take on
+// their own account
+func (h *AdminHandler) loadOtherUser(c *gin.Context) (*entity.User, bool) {
+	user, ok := h.loadUser(c)
+	if !ok {
+		return nil, false
+	}
+	if user.ID == c.GetInt64("user_id") {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "own_account",
+			"message": "this action cannot be applied to your own account",
+		})
+		return nil, false
+	}
+	return user, true
+}
@@ -0,0 +1,219 @@
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"watermap/internal/domain/entity"
+	"watermap/internal/domain/repository"
+)
+
+const (
+	defaultAPIKeyLifetimeDays = 90
+	maxAPIKeyLifetimeDays     = 365
+
+	// apiKeyDisplayPrefix is how many characters of a key are kept in clear
+	apiKeyDisplayPrefix = 12
+)
+
+type APIKeyHandler struct {
+	repo  repository.APIKeyRepository
+	audit *Auditor
+}
+
+func NewAPIKeyHandler(repo repository.APIKeyRepository, auditor *Auditor) *APIKeyHandler {
+	return &APIKeyHandler{
+		repo:  repo,
+		audit: auditor,
+	}
+}
+
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name" binding:"required,max=100"`
+	Scopes        []string `json:"scopes" binding:"required,min=1"`
+	ExpiresInDays *int     `json:"expires_in_days"`
+}
+
+// generateAPIKey returns a new random key in the wm_sk_ format
+func generateAPIKey() (string, error) {
+	buf := make([]byte, 30)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return entity.APIKeyPrefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// List returns the current user's API keys without their secrets
+func (h *APIKeyHandler) List(c *gin.Context) {
+	userID := c.GetInt64("user_id")
+
+	keys, err := h.repo.ListByUser(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "fetch_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"api_keys": keys})
+}
+
+// Get returns a single API key, including when and from where it was last used
+func (h *APIKeyHandler) Get(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_id",
+			"message": "invalid api key id",
+		})
+		return
+	}
+
+	key, err := h.repo.GetByID(c.Request.Context(), id, c.GetInt64("user_id"))
+	if err != nil {
+		if err == entity.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"message": "api key not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "fetch_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"api_key": key})
+}
+
+// Create issues a new API key. The secret is only returned once.
+func (h *APIKeyHandler) Create(c *gin.Context) {
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	user := c.MustGet("user").(*entity.User)
+
+	scopes := make([]entity.APIKeyScope, 0, len(req.Scopes))
+	for _, raw := range req.Scopes {
+		scope := entity.APIKeyScope(raw)
+		if !scope.IsValid() {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "validation_error",
+				"message": "invalid scope: " + raw,
+			})
+			return
+		}
+		if !scope.AllowedFor(permissionsFrom(c)) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "forbidden",
+				"message": "your permissions do not allow scope " + raw,
+			})
+			return
+		}
+		scopes = append(scopes, scope)
+	}
+
+	days := defaultAPIKeyLifetimeDays
+	if req.ExpiresInDays != nil {
+		days = *req.ExpiresInDays
+	}
+	if days < 1 || days > maxAPIKeyLifetimeDays {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": "expires_in_days must be between 1 and " + strconv.Itoa(maxAPIKeyLifetimeDays),
+		})
+		return
+	}
+	expiresAt := time.Now().AddDate(0, 0, days)
+
+	secret, err := generateAPIKey()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "token_failed",
+			"message": "failed to generate api key",
+		})
+		return
+	}
+
+	key, err := h.repo.Create(c.Request.Context(), &entity.APIKey{
+		UserID:    user.ID,
+		Name:      req.Name,
+		Prefix:    secret[:apiKeyDisplayPrefix],
+		KeyHash:   hashToken(secret),
+		Scopes:    scopes,
+		ExpiresAt: &expiresAt,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "create_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.audit.Record(c, entity.AuditEvent{
+		Action:     entity.AuditAPIKeyCreated,
+		TargetType: "api_key",
+		TargetID:   strconv.FormatInt(key.ID, 10),
+	}, nil, gin.H{
+		"name":       key.Name,
+		"prefix":     key.Prefix,
+		"scopes":     key.Scopes,
+		"expires_at": key.ExpiresAt,
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "api key created; store it now, it will not be shown again",
+		"api_key": key,
+		"key":     secret,
+	})
+}
+
+// Revoke disables one of the current user's API keys
+func (h *APIKeyHandler) Revoke(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_id",
+			"message": "invalid api key id",
+		})
+		return
+	}
+
+	if err := h.repo.Revoke(c.Request.Context(), id, c.GetInt64("user_id")); err != nil {
+		if err == entity.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"message": "api key not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "revoke_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.audit.Record(c, entity.AuditEvent{
+		Action:     entity.AuditAPIKeyRevoked,
+		TargetType: "api_key",
+		TargetID:   strconv.FormatInt(id, 10),
+	}, nil, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "api key revoked"})
+}
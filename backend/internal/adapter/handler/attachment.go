@@ -0,0 +1,424 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+
+	"watermap/internal/domain/entity"
+	"watermap/internal/domain/repository"
+	"watermap/internal/infrastructure/blob"
+	"watermap/internal/infrastructure/media"
+)
+
+// Attachments keeps the files of objects: their records in the database
+// and their content in the blob store
+type Attachments struct {
+	repo      repository.AttachmentRepository
+	blobs     blob.Store
+	maxSize   int64
+	thumbSize int
+}
+
+func NewAttachments(repo repository.AttachmentRepository, blobs blob.Store, maxSize int64, thumbSize int) *Attachments {
+	return &Attachments{
+		repo:      repo,
+		blobs:     blobs,
+		maxSize:   maxSize,
+		thumbSize: thumbSize,
+	}
+}
+
+// Attach fills in the attachments of the objects
+func (a *Attachments) Attach(ctx context.Context, objects ...*entity.WaterObject) error {
+	ids := make([]int64, 0, len(objects))
+	byID := make(map[int64]*entity.WaterObject, len(objects))
+	for _, obj := range objects {
+		if obj != nil {
+			ids = append(ids, obj.ID)
+			byID[obj.ID] = obj
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	attachments, err := a.repo.ListForObjects(ctx, ids...)
+	if err != nil {
+		return err
+	}
+	for _, att := range attachments {
+		obj := byID[att.ObjectID]
+		obj.Attachments = append(obj.Attachments, att)
+	}
+	return nil
+}
+
+// release deletes the stored files no object attaches any more. Failures
+// only leave unused files behind, so they are logged.
+func (a *Attachments) release(ctx context.Context, attachments ...*entity.Attachment) {
+	for _, att := range attachments {
+		unlock, err := a.repo.LockContent(ctx, att.SHA256)
+		if err != nil {
+			log.Printf("release attachment %s: %v", att.SHA256, err)
+			continue
+		}
+		a.releaseLocked(ctx, att)
+		unlock()
+	}
+}
+
+// releaseLocked is release under the content lock, which keeps an upload
+// of the same file from storing it between the check and the deletion
+func (a *Attachments) releaseLocked(ctx context.Context, att *entity.Attachment) {
+	referenced, err := a.repo.Referenced(ctx, att.SHA256)
+	if err != nil {
+		log.Printf("release attachment %s: %v", att.SHA256, err)
+		return
+	}
+	if referenced {
+		return
+	}
+	if err := a.blobs.Delete(ctx, att.BlobKey()); err != nil {
+		log.Printf("release attachment %s: %v", att.SHA256, err)
+	}
+	if att.HasThumbnail {
+		if err := a.blobs.Delete(ctx, att.ThumbnailKey()); err != nil {
+			log.Printf("release thumbnail %s: %v", att.SHA256, err)
+		}
+	}
+}
+
+type AttachmentHandler struct {
+	files   *Attachments
+	objects repository.WaterObjectRepository
+	orgRepo repository.OrganizationRepository
+}
+
+func NewAttachmentHandler(files *Attachments, objects repository.WaterObjectRepository, orgRepo repository.OrganizationRepository) *AttachmentHandler {
+	return &AttachmentHandler{
+		files:   files,
+		objects: objects,
+		orgRepo: orgRepo,
+	}
+}
+
+// maxFilenameLength matches the filename column
+const maxFilenameLength = 255
+
+// Upload attaches a file to a draft. The multipart form carries the file
+// in "file" and an optional "caption". The type is sniffed from the
+// content; photos with a GPS position suggest it as the object's location.
+func (h *AttachmentHandler) Upload(c *gin.Context) {
+	obj, ok := editableObject(c, h.objects, h.orgRepo)
+	if !ok {
+		return
+	}
+
+	// Leave room for the multipart framing and the caption
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.files.maxSize+1<<20)
+	header, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			h.tooLarge(c)
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": "expected a multipart form with the upload in \"file\"",
+		})
+		return
+	}
+	if header.Size > h.files.maxSize {
+		h.tooLarge(c)
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": err.Error(),
+		})
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, h.files.maxSize+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": err.Error(),
+		})
+		return
+	}
+	if int64(len(data)) > h.files.maxSize {
+		h.tooLarge(c)
+		return
+	}
+
+	info, err := media.Inspect(data, h.files.thumbSize)
+	if err != nil {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{
+			"error":   "unsupported_type",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	sum := sha256.Sum256(data)
+	userID := c.GetInt64("user_id")
+	att := &entity.Attachment{
+		ObjectID:     obj.ID,
+		Filename:     cleanFilename(header.Filename),
+		ContentType:  info.ContentType,
+		Kind:         info.Kind,
+		Size:         int64(len(data)),
+		SHA256:       hex.EncodeToString(sum[:]),
+		HasThumbnail: info.Thumbnail != nil,
+		Latitude:     info.Latitude,
+		Longitude:    info.Longitude,
+		UploadedBy:   &userID,
+	}
+	if caption := strings.TrimSpace(c.PostForm("caption")); caption != "" {
+		att.Caption = &caption
+	}
+
+	ctx := c.Request.Context()
+	// Until the record exists, nothing keeps a release of the same file
+	// from deleting it; hold the content lock from storing to recording
+	unlock, err := h.files.repo.LockContent(ctx, att.SHA256)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "upload_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+	defer unlock()
+
+	// Store the content first: a record must never point at a missing file
+	err = h.files.blobs.Put(ctx, att.BlobKey(), bytes.NewReader(data), att.Size, att.ContentType)
+	if err == nil && att.HasThumbnail {
+		err = h.files.blobs.Put(ctx, att.ThumbnailKey(), bytes.NewReader(info.Thumbnail), int64(len(info.Thumbnail)), "image/jpeg")
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "upload_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	created, err := h.files.repo.Create(ctx, att)
+	if err != nil {
+		if err == entity.ErrDuplicateAttachment {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "duplicate_attachment",
+				"message": err.Error(),
+			})
+			return
+		}
+		h.files.releaseLocked(ctx, att)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "upload_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	response := gin.H{"data": created}
+	if created.Latitude != nil {
+		response["suggested_location"] = entity.Geometry{
+			Type:        "Point",
+			Coordinates: []byte(fmt.Sprintf("[%g,%g]", *created.Longitude, *created.Latitude)),
+		}
+	}
+	c.JSON(http.StatusCreated, response)
+}
+
+func (h *AttachmentHandler) tooLarge(c *gin.Context) {
+	c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+		"error":    "file_too_large",
+		"message":  "the file exceeds the upload limit",
+		"max_size": h.files.maxSize,
+	})
+}
+
+// cleanFilename keeps the base name of an uploaded file, within the
+// column's length
+func cleanFilename(name string) string {
+	name = strings.TrimSpace(filepath.Base(strings.ReplaceAll(name, "\\", "/")))
+	if name == "" || name == "." || name == "/" {
+		return "file"
+	}
+	for len(name) > maxFilenameLength {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
+
+// Delete removes an attachment from a draft
+func (h *AttachmentHandler) Delete(c *gin.Context) {
+	attachmentID, err := strconv.ParseInt(c.Param("attachmentId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_id",
+			"message": "invalid attachment id",
+		})
+		return
+	}
+
+	obj, ok := editableObject(c, h.objects, h.orgRepo)
+	if !ok {
+		return
+	}
+
+	att, err := h.files.repo.Delete(c.Request.Context(), obj.ID, attachmentID)
+	if err != nil {
+		if err == entity.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"message": "attachment not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "delete_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+	h.files.release(c.Request.Context(), att)
+
+	c.JSON(http.StatusOK, gin.H{"message": "attachment removed"})
+}
+
+// Download serves a file of a published object
+func (h *AttachmentHandler) Download(c *gin.Context) {
+	h.serve(c, false, published)
+}
+
+// Thumbnail serves the preview of an image of a published object
+func (h *AttachmentHandler) Thumbnail(c *gin.Context) {
+	h.serve(c, true, published)
+}
+
+// DraftDownload serves a file of an object the user is an author of
+func (h *AttachmentHandler) DraftDownload(c *gin.Context) {
+	h.serve(c, false, h.editable(c))
+}
+
+func (h *AttachmentHandler) DraftThumbnail(c *gin.Context) {
+	h.serve(c, true, h.editable(c))
+}
+
+// editable is the check of editableObject, without its status condition so
+// submitted drafts stay readable
+func (h *AttachmentHandler) editable(c *gin.Context) func(*entity.WaterObject) bool {
+	return func(obj *entity.WaterObject) bool {
+		return permissionsFrom(c).Allows(entity.PermObjectsEdit, obj) && isEditor(c, h.orgRepo, obj)
+	}
+}
+
+// ReviewDownload serves a file of any object the reviewer may review
+func (h *AttachmentHandler) ReviewDownload(c *gin.Context) {
+	h.serve(c, false, allowedTo(c, entity.PermObjectsReview))
+}
+
+func (h *AttachmentHandler) ReviewThumbnail(c *gin.Context) {
+	h.serve(c, true, allowedTo(c, entity.PermObjectsReview))
+}
+
+func published(obj *entity.WaterObject) bool {
+	return obj.Status == entity.StatusPublished
+}
+
+func allowedTo(c *gin.Context, perm entity.Permission) func(*entity.WaterObject) bool {
+	return func(obj *entity.WaterObject) bool {
+		return permissionsFrom(c).Allows(perm, obj)
+	}
+}
+
+// serve streams an attachment, or its thumbnail, when visible tells that
+// the object it belongs to may be seen; other attachments are not found
+func (h *AttachmentHandler) serve(c *gin.Context, thumbnail bool, visible func(*entity.WaterObject) bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_id",
+			"message": "invalid attachment id",
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	att, err := h.files.repo.GetByID(ctx, id)
+	if err == nil {
+		var obj *entity.WaterObject
+		if obj, err = h.objects.GetByID(ctx, att.ObjectID); err == nil && !visible(obj) {
+			err = entity.ErrNotFound
+		}
+	}
+	if err == nil && thumbnail && !att.HasThumbnail {
+		err = entity.ErrNotFound
+	}
+	if err != nil {
+		if err == entity.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"message": "attachment not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "fetch_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// Files are stored by content hash and never change
+	key, size, contentType, etag := att.BlobKey(), att.Size, att.ContentType, `"`+att.SHA256+`"`
+	if thumbnail {
+		key, size, contentType, etag = att.ThumbnailKey(), -1, "image/jpeg", `"`+att.SHA256+`-thumb"`
+	}
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	content, err := h.files.blobs.Get(ctx, key)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "fetch_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+	defer content.Close()
+
+	disposition := "attachment"
+	if att.Kind == entity.AttachmentImage || att.ContentType == "application/pdf" {
+		disposition = "inline"
+	}
+	c.DataFromReader(http.StatusOK, size, contentType, content, map[string]string{
+		"Content-Disposition":    mime.FormatMediaType(disposition, map[string]string{"filename": att.Filename}),
+		"ETag":                   etag,
+		"X-Content-Type-Options": "nosniff",
+	})
+}
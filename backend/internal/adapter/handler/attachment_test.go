@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"watermap/internal/domain/entity"
+	"watermap/internal/domain/repository"
+	"watermap/internal/infrastructure/blob"
+)
+
+// contentRepo knows which files are attached and who holds their locks
+type contentRepo struct {
+	repository.AttachmentRepository
+	attached map[string]bool
+	locked   map[string]bool
+	lockErr  error
+}
+
+func (r *contentRepo) LockContent(_ context.Context, sha256 string) (func(), error) {
+	if r.lockErr != nil {
+		return nil, r.lockErr
+	}
+	if r.locked[sha256] {
+		return nil, errors.New("lock taken twice")
+	}
+	r.locked[sha256] = true
+	return func() { r.locked[sha256] = false }, nil
+}
+
+func (r *contentRepo) Referenced(_ context.Context, sha256 string) (bool, error) {
+	if !r.locked[sha256] {
+		return false, errors.New("reference checked without the content lock")
+	}
+	return r.attached[sha256], nil
+}
+
+// deletedBlobs records deletions and whether the content lock was held
+type deletedBlobs struct {
+	blob.Store
+	repo     *contentRepo
+	deleted  []string
+	unlocked []string
+}
+
+func (s *deletedBlobs) Delete(_ context.Context, key string) error {
+	s.deleted = append(s.deleted, key)
+	sha := key[strings.LastIndex(key, "/")+1:]
+	if !s.repo.locked[strings.TrimSuffix(sha, ".jpg")] {
+		s.unlocked = append(s.unlocked, key)
+	}
+	return nil
+}
+
+func TestAttachmentsRelease(t *testing.T) {
+	shared := strings.Repeat("a", 64)
+	orphan := strings.Repeat("b", 64)
+	photo := strings.Repeat("c", 64)
+
+	tests := []struct {
+		name    string
+		atts    []*entity.Attachment
+		lockErr error
+		want    []string
+	}{
+		{"still attached elsewhere", []*entity.Attachment{{SHA256: shared}}, nil, nil},
+		{"last reference", []*entity.Attachment{{SHA256: orphan}}, nil,
+			[]string{"attachments/bb/" + orphan}},
+		{"with a thumbnail", []*entity.Attachment{{SHA256: photo, HasThumbnail: true}}, nil,
+			[]string{"attachments/cc/" + photo, "thumbnails/cc/" + photo + ".jpg"}},
+		{"several", []*entity.Attachment{{SHA256: shared}, {SHA256: orphan}}, nil,
+			[]string{"attachments/bb/" + orphan}},
+		{"lock unavailable", []*entity.Attachment{{SHA256: orphan}}, errors.New("connection refused"), nil},
+	}
+	for _, tt := range tests {
+		repo := &contentRepo{attached: map[string]bool{shared: true}, locked: map[string]bool{}, lockErr: tt.lockErr}
+		blobs := &deletedBlobs{repo: repo}
+		files := NewAttachments(repo, blobs, 1<<20, 320)
+
+		files.release(context.Background(), tt.atts...)
+
+		sort.Strings(blobs.deleted)
+		if !reflect.DeepEqual(blobs.deleted, tt.want) {
+			t.Errorf("%s: deleted %v, want %v", tt.name, blobs.deleted, tt.want)
+		}
+		if len(blobs.unlocked) > 0 {
+			t.Errorf("%s: deleted %v without the content lock", tt.name, blobs.unlocked)
+		}
+		for sha, held := range repo.locked {
+			if held {
+				t.Errorf("%s: lock on %s still held", tt.name, sha[:4])
+			}
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package handler
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"watermap/internal/domain/entity"
+	"watermap/internal/domain/repository"
+)
+
+// auditTimeout bounds an append so a slow database cannot stall a request,
+// while a client hanging up does not cancel the write
+const auditTimeout = 5 * time.Second
+
+// Auditor writes security events to the audit log on behalf of the handlers
+type Auditor struct {
+	repo repository.AuditLogRepository
+}
+
+func NewAuditor(repo repository.AuditLogRepository) *Auditor {
+	return &Auditor{repo: repo}
+}
+
+// Record appends an event. The actor defaults to the authenticated user and
+// IP and user agent come from the request; before and after are stored as
+// JSON when not nil. Failures are logged and never fail the request.
+func (a *Auditor) Record(c *gin.Context, event entity.AuditEvent, before, after interface{}) {
+	if event.ActorID == nil {
+		if id, ok := c.Get("user_id"); ok {
+			actor := id.(int64)
+			event.ActorID = &actor
+		}
+	}
+	if event.ImpersonatorID == nil {
+		if id, ok := c.Get("impersonator_id"); ok {
+			impersonator := id.(int64)
+			event.ImpersonatorID = &impersonator
+		}
+	}
+	event.IP = c.ClientIP()
+	event.UserAgent = c.Request.UserAgent()
+	event.Before = auditValue(before)
+	event.After = auditValue(after)
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(c.Request.Context()), auditTimeout)
+	defer cancel()
+
+	if err := a.repo.Append(ctx, &event); err != nil {
+		log.Printf("audit %s: %v", event.Action, err)
+	}
+}
+
+func auditValue(v interface{}) json.RawMessage {
+	if v == nil {
+		return nil
+	}
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("encode audit value: %v", err)
+		return nil
+	}
+	return encoded
+}
+
+// auditUser is a shorthand for events about a user account
+func auditUser(action entity.AuditAction, userID int64) entity.AuditEvent {
+	return entity.AuditEvent{
+		Action:     action,
+		TargetType: "user",
+		TargetID:   strconv.FormatInt(userID, 10),
+	}
+}
+
+// auditObject is a shorthand for events about a water object version
+func auditObject(action entity.AuditAction, objectID int64) entity.AuditEvent {
+	return entity.AuditEvent{
+		Action:     action,
+		TargetType: "water_object",
+		TargetID:   strconv.FormatInt(objectID, 10),
+	}
+}
+
+type AuditHandler struct {
+	repo    repository.AuditLogRepository
+	auditor *Auditor
+}
+
+func NewAuditHandler(repo repository.AuditLogRepository, auditor *Auditor) *AuditHandler {
+	return &AuditHandler{
+		repo:    repo,
+		auditor: auditor,
+	}
+}
+
+// List returns audit events, newest first, filtered by action, actor_id,
+// target_type, target_id and an RFC 3339 from/to range
+func (h *AuditHandler) List(c *gin.Context) {
+	filter, ok := parseAuditFilter(c)
+	if !ok {
+		return
+	}
+
+	filter.Limit = 100
+	if limit, err := strconv.Atoi(c.Query("limit")); err == nil && limit > 0 && limit <= 1000 {
+		filter.Limit = limit
+	}
+	if offset, err := strconv.Atoi(c.Query("offset")); err == nil && offset > 0 {
+		filter.Offset = offset
+	}
+
+	events, err := h.repo.List(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "fetch_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"events": events})
+}
+
+// Export streams the filtered audit events as CSV in chain order
+func (h *AuditHandler) Export(c *gin.Context) {
+	filter, ok := parseAuditFilter(c)
+	if !ok {
+		return
+	}
+
+	// The export is itself audited, before any data leaves
+	h.auditor.Record(c, entity.AuditEvent{Action: entity.AuditAuditExported, TargetType: "audit_log"}, nil, gin.H{
+		"filter": c.Request.URL.RawQuery,
+	})
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="audit-log.csv"`)
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	w.Write([]string{
+		"id", "created_at", "action", "actor_id", "impersonator_id", "target_type", "target_id",
+		"ip", "user_agent", "before", "after", "prev_hash", "hash",
+	})
+
+	err := h.repo.Stream(c.Request.Context(), filter, func(e *entity.AuditEvent) error {
+		return w.Write([]string{
+			strconv.FormatInt(e.ID, 10), e.CreatedAt.UTC().Format(time.RFC3339Nano), string(e.Action),
+			formatOptionalID(e.ActorID), formatOptionalID(e.ImpersonatorID), e.TargetType, e.TargetID, e.IP, e.UserAgent, string(e.Before), string(e.After), e.PrevHash, e.Hash,
+		})
+	})
+	w.Flush()
+	if err == nil {
+		err = w.Error()
+	}
+	if err != nil {
+		// Headers are already sent; the truncated body is the only signal left
+		log.Printf("export audit log: %v", err)
+		c.Abort()
+	}
+}
+
+// Verify walks the whole chain and reports the first entry whose hash or
+// link to its predecessor does not match
+func (h *AuditHandler) Verify(c *gin.Context) {
+	var checked int64
+	var broken *entity.AuditEvent
+	prevHash := ""
+
+	err := h.repo.Stream(c.Request.Context(), nil, func(e *entity.AuditEvent) error {
+		if e.PrevHash != prevHash || e.ComputeHash(e.PrevHash) != e.Hash {
+			broken = e
+			return errChainBroken
+		}
+		prevHash = e.Hash
+		checked++
+		return nil
+	})
+	if err != nil && err != errChainBroken {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "verify_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if broken != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"valid":     false,
+			"checked":   checked,
+			"broken_at": broken.ID,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"valid":   true,
+		"checked": checked,
+		"head":    prevHash,
+	})
+}
+
+var errChainBroken = errors.New("audit chain broken")
+
+func formatOptionalID(id *int64) string {
+	if id == nil {
+		return ""
+	}
+	return strconv.FormatInt(*id, 10)
+}
+
+func parseAuditFilter(c *gin.Context) (*repository.AuditFilter, bool) {
+	filter := &repository.AuditFilter{
+		Action:     entity.AuditAction(c.Query("action")),
+		TargetType: c.Query("target_type"),
+		TargetID:   c.Query("target_id"),
+	}
+
+	if raw := c.Query("actor_id"); raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "validation_error",
+				"message": "invalid actor_id",
+			})
+			return nil, false
+		}
+		filter.ActorID = &id
+	}
+
+	for param, dst := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		raw := c.Query(param)
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "validation_error",
+				"message": param + " must be an RFC 3339 timestamp",
+			})
+			return nil, false
+		}
+		*dst = &t
+	}
+
+	return filter, true
+}
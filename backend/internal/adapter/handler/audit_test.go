@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"watermap/internal/domain/entity"
+	"watermap/internal/domain/repository"
+)
+
+// chainRepo streams a fixed audit log in order
+type chainRepo struct {
+	repository.AuditLogRepository
+	events []*entity.AuditEvent
+}
+
+func (r *chainRepo) Stream(_ context.Context, _ *repository.AuditFilter, fn func(*entity.AuditEvent) error) error {
+	for _, e := range r.events {
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sealedChain returns n linked entries, every third one made while
+// impersonating
+func sealedChain(n int) []*entity.AuditEvent {
+	start := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	admin := int64(1)
+	events := make([]*entity.AuditEvent, n)
+	prev := ""
+	for i := range events {
+		actor := int64(10 + i)
+		e := &entity.AuditEvent{
+			ID:         int64(i + 1),
+			Action:     entity.AuditObjectApproved,
+			ActorID:    &actor,
+			TargetType: "water_object",
+			TargetID:   "42",
+			IP:         "10.0.0.1",
+			UserAgent:  "test",
+			Before:     json.RawMessage(`{"status":"pending"}`),
+			After:      json.RawMessage(`{"status":"published"}`),
+			CreatedAt:  start.Add(time.Duration(i) * time.Minute),
+		}
+		if i%3 == 2 {
+			e.ImpersonatorID = &admin
+		}
+		e.Seal(prev)
+		prev = e.Hash
+		events[i] = e
+	}
+	return events
+}
+
+func TestAuditVerify(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	other := int64(99)
+
+	tests := []struct {
+		name     string
+		tamper   func([]*entity.AuditEvent) []*entity.AuditEvent
+		valid    bool
+		checked  float64
+		brokenAt float64
+	}{
+		{"intact", func(e []*entity.AuditEvent) []*entity.AuditEvent { return e }, true, 5, 0},
+		{"empty log", func([]*entity.AuditEvent) []*entity.AuditEvent { return nil }, true, 0, 0},
+		{"edited payload", func(e []*entity.AuditEvent) []*entity.AuditEvent {
+			e[2].After = json.RawMessage(`{"status":"rejected"}`)
+			return e
+		}, false, 2, 3},
+		{"edited actor", func(e []*entity.AuditEvent) []*entity.AuditEvent {
+			e[1].ActorID = &other
+			return e
+		}, false, 1, 2},
+		{"impersonation removed", func(e []*entity.AuditEvent) []*entity.AuditEvent {
+			e[2].ImpersonatorID = nil
+			return e
+		}, false, 2, 3},
+		{"deleted entry", func(e []*entity.AuditEvent) []*entity.AuditEvent {
+			return append(e[:1], e[2:]...)
+		}, false, 1, 3},
+		{"reordered entries", func(e []*entity.AuditEvent) []*entity.AuditEvent {
+			e[3], e[4] = e[4], e[3]
+			return e
+		}, false, 3, 5},
+		{"edited entry resealed", func(e []*entity.AuditEvent) []*entity.AuditEvent {
+			// Rehashing the edited entry still breaks the link of the next
+			e[1].IP = "192.0.2.1"
+			e[1].Seal(e[1].PrevHash)
+			return e
+		}, false, 2, 3},
+		{"first entry with a predecessor", func(e []*entity.AuditEvent) []*entity.AuditEvent {
+			return e[1:]
+		}, false, 0, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := tt.tamper(sealedChain(5))
+			h := NewAuditHandler(&chainRepo{events: events}, nil)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/api/admin/audit/verify", nil)
+			h.Verify(c)
+
+			var body map[string]interface{}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || w.Code != http.StatusOK {
+				t.Fatalf("Verify = %d %s", w.Code, w.Body)
+			}
+			if body["valid"] != tt.valid || body["checked"] != tt.checked {
+				t.Errorf("valid, checked = %v, %v; want %v, %v", body["valid"], body["checked"], tt.valid, tt.checked)
+			}
+			if !tt.valid && body["broken_at"] != tt.brokenAt {
+				t.Errorf("broken_at = %v, want %v", body["broken_at"], tt.brokenAt)
+			}
+			if tt.valid && len(events) > 0 && body["head"] != events[len(events)-1].Hash {
+				t.Errorf("head = %v, want the last hash", body["head"])
+			}
+		})
+	}
+}
@@ -1,29 +1,59 @@
 package handler
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 
+	"watermap/internal/adapter/middleware"
 	"watermap/internal/domain/entity"
 	"watermap/internal/domain/repository"
+	"watermap/internal/infrastructure/mailer"
 )
 
 type AuthHandler struct {
-	userRepo  repository.UserRepository
-	jwtSecret string
+	userRepo      repository.UserRepository
+	sessionRepo   repository.SessionRepository
+	twoFactorRepo repository.TwoFactorRepository
+	tokenRepo     repository.UserTokenRepository
+	mailer        mailer.Mailer
+	audit         *Auditor
+	jwtSecret     string
+	clientURL     string
+	accessTTL     time.Duration
+	refreshTTL    time.Duration
+	maxSessionAge time.Duration
 }
 
-func NewAuthHandler(userRepo repository.UserRepository, jwtSecret string) *AuthHandler {
+func NewAuthHandler(userRepo repository.UserRepository, sessionRepo repository.SessionRepository, twoFactorRepo repository.TwoFactorRepository, tokenRepo repository.UserTokenRepository, mail mailer.Mailer, auditor *Auditor, jwtSecret, clientURL string, accessTTL, refreshTTL, maxSessionAge time.Duration) *AuthHandler {
 	return &AuthHandler{
-		userRepo:  userRepo,
-		jwtSecret: jwtSecret,
+		userRepo:      userRepo,
+		sessionRepo:   sessionRepo,
+		twoFactorRepo: twoFactorRepo,
+		tokenRepo:     tokenRepo,
+		mailer:        mail,
+		audit:         auditor,
+		jwtSecret:     jwtSecret,
+		clientURL:     strings.TrimSuffix(clientURL, "/"),
+		accessTTL:     accessTTL,
+		refreshTTL:    refreshTTL,
+		maxSessionAge: maxSessionAge,
 	}
 }
 
+const refreshCookie = "refresh_token"
+
 type RegisterRequest struct {
 	Name     string `json:"name" binding:"required"`
 	Email    string `json:"email" binding:"required,email"`
@@ -35,10 +65,16 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
-func (h *AuthHandler) generateToken(userID int64) (string, error) {
+func (h *AuthHandler) generateToken(userID int64, sessionID uuid.UUID) (string, error) {
+	return h.signAccessToken(userID, sessionID, h.accessTTL)
+}
+
+func (h *AuthHandler) signAccessToken(userID int64, sessionID uuid.UUID, ttl time.Duration) (string, error) {
 	claims := jwt.MapClaims{
 		"id":  userID,
-		"exp": time.Now().Add(24 * time.Hour).Unix(),
+		"sid": sessionID.String(),
+		"typ": entity.TokenTypeAccess,
+		"exp": time.Now().Add(ttl).Unix(),
 		"iat": time.Now().Unix(),
 	}
 
@@ -46,12 +82,27 @@ func (h *AuthHandler) generateToken(userID int64) (string, error) {
 	return token.SignedString([]byte(h.jwtSecret))
 }
 
+// newRefreshToken returns a random refresh token and the hash stored for it
+func newRefreshToken() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
 func (h *AuthHandler) setTokenCookie(c *gin.Context, token string) {
 	c.SetSameSite(http.SameSiteLaxMode)
 	c.SetCookie(
 		"token",
 		token,
-		24*60*60, // 1 day
+		int(h.accessTTL.Seconds()),
 		"/",
 		"",
 		false, // secure (set to true in production with HTTPS)
@@ -59,6 +110,61 @@ func (h *AuthHandler) setTokenCookie(c *gin.Context, token string) {
 	)
 }
 
+func (h *AuthHandler) setRefreshCookie(c *gin.Context, token string) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(
+		refreshCookie,
+		token,
+		int(h.refreshTTL.Seconds()),
+		"/api/auth",
+		"",
+		false, // secure (set to true in production with HTTPS)
+		true,  // httpOnly
+	)
+}
+
+func (h *AuthHandler) clearCookies(c *gin.Context) {
+	c.SetCookie("token", "", -1, "/", "", false, true)
+	c.SetCookie(refreshCookie, "", -1, "/api/auth", "", false, true)
+}
+
+// startSession creates a server-side session for the user, sets the access
+// and refresh cookies and returns the access token
+func (h *AuthHandler) startSession(c *gin.Context, user *entity.User) (string, error) {
+	refresh, refreshHash, err := newRefreshToken()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	session, err := h.sessionRepo.Create(c.Request.Context(), &entity.Session{
+		UserID:    user.ID,
+		UserAgent: c.Request.UserAgent(),
+		IP:        c.ClientIP(),
+		ExpiresAt: now.Add(h.refreshTTL),
+	}, &entity.RefreshToken{
+		TokenHash: refreshHash,
+		ExpiresAt: now.Add(h.refreshTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	token, err := h.generateToken(user.ID, session.ID)
+	if err != nil {
+		return "", err
+	}
+
+	// Every new session is a completed sign-in, whichever flow created it
+	event := auditUser(entity.AuditLogin, user.ID)
+	event.ActorID = &user.ID
+	h.audit.Record(c, event, nil, gin.H{"session_id": session.ID})
+
+	h.setTokenCookie(c, token)
+	h.setRefreshCookie(c, refresh)
+	return token, nil
+}
+
 // Register creates a new user
 func (h *AuthHandler) Register(c *gin.Context) {
 	var req RegisterRequest
@@ -106,17 +212,15 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
-	token, err := h.generateToken(createdUser.ID)
-	if err != nil {
+	// The account can sign in once the emailed link has been followed
+	if err := h.sendVerification(c, createdUser); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "token_failed",
-			"message": "failed to generate token",
+			"error":   "mail_failed",
+			"message": "failed to send verification email",
 		})
 		return
 	}
 
-	h.setTokenCookie(c, token)
-
 	c.JSON(http.StatusCreated, gin.H{
 		"user": gin.H{
 			"id":    createdUser.ID,
@@ -124,6 +228,8 @@ func (h *AuthHandler) Register(c *gin.Context) {
 			"email": createdUser.Email,
 			"role":  createdUser.Role,
 		},
+		"verification_required": true,
+		"message":               "check your email to verify your account",
 	})
 }
 
@@ -140,6 +246,10 @@ func (h *AuthHandler) Login(c *gin.Context) {
 
 	user, err := h.userRepo.GetByEmail(c.Request.Context(), req.Email)
 	if err != nil {
+		h.audit.Record(c, entity.AuditEvent{Action: entity.AuditLoginFailed}, nil, gin.H{
+			"email":  req.Email,
+			"reason": "unknown_email",
+		})
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"error":   "invalid_credentials",
 			"message": "invalid email or password",
@@ -147,7 +257,14 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	if wait := user.LockedFor(time.Now()); wait > 0 {
+		h.audit.Record(c, auditUser(entity.AuditLoginFailed, user.ID), nil, gin.H{"reason": "locked"})
+		middleware.TooManyRequests(c, wait, "account_locked", accountLockedMessage)
+		return
+	}
+
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		h.recordLoginFailure(c, user, "password")
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"error":   "invalid_credentials",
 			"message": "invalid email or password",
@@ -155,7 +272,42 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	token, err := h.generateToken(user.ID)
+	if !user.IsActive() {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "account_inactive",
+			"message": entity.ErrAccountInactive.Error(),
+		})
+		return
+	}
+	if !user.IsEmailVerified() {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "email_unverified",
+			"message": entity.ErrEmailUnverified.Error(),
+		})
+		return
+	}
+
+	challenge, enroll, err := h.mfaChallenge(c.Request.Context(), user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "login_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+	if challenge != "" {
+		c.JSON(http.StatusOK, gin.H{
+			"mfa_required":        true,
+			"enrollment_required": enroll,
+			"challenge":           challenge,
+			"expires_in":          int(mfaChallengeTTL.Seconds()),
+		})
+		return
+	}
+
+	h.resetLoginFailures(c, user)
+
+	token, err := h.startSession(c, user)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "token_failed",
@@ -164,8 +316,6 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	h.setTokenCookie(c, token)
-
 	c.JSON(http.StatusOK, gin.H{
 		"user": gin.H{
 			"id":    user.ID,
@@ -173,15 +323,217 @@ func (h *AuthHandler) Login(c *gin.Context) {
 			"email": user.Email,
 			"role":  user.Role,
 		},
+		"access_token": token,
+		"expires_in":   int(h.accessTTL.Seconds()),
 	})
 }
 
-// Logout clears the auth cookie
+// mfaChallenge returns a challenge when the user must finish signing in
+// with a one-time code, and whether they have to enrol first; experts,
+// admins and anyone who opted in do. It returns "" when the first factor
+// is enough.
+func (h *AuthHandler) mfaChallenge(ctx context.Context, user *entity.User) (string, bool, error) {
+	tf, err := h.twoFactorRepo.Get(ctx, user.ID)
+	if err != nil && err != entity.ErrNotFound {
+		return "", false, err
+	}
+	if !tf.IsEnabled() && !user.Role.RequiresTwoFactor() {
+		return "", false, nil
+	}
+	challenge, err := h.generateChallenge(user.ID, !tf.IsEnabled())
+	if err != nil {
+		return "", false, err
+	}
+	return challenge, !tf.IsEnabled(), nil
+}
+
+// recordLoginFailure counts and audits a failed sign-in and locks the
+// account once the failures pass the lockout threshold
+func (h *AuthHandler) recordLoginFailure(c *gin.Context, user *entity.User, reason string) {
+	failures, err := h.userRepo.RecordLoginFailure(c.Request.Context(), user.ID)
+	if err != nil {
+		log.Printf("record login failure for user %d: %v", user.ID, err)
+		return
+	}
+
+	h.audit.Record(c, auditUser(entity.AuditLoginFailed, user.ID), nil, gin.H{
+		"reason":   reason,
+		"failures": failures,
+	})
+
+	if d := entity.LockoutDuration(failures); d > 0 {
+		until := time.Now().Add(d)
+		if err := h.userRepo.LockUntil(c.Request.Context(), user.ID, until); err != nil {
+			log.Printf("lock user %d: %v", user.ID, err)
+			return
+		}
+		h.audit.Record(c, auditUser(entity.AuditAccountLocked, user.ID), nil, gin.H{"locked_until": until})
+	}
+}
+
+func (h *AuthHandler) resetLoginFailures(c *gin.Context, user *entity.User) {
+	if user.FailedLogins == 0 && user.LockedUntil == nil {
+		return
+	}
+	if err := h.userRepo.ResetLoginFailures(c.Request.Context(), user.ID); err != nil {
+		log.Printf("reset login failures for user %d: %v", user.ID, err)
+	}
+}
+
+// accountLockedMessage explains the 429 of a locked account
+const accountLockedMessage = "too many failed sign-in attempts, try again later"
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// refreshTokenFromRequest reads the refresh token from its cookie or, for
+// non-browser clients, from the JSON body
+func refreshTokenFromRequest(c *gin.Context) string {
+	if cookie, err := c.Cookie(refreshCookie); err == nil && cookie != "" {
+		return cookie
+	}
+
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err == nil {
+		return req.RefreshToken
+	}
+	return ""
+}
+
+// Refresh rotates the refresh token and issues a new access token
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	presented := refreshTokenFromRequest(c)
+	if presented == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "unauthorized",
+			"message": "no refresh token provided",
+		})
+		return
+	}
+
+	refresh, refreshHash, err := newRefreshToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "token_failed",
+			"message": "failed to generate token",
+		})
+		return
+	}
+
+	now := time.Now()
+	session, err := h.sessionRepo.Rotate(c.Request.Context(), hashToken(presented), &entity.RefreshToken{
+		TokenHash: refreshHash,
+		ExpiresAt: now.Add(h.refreshTTL),
+	}, now, h.maxSessionAge)
+	if err != nil {
+		h.clearCookies(c)
+		switch err {
+		case entity.ErrTokenReused:
+			h.audit.Record(c, entity.AuditEvent{Action: entity.AuditTokenReused, TargetType: "session"}, nil, nil)
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error":   "token_reused",
+				"message": "refresh token was already used; the session has been revoked",
+			})
+		case entity.ErrNotFound, entity.ErrSessionRevoked:
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error":   "unauthorized",
+				"message": "session expired or revoked",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "refresh_failed",
+				"message": err.Error(),
+			})
+		}
+		return
+	}
+
+	token, err := h.generateToken(session.UserID, session.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "token_failed",
+			"message": "failed to generate token",
+		})
+		return
+	}
+
+	h.setTokenCookie(c, token)
+	h.setRefreshCookie(c, refresh)
+
+	c.JSON(http.StatusOK, gin.H{
+		"access_token": token,
+		"expires_in":   int(h.accessTTL.Seconds()),
+	})
+}
+
+// Logout revokes the current session and clears the auth cookies
 func (h *AuthHandler) Logout(c *gin.Context) {
-	c.SetCookie("token", "", -1, "/", "", false, true)
+	if presented := refreshTokenFromRequest(c); presented != "" {
+		sessionID, err := h.sessionRepo.GetSessionIDByTokenHash(c.Request.Context(), hashToken(presented))
+		if err == nil {
+			if err := h.sessionRepo.Revoke(c.Request.Context(), sessionID, entity.RevokeReasonLogout); err != nil && err != entity.ErrNotFound {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error":   "logout_failed",
+					"message": err.Error(),
+				})
+				return
+			}
+			h.audit.Record(c, entity.AuditEvent{
+				Action:     entity.AuditLogout,
+				TargetType: "session",
+				TargetID:   sessionID.String(),
+			}, nil, nil)
+		}
+	}
+
+	h.clearCookies(c)
 	c.JSON(http.StatusOK, gin.H{"message": "logged out"})
 }
 
+// LogoutAll revokes every session of the current user
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	userID := c.GetInt64("user_id")
+
+	revoked, err := h.sessionRepo.RevokeAllForUser(c.Request.Context(), userID, entity.RevokeReasonLogoutAll)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "logout_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+	h.audit.Record(c, auditUser(entity.AuditSessionsRevoked, userID), nil, gin.H{
+		"reason":  entity.RevokeReasonLogoutAll,
+		"revoked": revoked,
+	})
+
+	h.clearCookies(c)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "logged out everywhere",
+		"revoked": revoked,
+	})
+}
+
+// GetSessions lists the current user's active sessions
+func (h *AuthHandler) GetSessions(c *gin.Context) {
+	userID := c.GetInt64("user_id")
+
+	sessions, err := h.sessionRepo.GetActiveByUser(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "fetch_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sessions": sessions,
+		"current":  c.GetString("session_id"),
+	})
+}
+
 // Me returns the current user
 func (h *AuthHandler) Me(c *gin.Context) {
 	user, exists := c.Get("user")
@@ -194,10 +546,15 @@ func (h *AuthHandler) Me(c *gin.Context) {
 	}
 
 	u := user.(*entity.User)
-	c.JSON(http.StatusOK, gin.H{
-		"id":    u.ID,
-		"name":  u.Name,
-		"email": u.Email,
-		"role":  u.Role,
-	})
+	resp := gin.H{
+		"id":          u.ID,
+		"name":        u.Name,
+		"email":       u.Email,
+		"role":        u.Role,
+		"permissions": permissionsFrom(c),
+	}
+	if impersonator, ok := c.Get("impersonator_id"); ok {
+		resp["impersonator_id"] = impersonator
+	}
+	c.JSON(http.StatusOK, resp)
 }
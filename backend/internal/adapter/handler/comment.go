@@ -0,0 +1,351 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"watermap/internal/domain/entity"
+	"watermap/internal/domain/repository"
+)
+
+type CommentHandler struct {
+	repo            repository.CommentRepository
+	waterObjectRepo repository.WaterObjectRepository
+	orgRepo         repository.OrganizationRepository
+	notify          *Notifier
+}
+
+func NewCommentHandler(repo repository.CommentRepository, waterObjectRepo repository.WaterObjectRepository, orgRepo repository.OrganizationRepository, notifier *Notifier) *CommentHandler {
+	return &CommentHandler{
+		repo:            repo,
+		waterObjectRepo: waterObjectRepo,
+		orgRepo:         orgRepo,
+		notify:          notifier,
+	}
+}
+
+// List returns the discussion of ?object_id as threads, covering every
+// version of the object
+func (h *CommentHandler) List(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Query("object_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_id",
+			"message": "object_id is required",
+		})
+		return
+	}
+
+	obj, ok := h.discussable(c, id)
+	if !ok {
+		return
+	}
+
+	comments, err := h.repo.ListByCanonicalID(c.Request.Context(), obj.CanonicalID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "fetch_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"threads": entity.Threads(comments)})
+}
+
+type CreateCommentRequest struct {
+	ObjectID int64                 `json:"object_id" binding:"required"`
+	Body     string                `json:"body" binding:"required,max=5000"`
+	ParentID *int64                `json:"parent_id"`
+	Anchor   *entity.CommentAnchor `json:"anchor"`
+}
+
+// Create starts a thread, optionally anchored to a field, a coordinate or a
+// geometry vertex, or replies to one
+func (h *CommentHandler) Create(c *gin.Context) {
+	var req CreateCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	body := strings.TrimSpace(req.Body)
+	if body == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": "body is required",
+		})
+		return
+	}
+
+	obj, ok := h.discussable(c, req.ObjectID)
+	if !ok {
+		return
+	}
+
+	userID := c.GetInt64("user_id")
+	comment := &entity.ReviewComment{
+		CanonicalID:   obj.CanonicalID,
+		ObjectID:      &obj.ID,
+		ObjectVersion: obj.Version,
+		AuthorID:      &userID,
+		Body:          body,
+	}
+
+	ctx := c.Request.Context()
+	if req.ParentID != nil {
+		if req.Anchor != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "validation_error",
+				"message": "replies take the anchor of their thread",
+			})
+			return
+		}
+
+		parent, err := h.repo.GetByID(ctx, *req.ParentID)
+		if err != nil || parent.CanonicalID != obj.CanonicalID {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "validation_error",
+				"message": "parent comment not found on this object",
+			})
+			return
+		}
+		// Threads are one level deep; replies to replies join the thread
+		root := parent.ID
+		if parent.ParentID != nil {
+			root = *parent.ParentID
+		}
+		comment.ParentID = &root
+	}
+
+	if req.Anchor != nil {
+		if err := req.Anchor.Resolve(obj); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "validation_error",
+				"message": err.Error(),
+				"fields":  entity.CommentableFields,
+			})
+			return
+		}
+		comment.Anchor = req.Anchor
+	}
+
+	created, err := h.repo.Create(ctx, comment)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "create_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.notify.CommentAdded(c, obj, created, h.participants(c, created))
+
+	c.JSON(http.StatusCreated, created)
+}
+
+type UpdateCommentRequest struct {
+	Body string `json:"body" binding:"required,max=5000"`
+}
+
+// Update edits the text of the user's own comment
+func (h *CommentHandler) Update(c *gin.Context) {
+	comment, ok := h.loadOwnComment(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Body) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": "body is required",
+		})
+		return
+	}
+
+	if err := h.repo.UpdateBody(c.Request.Context(), comment.ID, strings.TrimSpace(req.Body)); err != nil {
+		commentError(c, err, "update_failed")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "comment updated"})
+}
+
+// Delete removes the user's own comment. A thread with replies stays; it
+// can be resolved instead.
+func (h *CommentHandler) Delete(c *gin.Context) {
+	comment, ok := h.loadOwnComment(c)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.Delete(c.Request.Context(), comment.ID); err != nil {
+		if err == entity.ErrHasReplies {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "has_replies",
+				"message": "a comment with replies cannot be deleted; resolve the thread instead",
+			})
+			return
+		}
+		commentError(c, err, "delete_failed")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "comment deleted"})
+}
+
+// Resolve marks a thread as resolved
+func (h *CommentHandler) Resolve(c *gin.Context) {
+	userID := c.GetInt64("user_id")
+	h.setResolved(c, &userID)
+}
+
+// Reopen marks a resolved thread as open again
+func (h *CommentHandler) Reopen(c *gin.Context) {
+	h.setResolved(c, nil)
+}
+
+func (h *CommentHandler) setResolved(c *gin.Context, resolvedBy *int64) {
+	comment, ok := h.loadComment(c)
+	if !ok {
+		return
+	}
+	if comment.ParentID != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": entity.ErrNotThreadRoot.Error(),
+		})
+		return
+	}
+
+	if err := h.repo.SetResolved(c.Request.Context(), comment.ID, resolvedBy); err != nil {
+		commentError(c, err, "update_failed")
+		return
+	}
+
+	if resolvedBy == nil {
+		c.JSON(http.StatusOK, gin.H{"message": "thread reopened"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "thread resolved"})
+}
+
+// discussable loads the object and checks that the user may take part in
+// its review: reviewers covering it, its author and its organization's
+// members
+func (h *CommentHandler) discussable(c *gin.Context, id int64) (*entity.WaterObject, bool) {
+	obj, err := h.waterObjectRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		if err == entity.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"message": "object not found",
+			})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "fetch_failed",
+			"message": err.Error(),
+		})
+		return nil, false
+	}
+
+	if permissionsFrom(c).Allows(entity.PermObjectsReview, obj) || isEditor(c, h.orgRepo, obj) {
+		return obj, true
+	}
+
+	c.JSON(http.StatusForbidden, gin.H{
+		"error":   "forbidden",
+		"message": "only the author, their organization and reviewers can discuss this object",
+	})
+	return nil, false
+}
+
+// loadComment reads the :id parameter, fetches the comment and checks
+// access to the object it was made on
+func (h *CommentHandler) loadComment(c *gin.Context) (*entity.ReviewComment, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_id",
+			"message": "invalid comment id",
+		})
+		return nil, false
+	}
+
+	comment, err := h.repo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		commentError(c, err, "fetch_failed")
+		return nil, false
+	}
+
+	if comment.ObjectID == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "not_found",
+			"message": "the object this comment was made on has been deleted",
+		})
+		return nil, false
+	}
+	if _, ok := h.discussable(c, *comment.ObjectID); !ok {
+		return nil, false
+	}
+	return comment, true
+}
+
+// loadOwnComment is loadComment for changes only the author may make
+func (h *CommentHandler) loadOwnComment(c *gin.Context) (*entity.ReviewComment, bool) {
+	comment, ok := h.loadComment(c)
+	if !ok {
+		return nil, false
+	}
+	if comment.AuthorID == nil || *comment.AuthorID != c.GetInt64("user_id") {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "forbidden",
+			"message": "only the author can change a comment",
+		})
+		return nil, false
+	}
+	return comment, true
+}
+
+// participants returns the authors of the comments in the thread
+func (h *CommentHandler) participants(c *gin.Context, comment *entity.ReviewComment) []int64 {
+	if comment.ParentID == nil {
+		return nil
+	}
+
+	comments, err := h.repo.ListByCanonicalID(c.Request.Context(), comment.CanonicalID)
+	if err != nil {
+		return nil
+	}
+
+	var ids []int64
+	for _, other := range comments {
+		inThread := other.ID == *comment.ParentID || (other.ParentID != nil && *other.ParentID == *comment.ParentID)
+		if inThread && other.AuthorID != nil {
+			ids = append(ids, *other.AuthorID)
+		}
+	}
+	return ids
+}
+
+func commentError(c *gin.Context, err error, code string) {
+	if err == entity.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "not_found",
+			"message": "comment not found",
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error":   code,
+		"message": err.Error(),
+	})
+}
@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"watermap/internal/domain/entity"
+	"watermap/internal/domain/repository"
+	"watermap/internal/infrastructure/duplicate"
+)
+
+// DuplicateChecker looks for published and pending objects that a
+// submission may duplicate
+type DuplicateChecker struct {
+	objects  repository.WaterObjectRepository
+	repo     repository.DuplicateRepository
+	detector *duplicate.Detector
+}
+
+func NewDuplicateChecker(objects repository.WaterObjectRepository, repo repository.DuplicateRepository, detector *duplicate.Detector) *DuplicateChecker {
+	return &DuplicateChecker{objects: objects, repo: repo, detector: detector}
+}
+
+// Check compares the object with the objects it may duplicate, stores the
+// likely duplicates in place of earlier findings and returns them
+func (d *DuplicateChecker) Check(ctx context.Context, obj *entity.WaterObject) ([]*entity.DuplicateMatch, error) {
+	area, err := d.detector.SearchArea(obj)
+	if err != nil {
+		return nil, err
+	}
+	candidates, err := d.objects.GetDuplicateCandidates(ctx, entity.ComparableTypes(obj.ObjectType), area)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.repo.Replace(ctx, obj.ID, d.detector.Find(obj, candidates)); err != nil {
+		return nil, err
+	}
+	return d.repo.ListForObject(ctx, obj.ID)
+}
+
+// Transitioned checks objects as they are submitted for review. Failures
+// are logged and never fail the submission.
+func (d *DuplicateChecker) Transitioned(ctx context.Context, change *entity.StatusChange) {
+	if change.Transition != entity.TransitionSubmit {
+		return
+	}
+	if _, err := d.Check(ctx, change.Object); err != nil {
+		log.Printf("duplicate check of object %d: %v", change.Object.ID, err)
+	}
+}
+
+// CheckDuplicates runs the duplicate check of a pending object again, for
+// submissions that predate objects published since
+func (h *AdminHandler) CheckDuplicates(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_id",
+			"message": "invalid object id",
+		})
+		return
+	}
+
+	obj, ok := objectAllowed(c, h.waterObjectRepo, id, entity.PermObjectsReview)
+	if !ok {
+		return
+	}
+
+	matches, err := h.duplicates.Check(c.Request.Context(), obj)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "check_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"duplicates": matches})
+}
+
+type MergeRequest struct {
+	Into int64 `json:"into" binding:"required"`
+}
+
+// Merge folds the history of an object into the object given by Into, for
+// duplicates describing the same water body. The merged versions follow
+// the target's own, and the old canonical id redirects to the target.
+// Merging rewrites the history of both objects, so it takes an admin.
+func (h *AdminHandler) Merge(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_id",
+			"message": "invalid object id",
+		})
+		return
+	}
+
+	var req MergeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	source, ok := objectAllowed(c, h.waterObjectRepo, id, entity.PermObjectsReview)
+	if !ok {
+		return
+	}
+	target, ok := objectAllowed(c, h.waterObjectRepo, req.Into, entity.PermObjectsReview)
+	if !ok {
+		return
+	}
+	if source.CanonicalID == target.CanonicalID {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "self_merge",
+			"message": entity.ErrSelfMerge.Error(),
+		})
+		return
+	}
+
+	if err := h.waterObjectRepo.Merge(c.Request.Context(), source.CanonicalID, target.CanonicalID, c.GetInt64("user_id")); err != nil {
+		if err == entity.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"message": "water object not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "merge_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.audit.Record(c, auditObject(entity.AuditObjectsMerged, id),
+		gin.H{"canonical_id": source.CanonicalID},
+		gin.H{"canonical_id": target.CanonicalID, "into": target.ID})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "objects merged",
+		"canonical_id": target.CanonicalID,
+	})
+}
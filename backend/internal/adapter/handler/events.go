@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"watermap/internal/domain/entity"
+	"watermap/internal/infrastructure/eventbus"
+)
+
+const (
+	// eventHeartbeat keeps idle streams open through proxies
+	eventHeartbeat = 25 * time.Second
+	// eventBuffer is how many events a slow client may lag behind before
+	// it is disconnected and left to resume
+	eventBuffer = 64
+	// eventReplayPage is the page size used when replaying missed events
+	eventReplayPage = 200
+	// eventWindow is how many delivered IDs a stream remembers to skip
+	// duplicates while still taking events that commit out of ID order
+	eventWindow = 1024
+)
+
+// deliveredEvents tracks the events a stream has sent. Event IDs are taken
+// when a transaction inserts them but become visible when it commits, so a
+// lower ID can arrive after a higher one; a plain high-water mark would drop
+// it. IDs at or below floor count as delivered, above it the recent ones
+// are remembered individually.
+type deliveredEvents struct {
+	floor int64
+	// ids holds the remembered IDs above floor, in ascending order
+	ids []int64
+	// last is the highest ID delivered, the position a client resumes from
+	last int64
+}
+
+func (d *deliveredEvents) has(id int64) bool {
+	if id <= d.floor {
+		return true
+	}
+	_, found := slices.BinarySearch(d.ids, id)
+	return found
+}
+
+func (d *deliveredEvents) add(id int64) {
+	i, found := slices.BinarySearch(d.ids, id)
+	if found || id <= d.floor {
+		return
+	}
+	d.ids = slices.Insert(d.ids, i, id)
+	if len(d.ids) > eventWindow {
+		// Forget the oldest; anything below it is too late to be waited for
+		d.floor = d.ids[0]
+		d.ids = d.ids[1:]
+	}
+	d.last = max(d.last, id)
+}
+
+type EventHandler struct {
+	bus *eventbus.Bus
+}
+
+func NewEventHandler(bus *eventbus.Bus) *EventHandler {
+	return &EventHandler{bus: bus}
+}
+
+// Stream sends workflow events as Server-Sent Events. Clients resume after
+// a reconnect by sending the last ID they received in Last-Event-ID (or
+// ?last_event_id); a "reset" event means the gap can no longer be replayed
+// and the client should reload its data.
+func (h *EventHandler) Stream(c *gin.Context) {
+	userID := c.GetInt64("user_id")
+	perms := permissionsFrom(c)
+
+	lastID, _ := strconv.ParseInt(c.GetHeader("Last-Event-ID"), 10, 64)
+	if lastID == 0 {
+		lastID, _ = strconv.ParseInt(c.Query("last_event_id"), 10, 64)
+	}
+	delivered := &deliveredEvents{floor: lastID, last: lastID}
+
+	// Subscribe before replaying so nothing published in between is lost;
+	// duplicates are skipped by the delivered window
+	sub := h.bus.Subscribe(eventBuffer)
+	defer h.bus.Unsubscribe(sub)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	rc := http.NewResponseController(c.Writer)
+	write := func(format string, args ...interface{}) bool {
+		// The server's write timeout would otherwise end the stream
+		if err := rc.SetWriteDeadline(time.Now().Add(2 * eventHeartbeat)); err != nil && err != http.ErrNotSupported {
+			return false
+		}
+		if _, err := fmt.Fprintf(c.Writer, format, args...); err != nil {
+			return false
+		}
+		return rc.Flush() == nil
+	}
+	send := func(event *entity.Event) bool {
+		if delivered.has(event.ID) {
+			return true
+		}
+		delivered.add(event.ID)
+		if !event.VisibleTo(userID, perms) {
+			return true
+		}
+		data, err := json.Marshal(event)
+		if err != nil {
+			return true
+		}
+		// The id field is where the client resumes, so it never goes back
+		return write("id: %d\nevent: %s\ndata: %s\n\n", delivered.last, event.Type, data)
+	}
+
+	if !write("retry: %d\n\n", 3000) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	for after := lastID; after > 0; {
+		events, ok, err := h.bus.Since(ctx, after, eventReplayPage)
+		if err != nil {
+			return
+		}
+		if !ok {
+			write("event: reset\ndata: {}\n\n")
+			break
+		}
+		for _, event := range events {
+			if !send(event) {
+				return
+			}
+		}
+		if len(events) < eventReplayPage {
+			break
+		}
+		after = events[len(events)-1].ID
+	}
+
+	heartbeat := time.NewTicker(eventHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-sub.C:
+			if !ok || !send(event) {
+				return
+			}
+		case <-heartbeat.C:
+			if !write(": ping\n\n") {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"watermap/internal/domain/entity"
+)
+
+const (
+	contentTypeGeoJSON    = "application/json; charset=utf-8"
+	contentTypeGeoJSONSeq = "application/geo+json-seq"
+
+	// recordSeparator prefixes every text in a GeoJSON text sequence (RFC 8142)
+	recordSeparator = 0x1E
+
+	// flushEvery controls how many features are written between flushes
+	flushEvery = 100
+
+	// flushTimeout is how long writing may take until the next flush; each
+	// flush pushes the deadline further, so a long export outlives the
+	// server's write timeout
+	flushTimeout = 30 * time.Second
+)
+
+// wantsGeoJSONSeq reports whether the client asked for a GeoJSON text sequence
+// either through the Accept header or the format=geojsonseq query parameter
+func wantsGeoJSONSeq(c *gin.Context) bool {
+	if c.Query("format") == "geojsonseq" {
+		return true
+	}
+	return strings.Contains(c.GetHeader("Accept"), contentTypeGeoJSONSeq)
+}
+
+// featureWriter streams GeoJSON features to the response. Headers are only
+// written with the first feature (or on Close), so an error raised before any
+// row has been scanned can still be reported with a proper status code.
+type featureWriter struct {
+	c       *gin.Context
+	w       *bufio.Writer
+	rc      *http.ResponseController
+	seq     bool
+	started bool
+	count   int
+}
+
+func newFeatureWriter(c *gin.Context, seq bool) *featureWriter {
+	return &featureWriter{
+		c:   c,
+		w:   bufio.NewWriterSize(c.Writer, 32*1024),
+		rc:  http.NewResponseController(c.Writer),
+		seq: seq,
+	}
+}
+
+func (fw *featureWriter) start() {
+	if fw.started {
+		return
+	}
+	fw.started = true
+	fw.extendDeadline()
+
+	if fw.seq {
+		fw.c.Header("Content-Type", contentTypeGeoJSONSeq)
+	} else {
+		fw.c.Header("Content-Type", contentTypeGeoJSON)
+	}
+	fw.c.Status(http.StatusOK)
+
+	if !fw.seq {
+		fw.w.WriteString(`{"type":"FeatureCollection","features":[`)
+	}
+}
+
+// WriteFeature encodes a single feature and periodically flushes it to the client
+func (fw *featureWriter) WriteFeature(feature interface{}) error {
+	data, err := json.Marshal(feature)
+	if err != nil {
+		return err
+	}
+
+	fw.start()
+
+	if fw.seq {
+		fw.w.WriteByte(recordSeparator)
+		fw.w.Write(data)
+		fw.w.WriteByte('\n')
+	} else {
+		if fw.count > 0 {
+			fw.w.WriteByte(',')
+		}
+		fw.w.Write(data)
+	}
+	fw.count++
+
+	if fw.count%flushEvery == 0 {
+		if err := fw.w.Flush(); err != nil {
+			return err
+		}
+		fw.c.Writer.Flush()
+		fw.extendDeadline()
+	}
+	return nil
+}
+
+// extendDeadline gives the stream another flushTimeout to write in.
+// Writers without deadlines keep the server's, so the error is ignored.
+func (fw *featureWriter) extendDeadline() {
+	_ = fw.rc.SetWriteDeadline(time.Now().Add(flushTimeout))
+}
+
+// Started reports whether the response headers have already been sent
+func (fw *featureWriter) Started() bool {
+	return fw.started
+}
+
+// Close terminates the collection and flushes any buffered output
+func (fw *featureWriter) Close() error {
+	fw.start()
+
+	if !fw.seq {
+		metadata, err := json.Marshal(gin.H{"total": fw.count})
+		if err != nil {
+			return err
+		}
+		fw.w.WriteString(`],"metadata":`)
+		fw.w.Write(metadata)
+		fw.w.WriteByte('}')
+	}
+
+	if err := fw.w.Flush(); err != nil {
+		return err
+	}
+	fw.c.Writer.Flush()
+	return nil
+}
+
+// toFeature converts a water object into a GeoJSON feature for the public listing
+func toFeature(obj *entity.WaterObject) map[string]interface{} {
+	return map[string]interface{}{
+		"type":     "Feature",
+		"geometry": obj.Geometry,
+		"properties": map[string]interface{}{
+			"id":           obj.ID,
+			"canonical_id": obj.CanonicalID,
+			"name_kz":      obj.NameKZ,
+			"name_ru":      obj.NameRU,
+			"name_en":      obj.NameEN,
+			"object_type":  obj.ObjectType,
+			"length_km":    obj.LengthKm,
+			"area_km2":     obj.AreaKm2,
+		},
+	}
+}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"watermap/internal/domain/entity"
+)
+
+// startImpersonation creates a short session for the target user on behalf
+// of an administrator and returns its access token. The session has no
+// refresh token, so it ends for good when it expires.
+func (h *AuthHandler) startImpersonation(c *gin.Context, target *entity.User, adminID int64, ttl time.Duration) (*entity.Session, string, error) {
+	session, err := h.sessionRepo.Create(c.Request.Context(), &entity.Session{
+		UserID:         target.ID,
+		UserAgent:      c.Request.UserAgent(),
+		IP:             c.ClientIP(),
+		ExpiresAt:      time.Now().Add(ttl),
+		ImpersonatorID: &adminID,
+	}, nil)
+	if err != nil {
+		return nil, "", err
+	}
+
+	token, err := h.signAccessToken(target.ID, session.ID, ttl)
+	if err != nil {
+		return nil, "", err
+	}
+	return session, token, nil
+}
+
+// EndImpersonation revokes the impersonation session the request is made with
+func (h *AuthHandler) EndImpersonation(c *gin.Context) {
+	if _, ok := c.Get("impersonator_id"); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "not_impersonating",
+			"message": "this session is not an impersonation session",
+		})
+		return
+	}
+
+	sessionID := c.GetString("session_id")
+	id, err := uuid.Parse(sessionID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_session",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if err := h.sessionRepo.Revoke(c.Request.Context(), id, entity.RevokeReasonImpersonate); err != nil && err != entity.ErrNotFound {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "revoke_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	event := auditUser(entity.AuditImpersonateEnd, c.GetInt64("user_id"))
+	h.audit.Record(c, event, nil, gin.H{"session_id": sessionID})
+
+	c.JSON(http.StatusOK, gin.H{"message": "impersonation ended"})
+}
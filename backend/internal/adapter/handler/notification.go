@@ -0,0 +1,367 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"watermap/internal/domain/entity"
+	"watermap/internal/domain/repository"
+	"watermap/internal/infrastructure/eventbus"
+)
+
+// notifyTimeout bounds delivery the same way auditTimeout bounds appends
+const notifyTimeout = 5 * time.Second
+
+// Notifier turns workflow events into in-app notifications for the users
+// they concern and, as a workflow hook, publishes status changes on the live
+// event stream
+type Notifier struct {
+	repo     repository.NotificationRepository
+	permRepo repository.PermissionRepository
+	bus      *eventbus.Bus
+}
+
+func NewNotifier(repo repository.NotificationRepository, permRepo repository.PermissionRepository, bus *eventbus.Bus) *Notifier {
+	return &Notifier{repo: repo, permRepo: permRepo, bus: bus}
+}
+
+// SubmissionApproved tells the author their object has been published
+func (n *Notifier) SubmissionApproved(c *gin.Context, obj *entity.WaterObject) {
+	n.send(c, &entity.Notification{
+		Event:    entity.NotifySubmissionApproved,
+		Level:    entity.LevelSuccess,
+		Title:    "Submission approved",
+		Message:  fmt.Sprintf("Your %q has been approved and published.", obj.NameKZ),
+		ObjectID: &obj.ID,
+	}, obj.CreatedBy)
+}
+
+// SubmissionRejected tells the author why their object was rejected
+func (n *Notifier) SubmissionRejected(c *gin.Context, obj *entity.WaterObject, reason string) {
+	n.send(c, &entity.Notification{
+		Event:    entity.NotifySubmissionRejected,
+		Level:    entity.LevelWarning,
+		Title:    "Submission rejected",
+		Message:  fmt.Sprintf("Your %q was rejected: %s", obj.NameKZ, reason),
+		ObjectID: &obj.ID,
+	}, obj.CreatedBy)
+}
+
+// ChangesRequested tells the author what to fix before resubmitting
+func (n *Notifier) ChangesRequested(c *gin.Context, obj *entity.WaterObject, reason string) {
+	n.send(c, &entity.Notification{
+		Event:    entity.NotifyChangesRequested,
+		Level:    entity.LevelWarning,
+		Title:    "Changes requested",
+		Message:  fmt.Sprintf("A reviewer asked for changes to your %q: %s", obj.NameKZ, reason),
+		ObjectID: &obj.ID,
+	}, obj.CreatedBy)
+}
+
+// ReviewQueued tells every reviewer whose permissions cover the object
+// that it is waiting for review
+func (n *Notifier) ReviewQueued(c *gin.Context, obj *entity.WaterObject) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(c.Request.Context()), notifyTimeout)
+	defer cancel()
+
+	reviewers, err := n.permRepo.UsersAllowed(ctx, entity.PermObjectsReview, obj)
+	if err != nil {
+		log.Printf("notify %s: %v", entity.NotifyReviewQueued, err)
+		return
+	}
+
+	n.send(c, &entity.Notification{
+		Event:    entity.NotifyReviewQueued,
+		Level:    entity.LevelInfo,
+		Title:    "New submission to review",
+		Message:  fmt.Sprintf("%q (%s) was submitted for review.", obj.NameKZ, obj.ObjectType),
+		ObjectID: &obj.ID,
+	}, reviewers...)
+}
+
+// RoleChanged tells a user their role is now different
+func (n *Notifier) RoleChanged(c *gin.Context, userID int64, from, to entity.UserRole) {
+	n.send(c, &entity.Notification{
+		Event:   entity.NotifyRoleChanged,
+		Level:   entity.LevelInfo,
+		Title:   "Role changed",
+		Message: fmt.Sprintf("Your role was changed from %s to %s.", from, to),
+	}, userID)
+}
+
+// CommentAdded tells the object's author and everyone else in the thread
+// about a new review comment
+func (n *Notifier) CommentAdded(c *gin.Context, obj *entity.WaterObject, comment *entity.ReviewComment, participants []int64) {
+	n.send(c, &entity.Notification{
+		Event:    entity.NotifyReviewComment,
+		Level:    entity.LevelInfo,
+		Title:    "New review comment",
+		Message:  fmt.Sprintf("%s commented on %q: %s", comment.AuthorName, obj.NameKZ, truncate(comment.Body, 200)),
+		ObjectID: &obj.ID,
+	}, append(participants, obj.CreatedBy)...)
+}
+
+// truncate shortens s to at most n runes
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
+
+// send delivers the notification once to each recipient, skipping whoever
+// caused it. Failures are logged and never fail the request.
+func (n *Notifier) send(c *gin.Context, note *entity.Notification, userIDs ...int64) {
+	seen := map[int64]bool{c.GetInt64("user_id"): true}
+	recipients := make([]int64, 0, len(userIDs))
+	for _, id := range userIDs {
+		if !seen[id] {
+			seen[id] = true
+			recipients = append(recipients, id)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(c.Request.Context()), notifyTimeout)
+	defer cancel()
+
+	if err := n.repo.Create(ctx, note, recipients); err != nil {
+		log.Printf("notify %s: %v", note.Event, err)
+	}
+}
+
+// transitionEvents maps workflow transitions to the events they publish
+var transitionEvents = map[entity.Transition][]entity.EventType{
+	entity.TransitionSubmit:         {entity.EventPendingAdded},
+	entity.TransitionApprove:        {entity.EventApproved, entity.EventObjectPublished},
+	entity.TransitionReject:         {entity.EventRejected},
+	entity.TransitionRequestChanges: {entity.EventChangesRequested},
+	entity.TransitionUnpublish:      {entity.EventObjectUnpublished},
+	entity.TransitionArchive:        {entity.EventObjectUnpublished},
+	entity.TransitionRestore:        {entity.EventObjectPublished},
+}
+
+// Transitioned is a workflow hook that puts the events of a status change
+// on the live stream
+func (n *Notifier) Transitioned(ctx context.Context, change *entity.StatusChange) {
+	for _, eventType := range transitionEvents[change.Transition] {
+		event := entity.NewObjectEvent(eventType, change.Object, &change.ActorID)
+		event.Reason = change.Reason
+		n.publish(ctx, event)
+	}
+}
+
+// publish puts an event on the live stream, logging failures
+func (n *Notifier) publish(ctx context.Context, event *entity.Event) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), notifyTimeout)
+	defer cancel()
+
+	if err := n.bus.Publish(ctx, event); err != nil {
+		log.Printf("publish %s: %v", event.Type, err)
+	}
+}
+
+type NotificationHandler struct {
+	repo repository.NotificationRepository
+}
+
+func NewNotificationHandler(repo repository.NotificationRepository) *NotificationHandler {
+	return &NotificationHandler{repo: repo}
+}
+
+const (
+	defaultNotificationPageSize = 50
+	maxNotificationPageSize     = 200
+)
+
+// List returns the user's notifications, newest first. ?unread=true limits
+// the list to unread ones.
+func (h *NotificationHandler) List(c *gin.Context) {
+	filter := &repository.NotificationFilter{
+		UnreadOnly: c.Query("unread") == "true",
+		Limit:      defaultNotificationPageSize,
+	}
+	if limit, err := strconv.Atoi(c.Query("limit")); err == nil && limit > 0 {
+		filter.Limit = min(limit, maxNotificationPageSize)
+	}
+	if offset, err := strconv.Atoi(c.Query("offset")); err == nil && offset > 0 {
+		filter.Offset = offset
+	}
+
+	userID := c.GetInt64("user_id")
+	notifications, err := h.repo.List(c.Request.Context(), userID, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "fetch_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	unread, err := h.repo.UnreadCount(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "fetch_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"notifications": notifications,
+		"unread":        unread,
+	})
+}
+
+// UnreadCount returns the number of unread notifications, for the badge
+func (h *NotificationHandler) UnreadCount(c *gin.Context) {
+	unread, err := h.repo.UnreadCount(c.Request.Context(), c.GetInt64("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "fetch_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"unread": unread})
+}
+
+// MarkRead marks one notification as read
+func (h *NotificationHandler) MarkRead(c *gin.Context) {
+	id, ok := notificationID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.MarkRead(c.Request.Context(), c.GetInt64("user_id"), id); err != nil {
+		notificationError(c, err, "update_failed")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "notification marked as read"})
+}
+
+// MarkAllRead marks every unread notification as read
+func (h *NotificationHandler) MarkAllRead(c *gin.Context) {
+	updated, err := h.repo.MarkAllRead(c.Request.Context(), c.GetInt64("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "update_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"updated": updated})
+}
+
+// Delete removes a notification
+func (h *NotificationHandler) Delete(c *gin.Context) {
+	id, ok := notificationID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.Delete(c.Request.Context(), c.GetInt64("user_id"), id); err != nil {
+		notificationError(c, err, "delete_failed")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "notification deleted"})
+}
+
+// GetPreferences returns whether each event is delivered to the user
+func (h *NotificationHandler) GetPreferences(c *gin.Context) {
+	prefs, err := h.repo.Preferences(c.Request.Context(), c.GetInt64("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "fetch_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"preferences": prefs})
+}
+
+type UpdatePreferencesRequest struct {
+	Preferences []entity.NotificationPreference `json:"preferences" binding:"required"`
+}
+
+// UpdatePreferences turns events on or off. Events not listed keep their
+// current setting.
+func (h *NotificationHandler) UpdatePreferences(c *gin.Context) {
+	var req UpdatePreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	for _, pref := range req.Preferences {
+		if !pref.Event.IsValid() {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "validation_error",
+				"message": "unknown event " + string(pref.Event),
+				"events":  entity.AllNotificationEvents,
+			})
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetInt64("user_id")
+	if err := h.repo.SetPreferences(ctx, userID, req.Preferences); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "update_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	prefs, err := h.repo.Preferences(ctx, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "fetch_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"preferences": prefs})
+}
+
+func notificationID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_id",
+			"message": "invalid notification id",
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+func notificationError(c *gin.Context, err error, code string) {
+	if err == entity.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "not_found",
+			"message": "notification not found",
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error":   code,
+		"message": err.Error(),
+	})
+}
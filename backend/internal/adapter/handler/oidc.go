@@ -0,0 +1,332 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+
+	"watermap/internal/domain/entity"
+	"watermap/internal/domain/repository"
+	"watermap/internal/infrastructure/oidc"
+)
+
+const (
+	oidcStateCookie = "oidc_auth"
+	oidcStatePath   = "/api/auth/oidc"
+	oidcStateTTL    = 10 * time.Minute
+	tokenTypeOIDC   = "oidc_state"
+)
+
+// RoleMapping maps values of the configured claim to local roles
+type RoleMapping map[string]entity.UserRole
+
+// ParseRoleMapping parses "value=role,value=role" pairs, skipping invalid entries
+func ParseRoleMapping(raw string) RoleMapping {
+	mapping := RoleMapping{}
+	for _, pair := range strings.Split(raw, ",") {
+		value, role, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			continue
+		}
+		r := entity.UserRole(strings.TrimSpace(role))
+		if !r.IsValid() {
+			log.Printf("oidc: ignoring mapping %q: invalid role", pair)
+			continue
+		}
+		mapping[strings.TrimSpace(value)] = r
+	}
+	return mapping
+}
+
+// roleFor returns the most privileged role granted by the claim values
+func (m RoleMapping) roleFor(claims jwt.MapClaims, claim string) entity.UserRole {
+	var values []string
+	switch v := claims[claim].(type) {
+	case string:
+		values = strings.Fields(v)
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+
+	role := entity.RoleUser
+	for _, v := range values {
+		if mapped, ok := m[v]; ok && mapped.Rank() > role.Rank() {
+			role = mapped
+		}
+	}
+	return role
+}
+
+type OIDCHandler struct {
+	auth         *AuthHandler
+	provider     *oidc.Provider
+	identityRepo repository.UserIdentityRepository
+	notify       *Notifier
+	roleClaim    string
+	roleMapping  RoleMapping
+	syncRoles    bool
+	clientURL    string
+}
+
+func NewOIDCHandler(auth *AuthHandler, provider *oidc.Provider, identityRepo repository.UserIdentityRepository, notifier *Notifier, roleClaim string, roleMapping RoleMapping, syncRoles bool, clientURL string) *OIDCHandler {
+	return &OIDCHandler{
+		auth:         auth,
+		provider:     provider,
+		identityRepo: identityRepo,
+		notify:       notifier,
+		roleClaim:    roleClaim,
+		roleMapping:  roleMapping,
+		syncRoles:    syncRoles,
+		clientURL:    strings.TrimSuffix(clientURL, "/"),
+	}
+}
+
+// Login redirects the browser to the identity provider. The state, nonce
+// and PKCE verifier travel in a short-lived signed cookie.
+func (h *OIDCHandler) Login(c *gin.Context) {
+	ar, err := oidc.NewAuthRequest()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "oidc_failed",
+			"message": "failed to start login",
+		})
+		return
+	}
+
+	target, err := h.provider.AuthCodeURL(c.Request.Context(), ar)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":   "oidc_unavailable",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	state := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"typ":       tokenTypeOIDC,
+		"state":     ar.State,
+		"nonce":     ar.Nonce,
+		"verifier":  ar.CodeVerifier,
+		"return_to": safeReturnPath(c.Query("return_to")),
+		"exp":       time.Now().Add(oidcStateTTL).Unix(),
+	})
+	signed, err := state.SignedString([]byte(h.auth.jwtSecret))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "oidc_failed",
+			"message": "failed to start login",
+		})
+		return
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, signed, int(oidcStateTTL.Seconds()), oidcStatePath, "", false, true)
+	c.Redirect(http.StatusFound, target)
+}
+
+// Callback completes the authorization code flow, links or creates the
+// local user and starts a session, or hands the client a two-factor
+// challenge when the user needs one
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	c.SetCookie(oidcStateCookie, "", -1, oidcStatePath, "", false, true)
+
+	if errCode := c.Query("error"); errCode != "" {
+		h.fail(c, errCode)
+		return
+	}
+
+	ar, returnTo, err := h.readState(c)
+	if err != nil || c.Query("state") != ar.State {
+		h.fail(c, "invalid_state")
+		return
+	}
+
+	claims, err := h.provider.Exchange(c.Request.Context(), c.Query("code"), ar)
+	if err != nil {
+		log.Printf("oidc callback: %v", err)
+		h.fail(c, "exchange_failed")
+		return
+	}
+
+	user, err := h.resolveUser(c, claims)
+	if err != nil {
+		log.Printf("oidc resolve user: %v", err)
+		h.fail(c, "account_failed")
+		return
+	}
+
+	// The provider vouches for the identity only; the account's own checks
+	// and second factor apply as they do to a password sign-in
+	if user.LockedFor(time.Now()) > 0 {
+		h.auth.audit.Record(c, auditUser(entity.AuditLoginFailed, user.ID), nil, gin.H{"reason": "locked"})
+		h.fail(c, "account_locked")
+		return
+	}
+	if !user.IsActive() {
+		h.fail(c, "account_inactive")
+		return
+	}
+
+	challenge, enroll, err := h.auth.mfaChallenge(c.Request.Context(), user)
+	if err != nil {
+		log.Printf("oidc mfa challenge: %v", err)
+		h.fail(c, "session_failed")
+		return
+	}
+	if challenge != "" {
+		// The fragment keeps the challenge out of server and proxy logs
+		c.Redirect(http.StatusFound, h.clientURL+"/login#"+url.Values{
+			"mfa_challenge":       {challenge},
+			"enrollment_required": {strconv.FormatBool(enroll)},
+			"return_to":           {returnTo},
+		}.Encode())
+		return
+	}
+
+	if _, err := h.auth.startSession(c, user); err != nil {
+		log.Printf("oidc start session: %v", err)
+		h.fail(c, "session_failed")
+		return
+	}
+
+	c.Redirect(http.StatusFound, h.clientURL+returnTo)
+}
+
+func (h *OIDCHandler) readState(c *gin.Context) (*oidc.AuthRequest, string, error) {
+	raw, err := c.Cookie(oidcStateCookie)
+	if err != nil {
+		return nil, "", err
+	}
+
+	token, err := jwt.Parse(raw, func(t *jwt.Token) (interface{}, error) {
+		return []byte(h.auth.jwtSecret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil || !token.Valid {
+		return nil, "", errors.New("invalid state cookie")
+	}
+
+	claims := token.Claims.(jwt.MapClaims)
+	if typ, _ := claims["typ"].(string); typ != tokenTypeOIDC {
+		return nil, "", errors.New("invalid state cookie")
+	}
+
+	ar := &oidc.AuthRequest{}
+	ar.State, _ = claims["state"].(string)
+	ar.Nonce, _ = claims["nonce"].(string)
+	ar.CodeVerifier, _ = claims["verifier"].(string)
+	returnTo, _ := claims["return_to"].(string)
+	return ar, safeReturnPath(returnTo), nil
+}
+
+// resolveUser finds the user linked to the identity, links an existing
+// account with the same verified email, or creates a new one
+func (h *OIDCHandler) resolveUser(c *gin.Context, claims jwt.MapClaims) (*entity.User, error) {
+	ctx := c.Request.Context()
+	issuer := h.provider.Issuer()
+	subject, _ := claims["sub"].(string)
+	email, _ := claims["email"].(string)
+	emailVerified, _ := claims["email_verified"].(bool)
+	role := h.roleMapping.roleFor(claims, h.roleClaim)
+
+	identity, err := h.identityRepo.GetByIssuerSubject(ctx, issuer, subject)
+	if err == nil {
+		user, err := h.auth.userRepo.GetByID(ctx, identity.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if h.syncRoles && user.Role != role {
+			if err := h.auth.userRepo.UpdateRole(ctx, user.ID, role); err != nil {
+				return nil, err
+			}
+			h.auth.audit.Record(c, auditUser(entity.AuditRoleChanged, user.ID),
+				gin.H{"role": user.Role}, gin.H{"role": role, "source": "oidc"})
+			h.notify.RoleChanged(c, user.ID, user.Role, role)
+			user.Role = role
+		}
+		return user, nil
+	}
+	if err != entity.ErrNotFound {
+		return nil, err
+	}
+
+	if email == "" {
+		return nil, errors.New("identity provider did not return an email")
+	}
+
+	user, err := h.auth.userRepo.GetByEmail(ctx, email)
+	switch {
+	case err == nil:
+		// Only link to an existing account when the IdP vouches for the address
+		if !emailVerified {
+			return nil, errors.New("refusing to link unverified email " + email)
+		}
+		if h.syncRoles && user.Role != role {
+			if err := h.auth.userRepo.UpdateRole(ctx, user.ID, role); err != nil {
+				return nil, err
+			}
+			h.auth.audit.Record(c, auditUser(entity.AuditRoleChanged, user.ID),
+				gin.H{"role": user.Role}, gin.H{"role": role, "source": "oidc"})
+			h.notify.RoleChanged(c, user.ID, user.Role, role)
+			user.Role = role
+		}
+		if !user.IsEmailVerified() {
+			if err := h.auth.userRepo.MarkEmailVerified(ctx, user.ID); err != nil {
+				return nil, err
+			}
+		}
+	case err == entity.ErrNotFound:
+		name, _ := claims["name"].(string)
+		if name == "" {
+			name = email
+		}
+		// SSO accounts have no local password; bcrypt never matches an empty hash
+		user = &entity.User{
+			Name:  name,
+			Email: email,
+			Role:  role,
+		}
+		if emailVerified {
+			now := time.Now()
+			user.EmailVerifiedAt = &now
+		}
+		user, err = h.auth.userRepo.Create(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	if _, err := h.identityRepo.Create(ctx, &entity.UserIdentity{
+		UserID:  user.ID,
+		Issuer:  issuer,
+		Subject: subject,
+		Email:   email,
+	}); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (h *OIDCHandler) fail(c *gin.Context, reason string) {
+	c.Redirect(http.StatusFound, h.clientURL+"/login?sso_error="+url.QueryEscape(reason))
+}
+
+// safeReturnPath only allows local absolute paths, preventing open redirects
+func safeReturnPath(p string) string {
+	if !strings.HasPrefix(p, "/") || strings.HasPrefix(p, "//") || strings.Contains(p, "\\") {
+		return "/"
+	}
+	return p
+}
@@ -0,0 +1,498 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"watermap/internal/domain/entity"
+	"watermap/internal/domain/repository"
+)
+
+type OrganizationHandler struct {
+	orgRepo         repository.OrganizationRepository
+	userRepo        repository.UserRepository
+	waterObjectRepo repository.WaterObjectRepository
+	audit           *Auditor
+}
+
+func NewOrganizationHandler(orgRepo repository.OrganizationRepository, userRepo repository.UserRepository, waterObjectRepo repository.WaterObjectRepository, auditor *Auditor) *OrganizationHandler {
+	return &OrganizationHandler{
+		orgRepo:         orgRepo,
+		userRepo:        userRepo,
+		waterObjectRepo: waterObjectRepo,
+		audit:           auditor,
+	}
+}
+
+// Mine returns the organizations the current user belongs to
+func (h *OrganizationHandler) Mine(c *gin.Context) {
+	orgs, err := h.orgRepo.ListForUser(c.Request.Context(), c.GetInt64("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "fetch_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"organizations": orgs})
+}
+
+// Get returns an organization and its members
+func (h *OrganizationHandler) Get(c *gin.Context) {
+	org, ok := h.loadOrganization(c)
+	if !ok {
+		return
+	}
+	if !h.requireMember(c, org.ID, false) {
+		return
+	}
+
+	members, err := h.orgRepo.ListMembers(c.Request.Context(), org.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "fetch_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"organization": org,
+		"members":      members,
+	})
+}
+
+// Submissions is the organization dashboard: its pending objects and those
+// sent back with requested changes or rejected
+func (h *OrganizationHandler) Submissions(c *gin.Context) {
+	org, ok := h.loadOrganization(c)
+	if !ok {
+		return
+	}
+	if !h.requireMember(c, org.ID, false) {
+		return
+	}
+
+	objects, err := h.waterObjectRepo.GetSubmissionsByOrganization(c.Request.Context(), org.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "fetch_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	pending := make([]*entity.WaterObject, 0, len(objects))
+	changesRequested := make([]*entity.WaterObject, 0, len(objects))
+	rejected := make([]*entity.WaterObject, 0, len(objects))
+	for _, obj := range objects {
+		switch obj.Status {
+		case entity.StatusPending:
+			pending = append(pending, obj)
+		case entity.StatusChangesRequested:
+			changesRequested = append(changesRequested, obj)
+		default:
+			rejected = append(rejected, obj)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"organization":      org,
+		"pending":           pending,
+		"changes_requested": changesRequested,
+		"rejected":          rejected,
+	})
+}
+
+type AddMemberRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role"`
+}
+
+// AddMember adds an existing user to the organization by email
+func (h *OrganizationHandler) AddMember(c *gin.Context) {
+	org, ok := h.loadOrganization(c)
+	if !ok {
+		return
+	}
+	if !h.requireMember(c, org.ID, true) {
+		return
+	}
+
+	var req AddMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	role, ok := parseOrganizationRole(req.Role)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": "role must be owner or member",
+		})
+		return
+	}
+
+	user, err := h.userRepo.GetByEmail(c.Request.Context(), req.Email)
+	if err != nil || !user.IsActive() {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "not_found",
+			"message": "user not found",
+		})
+		return
+	}
+
+	if err := h.orgRepo.AddMember(c.Request.Context(), org.ID, user.ID, role); err != nil {
+		if err == entity.ErrAlreadyMember {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "already_member",
+				"message": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "update_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.audit.Record(c, auditOrganization(entity.AuditMembershipChanged, org.ID), nil, gin.H{
+		"user_id": user.ID,
+		"role":    role,
+	})
+
+	c.JSON(http.StatusCreated, gin.H{"message": "member added"})
+}
+
+type UpdateMemberRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// UpdateMember changes a member's role
+func (h *OrganizationHandler) UpdateMember(c *gin.Context) {
+	org, ok := h.loadOrganization(c)
+	if !ok {
+		return
+	}
+	if !h.requireMember(c, org.ID, true) {
+		return
+	}
+
+	userID, err := strconv.ParseInt(c.Param("userId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_id",
+			"message": "invalid user id",
+		})
+		return
+	}
+
+	var req UpdateMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": "role is required",
+		})
+		return
+	}
+	role, ok := parseOrganizationRole(req.Role)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": "role must be owner or member",
+		})
+		return
+	}
+
+	if role != entity.OrgRoleOwner && !h.keepsOwner(c, org.ID, userID) {
+		return
+	}
+
+	if err := h.orgRepo.UpdateMemberRole(c.Request.Context(), org.ID, userID, role); err != nil {
+		h.memberError(c, err)
+		return
+	}
+
+	h.audit.Record(c, auditOrganization(entity.AuditMembershipChanged, org.ID), nil, gin.H{
+		"user_id": userID,
+		"role":    role,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "member updated"})
+}
+
+// RemoveMember removes a member. Owners may remove anyone; every member
+// may remove themselves to leave the organization.
+func (h *OrganizationHandler) RemoveMember(c *gin.Context) {
+	org, ok := h.loadOrganization(c)
+	if !ok {
+		return
+	}
+
+	userID, err := strconv.ParseInt(c.Param("userId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_id",
+			"message": "invalid user id",
+		})
+		return
+	}
+
+	leaving := userID == c.GetInt64("user_id")
+	if !h.requireMember(c, org.ID, !leaving) {
+		return
+	}
+
+	if !h.keepsOwner(c, org.ID, userID) {
+		return
+	}
+
+	if err := h.orgRepo.RemoveMember(c.Request.Context(), org.ID, userID); err != nil {
+		h.memberError(c, err)
+		return
+	}
+
+	h.audit.Record(c, auditOrganization(entity.AuditMembershipChanged, org.ID), gin.H{"user_id": userID}, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "member removed"})
+}
+
+// List returns every organization
+func (h *OrganizationHandler) List(c *gin.Context) {
+	orgs, err := h.orgRepo.List(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "fetch_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"organizations": orgs})
+}
+
+type OrganizationRequest struct {
+	Name        string  `json:"name" binding:"required,max=255"`
+	Description *string `json:"description"`
+	OwnerID     *int64  `json:"owner_id"`
+}
+
+// Create adds an organization, optionally with its first owner
+func (h *OrganizationHandler) Create(c *gin.Context) {
+	var req OrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if req.OwnerID != nil {
+		if _, err := h.userRepo.GetByID(ctx, *req.OwnerID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "validation_error",
+				"message": "owner not found",
+			})
+			return
+		}
+	}
+
+	org, err := h.orgRepo.Create(ctx, &entity.Organization{
+		Name:        strings.TrimSpace(req.Name),
+		Description: req.Description,
+	})
+	if err != nil {
+		h.organizationError(c, err, "create_failed")
+		return
+	}
+
+	if req.OwnerID != nil {
+		if err := h.orgRepo.AddMember(ctx, org.ID, *req.OwnerID, entity.OrgRoleOwner); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "create_failed",
+				"message": err.Error(),
+			})
+			return
+		}
+	}
+
+	h.audit.Record(c, auditOrganization(entity.AuditOrganizationSaved, org.ID), nil, gin.H{
+		"organization": org,
+		"owner_id":     req.OwnerID,
+	})
+
+	c.JSON(http.StatusCreated, org)
+}
+
+// Update renames an organization or changes its description
+func (h *OrganizationHandler) Update(c *gin.Context) {
+	org, ok := h.loadOrganization(c)
+	if !ok {
+		return
+	}
+
+	var req OrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	before := *org
+	org.Name = strings.TrimSpace(req.Name)
+	org.Description = req.Description
+	if err := h.orgRepo.Update(c.Request.Context(), org); err != nil {
+		h.organizationError(c, err, "update_failed")
+		return
+	}
+
+	h.audit.Record(c, auditOrganization(entity.AuditOrganizationSaved, org.ID), before, org)
+
+	c.JSON(http.StatusOK, org)
+}
+
+// loadOrganization reads the :id parameter and fetches the organization
+func (h *OrganizationHandler) loadOrganization(c *gin.Context) (*entity.Organization, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_id",
+			"message": "invalid organization id",
+		})
+		return nil, false
+	}
+
+	org, err := h.orgRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		h.organizationError(c, err, "fetch_failed")
+		return nil, false
+	}
+	return org, true
+}
+
+// requireMember checks that the current user belongs to the organization,
+// as an owner when owner is set. Holders of users.manage pass regardless.
+func (h *OrganizationHandler) requireMember(c *gin.Context, orgID int64, owner bool) bool {
+	if permissionsFrom(c).Has(entity.PermUsersManage) {
+		return true
+	}
+
+	member, err := h.orgRepo.GetMembership(c.Request.Context(), orgID, c.GetInt64("user_id"))
+	if err != nil {
+		if err == entity.ErrNotMember {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "forbidden",
+				"message": err.Error(),
+			})
+			return false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "fetch_failed",
+			"message": err.Error(),
+		})
+		return false
+	}
+
+	if owner && member.Role != entity.OrgRoleOwner {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "forbidden",
+			"message": "only organization owners can manage members",
+		})
+		return false
+	}
+	return true
+}
+
+// keepsOwner refuses to demote or remove the organization's last owner
+func (h *OrganizationHandler) keepsOwner(c *gin.Context, orgID, userID int64) bool {
+	members, err := h.orgRepo.ListMembers(c.Request.Context(), orgID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "fetch_failed",
+			"message": err.Error(),
+		})
+		return false
+	}
+
+	owners, target := 0, false
+	for _, m := range members {
+		if m.Role == entity.OrgRoleOwner {
+			owners++
+			if m.UserID == userID {
+				target = true
+			}
+		}
+	}
+	if target && owners <= 1 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "last_owner",
+			"message": "an organization needs at least one owner",
+		})
+		return false
+	}
+	return true
+}
+
+func (h *OrganizationHandler) memberError(c *gin.Context, err error) {
+	if err == entity.ErrNotMember {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "not_found",
+			"message": "member not found",
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error":   "update_failed",
+		"message": err.Error(),
+	})
+}
+
+func (h *OrganizationHandler) organizationError(c *gin.Context, err error, code string) {
+	switch err {
+	case entity.ErrNotFound:
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "not_found",
+			"message": "organization not found",
+		})
+	case entity.ErrOrganizationNameTaken:
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "name_taken",
+			"message": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   code,
+			"message": err.Error(),
+		})
+	}
+}
+
+func auditOrganization(action entity.AuditAction, orgID int64) entity.AuditEvent {
+	return entity.AuditEvent{
+		Action:     action,
+		TargetType: "organization",
+		TargetID:   strconv.FormatInt(orgID, 10),
+	}
+}
+
+// parseOrganizationRole defaults an empty role to member
+func parseOrganizationRole(raw string) (entity.OrganizationRole, bool) {
+	if raw == "" {
+		return entity.OrgRoleMember, true
+	}
+	role := entity.OrganizationRole(raw)
+	return role, role.IsValid()
+}
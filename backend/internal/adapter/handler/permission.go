@@ -0,0 +1,334 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"watermap/internal/domain/entity"
+	"watermap/internal/domain/repository"
+)
+
+// permissionsFrom returns the grants loaded by the auth middleware
+func permissionsFrom(c *gin.Context) entity.PermissionSet {
+	if value, ok := c.Get("permissions"); ok {
+		return value.(entity.PermissionSet)
+	}
+	return nil
+}
+
+// forbidObject writes a 403 for an object outside the user's permission scope
+func forbidObject(c *gin.Context, perm entity.Permission) {
+	forbiddenObject(perm).respond(c)
+}
+
+func forbiddenObject(perm entity.Permission) *requestError {
+	return &requestError{http.StatusForbidden, gin.H{
+		"error":    "forbidden",
+		"message":  "your permissions do not cover this object's region or type",
+		"required": perm,
+	}}
+}
+
+// objectAllowed loads the object and checks the permission against its
+// scope. It writes the error response and returns false when either fails.
+func objectAllowed(c *gin.Context, repo repository.WaterObjectRepository, id int64, perm entity.Permission) (*entity.WaterObject, bool) {
+	obj, failed := findObject(c, repo, id, perm)
+	if failed != nil {
+		failed.respond(c)
+		return nil, false
+	}
+	return obj, true
+}
+
+// findObject is objectAllowed for handlers that collect errors, such as
+// batch operations
+func findObject(c *gin.Context, repo repository.WaterObjectRepository, id int64, perm entity.Permission) (*entity.WaterObject, *requestError) {
+	obj, err := repo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		if err == entity.ErrNotFound {
+			return nil, &requestError{http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"message": "object not found",
+			}}
+		}
+		return nil, &requestError{http.StatusInternalServerError, gin.H{
+			"error":   "fetch_failed",
+			"message": err.Error(),
+		}}
+	}
+
+	if !permissionsFrom(c).Allows(perm, obj) {
+		return nil, forbiddenObject(perm)
+	}
+	return obj, nil
+}
+
+// editableObject loads the object in the path and checks that the user may
+// change it in its current status, for handlers managing what hangs off a
+// draft
+func editableObject(c *gin.Context, repo repository.WaterObjectRepository, orgRepo repository.OrganizationRepository) (*entity.WaterObject, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_id",
+			"message": "invalid object id",
+		})
+		return nil, false
+	}
+
+	obj, ok := objectAllowed(c, repo, id, entity.PermObjectsEdit)
+	if !ok {
+		return nil, false
+	}
+	if !isEditor(c, orgRepo, obj) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   entity.GuardNotEditor,
+			"message": "only the object's authors can change it",
+		})
+		return nil, false
+	}
+	for _, s := range entity.EditableStatuses {
+		if obj.Status == s {
+			return obj, true
+		}
+	}
+	c.JSON(http.StatusConflict, gin.H{
+		"error":   "not_editable",
+		"message": "the object can only be changed while it is a draft or has changes requested",
+		"status":  obj.Status,
+	})
+	return nil, false
+}
+
+type PermissionHandler struct {
+	permRepo repository.PermissionRepository
+	userRepo repository.UserRepository
+	audit    *Auditor
+}
+
+func NewPermissionHandler(permRepo repository.PermissionRepository, userRepo repository.UserRepository, auditor *Auditor) *PermissionHandler {
+	return &PermissionHandler{
+		permRepo: permRepo,
+		userRepo: userRepo,
+		audit:    auditor,
+	}
+}
+
+// Catalog lists the permissions, regions and object types grants can use
+func (h *PermissionHandler) Catalog(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"permissions": entity.AllPermissions,
+		"regions":     entity.AllRegions,
+		"object_types": []entity.ObjectType{
+			entity.ObjectTypeRiver, entity.ObjectTypeLake, entity.ObjectTypeReservoir,
+			entity.ObjectTypeCanal, entity.ObjectTypeGlacier, entity.ObjectTypeSpring,
+		},
+	})
+}
+
+// List returns every grant
+func (h *PermissionHandler) List(c *gin.Context) {
+	grants, err := h.permRepo.List(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "fetch_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"grants": grants})
+}
+
+// ForUser returns the effective grants of one user
+func (h *PermissionHandler) ForUser(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_id",
+			"message": "invalid user id",
+		})
+		return
+	}
+
+	user, err := h.userRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		if err == entity.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"message": "user not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "fetch_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	perms, err := h.permRepo.ForUser(c.Request.Context(), user.ID, user.Role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "fetch_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"grants": perms})
+}
+
+type CreateGrantRequest struct {
+	Permission string  `json:"permission" binding:"required"`
+	Role       *string `json:"role"`
+	UserID     *int64  `json:"user_id"`
+	Region     *string `json:"region"`
+	ObjectType *string `json:"object_type"`
+}
+
+// Create adds a grant to a role or a user
+func (h *PermissionHandler) Create(c *gin.Context) {
+	var req CreateGrantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	adminID := c.GetInt64("user_id")
+	grant := &entity.PermissionGrant{
+		Permission: entity.Permission(req.Permission),
+		UserID:     req.UserID,
+		CreatedBy:  &adminID,
+	}
+	if req.Role != nil {
+		role := entity.UserRole(*req.Role)
+		grant.Role = &role
+	}
+	if req.Region != nil {
+		region := entity.Region(*req.Region)
+		grant.Region = &region
+	}
+	if req.ObjectType != nil {
+		objType := entity.ObjectType(*req.ObjectType)
+		grant.ObjectType = &objType
+	}
+
+	if err := grant.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": "grant needs a valid permission, exactly one of role or user_id, and scopes only on object permissions",
+		})
+		return
+	}
+
+	if grant.UserID != nil {
+		if _, err := h.userRepo.GetByID(c.Request.Context(), *grant.UserID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "validation_error",
+				"message": "user not found",
+			})
+			return
+		}
+	}
+
+	created, err := h.permRepo.Create(c.Request.Context(), grant)
+	if err != nil {
+		if err == entity.ErrDuplicateGrant {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "duplicate_grant",
+				"message": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "create_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.audit.Record(c, auditGrant(entity.AuditPermissionGranted, created.ID), nil, created)
+
+	c.JSON(http.StatusCreated, created)
+}
+
+// Delete removes a grant. The last permissions.manage grant cannot be
+// removed, so that grants stay manageable.
+func (h *PermissionHandler) Delete(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_id",
+			"message": "invalid grant id",
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	grant, err := h.permRepo.GetByID(ctx, id)
+	if err != nil {
+		if err == entity.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"message": "grant not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "fetch_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if grant.Permission == entity.PermPermissionsManage {
+		grants, err := h.permRepo.List(ctx)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "fetch_failed",
+				"message": err.Error(),
+			})
+			return
+		}
+		if entity.PermissionSet(grants).Count(entity.PermPermissionsManage) <= 1 {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "last_grant",
+				"message": "cannot remove the last permissions.manage grant",
+			})
+			return
+		}
+	}
+
+	if err := h.permRepo.Delete(ctx, id); err != nil {
+		if err == entity.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"message": "grant not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "delete_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.audit.Record(c, auditGrant(entity.AuditPermissionRevoked, id), grant, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "grant removed"})
+}
+
+func auditGrant(action entity.AuditAction, grantID int64) entity.AuditEvent {
+	return entity.AuditEvent{
+		Action:     action,
+		TargetType: "permission_grant",
+		TargetID:   strconv.FormatInt(grantID, 10),
+	}
+}
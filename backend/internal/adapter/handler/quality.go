@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"watermap/internal/domain/entity"
+	"watermap/internal/domain/repository"
+	"watermap/internal/infrastructure/quality"
+)
+
+// QualityChecker runs the data-quality rules on single objects
+type QualityChecker struct {
+	rules *quality.RuleSet
+	repo  repository.QualityRepository
+}
+
+func NewQualityChecker(rules *quality.RuleSet, repo repository.QualityRepository) *QualityChecker {
+	return &QualityChecker{rules: rules, repo: repo}
+}
+
+// Check returns the rules the object breaks, errors first, and stores them
+// in place of the object's earlier violations
+func (q *QualityChecker) Check(ctx context.Context, obj *entity.WaterObject) ([]*entity.RuleViolation, error) {
+	violations := q.rules.Check(obj)
+	now := time.Now()
+	for _, v := range violations {
+		v.CheckedAt = now
+	}
+	if err := q.repo.ReplaceForObject(ctx, obj.ID, violations); err != nil {
+		return nil, err
+	}
+	return violations, nil
+}
+
+type QualityHandler struct {
+	repo    repository.QualityRepository
+	rules   *quality.RuleSet
+	sweeper *quality.Sweeper
+	audit   *Auditor
+}
+
+func NewQualityHandler(repo repository.QualityRepository, rules *quality.RuleSet, sweeper *quality.Sweeper, auditor *Auditor) *QualityHandler {
+	return &QualityHandler{
+		repo:    repo,
+		rules:   rules,
+		sweeper: sweeper,
+		audit:   auditor,
+	}
+}
+
+const maxViolationPageSize = 1000
+
+// Report lists rule violations within the reviewer's scope, errors first,
+// filtered by severity, rule, object_type and status, with totals per rule
+func (h *QualityHandler) Report(c *gin.Context) {
+	filter := &repository.ViolationFilter{
+		Severity:   entity.Severity(c.Query("severity")),
+		RuleID:     c.Query("rule"),
+		ObjectType: entity.ObjectType(c.Query("object_type")),
+		Status:     entity.ObjectStatus(c.Query("status")),
+	}
+	if filter.Severity != "" && !filter.Severity.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": "severity must be error or warning",
+		})
+		return
+	}
+
+	limit := 100
+	if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 {
+		limit = min(l, maxViolationPageSize)
+	}
+	offset := 0
+	if o, err := strconv.Atoi(c.Query("offset")); err == nil && o > 0 {
+		offset = o
+	}
+
+	violations, err := h.repo.List(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "fetch_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// Reviewers only see objects within their scope
+	perms := permissionsFrom(c)
+	visible := make([]*entity.RuleViolation, 0, len(violations))
+	byRule := make(map[string]int)
+	for _, v := range violations {
+		if !perms.Allows(entity.PermObjectsReview, &entity.WaterObject{ObjectType: v.ObjectType, Region: v.Region}) {
+			continue
+		}
+		visible = append(visible, v)
+		byRule[v.RuleID]++
+	}
+
+	page := visible[min(offset, len(visible)):min(offset+limit, len(visible))]
+	c.JSON(http.StatusOK, gin.H{
+		"violations": page,
+		"total":      len(visible),
+		"by_rule":    byRule,
+	})
+}
+
+// Rules returns the rules in force
+func (h *QualityHandler) Rules(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"rules": h.rules.Rules()})
+}
+
+// Sweep checks every published object now instead of waiting for the
+// nightly run
+func (h *QualityHandler) Sweep(c *gin.Context) {
+	sweep, err := h.sweeper.Sweep(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "sweep_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.audit.Record(c, entity.AuditEvent{Action: entity.AuditQualitySwept, TargetType: "quality_rules"}, nil, sweep)
+
+	c.JSON(http.StatusOK, sweep)
+}
@@ -0,0 +1,226 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"watermap/internal/domain/entity"
+)
+
+// Claim assigns a pending object to the current reviewer so that other
+// reviewers leave it alone. Claiming again renews the claim.
+func (h *AdminHandler) Claim(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_id",
+			"message": "invalid object id",
+		})
+		return
+	}
+
+	if _, ok := objectAllowed(c, h.waterObjectRepo, id, entity.PermObjectsReview); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	claim, err := h.reviewRepo.Claim(ctx, id, c.GetInt64("user_id"), time.Now().Add(h.claimTTL))
+	if err != nil {
+		switch err {
+		case entity.ErrNotFound:
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"message": "pending object not found",
+			})
+		case entity.ErrClaimed:
+			current, _ := h.reviewRepo.GetClaim(ctx, id)
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "claimed",
+				"message": err.Error(),
+				"claim":   current,
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "claim_failed",
+				"message": err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, claim)
+}
+
+// Unclaim releases the current reviewer's claim on an object
+func (h *AdminHandler) Unclaim(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_id",
+			"message": "invalid object id",
+		})
+		return
+	}
+
+	if err := h.reviewRepo.Unclaim(c.Request.Context(), id, c.GetInt64("user_id")); err != nil {
+		if err == entity.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"message": "you have not claimed this object",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "unclaim_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "claim released"})
+}
+
+// checkClaim fails when another reviewer holds an active claim on the object
+func (h *AdminHandler) checkClaim(c *gin.Context, objectID int64) *requestError {
+	claim, err := h.reviewRepo.GetClaim(c.Request.Context(), objectID)
+	if err != nil {
+		if err == entity.ErrNotFound {
+			return nil
+		}
+		return &requestError{http.StatusInternalServerError, gin.H{
+			"error":   "fetch_failed",
+			"message": err.Error(),
+		}}
+	}
+
+	if claim.ReviewerID != c.GetInt64("user_id") {
+		return &requestError{http.StatusConflict, gin.H{
+			"error":   "claimed",
+			"message": entity.ErrClaimed.Error(),
+			"claim":   claim,
+		}}
+	}
+	return nil
+}
+
+// ListQuorumRules returns the approval rules and the default that applies
+// where none matches
+func (h *AdminHandler) ListQuorumRules(c *gin.Context) {
+	rules, err := h.reviewRepo.ListQuorumRules(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "fetch_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rules": rules, "default": entity.DefaultQuorum})
+}
+
+type CreateQuorumRuleRequest struct {
+	ObjectType      *string `json:"object_type"`
+	Region          *string `json:"region"`
+	Approvals       int     `json:"approvals" binding:"required"`
+	SeniorApprovals int     `json:"senior_approvals"`
+	SeniorOverride  bool    `json:"senior_override"`
+}
+
+// CreateQuorumRule sets the approvals needed for objects of a type and/or
+// region; the most specific matching rule applies
+func (h *AdminHandler) CreateQuorumRule(c *gin.Context) {
+	var req CreateQuorumRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	adminID := c.GetInt64("user_id")
+	rule := &entity.QuorumRule{
+		Approvals:       req.Approvals,
+		SeniorApprovals: req.SeniorApprovals,
+		SeniorOverride:  req.SeniorOverride,
+		CreatedBy:       &adminID,
+	}
+	if req.ObjectType != nil {
+		objType := entity.ObjectType(*req.ObjectType)
+		rule.ObjectType = &objType
+	}
+	if req.Region != nil {
+		region := entity.Region(*req.Region)
+		rule.Region = &region
+	}
+
+	if err := rule.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": "rule needs at least one approval, no more senior approvals than approvals, and valid scopes",
+		})
+		return
+	}
+
+	created, err := h.reviewRepo.CreateQuorumRule(c.Request.Context(), rule)
+	if err != nil {
+		if err == entity.ErrDuplicateQuorumRule {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "duplicate_rule",
+				"message": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "create_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.audit.Record(c, auditQuorumRule(entity.AuditQuorumRuleSaved, created.ID), nil, created)
+
+	c.JSON(http.StatusCreated, created)
+}
+
+// DeleteQuorumRule removes a rule; its objects fall back to a broader rule
+func (h *AdminHandler) DeleteQuorumRule(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_id",
+			"message": "invalid rule id",
+		})
+		return
+	}
+
+	if err := h.reviewRepo.DeleteQuorumRule(c.Request.Context(), id); err != nil {
+		if err == entity.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"message": "rule not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "delete_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.audit.Record(c, auditQuorumRule(entity.AuditQuorumRuleDeleted, id), nil, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "rule removed"})
+}
+
+func auditQuorumRule(action entity.AuditAction, ruleID int64) entity.AuditEvent {
+	return entity.AuditEvent{
+		Action:     action,
+		TargetType: "quorum_rule",
+		TargetID:   strconv.FormatInt(ruleID, 10),
+	}
+}
@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"watermap/internal/domain/entity"
+)
+
+// maxBatchSize bounds the objects one batch request may decide
+const maxBatchSize = 500
+
+// BatchReviewRequest selects pending objects either by id or by filter
+type BatchReviewRequest struct {
+	IDs    []int64            `json:"ids"`
+	Filter *BatchReviewFilter `json:"filter"`
+	// Note is shared by every item: the approval note, or the reason of a
+	// rejection, where it is required
+	Note *string `json:"note"`
+}
+
+// BatchReviewFilter matches pending objects within the reviewer's scope
+type BatchReviewFilter struct {
+	ObjectType *string `json:"object_type"`
+	Region     *string `json:"region"`
+	CreatedBy  *int64  `json:"created_by"`
+}
+
+func (f *BatchReviewFilter) matches(obj *entity.WaterObject) bool {
+	if f.ObjectType != nil && string(obj.ObjectType) != *f.ObjectType {
+		return false
+	}
+	if f.Region != nil && (obj.Region == nil || string(*obj.Region) != *f.Region) {
+		return false
+	}
+	if f.CreatedBy != nil && obj.CreatedBy != *f.CreatedBy {
+		return false
+	}
+	return true
+}
+
+// BatchApprove approves many pending objects, each on its own as if
+// approved one by one
+func (h *AdminHandler) BatchApprove(c *gin.Context) {
+	h.batch(c, entity.TransitionApprove)
+}
+
+// BatchReject rejects many pending objects with a shared reason
+func (h *AdminHandler) BatchReject(c *gin.Context) {
+	h.batch(c, entity.TransitionReject)
+}
+
+// batch applies a review decision to each selected object in turn. Every
+// object is stored in its own transaction, so one failure does not undo the
+// others; the response reports the outcome per object.
+func (h *AdminHandler) batch(c *gin.Context, t entity.Transition) {
+	var req BatchReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if (len(req.IDs) == 0) == (req.Filter == nil) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": "give either ids or a filter",
+		})
+		return
+	}
+	reason := ""
+	if req.Note != nil {
+		reason = strings.TrimSpace(*req.Note)
+	}
+	if t != entity.TransitionApprove && reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": "note is required as the reason",
+		})
+		return
+	}
+
+	ids := req.IDs
+	if req.Filter != nil {
+		selected, ok := h.selectPending(c, req.Filter)
+		if !ok {
+			return
+		}
+		ids = selected
+	}
+	if len(ids) > maxBatchSize {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "batch_too_large",
+			"message": fmt.Sprintf("a batch can decide at most %d objects; narrow the selection", maxBatchSize),
+			"matched": len(ids),
+		})
+		return
+	}
+
+	results := make([]gin.H, 0, len(ids))
+	succeeded := 0
+	seen := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		var result gin.H
+		var failed *requestError
+		if t == entity.TransitionApprove {
+			result, failed = h.approve(c, id, req.Note)
+		} else {
+			result, failed = h.decline(c, id, t, reason)
+		}
+
+		item := gin.H{"id": id, "ok": failed == nil, "status": http.StatusOK}
+		if failed != nil {
+			item["status"] = failed.status
+			result = failed.body
+		} else {
+			succeeded++
+		}
+		for k, v := range result {
+			item[k] = v
+		}
+		results = append(results, item)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"results":   results,
+		"succeeded": succeeded,
+		"failed":    len(results) - succeeded,
+	})
+}
+
+// selectPending returns the ids of the pending objects within the
+// reviewer's scope that match the filter, oldest first
+func (h *AdminHandler) selectPending(c *gin.Context, filter *BatchReviewFilter) ([]int64, bool) {
+	pending, err := h.waterObjectRepo.GetPending(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "fetch_failed",
+			"message": err.Error(),
+		})
+		return nil, false
+	}
+
+	perms := permissionsFrom(c)
+	var ids []int64
+	for _, obj := range pending {
+		if filter.matches(obj) && perms.Allows(entity.PermObjectsReview, obj) {
+			ids = append(ids, obj.ID)
+		}
+	}
+	return ids, true
+}
@@ -0,0 +1,367 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"watermap/internal/domain/entity"
+	"watermap/internal/domain/repository"
+)
+
+// Provenance keeps track of the sources behind objects and holds back
+// measurement changes that cite none
+type Provenance struct {
+	objects repository.WaterObjectRepository
+	sources repository.SourceRepository
+}
+
+func NewProvenance(objects repository.WaterObjectRepository, sources repository.SourceRepository) *Provenance {
+	return &Provenance{objects: objects, sources: sources}
+}
+
+// Attach fills in the citations of the objects
+func (p *Provenance) Attach(ctx context.Context, objects ...*entity.WaterObject) error {
+	ids := make([]int64, 0, len(objects))
+	byID := make(map[int64]*entity.WaterObject, len(objects))
+	for _, obj := range objects {
+		if obj != nil {
+			ids = append(ids, obj.ID)
+			byID[obj.ID] = obj
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	citations, err := p.sources.Citations(ctx, ids...)
+	if err != nil {
+		return err
+	}
+	for _, c := range citations {
+		obj := byID[c.ObjectID]
+		obj.Sources = append(obj.Sources, c)
+	}
+	return nil
+}
+
+// Uncited returns the measured fields in which the object differs from its
+// published version without citing a source for them
+func (p *Provenance) Uncited(ctx context.Context, obj *entity.WaterObject) ([]string, error) {
+	published, err := p.objects.GetByCanonicalID(ctx, obj.CanonicalID.String(), entity.StatusPublished)
+	if err != nil && err != entity.ErrNotFound {
+		return nil, err
+	}
+	if published != nil && published.ID == obj.ID {
+		return nil, nil
+	}
+
+	changed := obj.ChangedMeasurements(published)
+	if len(changed) == 0 {
+		return nil, nil
+	}
+	citations, err := p.sources.Citations(ctx, obj.ID)
+	if err != nil {
+		return nil, err
+	}
+	return entity.UncitedFields(changed, citations), nil
+}
+
+// check is Uncited as a request error, for handlers gating a transition
+func (p *Provenance) check(ctx context.Context, obj *entity.WaterObject) *requestError {
+	fields, err := p.Uncited(ctx, obj)
+	if err != nil {
+		return &requestError{http.StatusInternalServerError, gin.H{
+			"error":   "sources_check_failed",
+			"message": err.Error(),
+		}}
+	}
+	if len(fields) > 0 {
+		return &requestError{http.StatusUnprocessableEntity, gin.H{
+			"error":   "sources_required",
+			"message": "changed measurements need at least one source; cite one for the object or for each field",
+			"fields":  fields,
+		}}
+	}
+	return nil
+}
+
+type SourceHandler struct {
+	repo    repository.SourceRepository
+	objects repository.WaterObjectRepository
+	orgRepo repository.OrganizationRepository
+}
+
+func NewSourceHandler(repo repository.SourceRepository, objects repository.WaterObjectRepository, orgRepo repository.OrganizationRepository) *SourceHandler {
+	return &SourceHandler{
+		repo:    repo,
+		objects: objects,
+		orgRepo: orgRepo,
+	}
+}
+
+const maxSourcePageSize = 200
+
+// List searches the bibliography by title, authors or DOI with ?q=
+func (h *SourceHandler) List(c *gin.Context) {
+	limit := 50
+	if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 {
+		limit = min(l, maxSourcePageSize)
+	}
+	offset := 0
+	if o, err := strconv.Atoi(c.Query("offset")); err == nil && o > 0 {
+		offset = o
+	}
+
+	sources, err := h.repo.Search(c.Request.Context(), c.Query("q"), limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "fetch_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sources": sources})
+}
+
+func (h *SourceHandler) Get(c *gin.Context) {
+	source, ok := h.load(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, source)
+}
+
+type SourceRequest struct {
+	Title     string  `json:"title" binding:"required"`
+	Authors   *string `json:"authors"`
+	Publisher *string `json:"publisher"`
+	Year      int     `json:"year" binding:"required"`
+	URL       *string `json:"url"`
+	DOI       *string `json:"doi"`
+	Licence   string  `json:"licence" binding:"required"`
+}
+
+func (r *SourceRequest) source() *entity.Source {
+	return &entity.Source{
+		Title:     r.Title,
+		Authors:   r.Authors,
+		Publisher: r.Publisher,
+		Year:      r.Year,
+		URL:       r.URL,
+		DOI:       r.DOI,
+		Licence:   r.Licence,
+	}
+}
+
+// Create adds an entry to the bibliography
+func (h *SourceHandler) Create(c *gin.Context) {
+	var req SourceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	source := req.source()
+	if err := source.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": err.Error(),
+		})
+		return
+	}
+	userID := c.GetInt64("user_id")
+	source.CreatedBy = &userID
+
+	created, err := h.repo.Create(c.Request.Context(), source)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "create_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, created)
+}
+
+// Update corrects a bibliographic entry. Sources are shared, so only the
+// user who added one or a reviewer may change it.
+func (h *SourceHandler) Update(c *gin.Context) {
+	existing, ok := h.load(c)
+	if !ok {
+		return
+	}
+
+	userID := c.GetInt64("user_id")
+	if (existing.CreatedBy == nil || *existing.CreatedBy != userID) && !permissionsFrom(c).Has(entity.PermObjectsReview) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "forbidden",
+			"message": "only the user who added a source or a reviewer can change it",
+		})
+		return
+	}
+
+	var req SourceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	source := req.source()
+	if err := source.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": err.Error(),
+		})
+		return
+	}
+	source.ID = existing.ID
+	source.CreatedBy = existing.CreatedBy
+	source.CreatedAt = existing.CreatedAt
+
+	if err := h.repo.Update(c.Request.Context(), source); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "update_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, source)
+}
+
+func (h *SourceHandler) load(c *gin.Context) (*entity.Source, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_id",
+			"message": "invalid source id",
+		})
+		return nil, false
+	}
+
+	source, err := h.repo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		if err == entity.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"message": "source not found",
+			})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "fetch_failed",
+			"message": err.Error(),
+		})
+		return nil, false
+	}
+	return source, true
+}
+
+type CiteRequest struct {
+	SourceID int64 `json:"source_id" binding:"required"`
+	// Field names the measurement the source backs; empty cites the object
+	Field   *string `json:"field"`
+	Locator *string `json:"locator"`
+}
+
+// Cite attaches a source to a draft, as a whole or for one measurement
+func (h *SourceHandler) Cite(c *gin.Context) {
+	obj, ok := editableObject(c, h.objects, h.orgRepo)
+	if !ok {
+		return
+	}
+
+	var req CiteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": err.Error(),
+		})
+		return
+	}
+	if req.Field != nil && !entity.IsMeasurementField(*req.Field) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": entity.ErrInvalidCiteField.Error(),
+			"allowed": entity.MeasurementFields,
+		})
+		return
+	}
+
+	userID := c.GetInt64("user_id")
+	citation, err := h.repo.Cite(c.Request.Context(), &entity.Citation{
+		ObjectID:  obj.ID,
+		SourceID:  req.SourceID,
+		Field:     req.Field,
+		Locator:   req.Locator,
+		CreatedBy: &userID,
+	})
+	if err != nil {
+		switch err {
+		case entity.ErrNotFound:
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"message": "source not found",
+			})
+			return
+		case entity.ErrDuplicateCitation:
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "duplicate_citation",
+				"message": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "cite_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, citation)
+}
+
+// Uncite removes a citation from a draft
+func (h *SourceHandler) Uncite(c *gin.Context) {
+	citationID, err := strconv.ParseInt(c.Param("citationId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_id",
+			"message": "invalid citation id",
+		})
+		return
+	}
+
+	obj, ok := editableObject(c, h.objects, h.orgRepo)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.Uncite(c.Request.Context(), obj.ID, citationID); err != nil {
+		if err == entity.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"message": "citation not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "delete_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "citation removed"})
+}
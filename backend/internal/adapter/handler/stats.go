@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"watermap/internal/domain/entity"
+	"watermap/internal/domain/repository"
+)
+
+// statsWeeks is how many weeks of submission history the dashboard shows
+const statsWeeks = 12
+
+type StatsHandler struct {
+	repo repository.StatsRepository
+	ttl  time.Duration
+
+	mu     sync.Mutex
+	cached *entity.Stats
+}
+
+func NewStatsHandler(repo repository.StatsRepository, ttl time.Duration) *StatsHandler {
+	return &StatsHandler{repo: repo, ttl: ttl}
+}
+
+// Get returns the dashboard statistics, recomputed at most once per TTL
+func (h *StatsHandler) Get(c *gin.Context) {
+	stats, err := h.current(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "stats_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.Header("Cache-Control", "private, max-age="+strconv.Itoa(int(h.ttl.Seconds())))
+	c.JSON(http.StatusOK, gin.H{"stats": stats})
+}
+
+// current returns the cached statistics while fresh. Concurrent requests
+// after expiry wait for a single recomputation rather than each running
+// the aggregates.
+func (h *StatsHandler) current(ctx context.Context) (*entity.Stats, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.cached != nil && time.Since(h.cached.GeneratedAt) < h.ttl {
+		return h.cached, nil
+	}
+
+	stats, err := h.repo.Collect(ctx, statsWeeks)
+	if err != nil {
+		return nil, err
+	}
+	h.cached = stats
+	return stats, nil
+}
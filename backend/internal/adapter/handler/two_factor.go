@@ -0,0 +1,565 @@
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+
+	"watermap/internal/adapter/middleware"
+	"watermap/internal/domain/entity"
+	"watermap/internal/infrastructure/totp"
+)
+
+const (
+	mfaChallengeTTL   = 5 * time.Minute
+	mfaIssuer         = "WaterMap"
+	recoveryCodeCount = 10
+)
+
+var errInvalidChallenge = errors.New("invalid or expired challenge")
+
+// generateChallenge issues the token that proves the password step passed.
+// enroll marks challenges for users who still have to set up TOTP.
+func (h *AuthHandler) generateChallenge(userID int64, enroll bool) (string, error) {
+	claims := jwt.MapClaims{
+		"id":     userID,
+		"typ":    entity.TokenTypeMFAChallenge,
+		"enroll": enroll,
+		"exp":    time.Now().Add(mfaChallengeTTL).Unix(),
+		"iat":    time.Now().Unix(),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(h.jwtSecret))
+}
+
+// ChallengeSubject returns the user a login challenge was issued to, for
+// limiting attempts at the second step per account
+func (h *AuthHandler) ChallengeSubject(challenge string) (int64, bool) {
+	userID, _, err := h.parseChallenge(challenge)
+	return userID, err == nil
+}
+
+func (h *AuthHandler) parseChallenge(raw string) (int64, bool, error) {
+	token, err := jwt.Parse(raw, func(t *jwt.Token) (interface{}, error) {
+		return []byte(h.jwtSecret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil || !token.Valid {
+		return 0, false, errInvalidChallenge
+	}
+
+	claims := token.Claims.(jwt.MapClaims)
+	if typ, _ := claims["typ"].(string); typ != entity.TokenTypeMFAChallenge {
+		return 0, false, errInvalidChallenge
+	}
+	id, ok := claims["id"].(float64)
+	if !ok {
+		return 0, false, errInvalidChallenge
+	}
+	enroll, _ := claims["enroll"].(bool)
+	return int64(id), enroll, nil
+}
+
+// generateRecoveryCodes returns fresh codes and the hashes to store
+func generateRecoveryCodes() ([]string, []string, error) {
+	enc := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+
+	for i := 0; i < recoveryCodeCount; i++ {
+		buf := make([]byte, 7)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(enc.EncodeToString(buf))[:10]
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+		hashes = append(hashes, hashToken(raw))
+	}
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
+
+// verifyCode checks a TOTP code against the enrolment and consumes its step
+func (h *AuthHandler) verifyCode(c *gin.Context, tf *entity.TwoFactor, code string) error {
+	step, ok := totp.Validate(tf.Secret, code, time.Now())
+	if !ok {
+		return entity.ErrInvalidOTP
+	}
+
+	fresh, err := h.twoFactorRepo.UseStep(c.Request.Context(), tf.UserID, step)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return entity.ErrInvalidOTP
+	}
+	return nil
+}
+
+// issueRecoveryCodes replaces the user's recovery codes and returns the new ones
+func (h *AuthHandler) issueRecoveryCodes(c *gin.Context, userID int64) ([]string, error) {
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := h.twoFactorRepo.ReplaceRecoveryCodes(c.Request.Context(), userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (h *AuthHandler) enrollmentResponse(c *gin.Context, user *entity.User) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "enroll_failed",
+			"message": "failed to generate secret",
+		})
+		return
+	}
+
+	if err := h.twoFactorRepo.SavePending(c.Request.Context(), user.ID, secret); err != nil {
+		if err == entity.ErrTwoFactorEnrolled {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "already_enrolled",
+				"message": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "enroll_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":           secret,
+		"provisioning_uri": totp.ProvisioningURI(mfaIssuer, user.Email, secret),
+	})
+}
+
+type ChallengeRequest struct {
+	Challenge string `json:"challenge" binding:"required"`
+}
+
+type VerifyTwoFactorRequest struct {
+	Challenge    string `json:"challenge" binding:"required"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// EnrollWithChallenge starts TOTP enrolment during login for users whose
+// role requires two-factor authentication but who have not set it up yet
+func (h *AuthHandler) EnrollWithChallenge(c *gin.Context) {
+	var req ChallengeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	userID, enroll, err := h.parseChallenge(req.Challenge)
+	if err != nil || !enroll {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "invalid_challenge",
+			"message": errInvalidChallenge.Error(),
+		})
+		return
+	}
+
+	user, err := h.userRepo.GetByID(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "invalid_challenge",
+			"message": errInvalidChallenge.Error(),
+		})
+		return
+	}
+	if !user.IsActive() {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "account_inactive",
+			"message": entity.ErrAccountInactive.Error(),
+		})
+		return
+	}
+
+	h.enrollmentResponse(c, user)
+}
+
+// LoginTwoFactor completes a login with a TOTP or recovery code. For
+// enrolment challenges the code also confirms the new authenticator.
+func (h *AuthHandler) LoginTwoFactor(c *gin.Context) {
+	var req VerifyTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil || (req.Code == "" && req.RecoveryCode == "") {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": "challenge and code or recovery_code are required",
+		})
+		return
+	}
+
+	userID, enroll, err := h.parseChallenge(req.Challenge)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "invalid_challenge",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, err := h.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "invalid_challenge",
+			"message": errInvalidChallenge.Error(),
+		})
+		return
+	}
+
+	if wait := user.LockedFor(time.Now()); wait > 0 {
+		middleware.TooManyRequests(c, wait, "account_locked", accountLockedMessage)
+		return
+	}
+
+	// The account may have been deactivated since the password step
+	if !user.IsActive() {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "account_inactive",
+			"message": entity.ErrAccountInactive.Error(),
+		})
+		return
+	}
+
+	tf, err := h.twoFactorRepo.Get(ctx, userID)
+	if err != nil {
+		if err == entity.ErrNotFound {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "enrollment_required",
+				"message": "start enrolment before verifying a code",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "verify_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	var recoveryCodes []string
+
+	switch {
+	case enroll && !tf.IsEnabled():
+		step, ok := totp.Validate(tf.Secret, req.Code, time.Now())
+		if !ok {
+			h.recordLoginFailure(c, user, "totp")
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error":   "invalid_code",
+				"message": entity.ErrInvalidOTP.Error(),
+			})
+			return
+		}
+		if err := h.twoFactorRepo.Confirm(ctx, userID, step); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "verify_failed",
+				"message": err.Error(),
+			})
+			return
+		}
+		if recoveryCodes, err = h.issueRecoveryCodes(c, userID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "verify_failed",
+				"message": err.Error(),
+			})
+			return
+		}
+		event := auditUser(entity.AuditTwoFactorOn, userID)
+		event.ActorID = &userID
+		h.audit.Record(c, event, nil, nil)
+
+	case !tf.IsEnabled():
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "enrollment_required",
+			"message": entity.ErrTwoFactorNotActive.Error(),
+		})
+		return
+
+	case req.RecoveryCode != "":
+		ok, err := h.twoFactorRepo.UseRecoveryCode(ctx, userID, hashToken(normalizeRecoveryCode(req.RecoveryCode)))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "verify_failed",
+				"message": err.Error(),
+			})
+			return
+		}
+		if !ok {
+			h.recordLoginFailure(c, user, "recovery_code")
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error":   "invalid_code",
+				"message": "invalid recovery code",
+			})
+			return
+		}
+
+	default:
+		if err := h.verifyCode(c, tf, req.Code); err != nil {
+			if err == entity.ErrInvalidOTP {
+				h.recordLoginFailure(c, user, "totp")
+				c.JSON(http.StatusUnauthorized, gin.H{
+					"error":   "invalid_code",
+					"message": err.Error(),
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "verify_failed",
+				"message": err.Error(),
+			})
+			return
+		}
+	}
+
+	h.resetLoginFailures(c, user)
+
+	token, err := h.startSession(c, user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "token_failed",
+			"message": "failed to generate token",
+		})
+		return
+	}
+
+	resp := gin.H{
+		"user": gin.H{
+			"id":    user.ID,
+			"name":  user.Name,
+			"email": user.Email,
+			"role":  user.Role,
+		},
+		"access_token": token,
+		"expires_in":   int(h.accessTTL.Seconds()),
+	}
+	if recoveryCodes != nil {
+		resp["recovery_codes"] = recoveryCodes
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// TwoFactorStatus reports whether the current user has TOTP enabled
+func (h *AuthHandler) TwoFactorStatus(c *gin.Context) {
+	user := c.MustGet("user").(*entity.User)
+
+	tf, err := h.twoFactorRepo.Get(c.Request.Context(), user.ID)
+	if err != nil && err != entity.ErrNotFound {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "fetch_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	remaining := 0
+	if tf.IsEnabled() {
+		if remaining, err = h.twoFactorRepo.CountRecoveryCodes(c.Request.Context(), user.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "fetch_failed",
+				"message": err.Error(),
+			})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":                  tf.IsEnabled(),
+		"required":                 user.Role.RequiresTwoFactor(),
+		"recovery_codes_remaining": remaining,
+	})
+}
+
+// EnrollTwoFactor starts TOTP enrolment for a signed-in user
+func (h *AuthHandler) EnrollTwoFactor(c *gin.Context) {
+	user := c.MustGet("user").(*entity.User)
+
+	tf, err := h.twoFactorRepo.Get(c.Request.Context(), user.ID)
+	if err != nil && err != entity.ErrNotFound {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "enroll_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+	if tf.IsEnabled() {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "already_enabled",
+			"message": "two-factor authentication is already enabled",
+		})
+		return
+	}
+
+	h.enrollmentResponse(c, user)
+}
+
+// ConfirmTwoFactor verifies the first code from a new authenticator and
+// returns the recovery codes
+func (h *AuthHandler) ConfirmTwoFactor(c *gin.Context) {
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	userID := c.GetInt64("user_id")
+	tf, err := h.twoFactorRepo.Get(c.Request.Context(), userID)
+	if err != nil || tf.IsEnabled() {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "no_pending_enrollment",
+			"message": "start enrolment before confirming",
+		})
+		return
+	}
+
+	step, ok := totp.Validate(tf.Secret, req.Code, time.Now())
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "invalid_code",
+			"message": entity.ErrInvalidOTP.Error(),
+		})
+		return
+	}
+
+	if err := h.twoFactorRepo.Confirm(c.Request.Context(), userID, step); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "confirm_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	codes, err := h.issueRecoveryCodes(c, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "confirm_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+	h.audit.Record(c, auditUser(entity.AuditTwoFactorOn, userID), nil, nil)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "two-factor authentication enabled",
+		"recovery_codes": codes,
+	})
+}
+
+// DisableTwoFactor removes TOTP for users whose role does not require it
+func (h *AuthHandler) DisableTwoFactor(c *gin.Context) {
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	user := c.MustGet("user").(*entity.User)
+	if user.Role.RequiresTwoFactor() {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "two_factor_required",
+			"message": entity.ErrTwoFactorRequired.Error(),
+		})
+		return
+	}
+
+	tf, err := h.twoFactorRepo.Get(c.Request.Context(), user.ID)
+	if err != nil || !tf.IsEnabled() {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "not_enabled",
+			"message": entity.ErrTwoFactorNotActive.Error(),
+		})
+		return
+	}
+
+	if err := h.verifyCode(c, tf, req.Code); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "invalid_code",
+			"message": entity.ErrInvalidOTP.Error(),
+		})
+		return
+	}
+
+	if err := h.twoFactorRepo.Delete(c.Request.Context(), user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "disable_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+	h.audit.Record(c, auditUser(entity.AuditTwoFactorOff, user.ID), nil, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes after checking a TOTP code
+func (h *AuthHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	userID := c.GetInt64("user_id")
+	tf, err := h.twoFactorRepo.Get(c.Request.Context(), userID)
+	if err != nil || !tf.IsEnabled() {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "not_enabled",
+			"message": entity.ErrTwoFactorNotActive.Error(),
+		})
+		return
+	}
+
+	if err := h.verifyCode(c, tf, req.Code); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "invalid_code",
+			"message": entity.ErrInvalidOTP.Error(),
+		})
+		return
+	}
+
+	codes, err := h.issueRecoveryCodes(c, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "regenerate_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+	h.audit.Record(c, auditUser(entity.AuditRecoveryCodes, userID), nil, nil)
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"watermap/internal/domain/entity"
+	"watermap/internal/domain/repository"
+	"watermap/internal/infrastructure/totp"
+)
+
+// stepRepo keeps the last used step the way the user_two_factor row does
+type stepRepo struct {
+	repository.TwoFactorRepository
+	last int64
+}
+
+func (r *stepRepo) UseStep(_ context.Context, _ int64, step int64) (bool, error) {
+	if step <= r.last {
+		return false, nil
+	}
+	r.last = step
+	return true, nil
+}
+
+func TestVerifyCodeRejectsReplays(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	step := totp.Step(time.Now())
+	code := func(s int64) string {
+		c, err := totp.CodeAt(secret, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	repo := &stepRepo{}
+	h := &AuthHandler{twoFactorRepo: repo}
+	tf := &entity.TwoFactor{UserID: 1, Secret: secret}
+
+	// The cases run in order against the same enrolment
+	tests := []struct {
+		name string
+		code string
+		want error
+	}{
+		{"previous step", code(step - 1), nil},
+		{"current step", code(step), nil},
+		{"current step replayed", code(step), entity.ErrInvalidOTP},
+		{"older step after a newer one", code(step - 1), entity.ErrInvalidOTP},
+		{"wrong code", "12345", entity.ErrInvalidOTP},
+	}
+	for _, tt := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("POST", "/api/auth/2fa/verify", nil)
+		if err := h.verifyCode(c, tf, tt.code); err != tt.want {
+			t.Errorf("%s: verifyCode = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+// accountRepo holds a single user
+type accountRepo struct {
+	repository.UserRepository
+	user *entity.User
+}
+
+func (r *accountRepo) GetByID(_ context.Context, id int64) (*entity.User, error) {
+	if r.user == nil || r.user.ID != id {
+		return nil, entity.ErrNotFound
+	}
+	return r.user, nil
+}
+
+func TestChallengeStepsRefuseInactiveAccounts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := totp.CodeAt(secret, totp.Step(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	confirmed := time.Now().Add(-24 * time.Hour)
+	deactivated := time.Now().Add(-time.Minute)
+
+	// No session repository: reaching startSession would panic
+	user := &entity.User{ID: 5, DeactivatedAt: &deactivated}
+	h := &AuthHandler{
+		jwtSecret:     "test-secret",
+		userRepo:      &accountRepo{user: user},
+		twoFactorRepo: &enrolledRepo{tf: &entity.TwoFactor{UserID: 5, Secret: secret, ConfirmedAt: &confirmed}},
+	}
+
+	login, err := h.generateChallenge(5, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enroll, err := h.generateChallenge(5, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, ok := h.ChallengeSubject(login); !ok || id != 5 {
+		t.Errorf("ChallengeSubject = %d, %v, want 5", id, ok)
+	}
+	if _, ok := h.ChallengeSubject("forged"); ok {
+		t.Error("ChallengeSubject accepted a forged challenge")
+	}
+
+	tests := []struct {
+		name    string
+		handler gin.HandlerFunc
+		body    string
+	}{
+		{"login", h.LoginTwoFactor, `{"challenge": "` + login + `", "code": "` + code + `"}`},
+		{"enrolment", h.EnrollWithChallenge, `{"challenge": "` + enroll + `"}`},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/api/auth/login/2fa", strings.NewReader(tt.body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		tt.handler(c)
+		if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), "account_inactive") {
+			t.Errorf("%s: status = %d %s, want 403 account_inactive", tt.name, w.Code, w.Body)
+		}
+	}
+}
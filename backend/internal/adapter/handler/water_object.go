@@ -2,25 +2,49 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/paulmach/orb/geojson"
 
 	"watermap/internal/domain/entity"
 	"watermap/internal/domain/repository"
+	"watermap/internal/infrastructure/crs"
+	"watermap/internal/infrastructure/geometry"
 	"watermap/internal/infrastructure/validator"
 )
 
 type WaterObjectHandler struct {
-	repo      repository.WaterObjectRepository
-	validator *validator.GeometryValidator
+	repo       repository.WaterObjectRepository
+	orgRepo    repository.OrganizationRepository
+	validator  *validator.GeometryValidator
+	quality    *QualityChecker
+	provenance *Provenance
+	files      *Attachments
+	simplifier *geometry.Simplifier
+	workflow   *entity.Workflow
+	audit      *Auditor
+	notify     *Notifier
 }
 
-func NewWaterObjectHandler(repo repository.WaterObjectRepository, validator *validator.GeometryValidator) *WaterObjectHandler {
+func NewWaterObjectHandler(repo repository.WaterObjectRepository, orgRepo repository.OrganizationRepository, validator *validator.GeometryValidator, quality *QualityChecker, provenance *Provenance, files *Attachments, simplifier *geometry.Simplifier, workflow *entity.Workflow, auditor *Auditor, notifier *Notifier) *WaterObjectHandler {
 	return &WaterObjectHandler{
-		repo:      repo,
-		validator: validator,
+		repo:       repo,
+		orgRepo:    orgRepo,
+		validator:  validator,
+		quality:    quality,
+		provenance: provenance,
+		files:      files,
+		simplifier: simplifier,
+		workflow:   workflow,
+		audit:      auditor,
+		notify:     notifier,
 	}
 }
 
@@ -29,6 +53,7 @@ type CreateWaterObjectRequest struct {
 	NameRU          *string         `json:"name_ru"`
 	NameEN          *string         `json:"name_en"`
 	ObjectType      string          `json:"object_type" binding:"required"`
+	Region          *string         `json:"region"`
 	Geometry        json.RawMessage `json:"geometry" binding:"required"`
 	LengthKm        *float64        `json:"length_km"`
 	AreaKm2         *float64        `json:"area_km2"`
@@ -43,61 +68,128 @@ type CreateWaterObjectRequest struct {
 	DescriptionKZ   *string         `json:"description_kz"`
 	DescriptionRU   *string         `json:"description_ru"`
 	DescriptionEN   *string         `json:"description_en"`
+	HistoricalNotes *string         `json:"historical_notes"`
+	CRS             *string         `json:"crs"`
+	// OrganizationID shares a new draft with an organization; it is ignored on update
+	OrganizationID *int64 `json:"organization_id"`
 }
 
-// GetPublished returns all published water objects as GeoJSON FeatureCollection
+// GetPublished streams all published water objects as a GeoJSON FeatureCollection,
+// or as a GeoJSON text sequence when application/geo+json-seq is requested
 func (h *WaterObjectHandler) GetPublished(c *gin.Context) {
 	filter := &repository.WaterObjectFilter{}
 
 	if objType := c.Query("type"); objType != "" {
 		filter.ObjectType = entity.ObjectType(objType)
 	}
+	if limit, err := strconv.Atoi(c.Query("limit")); err == nil && limit > 0 {
+		filter.Limit = limit
+	}
+	if offset, err := strconv.Atoi(c.Query("offset")); err == nil && offset > 0 {
+		filter.Offset = offset
+	}
 
-	objects, err := h.repo.GetPublished(c.Request.Context(), filter)
+	opts, err := parseSimplifyOptions(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "fetch_failed",
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
 			"message": err.Error(),
 		})
 		return
 	}
 
-	// Convert to GeoJSON FeatureCollection
-	features := make([]map[string]interface{}, 0, len(objects))
-	for _, obj := range objects {
-		feature := map[string]interface{}{
-			"type":     "Feature",
-			"geometry": obj.Geometry,
-			"properties": map[string]interface{}{
-				"id":           obj.ID,
-				"canonical_id": obj.CanonicalID,
-				"name_kz":      obj.NameKZ,
-				"name_ru":      obj.NameRU,
-				"name_en":      obj.NameEN,
-				"object_type":  obj.ObjectType,
-				"length_km":    obj.LengthKm,
-				"area_km2":     obj.AreaKm2,
-			},
-		}
-		features = append(features, feature)
+	outCRS, err := parseOutputCRS(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "crs_error",
+			"message": err.Error(),
+		})
+		return
 	}
+	c.Header("Content-Crs", "<"+outCRS.Code+">")
 
-	c.JSON(http.StatusOK, gin.H{
-		"type":     "FeatureCollection",
-		"features": features,
-		"metadata": gin.H{
-			"total": len(features),
-		},
+	seq := wantsGeoJSONSeq(c)
+	fw := newFeatureWriter(c, seq)
+	exported := 0
+
+	err = h.repo.StreamPublished(c.Request.Context(), filter, func(obj *entity.WaterObject) error {
+		exported++
+		if err := h.applySimplify(obj, opts); err != nil {
+			return err
+		}
+		if err := reprojectOutput(obj, outCRS); err != nil {
+			return err
+		}
+		return fw.WriteFeature(toFeature(obj))
 	})
+	if err != nil {
+		if !fw.Started() {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "fetch_failed",
+				"message": err.Error(),
+			})
+			return
+		}
+		// Headers are already sent; the truncated body is the only signal left
+		log.Printf("stream published objects: %v", err)
+		c.Abort()
+		return
+	}
+
+	if err := fw.Close(); err != nil {
+		log.Printf("finish published stream: %v", err)
+	}
+
+	// Text sequences are the bulk export format; the map's own
+	// FeatureCollection reads are too frequent to be worth auditing, and
+	// anonymous exports have no actor and would only flood the log
+	if _, identified := c.Get("user_id"); seq && identified {
+		h.audit.Record(c, entity.AuditEvent{Action: entity.AuditDataExported, TargetType: "water_objects"}, nil, gin.H{
+			"format":   "geojsonseq",
+			"crs":      outCRS.Code,
+			"query":    c.Request.URL.RawQuery,
+			"features": exported,
+		})
+	}
 }
 
 // GetByCanonicalID returns a single published water object
 func (h *WaterObjectHandler) GetByCanonicalID(c *gin.Context) {
 	canonicalID := c.Param("canonicalId")
 
+	opts, err := parseSimplifyOptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	outCRS, err := parseOutputCRS(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "crs_error",
+			"message": err.Error(),
+		})
+		return
+	}
+
 	obj, err := h.repo.GetByCanonicalID(c.Request.Context(), canonicalID, entity.StatusPublished)
 	if err != nil {
 		if err == entity.ErrNotFound {
+			// Objects merged into another keep resolving under their old id
+			if target, err := h.repo.MergedInto(c.Request.Context(), canonicalID); err == nil {
+				location := *c.Request.URL
+				location.Path = strings.TrimSuffix(location.Path, canonicalID) + target.String()
+				c.Header("Location", location.String())
+				c.JSON(http.StatusMovedPermanently, gin.H{
+					"error":        "merged",
+					"message":      "water object was merged into another",
+					"canonical_id": target,
+				})
+				return
+			}
 			c.JSON(http.StatusNotFound, gin.H{
 				"error":   "not_found",
 				"message": "water object not found",
@@ -111,14 +203,156 @@ func (h *WaterObjectHandler) GetByCanonicalID(c *gin.Context) {
 		return
 	}
 
+	err = h.provenance.Attach(c.Request.Context(), obj)
+	if err == nil {
+		err = h.files.Attach(c.Request.Context(), obj)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "fetch_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if err := h.applySimplify(obj, opts); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "simplify_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if err := reprojectOutput(obj, outCRS); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "crs_error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.Header("Content-Crs", "<"+outCRS.Code+">")
+
 	c.JSON(http.StatusOK, gin.H{"data": obj})
 }
 
-// GetMyDrafts returns the current user's drafts
+// simplifyOptions describes the optional read-time geometry simplification
+type simplifyOptions struct {
+	tolerance float64
+	method    geometry.Method
+}
+
+// parseSimplifyOptions reads simplify=<tolerance in degrees> or zoom=<z> and
+// the optional simplify_method=dp|vw. A nil result means full resolution.
+func parseSimplifyOptions(c *gin.Context) (*simplifyOptions, error) {
+	opts := &simplifyOptions{method: geometry.MethodDouglasPeucker}
+
+	if m := c.Query("simplify_method"); m != "" {
+		opts.method = geometry.Method(m)
+		if !opts.method.IsValid() {
+			return nil, errors.New("simplify_method must be dp or vw")
+		}
+	}
+
+	if raw := c.Query("simplify"); raw != "" {
+		tolerance, err := strconv.ParseFloat(raw, 64)
+		if err != nil || math.IsNaN(tolerance) || tolerance <= 0 || math.IsInf(tolerance, 0) {
+			return nil, geometry.ErrInvalidTolerance
+		}
+		opts.tolerance = tolerance
+		return opts, nil
+	}
+
+	if raw := c.Query("zoom"); raw != "" {
+		zoom, err := strconv.Atoi(raw)
+		if err != nil || zoom < 0 || zoom > geometry.MaxZoom {
+			return nil, fmt.Errorf("zoom must be between 0 and %d", geometry.MaxZoom)
+		}
+		opts.tolerance = geometry.ToleranceForZoom(zoom)
+		return opts, nil
+	}
+
+	return nil, nil
+}
+
+func (h *WaterObjectHandler) applySimplify(obj *entity.WaterObject, opts *simplifyOptions) error {
+	if opts == nil {
+		return nil
+	}
+
+	geom, err := h.simplifier.SimplifyObject(obj, opts.tolerance, opts.method)
+	if err != nil {
+		return err
+	}
+	obj.Geometry = geom
+	return nil
+}
+
+// parseOutputCRS reads the optional crs= query parameter, defaulting to EPSG:4326
+func parseOutputCRS(c *gin.Context) (*crs.CRS, error) {
+	code := c.Query("crs")
+	if code == "" {
+		code = crs.WGS84
+	}
+	return crs.Lookup(code)
+}
+
+func reprojectOutput(obj *entity.WaterObject, target *crs.CRS) error {
+	if target.IsWGS84() {
+		return nil
+	}
+
+	g, err := geometry.ToOrb(obj.Geometry)
+	if err != nil {
+		return err
+	}
+
+	geom, err := geometry.FromOrb(crs.FromWGS84(g, target))
+	if err != nil {
+		return err
+	}
+	obj.Geometry = geom
+	return nil
+}
+
+// reprojectInput converts a submitted geometry to EPSG:4326 and returns the
+// normalised source CRS to record for provenance
+func reprojectInput(raw json.RawMessage, code *string) (json.RawMessage, *string, error) {
+	if code == nil || *code == "" {
+		return raw, nil, nil
+	}
+
+	source, err := crs.Lookup(*code)
+	if err != nil {
+		return nil, nil, err
+	}
+	if source.IsWGS84() {
+		return raw, &source.Code, nil
+	}
+
+	g, err := geojson.UnmarshalGeometry(raw)
+	if err != nil || g.Geometry() == nil {
+		return nil, nil, validator.ErrInvalidGeoJSON
+	}
+
+	out, err := json.Marshal(geojson.NewGeometry(crs.ToWGS84(g.Geometry(), source)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("marshal geometry: %w", err)
+	}
+	return out, &source.Code, nil
+}
+
+// GetMyDrafts returns the current user's drafts and those of their organizations
 func (h *WaterObjectHandler) GetMyDrafts(c *gin.Context) {
 	userID := c.GetInt64("user_id")
 
 	drafts, err := h.repo.GetDraftsByUser(c.Request.Context(), userID)
+	if err == nil {
+		err = h.provenance.Attach(c.Request.Context(), drafts...)
+	}
+	if err == nil {
+		err = h.files.Attach(c.Request.Context(), drafts...)
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "fetch_failed",
@@ -150,8 +384,50 @@ func (h *WaterObjectHandler) Create(c *gin.Context) {
 		return
 	}
 
+	region, ok := parseRegion(req.Region)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": "invalid region",
+		})
+		return
+	}
+
+	if !permissionsFrom(c).Allows(entity.PermObjectsEdit, &entity.WaterObject{ObjectType: objType, Region: region}) {
+		forbidObject(c, entity.PermObjectsEdit)
+		return
+	}
+
+	userID := c.GetInt64("user_id")
+
+	if req.OrganizationID != nil {
+		if _, err := h.orgRepo.GetMembership(c.Request.Context(), *req.OrganizationID, userID); err != nil {
+			if err == entity.ErrNotMember {
+				c.JSON(http.StatusForbidden, gin.H{
+					"error":   "forbidden",
+					"message": "you can only share drafts with your own organizations",
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "fetch_failed",
+				"message": err.Error(),
+			})
+			return
+		}
+	}
+
+	geomJSON, sourceCRS, err := reprojectInput(req.Geometry, req.CRS)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "crs_error",
+			"message": err.Error(),
+		})
+		return
+	}
+
 	// Validate geometry
-	if err := h.validator.Validate(req.Geometry, objType); err != nil {
+	if err := h.validator.Validate(geomJSON, objType); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "geometry_error",
 			"message": err.Error(),
@@ -159,10 +435,8 @@ func (h *WaterObjectHandler) Create(c *gin.Context) {
 		return
 	}
 
-	userID := c.GetInt64("user_id")
-
 	var geom entity.Geometry
-	if err := json.Unmarshal(req.Geometry, &geom); err != nil {
+	if err := json.Unmarshal(geomJSON, &geom); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "geometry_parse_error",
 			"message": err.Error(),
@@ -175,7 +449,9 @@ func (h *WaterObjectHandler) Create(c *gin.Context) {
 		NameRU:           req.NameRU,
 		NameEN:           req.NameEN,
 		ObjectType:       objType,
+		Region:           region,
 		Geometry:         geom,
+		SourceCRS:        sourceCRS,
 		LengthKm:         req.LengthKm,
 		AreaKm2:          req.AreaKm2,
 		MaxDepthM:        req.MaxDepthM,
@@ -189,6 +465,8 @@ func (h *WaterObjectHandler) Create(c *gin.Context) {
 		DescriptionKZ:    req.DescriptionKZ,
 		DescriptionRU:    req.DescriptionRU,
 		DescriptionEN:    req.DescriptionEN,
+		HistoricalNotes:  req.HistoricalNotes,
+		OrganizationID:   req.OrganizationID,
 		CreatedBy:        userID,
 	}
 
@@ -227,10 +505,52 @@ func (h *WaterObjectHandler) Update(c *gin.Context) {
 		return
 	}
 
-	objType := entity.ObjectType(req.ObjectType)
+	region, ok := parseRegion(req.Region)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": "invalid region",
+		})
+		return
+	}
+
+	existing, err := h.repo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		if err == entity.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"message": "draft not found or not editable",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "fetch_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// The object type is fixed after creation; the region may move, so the
+	// editor needs permission for both the old and the new placement
+	objType := existing.ObjectType
+	perms := permissionsFrom(c)
+	if !perms.Allows(entity.PermObjectsEdit, existing) ||
+		!perms.Allows(entity.PermObjectsEdit, &entity.WaterObject{ObjectType: objType, Region: region}) {
+		forbidObject(c, entity.PermObjectsEdit)
+		return
+	}
+
+	geomJSON, sourceCRS, err := reprojectInput(req.Geometry, req.CRS)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "crs_error",
+			"message": err.Error(),
+		})
+		return
+	}
 
 	// Validate geometry
-	if err := h.validator.Validate(req.Geometry, objType); err != nil {
+	if err := h.validator.Validate(geomJSON, objType); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "geometry_error",
 			"message": err.Error(),
@@ -241,7 +561,7 @@ func (h *WaterObjectHandler) Update(c *gin.Context) {
 	userID := c.GetInt64("user_id")
 
 	var geom entity.Geometry
-	if err := json.Unmarshal(req.Geometry, &geom); err != nil {
+	if err := json.Unmarshal(geomJSON, &geom); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "geometry_parse_error",
 			"message": err.Error(),
@@ -255,7 +575,9 @@ func (h *WaterObjectHandler) Update(c *gin.Context) {
 		NameRU:           req.NameRU,
 		NameEN:           req.NameEN,
 		ObjectType:       objType,
+		Region:           region,
 		Geometry:         geom,
+		SourceCRS:        sourceCRS,
 		LengthKm:         req.LengthKm,
 		AreaKm2:          req.AreaKm2,
 		MaxDepthM:        req.MaxDepthM,
@@ -269,6 +591,7 @@ func (h *WaterObjectHandler) Update(c *gin.Context) {
 		DescriptionKZ:    req.DescriptionKZ,
 		DescriptionRU:    req.DescriptionRU,
 		DescriptionEN:    req.DescriptionEN,
+		HistoricalNotes:  req.HistoricalNotes,
 		UpdatedBy:        &userID,
 	}
 
@@ -307,12 +630,42 @@ func (h *WaterObjectHandler) SubmitForReview(c *gin.Context) {
 
 	userID := c.GetInt64("user_id")
 
+	obj, ok := objectAllowed(c, h.repo, id, entity.PermObjectsEdit)
+	if !ok {
+		return
+	}
+	if !transitionAllowed(c, h.workflow, entity.TransitionSubmit, obj, isEditor(c, h.orgRepo, obj)) {
+		return
+	}
+
+	// Changed measurements must cite where they come from
+	if reqErr := h.provenance.check(c.Request.Context(), obj); reqErr != nil {
+		reqErr.respond(c)
+		return
+	}
+
+	// Errors against the data-quality rules keep the object out of review;
+	// warnings go along with it
+	violations, err := h.quality.Check(c.Request.Context(), obj)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "quality_check_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+	if entity.HasErrors(violations) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":      "quality_rules_failed",
+			"message":    "the object breaks data-quality rules; fix the errors and submit again",
+			"violations": violations,
+		})
+		return
+	}
+
 	if err := h.repo.SubmitForReview(c.Request.Context(), id, userID); err != nil {
 		if err == entity.ErrNotFound {
-			c.JSON(http.StatusNotFound, gin.H{
-				"error":   "not_found",
-				"message": "draft not found",
-			})
+			transitionConflict(entity.TransitionSubmit).respond(c)
 			return
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -322,7 +675,10 @@ func (h *WaterObjectHandler) SubmitForReview(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "submitted for review"})
+	h.workflow.Completed(c.Request.Context(), entity.TransitionSubmit, obj, userID, "")
+	h.notify.ReviewQueued(c, obj)
+
+	c.JSON(http.StatusOK, gin.H{"message": "submitted for review", "warnings": violations})
 }
 
 // Delete removes a draft
@@ -338,6 +694,16 @@ func (h *WaterObjectHandler) Delete(c *gin.Context) {
 
 	userID := c.GetInt64("user_id")
 
+	// The files go once the draft is gone and nothing else attaches them
+	attachments, err := h.files.repo.ListForObjects(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "delete_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
 	if err := h.repo.Delete(c.Request.Context(), id, userID); err != nil {
 		if err == entity.ErrNotFound {
 			c.JSON(http.StatusNotFound, gin.H{
@@ -352,6 +718,19 @@ func (h *WaterObjectHandler) Delete(c *gin.Context) {
 		})
 		return
 	}
+	h.files.release(c.Request.Context(), attachments...)
 
 	c.JSON(http.StatusOK, gin.H{"message": "draft deleted"})
 }
+
+// parseRegion validates an optional region from a request
+func parseRegion(raw *string) (*entity.Region, bool) {
+	if raw == nil || *raw == "" {
+		return nil, true
+	}
+	region := entity.Region(*raw)
+	if !region.IsValid() {
+		return nil, false
+	}
+	return &region, true
+}
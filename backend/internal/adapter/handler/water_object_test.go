@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"watermap/internal/domain/entity"
+	"watermap/internal/domain/repository"
+	"watermap/internal/infrastructure/geometry"
+)
+
+// emptyMap publishes nothing
+type emptyMap struct {
+	repository.WaterObjectRepository
+}
+
+func (emptyMap) StreamPublished(context.Context, *repository.WaterObjectFilter, func(*entity.WaterObject) error) error {
+	return nil
+}
+
+// auditTrail keeps the events appended to it
+type auditTrail struct {
+	repository.AuditLogRepository
+	events []*entity.AuditEvent
+}
+
+func (r *auditTrail) Append(_ context.Context, e *entity.AuditEvent) error {
+	r.events = append(r.events, e)
+	return nil
+}
+
+func TestGetPublishedAudit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name    string
+		query   string
+		userID  int64
+		audited bool
+	}{
+		{"anonymous export", "?format=geojsonseq", 0, false},
+		{"signed-in export", "?format=geojsonseq", 7, true},
+		{"signed-in map read", "", 7, false},
+		{"anonymous map read", "", 0, false},
+	}
+	for _, tt := range tests {
+		trail := &auditTrail{}
+		h := &WaterObjectHandler{repo: emptyMap{}, audit: NewAuditor(trail)}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/water-objects"+tt.query, nil)
+		if tt.userID != 0 {
+			c.Set("user_id", tt.userID)
+		}
+		h.GetPublished(c)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d: %s", tt.name, w.Code, w.Body)
+		}
+		if got := len(trail.events) > 0; got != tt.audited {
+			t.Errorf("%s: audited = %v, want %v", tt.name, got, tt.audited)
+			continue
+		}
+		if tt.audited {
+			e := trail.events[0]
+			if e.Action != entity.AuditDataExported || e.ActorID == nil || *e.ActorID != tt.userID {
+				t.Errorf("%s: event = %+v", tt.name, e)
+			}
+		}
+	}
+}
+
+func TestParseSimplifyOptions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		query     string
+		tolerance float64
+		method    geometry.Method
+		ok        bool
+	}{
+		{"", 0, "", true},
+		{"?simplify=0.01", 0.01, geometry.MethodDouglasPeucker, true},
+		{"?simplify=0.01&simplify_method=vw", 0.01, geometry.MethodVisvalingam, true},
+		{"?zoom=0", geometry.ToleranceForZoom(0), geometry.MethodDouglasPeucker, true},
+		{"?simplify=0.5&zoom=3", 0.5, geometry.MethodDouglasPeucker, true},
+		{"?simplify=NaN", 0, "", false},
+		{"?simplify=nan", 0, "", false},
+		{"?simplify=Inf", 0, "", false},
+		{"?simplify=-1", 0, "", false},
+		{"?simplify=0", 0, "", false},
+		{"?simplify=fine", 0, "", false},
+		{"?zoom=23", 0, "", false},
+		{"?zoom=-1", 0, "", false},
+		{"?simplify=0.01&simplify_method=rdp", 0, "", false},
+	}
+	for _, tt := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/water-objects"+tt.query, nil)
+
+		opts, err := parseSimplifyOptions(c)
+		if (err == nil) != tt.ok {
+			t.Errorf("%q: err = %v, want ok %v", tt.query, err, tt.ok)
+			continue
+		}
+		if !tt.ok {
+			continue
+		}
+		if tt.tolerance == 0 {
+			if opts != nil {
+				t.Errorf("%q: options = %+v, want none", tt.query, opts)
+			}
+			continue
+		}
+		if opts == nil || opts.tolerance != tt.tolerance || opts.method != tt.method {
+			t.Errorf("%q: options = %+v, want tolerance %g by %s", tt.query, opts, tt.tolerance, tt.method)
+		}
+	}
+}
@@ -0,0 +1,373 @@
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"watermap/internal/domain/entity"
+	"watermap/internal/domain/repository"
+)
+
+// webhookSecretPrefix starts every generated signing secret
+const webhookSecretPrefix = "whsec_"
+
+type WebhookHandler struct {
+	repo  repository.WebhookRepository
+	audit *Auditor
+}
+
+func NewWebhookHandler(repo repository.WebhookRepository, auditor *Auditor) *WebhookHandler {
+	return &WebhookHandler{repo: repo, audit: auditor}
+}
+
+// generateWebhookSecret returns a new random signing secret
+func generateWebhookSecret() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return webhookSecretPrefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// List returns every webhook without its secret
+func (h *WebhookHandler) List(c *gin.Context) {
+	hooks, err := h.repo.List(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "fetch_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"webhooks": hooks,
+		"events":   entity.WebhookEvents,
+	})
+}
+
+// Get returns one webhook
+func (h *WebhookHandler) Get(c *gin.Context) {
+	hook, ok := h.loadWebhook(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, hook)
+}
+
+type WebhookRequest struct {
+	Name   string             `json:"name" binding:"required,max=100"`
+	URL    string             `json:"url" binding:"required"`
+	Events []entity.EventType `json:"events"`
+	Active *bool              `json:"active"`
+	// RotateSecret replaces the signing secret on update
+	RotateSecret bool `json:"rotate_secret"`
+}
+
+// Create registers a webhook. The signing secret is returned only here and
+// when it is rotated.
+func (h *WebhookHandler) Create(c *gin.Context) {
+	var req WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "create_failed",
+			"message": "failed to generate secret",
+		})
+		return
+	}
+
+	adminID := c.GetInt64("user_id")
+	hook := &entity.Webhook{Secret: secret, Active: true, CreatedBy: &adminID}
+	if !applyWebhookRequest(c, hook, &req) {
+		return
+	}
+
+	created, err := h.repo.Create(c.Request.Context(), hook)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "create_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.audit.Record(c, auditWebhook(entity.AuditWebhookSaved, created.ID), nil, created)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"webhook": created,
+		"secret":  secret,
+	})
+}
+
+// Update changes a webhook, optionally rotating its secret
+func (h *WebhookHandler) Update(c *gin.Context) {
+	hook, ok := h.loadWebhook(c)
+	if !ok {
+		return
+	}
+
+	var req WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	before := *hook
+	if !applyWebhookRequest(c, hook, &req) {
+		return
+	}
+
+	response := gin.H{"webhook": hook}
+	if req.RotateSecret {
+		secret, err := generateWebhookSecret()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "update_failed",
+				"message": "failed to generate secret",
+			})
+			return
+		}
+		hook.Secret = secret
+		response["secret"] = secret
+	}
+
+	if err := h.repo.Update(c.Request.Context(), hook); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "update_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.audit.Record(c, auditWebhook(entity.AuditWebhookSaved, hook.ID), before, gin.H{
+		"webhook":        hook,
+		"secret_rotated": req.RotateSecret,
+	})
+
+	c.JSON(http.StatusOK, response)
+}
+
+// Delete removes a webhook with its delivery log
+func (h *WebhookHandler) Delete(c *gin.Context) {
+	hook, ok := h.loadWebhook(c)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.Delete(c.Request.Context(), hook.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "delete_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.audit.Record(c, auditWebhook(entity.AuditWebhookDeleted, hook.ID), hook, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "webhook deleted"})
+}
+
+// Ping queues a test delivery, for checking a receiver and its signature
+// verification
+func (h *WebhookHandler) Ping(c *gin.Context) {
+	hook, ok := h.loadWebhook(c)
+	if !ok {
+		return
+	}
+
+	payload, _ := json.Marshal(gin.H{
+		"type":       entity.EventPing,
+		"webhook_id": hook.ID,
+		"created_at": time.Now().UTC(),
+	})
+
+	delivery, err := h.repo.Enqueue(c.Request.Context(), &entity.WebhookDelivery{
+		WebhookID: hook.ID,
+		EventType: entity.EventPing,
+		Payload:   payload,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "enqueue_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, delivery)
+}
+
+const (
+	defaultDeliveryPageSize = 50
+	maxDeliveryPageSize     = 200
+)
+
+// Deliveries returns the delivery log of a webhook, newest first, with
+// optional ?status=pending|succeeded|failed
+func (h *WebhookHandler) Deliveries(c *gin.Context) {
+	hook, ok := h.loadWebhook(c)
+	if !ok {
+		return
+	}
+
+	filter := &repository.DeliveryFilter{
+		Status: entity.DeliveryStatus(c.Query("status")),
+		Limit:  defaultDeliveryPageSize,
+	}
+	switch filter.Status {
+	case "", entity.DeliveryPending, entity.DeliverySucceeded, entity.DeliveryFailed:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": "status must be pending, succeeded or failed",
+		})
+		return
+	}
+	if limit, err := strconv.Atoi(c.Query("limit")); err == nil && limit > 0 {
+		filter.Limit = min(limit, maxDeliveryPageSize)
+	}
+	if offset, err := strconv.Atoi(c.Query("offset")); err == nil && offset > 0 {
+		filter.Offset = offset
+	}
+
+	deliveries, err := h.repo.ListDeliveries(c.Request.Context(), hook.ID, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "fetch_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deliveries": deliveries})
+}
+
+// Redeliver queues a fresh copy of a delivery. The original stays in the
+// log unchanged.
+func (h *WebhookHandler) Redeliver(c *gin.Context) {
+	hook, ok := h.loadWebhook(c)
+	if !ok {
+		return
+	}
+
+	deliveryID, err := strconv.ParseInt(c.Param("deliveryId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_id",
+			"message": "invalid delivery id",
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	original, err := h.repo.GetDelivery(ctx, deliveryID)
+	if err != nil || original.WebhookID != hook.ID {
+		if err == nil || err == entity.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"message": "delivery not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "fetch_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	delivery, err := h.repo.Enqueue(ctx, &entity.WebhookDelivery{
+		WebhookID: hook.ID,
+		EventID:   original.EventID,
+		EventType: original.EventType,
+		Payload:   original.Payload,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "enqueue_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, delivery)
+}
+
+// loadWebhook reads the :id parameter and fetches the webhook
+func (h *WebhookHandler) loadWebhook(c *gin.Context) (*entity.Webhook, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_id",
+			"message": "invalid webhook id",
+		})
+		return nil, false
+	}
+
+	hook, err := h.repo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		if err == entity.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"message": "webhook not found",
+			})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "fetch_failed",
+			"message": err.Error(),
+		})
+		return nil, false
+	}
+	return hook, true
+}
+
+// applyWebhookRequest copies and validates the request fields
+func applyWebhookRequest(c *gin.Context, hook *entity.Webhook, req *WebhookRequest) bool {
+	hook.Name = strings.TrimSpace(req.Name)
+	hook.URL = strings.TrimSpace(req.URL)
+	hook.Events = req.Events
+	if hook.Events == nil {
+		hook.Events = []entity.EventType{}
+	}
+	if req.Active != nil {
+		hook.Active = *req.Active
+	}
+
+	if err := hook.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": err.Error(),
+			"events":  entity.WebhookEvents,
+		})
+		return false
+	}
+	return true
+}
+
+func auditWebhook(action entity.AuditAction, webhookID int64) entity.AuditEvent {
+	return entity.AuditEvent{
+		Action:     action,
+		TargetType: "webhook",
+		TargetID:   strconv.FormatInt(webhookID, 10),
+	}
+}
@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"watermap/internal/domain/entity"
+	"watermap/internal/domain/repository"
+)
+
+// requestError is a failed step of a request together with the response
+// it maps to, for handlers that report errors per item
+type requestError struct {
+	status int
+	body   gin.H
+}
+
+func (e *requestError) respond(c *gin.Context) {
+	c.JSON(e.status, e.body)
+}
+
+// transitionAllowed checks a status transition against the workflow. It
+// writes a 409 when the object's status does not allow the transition and a
+// 403 when the user may not take it, and returns false in both cases.
+func transitionAllowed(c *gin.Context, workflow *entity.Workflow, t entity.Transition, obj *entity.WaterObject, editor bool) bool {
+	if failed := checkTransition(c, workflow, t, obj, editor); failed != nil {
+		failed.respond(c)
+		return false
+	}
+	return true
+}
+
+func checkTransition(c *gin.Context, workflow *entity.Workflow, t entity.Transition, obj *entity.WaterObject, editor bool) *requestError {
+	err := workflow.Check(t, obj, entity.Actor{
+		UserID:      c.GetInt64("user_id"),
+		Permissions: permissionsFrom(c),
+		Editor:      editor,
+	})
+	if err == nil {
+		return nil
+	}
+
+	var refused *entity.TransitionError
+	if !errors.As(err, &refused) || refused.Reason == entity.GuardInvalidState {
+		return &requestError{http.StatusConflict, gin.H{
+			"error":      entity.GuardInvalidState,
+			"message":    err.Error(),
+			"transition": t,
+			"status":     obj.Status,
+			"allowed":    entity.TransitionsFrom(obj.Status),
+		}}
+	}
+
+	return &requestError{http.StatusForbidden, gin.H{
+		"error":      refused.Reason,
+		"message":    refused.Error(),
+		"transition": t,
+	}}
+}
+
+// transitionConflict reports an object whose status changed between the
+// workflow check and the update
+func transitionConflict(t entity.Transition) *requestError {
+	return &requestError{http.StatusConflict, gin.H{
+		"error":      entity.GuardInvalidState,
+		"message":    "the object's status changed while the request was processed; reload it and try again",
+		"transition": t,
+	}}
+}
+
+// isEditor reports whether the user created the object or belongs to its
+// organization
+func isEditor(c *gin.Context, orgRepo repository.OrganizationRepository, obj *entity.WaterObject) bool {
+	userID := c.GetInt64("user_id")
+	if obj.CreatedBy == userID {
+		return true
+	}
+	if obj.OrganizationID == nil {
+		return false
+	}
+	_, err := orgRepo.GetMembership(c.Request.Context(), *obj.OrganizationID, userID)
+	return err == nil
+}
+
+// isAuthor reports whether the user had a hand in the object: an editor of
+// it or the last user to change it. Authors may not review their own work.
+func isAuthor(c *gin.Context, orgRepo repository.OrganizationRepository, obj *entity.WaterObject) bool {
+	if obj.UpdatedBy != nil && *obj.UpdatedBy == c.GetInt64("user_id") {
+		return true
+	}
+	return isEditor(c, orgRepo, obj)
+}
+
+type TransitionRequest struct {
+	Reason string `json:"reason"`
+}
+
+// Unpublish takes a published version off the map and returns a copy of it
+// to its authors as the next version's draft
+func (h *AdminHandler) Unpublish(c *gin.Context) {
+	h.transition(c, entity.TransitionUnpublish, entity.AuditObjectUnpublished, "object unpublished and returned to its authors as a draft")
+}
+
+// Archive takes a published version off the map for good
+func (h *AdminHandler) Archive(c *gin.Context) {
+	h.transition(c, entity.TransitionArchive, entity.AuditObjectArchived, "object archived")
+}
+
+// Restore publishes an archived version again, archiving the version that
+// is currently published
+func (h *AdminHandler) Restore(c *gin.Context) {
+	h.transition(c, entity.TransitionRestore, entity.AuditObjectRestored, "object restored and published")
+}
+
+// transition applies an administrative transition with an optional reason
+func (h *AdminHandler) transition(c *gin.Context, t entity.Transition, action entity.AuditAction, message string) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_id",
+			"message": "invalid object id",
+		})
+		return
+	}
+
+	var req TransitionRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	obj, ok := objectAllowed(c, h.waterObjectRepo, id, entity.PermObjectsReview)
+	if !ok {
+		return
+	}
+	if !transitionAllowed(c, h.workflow, t, obj, false) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	actorID := c.GetInt64("user_id")
+	var draftID int64
+	switch t {
+	case entity.TransitionUnpublish:
+		draftID, err = h.waterObjectRepo.Unpublish(ctx, id, actorID)
+	case entity.TransitionRestore:
+		err = h.waterObjectRepo.Restore(ctx, id, actorID)
+	default:
+		err = h.waterObjectRepo.Archive(ctx, id, actorID)
+	}
+	if err != nil {
+		if err == entity.ErrNotFound {
+			transitionConflict(t).respond(c)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "transition_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	after := gin.H{"status": t.Target(), "reason": req.Reason}
+	response := gin.H{"message": message, "status": t.Target()}
+	if draftID != 0 {
+		after["draft_id"] = draftID
+		response["draft_id"] = draftID
+	}
+	h.audit.Record(c, auditObject(action, id), gin.H{"status": obj.Status}, after)
+	h.workflow.Completed(ctx, t, obj, actorID, req.Reason)
+
+	c.JSON(http.StatusOK, response)
+}
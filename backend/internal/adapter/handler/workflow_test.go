@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"watermap/internal/domain/entity"
+	"watermap/internal/domain/repository"
+)
+
+// memberRepo knows the members of one organisation
+type memberRepo struct {
+	repository.OrganizationRepository
+	orgID   int64
+	members map[int64]bool
+}
+
+func (r *memberRepo) GetMembership(_ context.Context, orgID, userID int64) (*entity.OrganizationMember, error) {
+	if orgID != r.orgID || !r.members[userID] {
+		return nil, entity.ErrNotMember
+	}
+	return &entity.OrganizationMember{}, nil
+}
+
+func TestIsAuthor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	org, editor := int64(7), int64(3)
+	orgs := &memberRepo{orgID: org, members: map[int64]bool{2: true}}
+	obj := &entity.WaterObject{CreatedBy: 1, OrganizationID: &org, UpdatedBy: &editor}
+
+	tests := []struct {
+		name   string
+		userID int64
+		want   bool
+	}{
+		{"creator", 1, true},
+		{"organisation member", 2, true},
+		{"last editor", 3, true},
+		{"outsider", 4, false},
+	}
+	for _, tt := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("POST", "/api/admin/objects/1/approve", nil)
+		c.Set("user_id", tt.userID)
+		if got := isAuthor(c, orgs, obj); got != tt.want {
+			t.Errorf("%s: isAuthor = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
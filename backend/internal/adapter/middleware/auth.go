@@ -1,31 +1,47 @@
 package middleware
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 
 	"watermap/internal/domain/entity"
 	"watermap/internal/domain/repository"
 )
 
 type AuthMiddleware struct {
-	jwtSecret string
-	userRepo  repository.UserRepository
+	jwtSecret   string
+	userRepo    repository.UserRepository
+	sessionRepo repository.SessionRepository
+	apiKeyRepo  repository.APIKeyRepository
+	permRepo    repository.PermissionRepository
 }
 
-func NewAuthMiddleware(jwtSecret string, userRepo repository.UserRepository) *AuthMiddleware {
+func NewAuthMiddleware(jwtSecret string, userRepo repository.UserRepository, sessionRepo repository.SessionRepository, apiKeyRepo repository.APIKeyRepository, permRepo repository.PermissionRepository) *AuthMiddleware {
 	return &AuthMiddleware{
-		jwtSecret: jwtSecret,
-		userRepo:  userRepo,
+		jwtSecret:   jwtSecret,
+		userRepo:    userRepo,
+		sessionRepo: sessionRepo,
+		apiKeyRepo:  apiKeyRepo,
+		permRepo:    permRepo,
 	}
 }
 
-// Protect requires authentication via JWT
+// Protect requires authentication via an X-API-Key header or a JWT
 func (m *AuthMiddleware) Protect() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if apiKey := c.GetHeader("X-API-Key"); apiKey != "" {
+			m.authenticateAPIKey(c, apiKey)
+			return
+		}
+
 		tokenString := ""
 
 		// Try cookie first
@@ -85,21 +101,174 @@ func (m *AuthMiddleware) Protect() gin.HandlerFunc {
 
 		userID := int64(userIDFloat)
 
+		if typ, _ := claims["typ"].(string); typ != entity.TokenTypeAccess {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error":   "unauthorized",
+				"message": "invalid token type",
+			})
+			return
+		}
+
+		// Access tokens are bound to a server-side session so that logout
+		// and admin revocation take effect before the token expires
+		sid, _ := claims["sid"].(string)
+		sessionID, err := uuid.Parse(sid)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error":   "unauthorized",
+				"message": "invalid session in token",
+			})
+			return
+		}
+
+		session, err := m.sessionRepo.GetByID(c.Request.Context(), sessionID)
+		if err != nil || session.UserID != userID || !session.IsActive(time.Now()) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error":   "session_revoked",
+				"message": "session expired or revoked",
+			})
+			return
+		}
+
 		// Get user from database
 		user, err := m.userRepo.GetByID(c.Request.Context(), userID)
-		if err != nil {
+		if err != nil || !user.IsActive() {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
 				"error":   "unauthorized",
-				"message": "user not found",
+				"message": "user not found or deactivated",
 			})
 			return
 		}
 
+		if !m.setPermissions(c, user) {
+			return
+		}
+
 		// Set user in context
 		c.Set("user_id", user.ID)
 		c.Set("user_role", user.Role)
 		c.Set("user", user)
+		c.Set("session_id", session.ID.String())
+		if session.ImpersonatorID != nil {
+			c.Set("impersonator_id", *session.ImpersonatorID)
+		}
+
+		c.Next()
+	}
+}
+
+// Identify authenticates requests to public routes that carry an API key or
+// a bearer token, and lets anonymous ones through. The token cookie alone
+// does not count: browsers send it with every map read, and a lapsed one
+// must not break the public map.
+func (m *AuthMiddleware) Identify() gin.HandlerFunc {
+	protect := m.Protect()
+	return func(c *gin.Context) {
+		if c.GetHeader("X-API-Key") == "" && !strings.HasPrefix(c.GetHeader("Authorization"), "Bearer ") {
+			c.Next()
+			return
+		}
+		protect(c)
+	}
+}
+
+// authenticateAPIKey resolves a personal API key to its owner
+func (m *AuthMiddleware) authenticateAPIKey(c *gin.Context, secret string) {
+	sum := sha256.Sum256([]byte(secret))
+
+	key, err := m.apiKeyRepo.GetByHash(c.Request.Context(), hex.EncodeToString(sum[:]))
+	if err != nil || !key.IsActive(time.Now()) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error":   "unauthorized",
+			"message": "invalid, expired or revoked api key",
+		})
+		return
+	}
+
+	user, err := m.userRepo.GetByID(c.Request.Context(), key.UserID)
+	if err != nil || !user.IsActive() {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error":   "unauthorized",
+			"message": "user not found or deactivated",
+		})
+		return
+	}
+
+	if !m.setPermissions(c, user) {
+		return
+	}
 
+	if err := m.apiKeyRepo.TouchLastUsed(c.Request.Context(), key.ID, c.ClientIP(), time.Now()); err != nil {
+		log.Printf("record api key use: %v", err)
+	}
+
+	c.Set("user_id", user.ID)
+	c.Set("user_role", user.Role)
+	c.Set("user", user)
+	c.Set("api_key", key)
+
+	c.Next()
+}
+
+// setPermissions loads the user's effective grants into the context
+func (m *AuthMiddleware) setPermissions(c *gin.Context, user *entity.User) bool {
+	perms, err := m.permRepo.ForUser(c.Request.Context(), user.ID, user.Role)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"error":   "permissions_failed",
+			"message": err.Error(),
+		})
+		return false
+	}
+	c.Set("permissions", perms)
+	return true
+}
+
+// RequireScope limits requests authenticated with an API key to keys that
+// carry one of the scopes. Browser sessions are not restricted by scopes.
+func (m *AuthMiddleware) RequireScope(scopes ...entity.APIKeyScope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, ok := c.Get("api_key")
+		if !ok {
+			c.Next()
+			return
+		}
+
+		key := value.(*entity.APIKey)
+		for _, scope := range scopes {
+			if key.HasScope(scope) {
+				c.Next()
+				return
+			}
+		}
+
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"error":    "insufficient_scope",
+			"message":  "api key lacks the required scope",
+			"required": scopes,
+		})
+	}
+}
+
+// RequireInteractive rejects API key authentication and impersonation, for
+// endpoints such as key management that must only be reachable from the
+// account holder's own signed-in session
+func (m *AuthMiddleware) RequireInteractive() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get("api_key"); ok {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":   "forbidden",
+				"message": "this endpoint cannot be used with an api key",
+			})
+			return
+		}
+		if _, ok := c.Get("impersonator_id"); ok {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":   "forbidden",
+				"message": "this endpoint cannot be used while impersonating",
+			})
+			return
+		}
 		c.Next()
 	}
 }
@@ -134,12 +303,27 @@ func (m *AuthMiddleware) RequireRole(allowedRoles ...entity.UserRole) gin.Handle
 	}
 }
 
-// RequireExpert is a shorthand for RequireRole with expert and admin
-func (m *AuthMiddleware) RequireExpert() gin.HandlerFunc {
-	return m.RequireRole(entity.RoleExpert, entity.RoleAdmin)
-}
+// RequirePermission checks that the user holds the permission in at least
+// one scope. Handlers narrow the check to the object with PermissionSet.Allows.
+func (m *AuthMiddleware) RequirePermission(perm entity.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, exists := c.Get("permissions")
+		if !exists {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error":   "unauthorized",
+				"message": "authentication required",
+			})
+			return
+		}
 
-// RequireAdmin is a shorthand for RequireRole with admin only
-func (m *AuthMiddleware) RequireAdmin() gin.HandlerFunc {
-	return m.RequireRole(entity.RoleAdmin)
+		if !value.(entity.PermissionSet).Has(perm) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":    "forbidden",
+				"message":  "insufficient permissions",
+				"required": perm,
+			})
+			return
+		}
+		c.Next()
+	}
 }
@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"watermap/internal/infrastructure/ratelimit"
+)
+
+// maxPeekBody bounds how much of a request body is read to find the account
+const maxPeekBody = 64 << 10
+
+type RateLimiter struct {
+	store ratelimit.Store
+}
+
+func NewRateLimiter(store ratelimit.Store) *RateLimiter {
+	return &RateLimiter{store: store}
+}
+
+// PerIP limits requests from one client address across the route group
+func (m *RateLimiter) PerIP(name string, limit ratelimit.Limit) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		m.take(c, name+":ip:"+c.ClientIP(), limit)
+	}
+}
+
+// PerAccount limits requests for one account. Authenticated requests are
+// keyed on the user; anonymous ones on the email in the JSON body, which
+// throttles credential stuffing against a single account from many
+// addresses. Requests with neither pass through.
+func (m *RateLimiter) PerAccount(name string, limit ratelimit.Limit) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if userID := c.GetInt64("user_id"); userID != 0 {
+			m.take(c, name+":user:"+strconv.FormatInt(userID, 10), limit)
+			return
+		}
+
+		if email := strings.ToLower(strings.TrimSpace(peekField(c, "email"))); email != "" {
+			m.take(c, name+":email:"+email, limit)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// PerChallenge limits the second step of a sign-in per account. subject
+// resolves the "challenge" field of the JSON body to the user it was issued
+// to; requests without a valid challenge pass through for the handler to
+// reject. The bucket is the one PerAccount uses for signed-in users.
+func (m *RateLimiter) PerChallenge(name string, limit ratelimit.Limit, subject func(challenge string) (int64, bool)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if userID, ok := subject(peekField(c, "challenge")); ok {
+			m.take(c, name+":user:"+strconv.FormatInt(userID, 10), limit)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func (m *RateLimiter) take(c *gin.Context, key string, limit ratelimit.Limit) {
+	res, err := m.store.Take(c.Request.Context(), key, limit, time.Now())
+	if err != nil {
+		// Fail open: an unavailable limiter should not take the API down
+		log.Printf("rate limit %s: %v", key, err)
+		c.Next()
+		return
+	}
+
+	c.Header("X-RateLimit-Limit", limit.String())
+	c.Header("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+
+	if !res.Allowed {
+		TooManyRequests(c, res.RetryAfter, "rate_limited", "too many requests, try again later")
+		return
+	}
+	c.Next()
+}
+
+// TooManyRequests aborts with 429 and a Retry-After header in whole seconds
+func TooManyRequests(c *gin.Context, retryAfter time.Duration, code, message string) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+		"error":       code,
+		"message":     message,
+		"retry_after": seconds,
+	})
+}
+
+// peekField reads a string field of a JSON body and restores the body for
+// the handler
+func peekField(c *gin.Context, field string) string {
+	if c.Request.Body == nil || !strings.HasPrefix(c.ContentType(), "application/json") {
+		return ""
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxPeekBody))
+	c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), c.Request.Body))
+	if err != nil {
+		return ""
+	}
+
+	var payload map[string]json.RawMessage
+	if json.Unmarshal(body, &payload) != nil {
+		return ""
+	}
+	var value string
+	if json.Unmarshal(payload[field], &value) != nil {
+		return ""
+	}
+	return value
+}
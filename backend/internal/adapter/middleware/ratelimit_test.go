@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"watermap/internal/infrastructure/ratelimit"
+)
+
+func TestPerChallenge(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	subjects := map[string]int64{"alice-1": 1, "alice-2": 1, "bob": 2}
+	subject := func(challenge string) (int64, bool) {
+		id, ok := subjects[challenge]
+		return id, ok
+	}
+
+	limiter := NewRateLimiter(ratelimit.NewMemoryStore())
+	r := gin.New()
+	r.POST("/login/2fa", limiter.PerChallenge("auth", ratelimit.Limit{Requests: 2, Per: time.Hour}, subject), func(c *gin.Context) {
+		var body struct {
+			Challenge string `json:"challenge"`
+		}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+
+	// The cases run in order against the same limiter
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"first attempt", `{"challenge": "alice-1", "code": "000000"}`, http.StatusOK},
+		{"second attempt, new challenge", `{"challenge": "alice-2", "code": "000000"}`, http.StatusOK},
+		{"third attempt on the account", `{"challenge": "alice-1", "code": "000000"}`, http.StatusTooManyRequests},
+		{"another account", `{"challenge": "bob", "code": "000000"}`, http.StatusOK},
+		{"invalid challenge passes to the handler", `{"challenge": "forged"}`, http.StatusOK},
+		{"challenge of the wrong type", `{"challenge": 1}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/login/2fa", strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		if w.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.want)
+		}
+	}
+}
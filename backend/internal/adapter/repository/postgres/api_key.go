@@ -0,0 +1,125 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"watermap/internal/domain/entity"
+	"watermap/internal/domain/repository"
+)
+
+type APIKeyRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewAPIKeyRepo(pool *pgxpool.Pool) repository.APIKeyRepository {
+	return &APIKeyRepo{pool: pool}
+}
+
+const apiKeyColumns = `id, user_id, name, prefix, key_hash, scopes, expires_at, last_used_at, last_used_ip, revoked_at, created_at`
+
+// lastUsedGranularity avoids a write on every request made with a busy key
+const lastUsedGranularity = time.Minute
+
+func (r *APIKeyRepo) Create(ctx context.Context, key *entity.APIKey) (*entity.APIKey, error) {
+	query := `
+		INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+
+	err := r.pool.QueryRow(ctx, query,
+		key.UserID, key.Name, key.Prefix, key.KeyHash, scopesToStrings(key.Scopes), key.ExpiresAt,
+	).Scan(&key.ID, &key.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("create api key: %w", err)
+	}
+	return key, nil
+}
+
+func (r *APIKeyRepo) GetByHash(ctx context.Context, keyHash string) (*entity.APIKey, error) {
+	row := r.pool.QueryRow(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE key_hash = $1`, keyHash)
+	return scanAPIKey(row)
+}
+
+func (r *APIKeyRepo) GetByID(ctx context.Context, id int64, userID int64) (*entity.APIKey, error) {
+	row := r.pool.QueryRow(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE id = $1 AND user_id = $2`, id, userID)
+	return scanAPIKey(row)
+}
+
+func (r *APIKeyRepo) ListByUser(ctx context.Context, userID int64) ([]*entity.APIKey, error) {
+	rows, err := r.pool.Query(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE user_id = $1 ORDER BY created_at DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("query api keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []*entity.APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+func (r *APIKeyRepo) Revoke(ctx context.Context, id int64, userID int64) error {
+	result, err := r.pool.Exec(ctx,
+		"UPDATE api_keys SET revoked_at = NOW() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL",
+		id, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("revoke api key: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return entity.ErrNotFound
+	}
+	return nil
+}
+
+func (r *APIKeyRepo) TouchLastUsed(ctx context.Context, id int64, ip string, at time.Time) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE api_keys SET last_used_at = $1, last_used_ip = $2
+		WHERE id = $3 AND (last_used_at IS NULL OR last_used_at < $4 OR last_used_ip IS DISTINCT FROM $2)
+	`, at, ip, id, at.Add(-lastUsedGranularity))
+	if err != nil {
+		return fmt.Errorf("touch api key: %w", err)
+	}
+	return nil
+}
+
+func scanAPIKey(row pgx.Row) (*entity.APIKey, error) {
+	key := &entity.APIKey{}
+	var scopes []string
+
+	err := row.Scan(
+		&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.KeyHash, &scopes,
+		&key.ExpiresAt, &key.LastUsedAt, &key.LastUsedIP, &key.RevokedAt, &key.CreatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, entity.ErrNotFound
+		}
+		return nil, fmt.Errorf("scan api key: %w", err)
+	}
+
+	key.Scopes = make([]entity.APIKeyScope, 0, len(scopes))
+	for _, s := range scopes {
+		key.Scopes = append(key.Scopes, entity.APIKeyScope(s))
+	}
+	return key, nil
+}
+
+func scopesToStrings(scopes []entity.APIKeyScope) []string {
+	out := make([]string, 0, len(scopes))
+	for _, s := range scopes {
+		out = append(out, string(s))
+	}
+	return out
+}
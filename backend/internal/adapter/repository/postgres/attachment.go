@@ -0,0 +1,138 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"watermap/internal/domain/entity"
+	"watermap/internal/domain/repository"
+)
+
+type AttachmentRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewAttachmentRepo(pool *pgxpool.Pool) repository.AttachmentRepository {
+	return &AttachmentRepo{pool: pool}
+}
+
+const attachmentColumns = `
+	id, object_id, filename, content_type, kind, size, sha256, has_thumbnail,
+	caption, latitude, longitude, uploaded_by, created_at
+`
+
+func scanAttachment(row pgx.Row) (*entity.Attachment, error) {
+	a := &entity.Attachment{}
+	err := row.Scan(
+		&a.ID, &a.ObjectID, &a.Filename, &a.ContentType, &a.Kind, &a.Size, &a.SHA256, &a.HasThumbnail,
+		&a.Caption, &a.Latitude, &a.Longitude, &a.UploadedBy, &a.CreatedAt,
+	)
+	return a, err
+}
+
+func (r *AttachmentRepo) ListForObjects(ctx context.Context, objectIDs ...int64) ([]*entity.Attachment, error) {
+	rows, err := r.pool.Query(ctx, `SELECT `+attachmentColumns+`
+		FROM attachments
+		WHERE object_id = ANY($1)
+		ORDER BY object_id, created_at, id
+	`, objectIDs)
+	if err != nil {
+		return nil, fmt.Errorf("query attachments: %w", err)
+	}
+	defer rows.Close()
+
+	var attachments []*entity.Attachment
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan attachment: %w", err)
+		}
+		attachments = append(attachments, a)
+	}
+	return attachments, rows.Err()
+}
+
+func (r *AttachmentRepo) GetByID(ctx context.Context, id int64) (*entity.Attachment, error) {
+	a, err := scanAttachment(r.pool.QueryRow(ctx, `SELECT `+attachmentColumns+` FROM attachments WHERE id = $1`, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, entity.ErrNotFound
+		}
+		return nil, fmt.Errorf("get attachment: %w", err)
+	}
+	return a, nil
+}
+
+func (r *AttachmentRepo) Create(ctx context.Context, a *entity.Attachment) (*entity.Attachment, error) {
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO attachments (
+			object_id, filename, content_type, kind, size, sha256, has_thumbnail,
+			caption, latitude, longitude, uploaded_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id, created_at
+	`, a.ObjectID, a.Filename, a.ContentType, a.Kind, a.Size, a.SHA256, a.HasThumbnail,
+		a.Caption, a.Latitude, a.Longitude, a.UploadedBy,
+	).Scan(&a.ID, &a.CreatedAt)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err):
+			return nil, entity.ErrDuplicateAttachment
+		case isForeignKeyViolation(err):
+			return nil, entity.ErrNotFound
+		}
+		return nil, fmt.Errorf("create attachment: %w", err)
+	}
+	return a, nil
+}
+
+func (r *AttachmentRepo) Delete(ctx context.Context, objectID, id int64) (*entity.Attachment, error) {
+	a, err := scanAttachment(r.pool.QueryRow(ctx, `
+		DELETE FROM attachments WHERE id = $1 AND object_id = $2
+		RETURNING `+attachmentColumns, id, objectID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, entity.ErrNotFound
+		}
+		return nil, fmt.Errorf("delete attachment: %w", err)
+	}
+	return a, nil
+}
+
+// attachmentContentLock is the class of the advisory locks taken on
+// attachment contents, keyed by a hash of their sha256
+const attachmentContentLock = 7314002
+
+// LockContent holds a session-level advisory lock on a connection of its
+// own, as the work it guards spans blob store calls and other queries
+func (r *AttachmentRepo) LockContent(ctx context.Context, sha256 string) (func(), error) {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("acquire connection: %w", err)
+	}
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1, hashtext($2))", attachmentContentLock, sha256); err != nil {
+		conn.Release()
+		return nil, fmt.Errorf("lock attachment content: %w", err)
+	}
+
+	return func() {
+		_, err := conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1, hashtext($2))", attachmentContentLock, sha256)
+		if err != nil {
+			// Closing the session drops its locks too; the pool discards
+			// closed connections
+			conn.Conn().Close(context.Background())
+		}
+		conn.Release()
+	}, nil
+}
+
+func (r *AttachmentRepo) Referenced(ctx context.Context, sha256 string) (bool, error) {
+	var referenced bool
+	err := r.pool.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM attachments WHERE sha256 = $1)", sha256).Scan(&referenced)
+	if err != nil {
+		return false, fmt.Errorf("check attachment references: %w", err)
+	}
+	return referenced, nil
+}
@@ -0,0 +1,167 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"watermap/internal/domain/entity"
+	"watermap/internal/domain/repository"
+)
+
+// auditChainLock is the advisory lock that serialises appends to the chain
+const auditChainLock = 7314001
+
+type AuditLogRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewAuditLogRepo(pool *pgxpool.Pool) repository.AuditLogRepository {
+	return &AuditLogRepo{pool: pool}
+}
+
+const auditColumns = `id, action, actor_id, impersonator_id, target_type, target_id, ip, user_agent, before_value, after_value, created_at, prev_hash, hash`
+
+func (r *AuditLogRepo) Append(ctx context.Context, event *entity.AuditEvent) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", auditChainLock); err != nil {
+		return fmt.Errorf("lock audit chain: %w", err)
+	}
+
+	var prevHash string
+	err = tx.QueryRow(ctx, "SELECT hash FROM audit_log ORDER BY id DESC LIMIT 1").Scan(&prevHash)
+	if err != nil && err != pgx.ErrNoRows {
+		return fmt.Errorf("read audit chain head: %w", err)
+	}
+
+	// Timestamps are taken under the lock so the chain is also in time
+	// order, and truncated to what Postgres stores so hashes can be re-checked
+	event.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	event.Seal(prevHash)
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO audit_log (action, actor_id, impersonator_id, target_type, target_id, ip, user_agent, before_value, after_value, created_at, prev_hash, hash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING id
+	`,
+		event.Action, event.ActorID, event.ImpersonatorID, event.TargetType, event.TargetID, event.IP, event.UserAgent,
+		event.Before, event.After, event.CreatedAt, event.PrevHash, event.Hash,
+	).Scan(&event.ID)
+	if err != nil {
+		return fmt.Errorf("insert audit event: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *AuditLogRepo) List(ctx context.Context, filter *repository.AuditFilter) ([]*entity.AuditEvent, error) {
+	query, args := auditQuery(filter, "DESC")
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query audit log: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*entity.AuditEvent
+	for rows.Next() {
+		event, err := scanAuditEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+func (r *AuditLogRepo) Stream(ctx context.Context, filter *repository.AuditFilter, fn func(*entity.AuditEvent) error) error {
+	query, args := auditQuery(filter, "ASC")
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("query audit log: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		event, err := scanAuditEvent(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func auditQuery(filter *repository.AuditFilter, order string) (string, []interface{}) {
+	var conds []string
+	args := []interface{}{}
+
+	add := func(cond string, value interface{}) {
+		args = append(args, value)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+
+	if filter == nil {
+		filter = &repository.AuditFilter{}
+	}
+	if filter.Action != "" {
+		add("action = $%d", filter.Action)
+	}
+	if filter.ActorID != nil {
+		add("(actor_id = $%[1]d OR impersonator_id = $%[1]d)", *filter.ActorID)
+	}
+	if filter.TargetType != "" {
+		add("target_type = $%d", filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		add("target_id = $%d", filter.TargetID)
+	}
+	if filter.From != nil {
+		add("created_at >= $%d", *filter.From)
+	}
+	if filter.To != nil {
+		add("created_at < $%d", *filter.To)
+	}
+
+	query := `SELECT ` + auditColumns + ` FROM audit_log`
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	query += " ORDER BY id " + order
+
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if filter.Offset > 0 {
+		args = append(args, filter.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	return query, args
+}
+
+func scanAuditEvent(rows pgx.Rows) (*entity.AuditEvent, error) {
+	event := &entity.AuditEvent{}
+	err := rows.Scan(
+		&event.ID, &event.Action, &event.ActorID, &event.ImpersonatorID, &event.TargetType, &event.TargetID,
+		&event.IP, &event.UserAgent, &event.Before, &event.After,
+		&event.CreatedAt, &event.PrevHash, &event.Hash,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("scan audit event: %w", err)
+	}
+	return event, nil
+}
@@ -0,0 +1,148 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"watermap/internal/domain/entity"
+	"watermap/internal/domain/repository"
+)
+
+type CommentRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewCommentRepo(pool *pgxpool.Pool) repository.CommentRepository {
+	return &CommentRepo{pool: pool}
+}
+
+const commentSelect = `
+	SELECT rc.id, rc.canonical_id, rc.object_id, rc.object_version, rc.parent_id, rc.author_id,
+		COALESCE(u.name, ''), rc.body, rc.anchor, rc.resolved_at, rc.resolved_by, rc.created_at, rc.updated_at
+	FROM review_comments rc
+	LEFT JOIN users u ON u.id = rc.author_id
+`
+
+func (r *CommentRepo) ListByCanonicalID(ctx context.Context, canonicalID uuid.UUID) ([]*entity.ReviewComment, error) {
+	rows, err := r.pool.Query(ctx, commentSelect+` WHERE rc.canonical_id = $1 ORDER BY rc.created_at, rc.id`, canonicalID.String())
+	if err != nil {
+		return nil, fmt.Errorf("query review comments: %w", err)
+	}
+	defer rows.Close()
+
+	var comments []*entity.ReviewComment
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, comment)
+	}
+	return comments, rows.Err()
+}
+
+func (r *CommentRepo) GetByID(ctx context.Context, id int64) (*entity.ReviewComment, error) {
+	comment, err := scanComment(r.pool.QueryRow(ctx, commentSelect+` WHERE rc.id = $1`, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, entity.ErrNotFound
+		}
+		return nil, err
+	}
+	return comment, nil
+}
+
+func (r *CommentRepo) Create(ctx context.Context, comment *entity.ReviewComment) (*entity.ReviewComment, error) {
+	var anchor []byte
+	if comment.Anchor != nil {
+		encoded, err := json.Marshal(comment.Anchor)
+		if err != nil {
+			return nil, fmt.Errorf("encode comment anchor: %w", err)
+		}
+		anchor = encoded
+	}
+
+	var id int64
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO review_comments (canonical_id, object_id, object_version, parent_id, author_id, body, anchor)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`, comment.CanonicalID.String(), comment.ObjectID, comment.ObjectVersion, comment.ParentID,
+		comment.AuthorID, comment.Body, anchor,
+	).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("create review comment: %w", err)
+	}
+	return r.GetByID(ctx, id)
+}
+
+func (r *CommentRepo) UpdateBody(ctx context.Context, id int64, body string) error {
+	result, err := r.pool.Exec(ctx,
+		"UPDATE review_comments SET body = $1, updated_at = NOW() WHERE id = $2",
+		body, id,
+	)
+	if err != nil {
+		return fmt.Errorf("update review comment: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return entity.ErrNotFound
+	}
+	return nil
+}
+
+func (r *CommentRepo) Delete(ctx context.Context, id int64) error {
+	result, err := r.pool.Exec(ctx, "DELETE FROM review_comments WHERE id = $1", id)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return entity.ErrHasReplies
+		}
+		return fmt.Errorf("delete review comment: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return entity.ErrNotFound
+	}
+	return nil
+}
+
+func (r *CommentRepo) SetResolved(ctx context.Context, id int64, resolvedBy *int64) error {
+	result, err := r.pool.Exec(ctx, `
+		UPDATE review_comments
+		SET resolved_by = $1, resolved_at = CASE WHEN $1::int IS NULL THEN NULL ELSE NOW() END
+		WHERE id = $2 AND parent_id IS NULL
+	`, resolvedBy, id)
+	if err != nil {
+		return fmt.Errorf("resolve review comment: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return entity.ErrNotFound
+	}
+	return nil
+}
+
+func scanComment(row pgx.Row) (*entity.ReviewComment, error) {
+	comment := &entity.ReviewComment{}
+	var anchor []byte
+	err := row.Scan(
+		&comment.ID, &comment.CanonicalID, &comment.ObjectID, &comment.ObjectVersion, &comment.ParentID,
+		&comment.AuthorID, &comment.AuthorName, &comment.Body, &anchor,
+		&comment.ResolvedAt, &comment.ResolvedBy, &comment.CreatedAt, &comment.UpdatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("scan review comment: %w", err)
+	}
+	if anchor != nil {
+		comment.Anchor = &entity.CommentAnchor{}
+		if err := json.Unmarshal(anchor, comment.Anchor); err != nil {
+			return nil, fmt.Errorf("decode comment anchor: %w", err)
+		}
+	}
+	return comment, nil
+}
@@ -0,0 +1,84 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"watermap/internal/domain/entity"
+	"watermap/internal/domain/repository"
+)
+
+type DuplicateRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewDuplicateRepo(pool *pgxpool.Pool) repository.DuplicateRepository {
+	return &DuplicateRepo{pool: pool}
+}
+
+// duplicateSelect reads the candidate as it is now, leaving out candidates
+// that were since withdrawn or merged into the object
+const duplicateSelect = `
+	SELECT dm.object_id, dm.candidate_id, c.canonical_id, c.name_kz, c.object_type, c.status,
+		dm.method, dm.spatial_score, dm.name_similarity, dm.score, dm.distance_m, dm.detected_at
+	FROM duplicate_matches dm
+	JOIN water_objects wo ON wo.id = dm.object_id
+	JOIN water_objects c ON c.id = dm.candidate_id
+	WHERE c.status IN ('published', 'pending') AND c.canonical_id <> wo.canonical_id
+`
+
+func (r *DuplicateRepo) Replace(ctx context.Context, objectID int64, matches []*entity.DuplicateMatch) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "DELETE FROM duplicate_matches WHERE object_id = $1", objectID); err != nil {
+		return fmt.Errorf("clear duplicate matches: %w", err)
+	}
+
+	for _, m := range matches {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO duplicate_matches (object_id, candidate_id, method, spatial_score, name_similarity, score, distance_m)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`, objectID, m.CandidateID, m.Method, m.Spatial, m.NameSimilarity, m.Score, m.DistanceM)
+		if err != nil {
+			return fmt.Errorf("insert duplicate match: %w", err)
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *DuplicateRepo) ListForObject(ctx context.Context, objectID int64) ([]*entity.DuplicateMatch, error) {
+	return r.query(ctx, duplicateSelect+` AND dm.object_id = $1 ORDER BY dm.score DESC`, objectID)
+}
+
+func (r *DuplicateRepo) ListPending(ctx context.Context) ([]*entity.DuplicateMatch, error) {
+	return r.query(ctx, duplicateSelect+` AND wo.status = 'pending' ORDER BY dm.object_id, dm.score DESC`)
+}
+
+func (r *DuplicateRepo) query(ctx context.Context, query string, args ...interface{}) ([]*entity.DuplicateMatch, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query duplicate matches: %w", err)
+	}
+	defer rows.Close()
+
+	var matches []*entity.DuplicateMatch
+	for rows.Next() {
+		m := &entity.DuplicateMatch{}
+		err := rows.Scan(
+			&m.ObjectID, &m.CandidateID, &m.CanonicalID, &m.NameKZ, &m.ObjectType, &m.Status,
+			&m.Method, &m.Spatial, &m.NameSimilarity, &m.Score, &m.DistanceM, &m.DetectedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scan duplicate match: %w", err)
+		}
+		matches = append(matches, m)
+	}
+	return matches, rows.Err()
+}
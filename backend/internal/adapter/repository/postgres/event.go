@@ -0,0 +1,176 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"watermap/internal/domain/entity"
+	"watermap/internal/domain/repository"
+)
+
+// eventChannel is the LISTEN/NOTIFY channel that carries new event IDs.
+// Payloads are limited to 8000 bytes, so listeners load the event itself.
+const eventChannel = "watermap_events"
+
+type EventRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewEventRepo(pool *pgxpool.Pool) repository.EventRepository {
+	return &EventRepo{pool: pool}
+}
+
+func (r *EventRepo) Append(ctx context.Context, event *entity.Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encode event: %w", err)
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx,
+		"INSERT INTO events (type, payload) VALUES ($1, $2) RETURNING id, created_at",
+		event.Type, payload,
+	).Scan(&event.ID, &event.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("insert event: %w", err)
+	}
+
+	delivery, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encode event: %w", err)
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload)
+		SELECT id, $1, $2, $3 FROM webhooks
+		WHERE active AND (cardinality(events) = 0 OR $2::text = ANY(events))
+	`, event.ID, event.Type, delivery)
+	if err != nil {
+		return fmt.Errorf("queue webhook deliveries: %w", err)
+	}
+
+	// Delivered on commit, so listeners never see an uncommitted ID
+	if _, err := tx.Exec(ctx, "SELECT pg_notify($1, $2)", eventChannel, strconv.FormatInt(event.ID, 10)); err != nil {
+		return fmt.Errorf("notify event: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *EventRepo) ListSince(ctx context.Context, afterID int64, limit int) ([]*entity.Event, error) {
+	rows, err := r.pool.Query(ctx,
+		"SELECT id, payload, created_at FROM events WHERE id > $1 ORDER BY id LIMIT $2",
+		afterID, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*entity.Event
+	for rows.Next() {
+		event, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+func (r *EventRepo) FirstID(ctx context.Context) (int64, error) {
+	var id int64
+	if err := r.pool.QueryRow(ctx, "SELECT COALESCE(MIN(id), 0) FROM events").Scan(&id); err != nil {
+		return 0, fmt.Errorf("get first event: %w", err)
+	}
+	return id, nil
+}
+
+func (r *EventRepo) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.pool.Exec(ctx, "DELETE FROM events WHERE created_at < $1", before)
+	if err != nil {
+		return 0, fmt.Errorf("delete events: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
+
+func (r *EventRepo) Listen(ctx context.Context, fn func(*entity.Event)) error {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("acquire listener connection: %w", err)
+	}
+	// A connection still listening must not go back to the pool, so it is
+	// taken out of it for good and closed when the listener stops
+	pgConn := conn.Hijack()
+	defer pgConn.Close(context.Background())
+
+	return listen(ctx, pgConn, fn)
+}
+
+// listenConn is the part of *pgx.Conn a listener uses
+type listenConn interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	WaitForNotification(ctx context.Context) (*pgconn.Notification, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// listen subscribes conn to the event channel and passes each notified
+// event to fn until ctx ends or the connection fails
+func listen(ctx context.Context, conn listenConn, fn func(*entity.Event)) error {
+	if _, err := conn.Exec(ctx, "LISTEN "+eventChannel); err != nil {
+		return fmt.Errorf("listen for events: %w", err)
+	}
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("wait for event: %w", err)
+		}
+
+		id, err := strconv.ParseInt(notification.Payload, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		row := conn.QueryRow(ctx, "SELECT id, payload, created_at FROM events WHERE id = $1", id)
+		event, err := scanEvent(row)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				continue
+			}
+			return err
+		}
+		fn(event)
+	}
+}
+
+func scanEvent(row pgx.Row) (*entity.Event, error) {
+	var id int64
+	var payload []byte
+	var createdAt time.Time
+	if err := row.Scan(&id, &payload, &createdAt); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("scan event: %w", err)
+	}
+
+	event := &entity.Event{}
+	if err := json.Unmarshal(payload, event); err != nil {
+		return nil, fmt.Errorf("decode event %d: %w", id, err)
+	}
+	event.ID = id
+	event.CreatedAt = createdAt
+	return event, nil
+}
@@ -0,0 +1,108 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"watermap/internal/domain/entity"
+)
+
+// eventRow scans a stored event, or reports it gone
+type eventRow struct {
+	event *entity.Event
+}
+
+func (r eventRow) Scan(dest ...any) error {
+	if r.event == nil {
+		return pgx.ErrNoRows
+	}
+	payload, err := json.Marshal(r.event)
+	if err != nil {
+		return err
+	}
+	*dest[0].(*int64) = r.event.ID
+	*dest[1].(*[]byte) = payload
+	*dest[2].(*time.Time) = r.event.CreatedAt
+	return nil
+}
+
+// notifyConn replays queued notifications against a fixed event table,
+// then fails like a closed connection
+type notifyConn struct {
+	listenErr error
+	payloads  []string
+	events    map[int64]*entity.Event
+	statement string
+}
+
+func (c *notifyConn) Exec(_ context.Context, sql string, _ ...any) (pgconn.CommandTag, error) {
+	c.statement = sql
+	return pgconn.CommandTag{}, c.listenErr
+}
+
+func (c *notifyConn) WaitForNotification(context.Context) (*pgconn.Notification, error) {
+	if len(c.payloads) == 0 {
+		return nil, context.Canceled
+	}
+	payload := c.payloads[0]
+	c.payloads = c.payloads[1:]
+	return &pgconn.Notification{Channel: eventChannel, Payload: payload}, nil
+}
+
+func (c *notifyConn) QueryRow(_ context.Context, _ string, args ...any) pgx.Row {
+	return eventRow{c.events[args[0].(int64)]}
+}
+
+func TestListen(t *testing.T) {
+	created := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	events := map[int64]*entity.Event{
+		1: {ID: 1, Type: entity.EventPendingAdded, CreatedAt: created},
+		3: {ID: 3, Type: entity.EventApproved, CreatedAt: created},
+	}
+
+	tests := []struct {
+		name      string
+		listenErr error
+		payloads  []string
+		want      []int64
+	}{
+		{"delivers in order", nil, []string{"1", "3"}, []int64{1, 3}},
+		{"skips a malformed payload", nil, []string{"x", "3"}, []int64{3}},
+		{"skips a pruned event", nil, []string{"2", "1"}, []int64{1}},
+		{"listen fails", errors.New("connection reset"), []string{"1"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := &notifyConn{listenErr: tt.listenErr, payloads: tt.payloads, events: events}
+			var got []int64
+			err := listen(context.Background(), conn, func(e *entity.Event) {
+				got = append(got, e.ID)
+			})
+
+			if conn.statement != "LISTEN "+eventChannel {
+				t.Errorf("statement = %q, want LISTEN %s", conn.statement, eventChannel)
+			}
+			if tt.listenErr != nil {
+				if !errors.Is(err, tt.listenErr) {
+					t.Errorf("err = %v, want %v", err, tt.listenErr)
+				}
+			} else if !errors.Is(err, context.Canceled) {
+				t.Errorf("err = %v, want the connection's error", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("delivered %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("delivered %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"watermap/internal/domain/entity"
+	"watermap/internal/domain/repository"
+)
+
+type NotificationRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewNotificationRepo(pool *pgxpool.Pool) repository.NotificationRepository {
+	return &NotificationRepo{pool: pool}
+}
+
+const notificationColumns = `id, user_id, event, level, title, message, object_id, read_at, created_at`
+
+func (r *NotificationRepo) Create(ctx context.Context, n *entity.Notification, userIDs []int64) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO notifications (user_id, event, level, title, message, object_id)
+		SELECT recipient, $2, $3, $4, $5, $6
+		FROM unnest($1::int[]) AS recipient
+		WHERE NOT EXISTS (
+			SELECT 1 FROM notification_preferences p
+			WHERE p.user_id = recipient AND p.event = $2 AND NOT p.enabled
+		)
+	`, userIDs, n.Event, n.Level, n.Title, n.Message, n.ObjectID)
+	if err != nil {
+		return fmt.Errorf("create notifications: %w", err)
+	}
+	return nil
+}
+
+func (r *NotificationRepo) List(ctx context.Context, userID int64, filter *repository.NotificationFilter) ([]*entity.Notification, error) {
+	query := `SELECT ` + notificationColumns + ` FROM notifications WHERE user_id = $1`
+	if filter.UnreadOnly {
+		query += ` AND read_at IS NULL`
+	}
+	query += ` ORDER BY created_at DESC, id DESC LIMIT $2 OFFSET $3`
+
+	rows, err := r.pool.Query(ctx, query, userID, filter.Limit, filter.Offset)
+	if err != nil {
+		return nil, fmt.Errorf("query notifications: %w", err)
+	}
+	defer rows.Close()
+
+	notifications := []*entity.Notification{}
+	for rows.Next() {
+		n := &entity.Notification{}
+		if err := rows.Scan(
+			&n.ID, &n.UserID, &n.Event, &n.Level, &n.Title, &n.Message, &n.ObjectID, &n.ReadAt, &n.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan notification: %w", err)
+		}
+		n.Read = n.ReadAt != nil
+		notifications = append(notifications, n)
+	}
+	return notifications, rows.Err()
+}
+
+func (r *NotificationRepo) UnreadCount(ctx context.Context, userID int64) (int, error) {
+	var count int
+	err := r.pool.QueryRow(ctx,
+		"SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL",
+		userID,
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("count unread notifications: %w", err)
+	}
+	return count, nil
+}
+
+func (r *NotificationRepo) MarkRead(ctx context.Context, userID, id int64) error {
+	result, err := r.pool.Exec(ctx,
+		"UPDATE notifications SET read_at = COALESCE(read_at, NOW()) WHERE id = $1 AND user_id = $2",
+		id, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("mark notification read: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return entity.ErrNotFound
+	}
+	return nil
+}
+
+func (r *NotificationRepo) MarkAllRead(ctx context.Context, userID int64) (int64, error) {
+	result, err := r.pool.Exec(ctx,
+		"UPDATE notifications SET read_at = NOW() WHERE user_id = $1 AND read_at IS NULL",
+		userID,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("mark notifications read: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
+
+func (r *NotificationRepo) Delete(ctx context.Context, userID, id int64) error {
+	result, err := r.pool.Exec(ctx, "DELETE FROM notifications WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return fmt.Errorf("delete notification: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return entity.ErrNotFound
+	}
+	return nil
+}
+
+func (r *NotificationRepo) Preferences(ctx context.Context, userID int64) ([]entity.NotificationPreference, error) {
+	rows, err := r.pool.Query(ctx,
+		"SELECT event, enabled FROM notification_preferences WHERE user_id = $1",
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query notification preferences: %w", err)
+	}
+	defer rows.Close()
+
+	stored := map[entity.NotificationEvent]bool{}
+	for rows.Next() {
+		var event entity.NotificationEvent
+		var enabled bool
+		if err := rows.Scan(&event, &enabled); err != nil {
+			return nil, fmt.Errorf("scan notification preference: %w", err)
+		}
+		stored[event] = enabled
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	prefs := make([]entity.NotificationPreference, 0, len(entity.AllNotificationEvents))
+	for _, event := range entity.AllNotificationEvents {
+		enabled, ok := stored[event]
+		prefs = append(prefs, entity.NotificationPreference{Event: event, Enabled: enabled || !ok})
+	}
+	return prefs, nil
+}
+
+func (r *NotificationRepo) SetPreferences(ctx context.Context, userID int64, prefs []entity.NotificationPreference) error {
+	batch := &pgx.Batch{}
+	for _, pref := range prefs {
+		batch.Queue(`
+			INSERT INTO notification_preferences (user_id, event, enabled) VALUES ($1, $2, $3)
+			ON CONFLICT (user_id, event) DO UPDATE SET enabled = EXCLUDED.enabled
+		`, userID, pref.Event, pref.Enabled)
+	}
+
+	if err := r.pool.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("save notification preferences: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"watermap/internal/domain/entity"
+	"watermap/internal/domain/repository"
+)
+
+type OrganizationRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewOrganizationRepo(pool *pgxpool.Pool) repository.OrganizationRepository {
+	return &OrganizationRepo{pool: pool}
+}
+
+func (r *OrganizationRepo) List(ctx context.Context) ([]*entity.Organization, error) {
+	rows, err := r.pool.Query(ctx, `SELECT id, name, description, created_at FROM organizations ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("query organizations: %w", err)
+	}
+	defer rows.Close()
+
+	var orgs []*entity.Organization
+	for rows.Next() {
+		org := &entity.Organization{}
+		if err := rows.Scan(&org.ID, &org.Name, &org.Description, &org.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan organization: %w", err)
+		}
+		orgs = append(orgs, org)
+	}
+	return orgs, rows.Err()
+}
+
+func (r *OrganizationRepo) ListForUser(ctx context.Context, userID int64) ([]*entity.Organization, error) {
+	query := `
+		SELECT o.id, o.name, o.description, o.created_at, m.role
+		FROM organizations o
+		JOIN organization_members m ON m.organization_id = o.id
+		WHERE m.user_id = $1
+		ORDER BY o.name
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("query user organizations: %w", err)
+	}
+	defer rows.Close()
+
+	var orgs []*entity.Organization
+	for rows.Next() {
+		org := &entity.Organization{}
+		if err := rows.Scan(&org.ID, &org.Name, &org.Description, &org.CreatedAt, &org.Role); err != nil {
+			return nil, fmt.Errorf("scan organization: %w", err)
+		}
+		orgs = append(orgs, org)
+	}
+	return orgs, rows.Err()
+}
+
+func (r *OrganizationRepo) GetByID(ctx context.Context, id int64) (*entity.Organization, error) {
+	org := &entity.Organization{}
+	err := r.pool.QueryRow(ctx,
+		`SELECT id, name, description, created_at FROM organizations WHERE id = $1`, id,
+	).Scan(&org.ID, &org.Name, &org.Description, &org.CreatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, entity.ErrNotFound
+		}
+		return nil, fmt.Errorf("get organization: %w", err)
+	}
+	return org, nil
+}
+
+func (r *OrganizationRepo) Create(ctx context.Context, org *entity.Organization) (*entity.Organization, error) {
+	err := r.pool.QueryRow(ctx,
+		`INSERT INTO organizations (name, description) VALUES ($1, $2) RETURNING id, created_at`,
+		org.Name, org.Description,
+	).Scan(&org.ID, &org.CreatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, entity.ErrOrganizationNameTaken
+		}
+		return nil, fmt.Errorf("create organization: %w", err)
+	}
+	return org, nil
+}
+
+func (r *OrganizationRepo) Update(ctx context.Context, org *entity.Organization) error {
+	result, err := r.pool.Exec(ctx,
+		`UPDATE organizations SET name = $1, description = $2 WHERE id = $3`,
+		org.Name, org.Description, org.ID,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return entity.ErrOrganizationNameTaken
+		}
+		return fmt.Errorf("update organization: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return entity.ErrNotFound
+	}
+	return nil
+}
+
+func (r *OrganizationRepo) ListMembers(ctx context.Context, orgID int64) ([]*entity.OrganizationMember, error) {
+	query := `
+		SELECT m.organization_id, m.user_id, u.name, u.email, m.role, m.joined_at
+		FROM organization_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.organization_id = $1
+		ORDER BY m.role DESC, u.name
+	`
+
+	rows, err := r.pool.Query(ctx, query, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("query organization members: %w", err)
+	}
+	defer rows.Close()
+
+	var members []*entity.OrganizationMember
+	for rows.Next() {
+		member, err := scanMember(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan organization member: %w", err)
+		}
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
+
+func (r *OrganizationRepo) GetMembership(ctx context.Context, orgID, userID int64) (*entity.OrganizationMember, error) {
+	query := `
+		SELECT m.organization_id, m.user_id, u.name, u.email, m.role, m.joined_at
+		FROM organization_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.organization_id = $1 AND m.user_id = $2
+	`
+
+	member, err := scanMember(r.pool.QueryRow(ctx, query, orgID, userID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, entity.ErrNotMember
+		}
+		return nil, fmt.Errorf("get organization membership: %w", err)
+	}
+	return member, nil
+}
+
+func (r *OrganizationRepo) AddMember(ctx context.Context, orgID, userID int64, role entity.OrganizationRole) error {
+	_, err := r.pool.Exec(ctx,
+		`INSERT INTO organization_members (organization_id, user_id, role) VALUES ($1, $2, $3)`,
+		orgID, userID, role,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return entity.ErrAlreadyMember
+		}
+		return fmt.Errorf("add organization member: %w", err)
+	}
+	return nil
+}
+
+func (r *OrganizationRepo) UpdateMemberRole(ctx context.Context, orgID, userID int64, role entity.OrganizationRole) error {
+	result, err := r.pool.Exec(ctx,
+		`UPDATE organization_members SET role = $1 WHERE organization_id = $2 AND user_id = $3`,
+		role, orgID, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("update organization member: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return entity.ErrNotMember
+	}
+	return nil
+}
+
+func (r *OrganizationRepo) RemoveMember(ctx context.Context, orgID, userID int64) error {
+	result, err := r.pool.Exec(ctx,
+		`DELETE FROM organization_members WHERE organization_id = $1 AND user_id = $2`,
+		orgID, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("remove organization member: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return entity.ErrNotMember
+	}
+	return nil
+}
+
+func scanMember(row pgx.Row) (*entity.OrganizationMember, error) {
+	member := &entity.OrganizationMember{}
+	err := row.Scan(
+		&member.OrganizationID, &member.UserID, &member.Name, &member.Email, &member.Role, &member.JoinedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+func isForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23503"
+}
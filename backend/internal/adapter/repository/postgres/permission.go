@@ -0,0 +1,145 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"watermap/internal/domain/entity"
+	"watermap/internal/domain/repository"
+)
+
+type PermissionRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewPermissionRepo(pool *pgxpool.Pool) repository.PermissionRepository {
+	return &PermissionRepo{pool: pool}
+}
+
+const grantColumns = `id, permission, role, user_id, region, object_type, created_by, created_at`
+
+func (r *PermissionRepo) List(ctx context.Context) ([]*entity.PermissionGrant, error) {
+	rows, err := r.pool.Query(ctx, `SELECT `+grantColumns+` FROM permission_grants ORDER BY permission, id`)
+	if err != nil {
+		return nil, fmt.Errorf("query permission grants: %w", err)
+	}
+	defer rows.Close()
+
+	return scanGrants(rows)
+}
+
+func (r *PermissionRepo) GetByID(ctx context.Context, id int64) (*entity.PermissionGrant, error) {
+	row := r.pool.QueryRow(ctx, `SELECT `+grantColumns+` FROM permission_grants WHERE id = $1`, id)
+
+	grant, err := scanGrant(row)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, entity.ErrNotFound
+		}
+		return nil, fmt.Errorf("get permission grant: %w", err)
+	}
+	return grant, nil
+}
+
+func (r *PermissionRepo) Create(ctx context.Context, grant *entity.PermissionGrant) (*entity.PermissionGrant, error) {
+	query := `
+		INSERT INTO permission_grants (permission, role, user_id, region, object_type, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+
+	err := r.pool.QueryRow(ctx, query,
+		grant.Permission, grant.Role, grant.UserID, grant.Region, grant.ObjectType, grant.CreatedBy,
+	).Scan(&grant.ID, &grant.CreatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, entity.ErrDuplicateGrant
+		}
+		return nil, fmt.Errorf("create permission grant: %w", err)
+	}
+	return grant, nil
+}
+
+func (r *PermissionRepo) Delete(ctx context.Context, id int64) error {
+	result, err := r.pool.Exec(ctx, "DELETE FROM permission_grants WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("delete permission grant: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return entity.ErrNotFound
+	}
+	return nil
+}
+
+func (r *PermissionRepo) ForUser(ctx context.Context, userID int64, role entity.UserRole) (entity.PermissionSet, error) {
+	rows, err := r.pool.Query(ctx,
+		`SELECT `+grantColumns+` FROM permission_grants WHERE user_id = $1 OR role = $2`,
+		userID, role,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query user permissions: %w", err)
+	}
+	defer rows.Close()
+
+	grants, err := scanGrants(rows)
+	if err != nil {
+		return nil, err
+	}
+	return entity.PermissionSet(grants), nil
+}
+
+func (r *PermissionRepo) UsersAllowed(ctx context.Context, perm entity.Permission, obj *entity.WaterObject) ([]int64, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT u.id FROM users u
+		WHERE u.deactivated_at IS NULL AND u.deleted_at IS NULL
+			AND EXISTS (
+				SELECT 1 FROM permission_grants g
+				WHERE g.permission = $1
+					AND (g.user_id = u.id OR g.role = u.role)
+					AND (g.region IS NULL OR g.region = $2)
+					AND (g.object_type IS NULL OR g.object_type = $3)
+			)
+		ORDER BY u.id
+	`, perm, obj.Region, obj.ObjectType)
+	if err != nil {
+		return nil, fmt.Errorf("query permitted users: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan permitted user: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func scanGrants(rows pgx.Rows) ([]*entity.PermissionGrant, error) {
+	var grants []*entity.PermissionGrant
+	for rows.Next() {
+		grant, err := scanGrant(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan permission grant: %w", err)
+		}
+		grants = append(grants, grant)
+	}
+	return grants, rows.Err()
+}
+
+func scanGrant(row pgx.Row) (*entity.PermissionGrant, error) {
+	grant := &entity.PermissionGrant{}
+	err := row.Scan(
+		&grant.ID, &grant.Permission, &grant.Role, &grant.UserID,
+		&grant.Region, &grant.ObjectType, &grant.CreatedBy, &grant.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return grant, nil
+}
@@ -0,0 +1,129 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"watermap/internal/domain/entity"
+	"watermap/internal/domain/repository"
+)
+
+type QualityRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewQualityRepo(pool *pgxpool.Pool) repository.QualityRepository {
+	return &QualityRepo{pool: pool}
+}
+
+var violationColumns = []string{"object_id", "rule_id", "severity", "field", "message", "checked_at"}
+
+func (r *QualityRepo) ReplaceForObject(ctx context.Context, objectID int64, violations []*entity.RuleViolation) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "DELETE FROM quality_violations WHERE object_id = $1", objectID); err != nil {
+		return fmt.Errorf("clear violations: %w", err)
+	}
+	if err := insertViolations(ctx, tx, violations); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *QualityRepo) ReplacePublished(ctx context.Context, violations []*entity.RuleViolation) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		DELETE FROM quality_violations qv
+		USING water_objects wo
+		WHERE wo.id = qv.object_id AND wo.status = 'published'
+	`)
+	if err != nil {
+		return fmt.Errorf("clear violations: %w", err)
+	}
+	if err := insertViolations(ctx, tx, violations); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func insertViolations(ctx context.Context, tx pgx.Tx, violations []*entity.RuleViolation) error {
+	_, err := tx.CopyFrom(ctx, pgx.Identifier{"quality_violations"}, violationColumns,
+		pgx.CopyFromSlice(len(violations), func(i int) ([]interface{}, error) {
+			v := violations[i]
+			return []interface{}{v.ObjectID, v.RuleID, string(v.Severity), v.Field, v.Message, v.CheckedAt}, nil
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("insert violations: %w", err)
+	}
+	return nil
+}
+
+func (r *QualityRepo) List(ctx context.Context, filter *repository.ViolationFilter) ([]*entity.RuleViolation, error) {
+	query := `
+		SELECT qv.object_id, qv.rule_id, qv.severity, qv.field, qv.message, qv.checked_at,
+			wo.canonical_id, wo.name_kz, wo.object_type, wo.region, wo.status
+		FROM quality_violations qv
+		JOIN water_objects wo ON wo.id = qv.object_id
+		WHERE TRUE
+	`
+
+	args := []interface{}{}
+	argIdx := 1
+
+	if filter != nil && filter.Severity != "" {
+		query += fmt.Sprintf(" AND qv.severity = $%d", argIdx)
+		args = append(args, filter.Severity)
+		argIdx++
+	}
+	if filter != nil && filter.RuleID != "" {
+		query += fmt.Sprintf(" AND qv.rule_id = $%d", argIdx)
+		args = append(args, filter.RuleID)
+		argIdx++
+	}
+	if filter != nil && filter.ObjectType != "" {
+		query += fmt.Sprintf(" AND wo.object_type = $%d", argIdx)
+		args = append(args, filter.ObjectType)
+		argIdx++
+	}
+	if filter != nil && filter.Status != "" {
+		query += fmt.Sprintf(" AND wo.status = $%d", argIdx)
+		args = append(args, filter.Status)
+	}
+
+	query += " ORDER BY qv.severity, qv.rule_id, wo.name_kz, qv.object_id"
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query violations: %w", err)
+	}
+	defer rows.Close()
+
+	var violations []*entity.RuleViolation
+	for rows.Next() {
+		v := &entity.RuleViolation{}
+		err := rows.Scan(
+			&v.ObjectID, &v.RuleID, &v.Severity, &v.Field, &v.Message, &v.CheckedAt,
+			&v.CanonicalID, &v.NameKZ, &v.ObjectType, &v.Region, &v.Status,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scan violation: %w", err)
+		}
+		violations = append(violations, v)
+	}
+	return violations, rows.Err()
+}
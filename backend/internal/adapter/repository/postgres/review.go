@@ -0,0 +1,253 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"watermap/internal/domain/entity"
+	"watermap/internal/domain/repository"
+)
+
+type ReviewRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewReviewRepo(pool *pgxpool.Pool) repository.ReviewRepository {
+	return &ReviewRepo{pool: pool}
+}
+
+const claimSelect = `
+	SELECT rc.object_id, rc.reviewer_id, u.name, rc.claimed_at, rc.expires_at
+	FROM review_claims rc
+	JOIN users u ON u.id = rc.reviewer_id
+	JOIN water_objects wo ON wo.id = rc.object_id AND wo.status = 'pending'
+	WHERE rc.expires_at > NOW()
+`
+
+func (r *ReviewRepo) Claim(ctx context.Context, objectID, reviewerID int64, expiresAt time.Time) (*entity.ReviewClaim, error) {
+	// A lapsed claim is taken over; a live one only renews for its holder
+	result, err := r.pool.Exec(ctx, `
+		INSERT INTO review_claims (object_id, reviewer_id, expires_at)
+		SELECT id, $2, $3 FROM water_objects WHERE id = $1 AND status = 'pending'
+		ON CONFLICT (object_id) DO UPDATE SET
+			reviewer_id = EXCLUDED.reviewer_id,
+			claimed_at = CASE WHEN review_claims.reviewer_id = EXCLUDED.reviewer_id
+				THEN review_claims.claimed_at ELSE NOW() END,
+			expires_at = EXCLUDED.expires_at
+		WHERE review_claims.reviewer_id = EXCLUDED.reviewer_id OR review_claims.expires_at <= NOW()
+	`, objectID, reviewerID, expiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("claim object: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		var pending bool
+		err := r.pool.QueryRow(ctx,
+			"SELECT EXISTS (SELECT 1 FROM water_objects WHERE id = $1 AND status = 'pending')", objectID,
+		).Scan(&pending)
+		if err != nil {
+			return nil, fmt.Errorf("check pending object: %w", err)
+		}
+		if !pending {
+			return nil, entity.ErrNotFound
+		}
+		return nil, entity.ErrClaimed
+	}
+
+	return r.GetClaim(ctx, objectID)
+}
+
+func (r *ReviewRepo) Unclaim(ctx context.Context, objectID, reviewerID int64) error {
+	result, err := r.pool.Exec(ctx,
+		"DELETE FROM review_claims WHERE object_id = $1 AND reviewer_id = $2",
+		objectID, reviewerID,
+	)
+	if err != nil {
+		return fmt.Errorf("release claim: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return entity.ErrNotFound
+	}
+	return nil
+}
+
+func (r *ReviewRepo) GetClaim(ctx context.Context, objectID int64) (*entity.ReviewClaim, error) {
+	claim, err := scanClaim(r.pool.QueryRow(ctx, claimSelect+` AND rc.object_id = $1`, objectID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, entity.ErrNotFound
+		}
+		return nil, fmt.Errorf("get claim: %w", err)
+	}
+	return claim, nil
+}
+
+func (r *ReviewRepo) ActiveClaims(ctx context.Context) ([]*entity.ReviewClaim, error) {
+	rows, err := r.pool.Query(ctx, claimSelect+` ORDER BY rc.claimed_at`)
+	if err != nil {
+		return nil, fmt.Errorf("query claims: %w", err)
+	}
+	defer rows.Close()
+
+	var claims []*entity.ReviewClaim
+	for rows.Next() {
+		claim, err := scanClaim(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan claim: %w", err)
+		}
+		claims = append(claims, claim)
+	}
+	return claims, rows.Err()
+}
+
+func scanClaim(row pgx.Row) (*entity.ReviewClaim, error) {
+	claim := &entity.ReviewClaim{}
+	err := row.Scan(&claim.ObjectID, &claim.ReviewerID, &claim.ReviewerName, &claim.ClaimedAt, &claim.ExpiresAt)
+	return claim, err
+}
+
+const approvalSelect = `
+	SELECT ra.object_id, ra.reviewer_id, u.name, ra.senior, ra.note, ra.created_at
+	FROM review_approvals ra
+	JOIN users u ON u.id = ra.reviewer_id
+`
+
+func (r *ReviewRepo) AddApproval(ctx context.Context, approval *entity.ReviewApproval, quorumMet func([]*entity.ReviewApproval) bool) ([]*entity.ReviewApproval, bool, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// The lock makes concurrent approvals count one after the other, so
+	// exactly one of them sees the quorum met
+	var id int64
+	err = tx.QueryRow(ctx,
+		"SELECT id FROM water_objects WHERE id = $1 AND status = ANY($2) FOR UPDATE",
+		approval.ObjectID, statusList(entity.TransitionApprove.Sources()),
+	).Scan(&id)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, false, entity.ErrNotFound
+		}
+		return nil, false, fmt.Errorf("lock object: %w", err)
+	}
+
+	_, err = tx.Exec(ctx,
+		"INSERT INTO review_approvals (object_id, reviewer_id, senior, note) VALUES ($1, $2, $3, $4)",
+		approval.ObjectID, approval.ReviewerID, approval.Senior, approval.Note,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, false, entity.ErrAlreadyApproved
+		}
+		return nil, false, fmt.Errorf("record approval: %w", err)
+	}
+
+	rows, err := tx.Query(ctx, approvalSelect+` WHERE ra.object_id = $1 ORDER BY ra.created_at`, approval.ObjectID)
+	if err != nil {
+		return nil, false, fmt.Errorf("query approvals: %w", err)
+	}
+	approvals, err := scanApprovals(rows)
+	if err != nil {
+		return nil, false, err
+	}
+
+	published := quorumMet(approvals)
+	if published {
+		if err := publishVersion(ctx, tx, approval.ObjectID, entity.TransitionApprove, approval.ReviewerID); err != nil {
+			return nil, false, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, false, fmt.Errorf("commit approval: %w", err)
+	}
+	return approvals, published, nil
+}
+
+func (r *ReviewRepo) ListApprovals(ctx context.Context, objectID int64) ([]*entity.ReviewApproval, error) {
+	return r.queryApprovals(ctx, approvalSelect+` WHERE ra.object_id = $1 ORDER BY ra.created_at`, objectID)
+}
+
+func (r *ReviewRepo) PendingApprovals(ctx context.Context) ([]*entity.ReviewApproval, error) {
+	return r.queryApprovals(ctx, approvalSelect+`
+		JOIN water_objects wo ON wo.id = ra.object_id
+		WHERE wo.status = 'pending'
+		ORDER BY ra.object_id, ra.created_at
+	`)
+}
+
+func (r *ReviewRepo) queryApprovals(ctx context.Context, query string, args ...interface{}) ([]*entity.ReviewApproval, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query approvals: %w", err)
+	}
+	return scanApprovals(rows)
+}
+
+func scanApprovals(rows pgx.Rows) ([]*entity.ReviewApproval, error) {
+	defer rows.Close()
+
+	var approvals []*entity.ReviewApproval
+	for rows.Next() {
+		a := &entity.ReviewApproval{}
+		if err := rows.Scan(&a.ObjectID, &a.ReviewerID, &a.ReviewerName, &a.Senior, &a.Note, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan approval: %w", err)
+		}
+		approvals = append(approvals, a)
+	}
+	return approvals, rows.Err()
+}
+
+func (r *ReviewRepo) ListQuorumRules(ctx context.Context) ([]*entity.QuorumRule, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, object_type, region, approvals, senior_approvals, senior_override, created_by, created_at
+		FROM review_quorum_rules
+		ORDER BY object_type NULLS LAST, region NULLS LAST
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("query quorum rules: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []*entity.QuorumRule
+	for rows.Next() {
+		q := &entity.QuorumRule{}
+		if err := rows.Scan(&q.ID, &q.ObjectType, &q.Region, &q.Approvals, &q.SeniorApprovals, &q.SeniorOverride, &q.CreatedBy, &q.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan quorum rule: %w", err)
+		}
+		rules = append(rules, q)
+	}
+	return rules, rows.Err()
+}
+
+func (r *ReviewRepo) CreateQuorumRule(ctx context.Context, rule *entity.QuorumRule) (*entity.QuorumRule, error) {
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO review_quorum_rules (object_type, region, approvals, senior_approvals, senior_override, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`, rule.ObjectType, rule.Region, rule.Approvals, rule.SeniorApprovals, rule.SeniorOverride, rule.CreatedBy,
+	).Scan(&rule.ID, &rule.CreatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, entity.ErrDuplicateQuorumRule
+		}
+		return nil, fmt.Errorf("create quorum rule: %w", err)
+	}
+	return rule, nil
+}
+
+func (r *ReviewRepo) DeleteQuorumRule(ctx context.Context, id int64) error {
+	result, err := r.pool.Exec(ctx, "DELETE FROM review_quorum_rules WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("delete quorum rule: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return entity.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,229 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"watermap/internal/domain/entity"
+	"watermap/internal/domain/repository"
+)
+
+type SessionRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewSessionRepo(pool *pgxpool.Pool) repository.SessionRepository {
+	return &SessionRepo{pool: pool}
+}
+
+const sessionColumns = `id, user_id, user_agent, ip, created_at, last_seen_at, expires_at, revoked_at, revoked_reason, impersonator_id`
+
+func (r *SessionRepo) Create(ctx context.Context, session *entity.Session, refresh *entity.RefreshToken) (*entity.Session, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO sessions (user_id, user_agent, ip, expires_at, impersonator_id)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, last_seen_at
+	`, session.UserID, session.UserAgent, session.IP, session.ExpiresAt, session.ImpersonatorID).Scan(
+		&session.ID, &session.CreatedAt, &session.LastSeenAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("insert session: %w", err)
+	}
+
+	// Impersonation sessions are created without a refresh token
+	if refresh != nil {
+		refresh.SessionID = session.ID
+		if err := insertRefreshToken(ctx, tx, refresh); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit session: %w", err)
+	}
+	return session, nil
+}
+
+func (r *SessionRepo) GetByID(ctx context.Context, id uuid.UUID) (*entity.Session, error) {
+	row := r.pool.QueryRow(ctx, `SELECT `+sessionColumns+` FROM sessions WHERE id = $1`, id)
+	return scanSession(row)
+}
+
+func (r *SessionRepo) GetActiveByUser(ctx context.Context, userID int64) ([]*entity.Session, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+sessionColumns+`
+		FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		ORDER BY last_seen_at DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("query sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []*entity.Session
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+func (r *SessionRepo) Rotate(ctx context.Context, tokenHash string, next *entity.RefreshToken, lastSeen time.Time, maxLifetime time.Duration) (*entity.Session, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		sessionID uuid.UUID
+		usedAt    *time.Time
+		expiresAt time.Time
+	)
+	err = tx.QueryRow(ctx,
+		"SELECT session_id, used_at, expires_at FROM refresh_tokens WHERE token_hash = $1 FOR UPDATE",
+		tokenHash,
+	).Scan(&sessionID, &usedAt, &expiresAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, entity.ErrNotFound
+		}
+		return nil, fmt.Errorf("get refresh token: %w", err)
+	}
+
+	// A consumed token presented again means it leaked: revoke the session
+	// so neither the thief nor the victim can keep using it
+	if usedAt != nil {
+		_, err = tx.Exec(ctx,
+			"UPDATE sessions SET revoked_at = NOW(), revoked_reason = $1 WHERE id = $2 AND revoked_at IS NULL",
+			entity.RevokeReasonTokenReused, sessionID,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("revoke reused session: %w", err)
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return nil, fmt.Errorf("commit revocation: %w", err)
+		}
+		return nil, entity.ErrTokenReused
+	}
+
+	session, err := scanSession(tx.QueryRow(ctx,
+		`SELECT `+sessionColumns+` FROM sessions WHERE id = $1 FOR UPDATE`, sessionID,
+	))
+	if err != nil {
+		return nil, err
+	}
+	if !session.IsActive(lastSeen) || !lastSeen.Before(expiresAt) {
+		return nil, entity.ErrSessionRevoked
+	}
+
+	if _, err := tx.Exec(ctx, "UPDATE refresh_tokens SET used_at = $1 WHERE token_hash = $2", lastSeen, tokenHash); err != nil {
+		return nil, fmt.Errorf("consume refresh token: %w", err)
+	}
+
+	// Refreshing keeps the session alive, up to its absolute limit
+	if limit := session.CreatedAt.Add(maxLifetime); next.ExpiresAt.After(limit) {
+		next.ExpiresAt = limit
+	}
+	next.SessionID = sessionID
+	if err := insertRefreshToken(ctx, tx, next); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(ctx,
+		"UPDATE sessions SET last_seen_at = $1, expires_at = $2 WHERE id = $3",
+		lastSeen, next.ExpiresAt, sessionID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("touch session: %w", err)
+	}
+	session.LastSeenAt = lastSeen
+	session.ExpiresAt = next.ExpiresAt
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit rotation: %w", err)
+	}
+	return session, nil
+}
+
+func (r *SessionRepo) GetSessionIDByTokenHash(ctx context.Context, tokenHash string) (uuid.UUID, error) {
+	var sessionID uuid.UUID
+	err := r.pool.QueryRow(ctx,
+		"SELECT session_id FROM refresh_tokens WHERE token_hash = $1", tokenHash,
+	).Scan(&sessionID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return uuid.Nil, entity.ErrNotFound
+		}
+		return uuid.Nil, fmt.Errorf("get refresh token: %w", err)
+	}
+	return sessionID, nil
+}
+
+func (r *SessionRepo) Revoke(ctx context.Context, id uuid.UUID, reason string) error {
+	result, err := r.pool.Exec(ctx,
+		"UPDATE sessions SET revoked_at = NOW(), revoked_reason = $1 WHERE id = $2 AND revoked_at IS NULL",
+		reason, id,
+	)
+	if err != nil {
+		return fmt.Errorf("revoke session: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return entity.ErrNotFound
+	}
+	return nil
+}
+
+func (r *SessionRepo) RevokeAllForUser(ctx context.Context, userID int64, reason string) (int64, error) {
+	result, err := r.pool.Exec(ctx,
+		"UPDATE sessions SET revoked_at = NOW(), revoked_reason = $1 WHERE user_id = $2 AND revoked_at IS NULL",
+		reason, userID,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("revoke user sessions: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
+
+func insertRefreshToken(ctx context.Context, tx pgx.Tx, token *entity.RefreshToken) error {
+	err := tx.QueryRow(ctx, `
+		INSERT INTO refresh_tokens (token_hash, session_id, expires_at)
+		VALUES ($1, $2, $3)
+		RETURNING created_at
+	`, token.TokenHash, token.SessionID, token.ExpiresAt).Scan(&token.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("insert refresh token: %w", err)
+	}
+	return nil
+}
+
+func scanSession(row pgx.Row) (*entity.Session, error) {
+	session := &entity.Session{}
+	err := row.Scan(
+		&session.ID, &session.UserID, &session.UserAgent, &session.IP,
+		&session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt,
+		&session.RevokedAt, &session.RevokedReason, &session.ImpersonatorID,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, entity.ErrNotFound
+		}
+		return nil, fmt.Errorf("scan session: %w", err)
+	}
+	return session, nil
+}
@@ -0,0 +1,164 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"watermap/internal/domain/entity"
+	"watermap/internal/domain/repository"
+)
+
+type SourceRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewSourceRepo(pool *pgxpool.Pool) repository.SourceRepository {
+	return &SourceRepo{pool: pool}
+}
+
+const sourceSelect = `
+	SELECT id, title, authors, publisher, year, url, doi, licence, created_by, created_at
+	FROM sources
+`
+
+func (r *SourceRepo) Search(ctx context.Context, query string, limit, offset int) ([]*entity.Source, error) {
+	rows, err := r.pool.Query(ctx, sourceSelect+`
+		WHERE $1 = '' OR title ILIKE '%' || $1 || '%' OR authors ILIKE '%' || $1 || '%' OR doi = $1
+		ORDER BY year DESC, title
+		LIMIT $2 OFFSET $3
+	`, query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("query sources: %w", err)
+	}
+	defer rows.Close()
+
+	var sources []*entity.Source
+	for rows.Next() {
+		source, err := scanSource(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan source: %w", err)
+		}
+		sources = append(sources, source)
+	}
+	return sources, rows.Err()
+}
+
+func (r *SourceRepo) GetByID(ctx context.Context, id int64) (*entity.Source, error) {
+	source, err := scanSource(r.pool.QueryRow(ctx, sourceSelect+` WHERE id = $1`, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, entity.ErrNotFound
+		}
+		return nil, fmt.Errorf("get source: %w", err)
+	}
+	return source, nil
+}
+
+func scanSource(row pgx.Row) (*entity.Source, error) {
+	s := &entity.Source{}
+	err := row.Scan(&s.ID, &s.Title, &s.Authors, &s.Publisher, &s.Year, &s.URL, &s.DOI, &s.Licence, &s.CreatedBy, &s.CreatedAt)
+	return s, err
+}
+
+func (r *SourceRepo) Create(ctx context.Context, source *entity.Source) (*entity.Source, error) {
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO sources (title, authors, publisher, year, url, doi, licence, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at
+	`, source.Title, source.Authors, source.Publisher, source.Year, source.URL, source.DOI, source.Licence, source.CreatedBy,
+	).Scan(&source.ID, &source.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("create source: %w", err)
+	}
+	return source, nil
+}
+
+func (r *SourceRepo) Update(ctx context.Context, source *entity.Source) error {
+	result, err := r.pool.Exec(ctx, `
+		UPDATE sources SET title = $1, authors = $2, publisher = $3, year = $4, url = $5, doi = $6, licence = $7
+		WHERE id = $8
+	`, source.Title, source.Authors, source.Publisher, source.Year, source.URL, source.DOI, source.Licence, source.ID)
+	if err != nil {
+		return fmt.Errorf("update source: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return entity.ErrNotFound
+	}
+	return nil
+}
+
+const citationSelect = `
+	SELECT os.id, os.object_id, os.source_id, os.field, os.locator, os.created_by, os.created_at,
+		s.title, s.authors, s.year, s.url, s.licence
+	FROM object_sources os
+	JOIN sources s ON s.id = os.source_id
+`
+
+func (r *SourceRepo) Citations(ctx context.Context, objectIDs ...int64) ([]*entity.Citation, error) {
+	rows, err := r.pool.Query(ctx, citationSelect+`
+		WHERE os.object_id = ANY($1)
+		ORDER BY os.object_id, os.field NULLS FIRST, s.year DESC, os.id
+	`, objectIDs)
+	if err != nil {
+		return nil, fmt.Errorf("query citations: %w", err)
+	}
+	defer rows.Close()
+
+	var citations []*entity.Citation
+	for rows.Next() {
+		citation, err := scanCitation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan citation: %w", err)
+		}
+		citations = append(citations, citation)
+	}
+	return citations, rows.Err()
+}
+
+func scanCitation(row pgx.Row) (*entity.Citation, error) {
+	c := &entity.Citation{}
+	err := row.Scan(
+		&c.ID, &c.ObjectID, &c.SourceID, &c.Field, &c.Locator, &c.CreatedBy, &c.CreatedAt,
+		&c.Title, &c.Authors, &c.Year, &c.URL, &c.Licence,
+	)
+	return c, err
+}
+
+func (r *SourceRepo) Cite(ctx context.Context, citation *entity.Citation) (*entity.Citation, error) {
+	var id int64
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO object_sources (object_id, source_id, field, locator, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`, citation.ObjectID, citation.SourceID, citation.Field, citation.Locator, citation.CreatedBy,
+	).Scan(&id)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err):
+			return nil, entity.ErrDuplicateCitation
+		case isForeignKeyViolation(err):
+			return nil, entity.ErrNotFound
+		}
+		return nil, fmt.Errorf("cite source: %w", err)
+	}
+
+	created, err := scanCitation(r.pool.QueryRow(ctx, citationSelect+` WHERE os.id = $1`, id))
+	if err != nil {
+		return nil, fmt.Errorf("get citation: %w", err)
+	}
+	return created, nil
+}
+
+func (r *SourceRepo) Uncite(ctx context.Context, objectID, citationID int64) error {
+	result, err := r.pool.Exec(ctx, "DELETE FROM object_sources WHERE id = $1 AND object_id = $2", citationID, objectID)
+	if err != nil {
+		return fmt.Errorf("remove citation: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return entity.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"watermap/internal/domain/entity"
+	"watermap/internal/domain/repository"
+)
+
+type StatsRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewStatsRepo(pool *pgxpool.Pool) repository.StatsRepository {
+	return &StatsRepo{pool: pool}
+}
+
+// Objects submitted before submitted_at was recorded fall back to the last
+// update
+const submittedAt = "COALESCE(submitted_at, updated_at)"
+
+// Review decisions are counted from the audit log rather than from the
+// objects: approvals are cleared once a version is published, and an
+// object sent back and resubmitted keeps no trace of the earlier outcome.
+// Each approval of a quorum is logged, the one that publishes as
+// review.approved and the others as review.approval_recorded.
+var (
+	votedActions = []string{string(entity.AuditApprovalRecorded), string(entity.AuditObjectApproved)}
+	published    = string(entity.AuditObjectApproved)
+	rejected     = string(entity.AuditObjectRejected)
+	sentBack     = string(entity.AuditChangesRequested)
+)
+
+// Collect sends every aggregate in one batch, so the dashboard costs a
+// single round trip
+func (r *StatsRepo) Collect(ctx context.Context, weeks int) (*entity.Stats, error) {
+	stats := &entity.Stats{
+		ByStatus:    map[entity.ObjectStatus]int{},
+		ByType:      map[entity.ObjectType]map[entity.ObjectStatus]int{},
+		Reviewers:   []entity.DecisionStats{},
+		Experts:     []entity.DecisionStats{},
+		Submissions: []entity.SubmissionBucket{},
+		GeneratedAt: time.Now(),
+	}
+
+	batch := &pgx.Batch{}
+
+	batch.Queue(
+		"SELECT object_type, status, COUNT(*) FROM water_objects GROUP BY object_type, status",
+	).Query(func(rows pgx.Rows) error {
+		for rows.Next() {
+			var objType entity.ObjectType
+			var status entity.ObjectStatus
+			var n int
+			if err := rows.Scan(&objType, &status, &n); err != nil {
+				return err
+			}
+			if stats.ByType[objType] == nil {
+				stats.ByType[objType] = map[entity.ObjectStatus]int{}
+			}
+			stats.ByType[objType][status] = n
+			stats.ByStatus[status] += n
+		}
+		return rows.Err()
+	})
+
+	batch.Queue(`
+		SELECT COUNT(*),
+			COUNT(*) FILTER (WHERE role = 'expert'),
+			COUNT(*) FILTER (WHERE role = 'admin')
+		FROM users WHERE deleted_at IS NULL
+	`).QueryRow(func(row pgx.Row) error {
+		return row.Scan(&stats.TotalUsers, &stats.ExpertCount, &stats.AdminCount)
+	})
+
+	batch.Queue(`
+		SELECT COUNT(*),
+			COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY age), 0),
+			COALESCE(percentile_cont(0.9) WITHIN GROUP (ORDER BY age), 0),
+			COALESCE(percentile_cont(0.99) WITHIN GROUP (ORDER BY age), 0),
+			COALESCE(MAX(age), 0)
+		FROM (
+			SELECT EXTRACT(EPOCH FROM LOCALTIMESTAMP - ` + submittedAt + `)::float8 AS age
+			FROM water_objects WHERE status = 'pending'
+		) pending
+	`).QueryRow(func(row pgx.Row) error {
+		age := &stats.PendingAge
+		return row.Scan(&age.Count, &age.P50, &age.P90, &age.P99, &age.Oldest)
+	})
+
+	batch.Queue(`
+		SELECT u.id, u.name,
+			COUNT(*) FILTER (WHERE a.action = ANY($1)),
+			COUNT(*) FILTER (WHERE a.action = $2),
+			COUNT(*) FILTER (WHERE a.action = $3),
+			0
+		FROM audit_log a
+		JOIN users u ON u.id = a.actor_id
+		WHERE a.target_type = 'water_object' AND (a.action = ANY($1) OR a.action IN ($2, $3))
+		GROUP BY u.id, u.name
+		ORDER BY COUNT(*) DESC, u.id
+	`, votedActions, rejected, sentBack).Query(scanDecisions(&stats.Reviewers))
+
+	batch.Queue(`
+		SELECT u.id, u.name,
+			COUNT(a.id) FILTER (WHERE a.action = $1),
+			COUNT(a.id) FILTER (WHERE a.action = $2),
+			COUNT(a.id) FILTER (WHERE a.action = $3),
+			COUNT(DISTINCT wo.id) FILTER (WHERE wo.status = 'pending')
+		FROM water_objects wo
+		JOIN users u ON u.id = wo.created_by
+		LEFT JOIN audit_log a ON a.target_type = 'water_object' AND a.target_id = wo.id::text
+			AND a.action IN ($1, $2, $3)
+		WHERE wo.status <> 'draft' AND u.role = 'expert'
+		GROUP BY u.id, u.name
+		ORDER BY COUNT(DISTINCT wo.id) DESC, u.id
+	`, published, rejected, sentBack).Query(scanDecisions(&stats.Experts))
+
+	batch.Queue(`
+		WITH submitted AS (
+			SELECT date_trunc('week', `+submittedAt+`) AS week, COUNT(*) AS n
+			FROM water_objects
+			WHERE status <> 'draft' AND `+submittedAt+` >= date_trunc('week', LOCALTIMESTAMP) - make_interval(weeks => $1)
+			GROUP BY 1
+		), decided AS (
+			SELECT date_trunc('week', created_at::timestamp) AS week,
+				COUNT(*) FILTER (WHERE action = $2) AS approved,
+				COUNT(*) FILTER (WHERE action = $3) AS rejected,
+				COUNT(*) FILTER (WHERE action = $4) AS changes_requested
+			FROM audit_log
+			WHERE action IN ($2, $3, $4) AND created_at >= date_trunc('week', LOCALTIMESTAMP) - make_interval(weeks => $1)
+			GROUP BY 1
+		)
+		SELECT weeks.week, COALESCE(s.n, 0), COALESCE(d.approved, 0), COALESCE(d.rejected, 0),
+			COALESCE(d.changes_requested, 0)
+		FROM generate_series(
+			date_trunc('week', LOCALTIMESTAMP) - make_interval(weeks => $1),
+			date_trunc('week', LOCALTIMESTAMP),
+			interval '1 week'
+		) AS weeks(week)
+		LEFT JOIN submitted s ON s.week = weeks.week
+		LEFT JOIN decided d ON d.week = weeks.week
+		ORDER BY weeks.week
+	`, weeks-1, published, rejected, sentBack).Query(func(rows pgx.Rows) error {
+		for rows.Next() {
+			var b entity.SubmissionBucket
+			if err := rows.Scan(&b.Week, &b.Submitted, &b.Approved, &b.Rejected, &b.ChangesRequested); err != nil {
+				return err
+			}
+			stats.Submissions = append(stats.Submissions, b)
+		}
+		return rows.Err()
+	})
+
+	batch.Queue(`
+		SELECT COALESCE(SUM(length_km) FILTER (WHERE object_type = 'river'), 0),
+			COALESCE(SUM(area_km2) FILTER (WHERE object_type = 'lake'), 0)
+		FROM water_objects WHERE status = 'published'
+	`).QueryRow(func(row pgx.Row) error {
+		return row.Scan(&stats.RiverLengthKm, &stats.LakeAreaKm2)
+	})
+
+	if err := r.pool.SendBatch(ctx, batch).Close(); err != nil {
+		return nil, fmt.Errorf("collect stats: %w", err)
+	}
+
+	stats.PublishedCount = stats.ByStatus[entity.StatusPublished]
+	stats.PendingCount = stats.ByStatus[entity.StatusPending]
+	stats.DraftCount = stats.ByStatus[entity.StatusDraft]
+
+	return stats, nil
+}
+
+func scanDecisions(dst *[]entity.DecisionStats) func(pgx.Rows) error {
+	return func(rows pgx.Rows) error {
+		for rows.Next() {
+			var d entity.DecisionStats
+			if err := rows.Scan(&d.UserID, &d.Name, &d.Approved, &d.Rejected, &d.ChangesRequested, &d.Pending); err != nil {
+				return err
+			}
+			d.SetRates()
+			*dst = append(*dst, d)
+		}
+		return rows.Err()
+	}
+}
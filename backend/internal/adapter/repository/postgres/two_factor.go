@@ -0,0 +1,142 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"watermap/internal/domain/entity"
+	"watermap/internal/domain/repository"
+)
+
+type TwoFactorRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewTwoFactorRepo(pool *pgxpool.Pool) repository.TwoFactorRepository {
+	return &TwoFactorRepo{pool: pool}
+}
+
+func (r *TwoFactorRepo) Get(ctx context.Context, userID int64) (*entity.TwoFactor, error) {
+	query := `SELECT user_id, secret, confirmed_at, last_used_step, created_at FROM user_two_factor WHERE user_id = $1`
+
+	tf := &entity.TwoFactor{}
+	err := r.pool.QueryRow(ctx, query, userID).Scan(
+		&tf.UserID, &tf.Secret, &tf.ConfirmedAt, &tf.LastUsedStep, &tf.CreatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, entity.ErrNotFound
+		}
+		return nil, fmt.Errorf("get two factor: %w", err)
+	}
+	return tf, nil
+}
+
+func (r *TwoFactorRepo) SavePending(ctx context.Context, userID int64, secret string) error {
+	result, err := r.pool.Exec(ctx, `
+		INSERT INTO user_two_factor (user_id, secret)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, confirmed_at = NULL, last_used_step = 0, created_at = NOW()
+		WHERE user_two_factor.confirmed_at IS NULL
+	`, userID, secret)
+	if err != nil {
+		return fmt.Errorf("save two factor secret: %w", err)
+	}
+	// A confirmed secret is left alone by the conflict clause
+	if result.RowsAffected() == 0 {
+		return entity.ErrTwoFactorEnrolled
+	}
+	return nil
+}
+
+func (r *TwoFactorRepo) Confirm(ctx context.Context, userID int64, step int64) error {
+	result, err := r.pool.Exec(ctx,
+		"UPDATE user_two_factor SET confirmed_at = NOW(), last_used_step = $1 WHERE user_id = $2 AND confirmed_at IS NULL",
+		step, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("confirm two factor: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return entity.ErrNotFound
+	}
+	return nil
+}
+
+func (r *TwoFactorRepo) UseStep(ctx context.Context, userID int64, step int64) (bool, error) {
+	result, err := r.pool.Exec(ctx,
+		"UPDATE user_two_factor SET last_used_step = $1 WHERE user_id = $2 AND last_used_step < $1",
+		step, userID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("use two factor step: %w", err)
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+func (r *TwoFactorRepo) Delete(ctx context.Context, userID int64) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "DELETE FROM user_recovery_codes WHERE user_id = $1", userID); err != nil {
+		return fmt.Errorf("delete recovery codes: %w", err)
+	}
+	result, err := tx.Exec(ctx, "DELETE FROM user_two_factor WHERE user_id = $1", userID)
+	if err != nil {
+		return fmt.Errorf("delete two factor: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return entity.ErrNotFound
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *TwoFactorRepo) ReplaceRecoveryCodes(ctx context.Context, userID int64, codeHashes []string) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "DELETE FROM user_recovery_codes WHERE user_id = $1", userID); err != nil {
+		return fmt.Errorf("delete recovery codes: %w", err)
+	}
+	for _, hash := range codeHashes {
+		if _, err := tx.Exec(ctx,
+			"INSERT INTO user_recovery_codes (user_id, code_hash) VALUES ($1, $2)",
+			userID, hash,
+		); err != nil {
+			return fmt.Errorf("insert recovery code: %w", err)
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *TwoFactorRepo) UseRecoveryCode(ctx context.Context, userID int64, codeHash string) (bool, error) {
+	result, err := r.pool.Exec(ctx,
+		"UPDATE user_recovery_codes SET used_at = NOW() WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL",
+		userID, codeHash,
+	)
+	if err != nil {
+		return false, fmt.Errorf("use recovery code: %w", err)
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+func (r *TwoFactorRepo) CountRecoveryCodes(ctx context.Context, userID int64) (int, error) {
+	var count int
+	err := r.pool.QueryRow(ctx,
+		"SELECT COUNT(*) FROM user_recovery_codes WHERE user_id = $1 AND used_at IS NULL", userID,
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("count recovery codes: %w", err)
+	}
+	return count, nil
+}
@@ -3,6 +3,8 @@ package postgres
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -19,13 +21,21 @@ func NewUserRepo(pool *pgxpool.Pool) repository.UserRepository {
 	return &UserRepo{pool: pool}
 }
 
+const userColumns = `id, name, email, password, role, email_verified_at, deactivated_at, deleted_at, failed_login_count, locked_until, created_at`
+
+func scanUser(row pgx.Row, user *entity.User) error {
+	return row.Scan(
+		&user.ID, &user.Name, &user.Email, &user.Password, &user.Role,
+		&user.EmailVerifiedAt, &user.DeactivatedAt, &user.DeletedAt,
+		&user.FailedLogins, &user.LockedUntil, &user.CreatedAt,
+	)
+}
+
 func (r *UserRepo) GetByID(ctx context.Context, id int64) (*entity.User, error) {
-	query := `SELECT id, name, email, password, role, created_at FROM users WHERE id = $1`
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1`
 
 	user := &entity.User{}
-	err := r.pool.QueryRow(ctx, query, id).Scan(
-		&user.ID, &user.Name, &user.Email, &user.Password, &user.Role, &user.CreatedAt,
-	)
+	err := scanUser(r.pool.QueryRow(ctx, query, id), user)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, entity.ErrNotFound
@@ -36,12 +46,10 @@ func (r *UserRepo) GetByID(ctx context.Context, id int64) (*entity.User, error)
 }
 
 func (r *UserRepo) GetByEmail(ctx context.Context, email string) (*entity.User, error) {
-	query := `SELECT id, name, email, password, role, created_at FROM users WHERE email = $1`
+	query := `SELECT ` + userColumns + ` FROM users WHERE email = $1`
 
 	user := &entity.User{}
-	err := r.pool.QueryRow(ctx, query, email).Scan(
-		&user.ID, &user.Name, &user.Email, &user.Password, &user.Role, &user.CreatedAt,
-	)
+	err := scanUser(r.pool.QueryRow(ctx, query, email), user)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, entity.ErrNotFound
@@ -53,12 +61,12 @@ func (r *UserRepo) GetByEmail(ctx context.Context, email string) (*entity.User,
 
 func (r *UserRepo) Create(ctx context.Context, user *entity.User) (*entity.User, error) {
 	query := `
-		INSERT INTO users (name, email, password, role)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO users (name, email, password, role, email_verified_at)
+		VALUES ($1, $2, $3, $4, $5)
 		RETURNING id, created_at
 	`
 
-	err := r.pool.QueryRow(ctx, query, user.Name, user.Email, user.Password, user.Role).Scan(
+	err := r.pool.QueryRow(ctx, query, user.Name, user.Email, user.Password, user.Role, user.EmailVerifiedAt).Scan(
 		&user.ID, &user.CreatedAt,
 	)
 	if err != nil {
@@ -78,22 +86,227 @@ func (r *UserRepo) UpdateRole(ctx context.Context, id int64, role entity.UserRol
 	return nil
 }
 
-func (r *UserRepo) GetAll(ctx context.Context) ([]*entity.User, error) {
-	query := `SELECT id, name, email, role, created_at FROM users ORDER BY id`
+func (r *UserRepo) Search(ctx context.Context, filter *repository.UserFilter) ([]*entity.User, int, error) {
+	var conds []string
+	args := []interface{}{}
 
-	rows, err := r.pool.Query(ctx, query)
+	add := func(cond string, value interface{}) {
+		args = append(args, value)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+
+	if filter.Query != "" {
+		add("(name ILIKE $%[1]d OR email ILIKE $%[1]d)", "%"+escapeLike(filter.Query)+"%")
+	}
+	if filter.Role != "" {
+		add("role = $%d", filter.Role)
+	}
+	switch filter.Status {
+	case repository.UserStatusActive:
+		conds = append(conds, "deactivated_at IS NULL AND deleted_at IS NULL")
+	case repository.UserStatusDeactivated:
+		conds = append(conds, "deactivated_at IS NOT NULL AND deleted_at IS NULL")
+	case repository.UserStatusDeleted:
+		conds = append(conds, "deleted_at IS NOT NULL")
+	case repository.UserStatusInvited:
+		conds = append(conds, "password = '' AND email_verified_at IS NULL AND deleted_at IS NULL")
+	}
+
+	where := ""
+	if len(conds) > 0 {
+		where = " WHERE " + strings.Join(conds, " AND ")
+	}
+
+	var total int
+	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM users`+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("count users: %w", err)
+	}
+
+	query := `SELECT ` + userColumns + ` FROM users` + where + ` ORDER BY id`
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if filter.Offset > 0 {
+		args = append(args, filter.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	rows, err := r.pool.Query(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("get all users: %w", err)
+		return nil, 0, fmt.Errorf("search users: %w", err)
 	}
 	defer rows.Close()
 
 	var users []*entity.User
 	for rows.Next() {
 		user := &entity.User{}
-		if err := rows.Scan(&user.ID, &user.Name, &user.Email, &user.Role, &user.CreatedAt); err != nil {
-			return nil, fmt.Errorf("scan user: %w", err)
+		if err := scanUser(rows, user); err != nil {
+			return nil, 0, fmt.Errorf("scan user: %w", err)
 		}
 		users = append(users, user)
 	}
-	return users, rows.Err()
+	return users, total, rows.Err()
+}
+
+// escapeLike escapes the LIKE wildcards in user input
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+func (r *UserRepo) UpdatePassword(ctx context.Context, id int64, passwordHash string) error {
+	result, err := r.pool.Exec(ctx, `
+		UPDATE users SET password = $1, failed_login_count = 0, locked_until = NULL
+		WHERE id = $2 AND deleted_at IS NULL
+	`, passwordHash, id)
+	if err != nil {
+		return fmt.Errorf("update password: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return entity.ErrNotFound
+	}
+	return nil
+}
+
+func (r *UserRepo) MarkEmailVerified(ctx context.Context, id int64) error {
+	_, err := r.pool.Exec(ctx,
+		"UPDATE users SET email_verified_at = COALESCE(email_verified_at, NOW()) WHERE id = $1",
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("mark email verified: %w", err)
+	}
+	return nil
+}
+
+func (r *UserRepo) Deactivate(ctx context.Context, id int64) error {
+	result, err := r.pool.Exec(ctx,
+		"UPDATE users SET deactivated_at = NOW() WHERE id = $1 AND deactivated_at IS NULL AND deleted_at IS NULL",
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("deactivate user: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return entity.ErrNotFound
+	}
+	return nil
+}
+
+func (r *UserRepo) Reactivate(ctx context.Context, id int64) error {
+	result, err := r.pool.Exec(ctx,
+		"UPDATE users SET deactivated_at = NULL WHERE id = $1 AND deactivated_at IS NOT NULL AND deleted_at IS NULL",
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("reactivate user: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return entity.ErrNotFound
+	}
+	return nil
+}
+
+func (r *UserRepo) SoftDelete(ctx context.Context, id int64) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, "UPDATE users SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL", id)
+	if err != nil {
+		return fmt.Errorf("delete user: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return entity.ErrNotFound
+	}
+
+	// Access is revoked rather than erased; the row and name stay for attribution
+	for _, stmt := range []string{
+		"UPDATE sessions SET revoked_at = NOW(), revoked_reason = '" + entity.RevokeReasonAdmin + "' WHERE user_id = $1 AND revoked_at IS NULL",
+		"UPDATE api_keys SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL",
+		"DELETE FROM user_tokens WHERE user_id = $1",
+	} {
+		if _, err := tx.Exec(ctx, stmt, id); err != nil {
+			return fmt.Errorf("delete user: %w", err)
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *UserRepo) Anonymize(ctx context.Context, id int64) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, `
+		UPDATE users
+		SET name = 'Deleted user', email = 'deleted-' || id || '@invalid', password = '',
+		    deleted_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
+	`, id)
+	if err != nil {
+		return fmt.Errorf("anonymize user: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return entity.ErrNotFound
+	}
+
+	// Sessions hold IP addresses and user agents; they go with the
+	// credentials and linked identities
+	for _, stmt := range []string{
+		"DELETE FROM sessions WHERE user_id = $1",
+		"DELETE FROM api_keys WHERE user_id = $1",
+		"DELETE FROM user_identities WHERE user_id = $1",
+		"DELETE FROM user_two_factor WHERE user_id = $1",
+		"DELETE FROM user_recovery_codes WHERE user_id = $1",
+		"DELETE FROM user_tokens WHERE user_id = $1",
+		"DELETE FROM organization_members WHERE user_id = $1",
+		"DELETE FROM notifications WHERE user_id = $1",
+		"DELETE FROM notification_preferences WHERE user_id = $1",
+	} {
+		if _, err := tx.Exec(ctx, stmt, id); err != nil {
+			return fmt.Errorf("anonymize user: %w", err)
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *UserRepo) RecordLoginFailure(ctx context.Context, id int64) (int, error) {
+	var count int
+	err := r.pool.QueryRow(ctx,
+		"UPDATE users SET failed_login_count = failed_login_count + 1 WHERE id = $1 RETURNING failed_login_count",
+		id,
+	).Scan(&count)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, entity.ErrNotFound
+		}
+		return 0, fmt.Errorf("record login failure: %w", err)
+	}
+	return count, nil
+}
+
+func (r *UserRepo) LockUntil(ctx context.Context, id int64, until time.Time) error {
+	_, err := r.pool.Exec(ctx, "UPDATE users SET locked_until = $1 WHERE id = $2", until, id)
+	if err != nil {
+		return fmt.Errorf("lock user: %w", err)
+	}
+	return nil
+}
+
+func (r *UserRepo) ResetLoginFailures(ctx context.Context, id int64) error {
+	_, err := r.pool.Exec(ctx,
+		"UPDATE users SET failed_login_count = 0, locked_until = NULL WHERE id = $1 AND (failed_login_count > 0 OR locked_until IS NOT NULL)",
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("reset login failures: %w", err)
+	}
+	return nil
 }
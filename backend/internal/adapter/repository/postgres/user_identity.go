@@ -0,0 +1,52 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"watermap/internal/domain/entity"
+	"watermap/internal/domain/repository"
+)
+
+type UserIdentityRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewUserIdentityRepo(pool *pgxpool.Pool) repository.UserIdentityRepository {
+	return &UserIdentityRepo{pool: pool}
+}
+
+func (r *UserIdentityRepo) GetByIssuerSubject(ctx context.Context, issuer, subject string) (*entity.UserIdentity, error) {
+	query := `SELECT id, user_id, issuer, subject, email, created_at FROM user_identities WHERE issuer = $1 AND subject = $2`
+
+	identity := &entity.UserIdentity{}
+	err := r.pool.QueryRow(ctx, query, issuer, subject).Scan(
+		&identity.ID, &identity.UserID, &identity.Issuer, &identity.Subject, &identity.Email, &identity.CreatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, entity.ErrNotFound
+		}
+		return nil, fmt.Errorf("get identity: %w", err)
+	}
+	return identity, nil
+}
+
+func (r *UserIdentityRepo) Create(ctx context.Context, identity *entity.UserIdentity) (*entity.UserIdentity, error) {
+	query := `
+		INSERT INTO user_identities (user_id, issuer, subject, email)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+
+	err := r.pool.QueryRow(ctx, query, identity.UserID, identity.Issuer, identity.Subject, identity.Email).Scan(
+		&identity.ID, &identity.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create identity: %w", err)
+	}
+	return identity, nil
+}
@@ -0,0 +1,65 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"watermap/internal/domain/entity"
+	"watermap/internal/domain/repository"
+)
+
+type UserTokenRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewUserTokenRepo(pool *pgxpool.Pool) repository.UserTokenRepository {
+	return &UserTokenRepo{pool: pool}
+}
+
+func (r *UserTokenRepo) Create(ctx context.Context, token *entity.UserToken) error {
+	query := `
+		INSERT INTO user_tokens (user_id, purpose, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+
+	err := r.pool.QueryRow(ctx, query, token.UserID, token.Purpose, token.TokenHash, token.ExpiresAt).Scan(
+		&token.ID, &token.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("create user token: %w", err)
+	}
+	return nil
+}
+
+func (r *UserTokenRepo) Consume(ctx context.Context, purpose entity.UserTokenPurpose, tokenHash string) (*entity.UserToken, error) {
+	query := `
+		UPDATE user_tokens SET used_at = NOW()
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING id, user_id, purpose, token_hash, expires_at, used_at, created_at
+	`
+
+	token := &entity.UserToken{}
+	err := r.pool.QueryRow(ctx, query, tokenHash, purpose).Scan(
+		&token.ID, &token.UserID, &token.Purpose, &token.TokenHash,
+		&token.ExpiresAt, &token.UsedAt, &token.CreatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, entity.ErrInvalidToken
+		}
+		return nil, fmt.Errorf("consume user token: %w", err)
+	}
+	return token, nil
+}
+
+func (r *UserTokenRepo) DeleteForUser(ctx context.Context, userID int64, purpose entity.UserTokenPurpose) error {
+	_, err := r.pool.Exec(ctx, "DELETE FROM user_tokens WHERE user_id = $1 AND purpose = $2", userID, purpose)
+	if err != nil {
+		return fmt.Errorf("delete user tokens: %w", err)
+	}
+	return nil
+}
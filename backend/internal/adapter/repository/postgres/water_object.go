@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 
@@ -21,15 +22,50 @@ func NewWaterObjectRepo(pool *pgxpool.Pool) repository.WaterObjectRepository {
 }
 
 func (r *WaterObjectRepo) GetPublished(ctx context.Context, filter *repository.WaterObjectFilter) ([]*entity.WaterObject, error) {
+	query, args := r.publishedQuery(filter)
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query published objects: %w", err)
+	}
+	defer rows.Close()
+
+	return r.scanWaterObjects(rows)
+}
+
+// StreamPublished calls fn for each published object as rows arrive, without
+// buffering the whole result set. Iteration stops at the first error from fn.
+func (r *WaterObjectRepo) StreamPublished(ctx context.Context, filter *repository.WaterObjectFilter, fn func(*entity.WaterObject) error) error {
+	query, args := r.publishedQuery(filter)
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("query published objects: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		obj, err := r.scanWaterObjectRow(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(obj); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (r *WaterObjectRepo) publishedQuery(filter *repository.WaterObjectFilter) (string, []interface{}) {
 	query := `
 		SELECT 
 			id, canonical_id, version, name_kz, name_ru, name_en,
-			object_type, geometry,
+			object_type, region, geometry,
 			length_km, area_km2, max_depth_m, avg_depth_m,
 			water_volume_km3, basin_area_km2, avg_discharge_m3s,
 			salinity_level, pollution_index, ecological_status,
 			description_kz, description_ru, description_en,
-			status, created_by, created_at, published_at
+			status, created_by, organization_id, created_at, published_at
 		FROM water_objects
 		WHERE status = 'published'
 	`
@@ -51,25 +87,24 @@ func (r *WaterObjectRepo) GetPublished(ctx context.Context, filter *repository.W
 		argIdx++
 	}
 
-	rows, err := r.pool.Query(ctx, query, args...)
-	if err != nil {
-		return nil, fmt.Errorf("query published objects: %w", err)
+	if filter != nil && filter.Offset > 0 {
+		query += fmt.Sprintf(" OFFSET $%d", argIdx)
+		args = append(args, filter.Offset)
 	}
-	defer rows.Close()
 
-	return r.scanWaterObjects(rows)
+	return query, args
 }
 
 func (r *WaterObjectRepo) GetByCanonicalID(ctx context.Context, canonicalID string, status entity.ObjectStatus) (*entity.WaterObject, error) {
 	query := `
 		SELECT 
 			id, canonical_id, version, name_kz, name_ru, name_en,
-			object_type, geometry,
+			object_type, region, geometry, source_crs,
 			length_km, area_km2, max_depth_m, avg_depth_m,
 			water_volume_km3, basin_area_km2, avg_discharge_m3s,
 			salinity_level, pollution_index, ecological_status,
-			description_kz, description_ru, description_en,
-			status, rejection_reason, created_by, updated_by, reviewed_by,
+			description_kz, description_ru, description_en, historical_notes,
+			status, rejection_reason, created_by, updated_by, reviewed_by, organization_id,
 			created_at, updated_at, published_at
 		FROM water_objects
 		WHERE canonical_id = $1 AND status = $2
@@ -83,12 +118,12 @@ func (r *WaterObjectRepo) GetByID(ctx context.Context, id int64) (*entity.WaterO
 	query := `
 		SELECT 
 			id, canonical_id, version, name_kz, name_ru, name_en,
-			object_type, geometry,
+			object_type, region, geometry, source_crs,
 			length_km, area_km2, max_depth_m, avg_depth_m,
 			water_volume_km3, basin_area_km2, avg_discharge_m3s,
 			salinity_level, pollution_index, ecological_status,
-			description_kz, description_ru, description_en,
-			status, rejection_reason, created_by, updated_by, reviewed_by,
+			description_kz, description_ru, description_en, historical_notes,
+			status, rejection_reason, created_by, updated_by, reviewed_by, organization_id,
 			created_at, updated_at, published_at
 		FROM water_objects
 		WHERE id = $1
@@ -102,12 +137,12 @@ func (r *WaterObjectRepo) GetVersionHistory(ctx context.Context, canonicalID str
 	query := `
 		SELECT 
 			id, canonical_id, version, name_kz, name_ru, name_en,
-			object_type, geometry,
+			object_type, region, geometry,
 			length_km, area_km2, max_depth_m, avg_depth_m,
 			water_volume_km3, basin_area_km2, avg_discharge_m3s,
 			salinity_level, pollution_index, ecological_status,
 			description_kz, description_ru, description_en,
-			status, created_by, created_at, published_at
+			status, created_by, organization_id, created_at, published_at
 		FROM water_objects
 		WHERE canonical_id = $1
 		ORDER BY version DESC
@@ -126,14 +161,15 @@ func (r *WaterObjectRepo) GetDraftsByUser(ctx context.Context, userID int64) ([]
 	query := `
 		SELECT 
 			id, canonical_id, version, name_kz, name_ru, name_en,
-			object_type, geometry,
+			object_type, region, geometry, source_crs,
 			length_km, area_km2, max_depth_m, avg_depth_m,
 			water_volume_km3, basin_area_km2, avg_discharge_m3s,
 			salinity_level, pollution_index, ecological_status,
-			description_kz, description_ru, description_en,
-			status, rejection_reason, created_by, created_at, updated_at
+			description_kz, description_ru, description_en, historical_notes,
+			status, rejection_reason, created_by, updated_by, reviewed_by, organization_id,
+			created_at, updated_at, published_at
 		FROM water_objects
-		WHERE created_by = $1 AND status IN ('draft', 'pending', 'rejected')
+		WHERE status IN ('draft', 'pending', 'changes_requested', 'rejected') AND ` + editableBy(1) + `
 		ORDER BY updated_at DESC
 	`
 
@@ -143,7 +179,41 @@ func (r *WaterObjectRepo) GetDraftsByUser(ctx context.Context, userID int64) ([]
 	}
 	defer rows.Close()
 
-	return r.scanWaterObjects(rows)
+	return r.scanFullWaterObjects(rows)
+}
+
+func (r *WaterObjectRepo) GetSubmissionsByOrganization(ctx context.Context, orgID int64) ([]*entity.WaterObject, error) {
+	query := `
+		SELECT 
+			id, canonical_id, version, name_kz, name_ru, name_en,
+			object_type, region, geometry, source_crs,
+			length_km, area_km2, max_depth_m, avg_depth_m,
+			water_volume_km3, basin_area_km2, avg_discharge_m3s,
+			salinity_level, pollution_index, ecological_status,
+			description_kz, description_ru, description_en, historical_notes,
+			status, rejection_reason, created_by, updated_by, reviewed_by, organization_id,
+			created_at, updated_at, published_at
+		FROM water_objects
+		WHERE organization_id = $1 AND status IN ('pending', 'changes_requested', 'rejected')
+		ORDER BY status, updated_at DESC
+	`
+
+	rows, err := r.pool.Query(ctx, query, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("query organization submissions: %w", err)
+	}
+	defer rows.Close()
+
+	return r.scanFullWaterObjects(rows)
+}
+
+// editableBy returns a condition matching objects the user created or that
+// belong to one of their organizations; arg is the user id placeholder index
+func editableBy(arg int) string {
+	return fmt.Sprintf(
+		"(created_by = $%[1]d OR organization_id IN (SELECT organization_id FROM organization_members WHERE user_id = $%[1]d))",
+		arg,
+	)
 }
 
 func (r *WaterObjectRepo) Create(ctx context.Context, obj *entity.WaterObject) (*entity.WaterObject, error) {
@@ -154,32 +224,32 @@ func (r *WaterObjectRepo) Create(ctx context.Context, obj *entity.WaterObject) (
 
 	query := `
 		INSERT INTO water_objects (
-			name_kz, name_ru, name_en, object_type,
-			geometry,
+			name_kz, name_ru, name_en, object_type, region,
+			geometry, source_crs,
 			length_km, area_km2, max_depth_m, avg_depth_m,
 			water_volume_km3, basin_area_km2, avg_discharge_m3s,
 			salinity_level, pollution_index, ecological_status,
-			description_kz, description_ru, description_en,
-			status, created_by
+			description_kz, description_ru, description_en, historical_notes,
+			status, created_by, organization_id
 		) VALUES (
-			$1, $2, $3, $4,
-			$5::jsonb,
-			$6, $7, $8, $9, $10, $11, $12,
-			$13, $14, $15,
-			$16, $17, $18,
-			'draft', $19
+			$1, $2, $3, $4, $5,
+			$6::jsonb, $7,
+			$8, $9, $10, $11, $12, $13, $14,
+			$15, $16, $17,
+			$18, $19, $20, $21,
+			'draft', $22, $23
 		)
 		RETURNING id, canonical_id, version, created_at, updated_at
 	`
 
 	row := r.pool.QueryRow(ctx, query,
-		obj.NameKZ, obj.NameRU, obj.NameEN, obj.ObjectType,
-		string(geometryJSON),
+		obj.NameKZ, obj.NameRU, obj.NameEN, obj.ObjectType, obj.Region,
+		string(geometryJSON), obj.SourceCRS,
 		obj.LengthKm, obj.AreaKm2, obj.MaxDepthM, obj.AvgDepthM,
 		obj.WaterVolumeKm3, obj.BasinAreaKm2, obj.AvgDischargeM3s,
 		obj.SalinityLevel, obj.PollutionIndex, obj.EcologicalStatus,
-		obj.DescriptionKZ, obj.DescriptionRU, obj.DescriptionEN,
-		obj.CreatedBy,
+		obj.DescriptionKZ, obj.DescriptionRU, obj.DescriptionEN, obj.HistoricalNotes,
+		obj.CreatedBy, obj.OrganizationID,
 	)
 
 	if err := row.Scan(&obj.ID, &obj.CanonicalID, &obj.Version, &obj.CreatedAt, &obj.UpdatedAt); err != nil {
@@ -198,28 +268,28 @@ func (r *WaterObjectRepo) Update(ctx context.Context, obj *entity.WaterObject) (
 
 	query := `
 		UPDATE water_objects SET
-			name_kz = $1, name_ru = $2, name_en = $3,
-			geometry = $4::jsonb,
-			length_km = $5, area_km2 = $6, max_depth_m = $7, avg_depth_m = $8,
-			water_volume_km3 = $9, basin_area_km2 = $10, avg_discharge_m3s = $11,
-			salinity_level = $12, pollution_index = $13, ecological_status = $14,
-			description_kz = $15, description_ru = $16, description_en = $17,
-			updated_by = $18, updated_at = NOW()
-		WHERE id = $19 AND status IN ('draft', 'rejected')
-		RETURNING version, updated_at
+			name_kz = $1, name_ru = $2, name_en = $3, region = $4,
+			geometry = $5::jsonb, source_crs = $6,
+			length_km = $7, area_km2 = $8, max_depth_m = $9, avg_depth_m = $10,
+			water_volume_km3 = $11, basin_area_km2 = $12, avg_discharge_m3s = $13,
+			salinity_level = $14, pollution_index = $15, ecological_status = $16,
+			description_kz = $17, description_ru = $18, description_en = $19,
+			historical_notes = $20, updated_by = $21, updated_at = NOW()
+		WHERE id = $22 AND status = ANY($23) AND ` + editableBy(21) + `
+		RETURNING version, organization_id, updated_at
 	`
 
 	row := r.pool.QueryRow(ctx, query,
-		obj.NameKZ, obj.NameRU, obj.NameEN,
-		string(geometryJSON),
+		obj.NameKZ, obj.NameRU, obj.NameEN, obj.Region,
+		string(geometryJSON), obj.SourceCRS,
 		obj.LengthKm, obj.AreaKm2, obj.MaxDepthM, obj.AvgDepthM,
 		obj.WaterVolumeKm3, obj.BasinAreaKm2, obj.AvgDischargeM3s,
 		obj.SalinityLevel, obj.PollutionIndex, obj.EcologicalStatus,
-		obj.DescriptionKZ, obj.DescriptionRU, obj.DescriptionEN,
-		obj.UpdatedBy, obj.ID,
+		obj.DescriptionKZ, obj.DescriptionRU, obj.DescriptionEN, obj.HistoricalNotes,
+		obj.UpdatedBy, obj.ID, statusList(entity.EditableStatuses),
 	)
 
-	if err := row.Scan(&obj.Version, &obj.UpdatedAt); err != nil {
+	if err := row.Scan(&obj.Version, &obj.OrganizationID, &obj.UpdatedAt); err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, entity.ErrNotFound
 		}
@@ -231,8 +301,8 @@ func (r *WaterObjectRepo) Update(ctx context.Context, obj *entity.WaterObject) (
 
 func (r *WaterObjectRepo) Delete(ctx context.Context, id int64, userID int64) error {
 	result, err := r.pool.Exec(ctx,
-		"DELETE FROM water_objects WHERE id = $1 AND created_by = $2 AND status = 'draft'",
-		id, userID,
+		"DELETE FROM water_objects WHERE id = $1 AND status = ANY($3) AND "+editableBy(2),
+		id, userID, statusList(entity.DeletableStatuses),
 	)
 	if err != nil {
 		return fmt.Errorf("delete water object: %w", err)
@@ -245,8 +315,8 @@ func (r *WaterObjectRepo) Delete(ctx context.Context, id int64, userID int64) er
 
 func (r *WaterObjectRepo) SubmitForReview(ctx context.Context, id int64, userID int64) error {
 	result, err := r.pool.Exec(ctx,
-		"UPDATE water_objects SET status = 'pending', updated_at = NOW() WHERE id = $1 AND created_by = $2 AND status IN ('draft', 'rejected')",
-		id, userID,
+		"UPDATE water_objects SET status = $3, submitted_at = NOW(), updated_at = NOW() WHERE id = $1 AND status = ANY($4) AND "+editableBy(2),
+		id, userID, entity.TransitionSubmit.Target(), statusList(entity.TransitionSubmit.Sources()),
 	)
 	if err != nil {
 		return fmt.Errorf("submit for review: %w", err)
@@ -261,12 +331,12 @@ func (r *WaterObjectRepo) GetPending(ctx context.Context) ([]*entity.WaterObject
 	query := `
 		SELECT 
 			wo.id, wo.canonical_id, wo.version, wo.name_kz, wo.name_ru, wo.name_en,
-			wo.object_type, wo.geometry,
+			wo.object_type, wo.region, wo.geometry,
 			wo.length_km, wo.area_km2, wo.max_depth_m, wo.avg_depth_m,
 			wo.water_volume_km3, wo.basin_area_km2, wo.avg_discharge_m3s,
 			wo.salinity_level, wo.pollution_index, wo.ecological_status,
 			wo.description_kz, wo.description_ru, wo.description_en,
-			wo.status, wo.created_by, wo.created_at, wo.updated_at
+			wo.status, wo.created_by, wo.organization_id, wo.created_at, wo.updated_at
 		FROM water_objects wo
 		WHERE wo.status = 'pending'
 		ORDER BY wo.updated_at ASC
@@ -281,18 +351,27 @@ func (r *WaterObjectRepo) GetPending(ctx context.Context) ([]*entity.WaterObject
 	return r.scanWaterObjects(rows)
 }
 
-func (r *WaterObjectRepo) Approve(ctx context.Context, id int64, reviewerID int64) error {
+func (r *WaterObjectRepo) Restore(ctx context.Context, id int64, actorID int64) error {
 	tx, err := r.pool.Begin(ctx)
 	if err != nil {
 		return fmt.Errorf("begin tx: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
-	// Get pending object's canonical_id
+	if err := publishVersion(ctx, tx, id, entity.TransitionRestore, actorID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// publishVersion makes the version the published one of its object,
+// archiving the version it replaces. The review ends here, so its claim and
+// approvals go. Approvals publish through ReviewRepository.AddApproval.
+func publishVersion(ctx context.Context, tx pgx.Tx, id int64, t entity.Transition, actorID int64) error {
 	var canonicalID string
-	err = tx.QueryRow(ctx,
-		"SELECT canonical_id FROM water_objects WHERE id = $1 AND status = 'pending'",
-		id,
+	err := tx.QueryRow(ctx,
+		"SELECT canonical_id FROM water_objects WHERE id = $1 AND status = ANY($2) FOR UPDATE",
+		id, statusList(t.Sources()),
 	).Scan(&canonicalID)
 	if err != nil {
 		if err == pgx.ErrNoRows {
@@ -310,25 +389,147 @@ func (r *WaterObjectRepo) Approve(ctx context.Context, id int64, reviewerID int6
 		return fmt.Errorf("archive old version: %w", err)
 	}
 
-	// Publish new version
-	_, err = tx.Exec(ctx,
-		"UPDATE water_objects SET status = 'published', published_at = NOW(), reviewed_by = $1 WHERE id = $2",
-		reviewerID, id,
+	// Publish this version; a restore keeps the original review
+	query := "UPDATE water_objects SET status = 'published', published_at = NOW(), reviewed_by = $1, reviewed_at = NOW() WHERE id = $2"
+	if t == entity.TransitionRestore {
+		query = "UPDATE water_objects SET status = 'published', published_at = NOW(), updated_by = $1, updated_at = NOW() WHERE id = $2"
+	}
+	if _, err := tx.Exec(ctx, query, actorID, id); err != nil {
+		return fmt.Errorf("publish version: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM review_claims WHERE object_id = $1", id); err != nil {
+		return fmt.Errorf("release claim: %w", err)
+	}
+	if _, err := tx.Exec(ctx, "DELETE FROM review_approvals WHERE object_id = $1", id); err != nil {
+		return fmt.Errorf("clear approvals: %w", err)
+	}
+	return nil
+}
+
+func (r *WaterObjectRepo) Reject(ctx context.Context, id int64, reviewerID int64, reason string) error {
+	return r.sendBack(ctx, id, reviewerID, entity.TransitionReject, reason)
+}
+
+func (r *WaterObjectRepo) RequestChanges(ctx context.Context, id int64, reviewerID int64, reason string) error {
+	return r.sendBack(ctx, id, reviewerID, entity.TransitionRequestChanges, reason)
+}
+
+// sendBack ends the review of a pending object without publishing it. The
+// approvals are dropped so that a resubmission is reviewed afresh.
+func (r *WaterObjectRepo) sendBack(ctx context.Context, id int64, reviewerID int64, t entity.Transition, reason string) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx,
+		"UPDATE water_objects SET status = $1, rejection_reason = $2, reviewed_by = $3, reviewed_at = NOW(), updated_at = NOW() WHERE id = $4 AND status = ANY($5)",
+		t.Target(), reason, reviewerID, id, statusList(t.Sources()),
 	)
 	if err != nil {
-		return fmt.Errorf("publish version: %w", err)
+		return fmt.Errorf("%s water object: %w", t, err)
+	}
+	if result.RowsAffected() == 0 {
+		return entity.ErrNotFound
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM review_claims WHERE object_id = $1", id); err != nil {
+		return fmt.Errorf("release claim: %w", err)
+	}
+	if _, err := tx.Exec(ctx, "DELETE FROM review_approvals WHERE object_id = $1", id); err != nil {
+		return fmt.Errorf("clear approvals: %w", err)
 	}
 
 	return tx.Commit(ctx)
 }
 
-func (r *WaterObjectRepo) Reject(ctx context.Context, id int64, reviewerID int64, reason string) error {
+func (r *WaterObjectRepo) Unpublish(ctx context.Context, id int64, actorID int64) (int64, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx,
+		"UPDATE water_objects SET status = 'archived', updated_by = $2, updated_at = NOW() WHERE id = $1 AND status = ANY($3)",
+		id, actorID, statusList(entity.TransitionUnpublish.Sources()),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("archive published version: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return 0, entity.ErrNotFound
+	}
+
+	var draftID int64
+	err = tx.QueryRow(ctx, `
+		INSERT INTO water_objects (
+			canonical_id, version,
+			name_kz, name_ru, name_en, object_type, region,
+			geometry, source_crs,
+			length_km, area_km2, max_depth_m, avg_depth_m,
+			water_volume_km3, basin_area_km2, avg_discharge_m3s,
+			salinity_level, pollution_index, ecological_status,
+			description_kz, description_ru, description_en, historical_notes,
+			status, created_by, organization_id, updated_by
+		)
+		SELECT
+			canonical_id, (SELECT MAX(version) + 1 FROM water_objects v WHERE v.canonical_id = wo.canonical_id),
+			name_kz, name_ru, name_en, object_type, region,
+			geometry, source_crs,
+			length_km, area_km2, max_depth_m, avg_depth_m,
+			water_volume_km3, basin_area_km2, avg_discharge_m3s,
+			salinity_level, pollution_index, ecological_status,
+			description_kz, description_ru, description_en, historical_notes,
+			'draft', created_by, organization_id, updated_by
+		FROM water_objects wo
+		WHERE id = $1
+		RETURNING id
+	`, id).Scan(&draftID)
+	if err != nil {
+		return 0, fmt.Errorf("create draft version: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO object_sources (object_id, source_id, field, locator, created_by, created_at)
+		SELECT $2, source_id, field, locator, created_by, created_at FROM object_sources WHERE object_id = $1
+	`, id, draftID)
+	if err != nil {
+		return 0, fmt.Errorf("copy citations: %w", err)
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO attachments (
+			object_id, filename, content_type, kind, size, sha256, has_thumbnail,
+			caption, latitude, longitude, uploaded_by, created_at
+		)
+		SELECT $2, filename, content_type, kind, size, sha256, has_thumbnail,
+			caption, latitude, longitude, uploaded_by, created_at
+		FROM attachments WHERE object_id = $1
+	`, id, draftID)
+	if err != nil {
+		return 0, fmt.Errorf("copy attachments: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return draftID, nil
+}
+
+func (r *WaterObjectRepo) Archive(ctx context.Context, id int64, actorID int64) error {
+	return r.withdraw(ctx, id, entity.TransitionArchive, actorID)
+}
+
+// withdraw takes a published version off the map
+func (r *WaterObjectRepo) withdraw(ctx context.Context, id int64, t entity.Transition, actorID int64) error {
 	result, err := r.pool.Exec(ctx,
-		"UPDATE water_objects SET status = 'rejected', rejection_reason = $1, reviewed_by = $2, updated_at = NOW() WHERE id = $3 AND status = 'pending'",
-		reason, reviewerID, id,
+		"UPDATE water_objects SET status = $1, updated_by = $2, updated_at = NOW() WHERE id = $3 AND status = ANY($4)",
+		t.Target(), actorID, id, statusList(t.Sources()),
 	)
 	if err != nil {
-		return fmt.Errorf("reject water object: %w", err)
+		return fmt.Errorf("%s water object: %w", t, err)
 	}
 	if result.RowsAffected() == 0 {
 		return entity.ErrNotFound
@@ -336,36 +537,201 @@ func (r *WaterObjectRepo) Reject(ctx context.Context, id int64, reviewerID int64
 	return nil
 }
 
-func (r *WaterObjectRepo) scanWaterObjects(rows pgx.Rows) ([]*entity.WaterObject, error) {
-	var objects []*entity.WaterObject
+// GetDuplicateCandidates compares bounds with geometry_extent, which the
+// schema indexes for published and pending objects
+func (r *WaterObjectRepo) GetDuplicateCandidates(ctx context.Context, types []entity.ObjectType, within entity.Extent) ([]*entity.WaterObject, error) {
+	query := `
+		SELECT 
+			id, canonical_id, version, name_kz, name_ru, name_en,
+			object_type, region, geometry,
+			length_km, area_km2, max_depth_m, avg_depth_m,
+			water_volume_km3, basin_area_km2, avg_discharge_m3s,
+			salinity_level, pollution_index, ecological_status,
+			description_kz, description_ru, description_en,
+			status, created_by, organization_id, created_at, updated_at
+		FROM water_objects
+		WHERE status IN ('published', 'pending') AND object_type = ANY($1)
+			AND geometry_extent(geometry) && box(point($2, $3), point($4, $5))
+	`
+
+	typeList := make([]string, len(types))
+	for i, t := range types {
+		typeList[i] = string(t)
+	}
+
+	rows, err := r.pool.Query(ctx, query, typeList, within.MinLon, within.MinLat, within.MaxLon, within.MaxLat)
+	if err != nil {
+		return nil, fmt.Errorf("query duplicate candidates: %w", err)
+	}
+	defer rows.Close()
+
+	return r.scanWaterObjects(rows)
+}
+
+func (r *WaterObjectRepo) Merge(ctx context.Context, source, target uuid.UUID, actorID int64) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock both histories; neither may gain a version while they are merged
+	rows, err := tx.Query(ctx,
+		"SELECT canonical_id, version, status FROM water_objects WHERE canonical_id = ANY($1) FOR UPDATE",
+		[]string{source.String(), target.String()},
+	)
+	if err != nil {
+		return fmt.Errorf("lock versions: %w", err)
+	}
+
+	var sourceFound, targetPublished, sourcePublished bool
+	targetVersion := 0
 	for rows.Next() {
-		obj := &entity.WaterObject{}
-		var geometryJSON []byte
-
-		// Flexible scan based on available columns
-		err := rows.Scan(
-			&obj.ID, &obj.CanonicalID, &obj.Version,
-			&obj.NameKZ, &obj.NameRU, &obj.NameEN,
-			&obj.ObjectType, &geometryJSON,
-			&obj.LengthKm, &obj.AreaKm2, &obj.MaxDepthM, &obj.AvgDepthM,
-			&obj.WaterVolumeKm3, &obj.BasinAreaKm2, &obj.AvgDischargeM3s,
-			&obj.SalinityLevel, &obj.PollutionIndex, &obj.EcologicalStatus,
-			&obj.DescriptionKZ, &obj.DescriptionRU, &obj.DescriptionEN,
-			&obj.Status, &obj.CreatedBy, &obj.CreatedAt, &obj.UpdatedAt,
+		var canonicalID string
+		var version int
+		var status entity.ObjectStatus
+		if err := rows.Scan(&canonicalID, &version, &status); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan version: %w", err)
+		}
+		if canonicalID == source.String() {
+			sourceFound = true
+			sourcePublished = sourcePublished || status == entity.StatusPublished
+			continue
+		}
+		targetVersion = max(targetVersion, version)
+		targetPublished = targetPublished || status == entity.StatusPublished
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("lock versions: %w", err)
+	}
+	if !sourceFound || targetVersion == 0 {
+		return entity.ErrNotFound
+	}
+
+	// Only one version of the merged object stays on the map
+	if sourcePublished && targetPublished {
+		_, err := tx.Exec(ctx,
+			"UPDATE water_objects SET status = 'archived', updated_by = $2, updated_at = NOW() WHERE canonical_id = $1 AND status = 'published'",
+			source.String(), actorID,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("scan row: %w", err)
+			return fmt.Errorf("archive merged version: %w", err)
 		}
+	}
+
+	_, err = tx.Exec(ctx,
+		"UPDATE water_objects SET canonical_id = $2, version = version + $3 WHERE canonical_id = $1",
+		source.String(), target.String(), targetVersion,
+	)
+	if err != nil {
+		return fmt.Errorf("move versions: %w", err)
+	}
+
+	_, err = tx.Exec(ctx,
+		"UPDATE review_comments SET canonical_id = $2, object_version = object_version + $3 WHERE canonical_id = $1",
+		source.String(), target.String(), targetVersion,
+	)
+	if err != nil {
+		return fmt.Errorf("move comments: %w", err)
+	}
 
-		if err := json.Unmarshal(geometryJSON, &obj.Geometry); err != nil {
-			return nil, fmt.Errorf("unmarshal geometry: %w", err)
+	// Objects merged into the source earlier now resolve to the target too
+	_, err = tx.Exec(ctx,
+		"UPDATE object_merges SET target_canonical_id = $2 WHERE target_canonical_id = $1",
+		source.String(), target.String(),
+	)
+	if err != nil {
+		return fmt.Errorf("redirect earlier merges: %w", err)
+	}
+	_, err = tx.Exec(ctx,
+		"INSERT INTO object_merges (source_canonical_id, target_canonical_id, merged_by) VALUES ($1, $2, $3)",
+		source.String(), target.String(), actorID,
+	)
+	if err != nil {
+		return fmt.Errorf("record merge: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *WaterObjectRepo) MergedInto(ctx context.Context, canonicalID string) (uuid.UUID, error) {
+	var target uuid.UUID
+	err := r.pool.QueryRow(ctx,
+		"SELECT target_canonical_id FROM object_merges WHERE source_canonical_id = $1",
+		canonicalID,
+	).Scan(&target)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return uuid.Nil, entity.ErrNotFound
+		}
+		return uuid.Nil, fmt.Errorf("get merge: %w", err)
+	}
+	return target, nil
+}
+
+// statusList converts statuses to a text array parameter
+func statusList(statuses []entity.ObjectStatus) []string {
+	out := make([]string, len(statuses))
+	for i, s := range statuses {
+		out[i] = string(s)
+	}
+	return out
+}
+
+func (r *WaterObjectRepo) scanWaterObjects(rows pgx.Rows) ([]*entity.WaterObject, error) {
+	var objects []*entity.WaterObject
+	for rows.Next() {
+		obj, err := r.scanWaterObjectRow(rows)
+		if err != nil {
+			return nil, err
 		}
+		objects = append(objects, obj)
+	}
+	return objects, rows.Err()
+}
 
+// scanFullWaterObjects scans rows selected with the full column list
+func (r *WaterObjectRepo) scanFullWaterObjects(rows pgx.Rows) ([]*entity.WaterObject, error) {
+	var objects []*entity.WaterObject
+	for rows.Next() {
+		obj, err := r.scanSingleWaterObject(rows)
+		if err != nil {
+			return nil, err
+		}
 		objects = append(objects, obj)
 	}
 	return objects, rows.Err()
 }
 
+// scanWaterObjectRow scans the short column list shared by the list queries
+func (r *WaterObjectRepo) scanWaterObjectRow(rows pgx.Rows) (*entity.WaterObject, error) {
+	obj := &entity.WaterObject{}
+	var geometryJSON []byte
+
+	// Flexible scan based on available columns
+	err := rows.Scan(
+		&obj.ID, &obj.CanonicalID, &obj.Version,
+		&obj.NameKZ, &obj.NameRU, &obj.NameEN,
+		&obj.ObjectType, &obj.Region, &geometryJSON,
+		&obj.LengthKm, &obj.AreaKm2, &obj.MaxDepthM, &obj.AvgDepthM,
+		&obj.WaterVolumeKm3, &obj.BasinAreaKm2, &obj.AvgDischargeM3s,
+		&obj.SalinityLevel, &obj.PollutionIndex, &obj.EcologicalStatus,
+		&obj.DescriptionKZ, &obj.DescriptionRU, &obj.DescriptionEN,
+		&obj.Status, &obj.CreatedBy, &obj.OrganizationID, &obj.CreatedAt, &obj.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("scan row: %w", err)
+	}
+
+	if err := json.Unmarshal(geometryJSON, &obj.Geometry); err != nil {
+		return nil, fmt.Errorf("unmarshal geometry: %w", err)
+	}
+
+	return obj, nil
+}
+
 func (r *WaterObjectRepo) scanSingleWaterObject(row pgx.Row) (*entity.WaterObject, error) {
 	obj := &entity.WaterObject{}
 	var geometryJSON []byte
@@ -373,12 +739,12 @@ func (r *WaterObjectRepo) scanSingleWaterObject(row pgx.Row) (*entity.WaterObjec
 	err := row.Scan(
 		&obj.ID, &obj.CanonicalID, &obj.Version,
 		&obj.NameKZ, &obj.NameRU, &obj.NameEN,
-		&obj.ObjectType, &geometryJSON,
+		&obj.ObjectType, &obj.Region, &geometryJSON, &obj.SourceCRS,
 		&obj.LengthKm, &obj.AreaKm2, &obj.MaxDepthM, &obj.AvgDepthM,
 		&obj.WaterVolumeKm3, &obj.BasinAreaKm2, &obj.AvgDischargeM3s,
 		&obj.SalinityLevel, &obj.PollutionIndex, &obj.EcologicalStatus,
-		&obj.DescriptionKZ, &obj.DescriptionRU, &obj.DescriptionEN,
-		&obj.Status, &obj.RejectionReason, &obj.CreatedBy, &obj.UpdatedBy, &obj.ReviewedBy,
+		&obj.DescriptionKZ, &obj.DescriptionRU, &obj.DescriptionEN, &obj.HistoricalNotes,
+		&obj.Status, &obj.RejectionReason, &obj.CreatedBy, &obj.UpdatedBy, &obj.ReviewedBy, &obj.OrganizationID,
 		&obj.CreatedAt, &obj.UpdatedAt, &obj.PublishedAt,
 	)
 	if err != nil {
@@ -0,0 +1,260 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"watermap/internal/domain/entity"
+	"watermap/internal/domain/repository"
+)
+
+type WebhookRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewWebhookRepo(pool *pgxpool.Pool) repository.WebhookRepository {
+	return &WebhookRepo{pool: pool}
+}
+
+const webhookColumns = `id, name, url, events, secret, active, created_by, created_at, updated_at`
+
+const deliveryColumns = `id, webhook_id, event_id, event_type, payload, status, attempts, next_attempt_at,
+	last_status_code, last_error, created_at, delivered_at`
+
+func (r *WebhookRepo) List(ctx context.Context) ([]*entity.Webhook, error) {
+	rows, err := r.pool.Query(ctx, `SELECT `+webhookColumns+` FROM webhooks ORDER BY name, id`)
+	if err != nil {
+		return nil, fmt.Errorf("query webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	hooks := []*entity.Webhook{}
+	for rows.Next() {
+		hook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks, rows.Err()
+}
+
+func (r *WebhookRepo) GetByID(ctx context.Context, id int64) (*entity.Webhook, error) {
+	row := r.pool.QueryRow(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE id = $1`, id)
+	hook, err := scanWebhook(row)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, entity.ErrNotFound
+		}
+		return nil, err
+	}
+	return hook, nil
+}
+
+func (r *WebhookRepo) Create(ctx context.Context, hook *entity.Webhook) (*entity.Webhook, error) {
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO webhooks (name, url, events, secret, active, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at
+	`, hook.Name, hook.URL, eventsToStrings(hook.Events), hook.Secret, hook.Active, hook.CreatedBy,
+	).Scan(&hook.ID, &hook.CreatedAt, &hook.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("create webhook: %w", err)
+	}
+	return hook, nil
+}
+
+func (r *WebhookRepo) Update(ctx context.Context, hook *entity.Webhook) error {
+	err := r.pool.QueryRow(ctx, `
+		UPDATE webhooks SET name = $1, url = $2, events = $3, secret = $4, active = $5, updated_at = NOW()
+		WHERE id = $6
+		RETURNING updated_at
+	`, hook.Name, hook.URL, eventsToStrings(hook.Events), hook.Secret, hook.Active, hook.ID,
+	).Scan(&hook.UpdatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return entity.ErrNotFound
+		}
+		return fmt.Errorf("update webhook: %w", err)
+	}
+	return nil
+}
+
+func (r *WebhookRepo) Delete(ctx context.Context, id int64) error {
+	result, err := r.pool.Exec(ctx, "DELETE FROM webhooks WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("delete webhook: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return entity.ErrNotFound
+	}
+	return nil
+}
+
+func (r *WebhookRepo) ListDeliveries(ctx context.Context, webhookID int64, filter *repository.DeliveryFilter) ([]*entity.WebhookDelivery, error) {
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries WHERE webhook_id = $1`
+	args := []interface{}{webhookID}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		query += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+	args = append(args, filter.Limit, filter.Offset)
+	query += fmt.Sprintf(" ORDER BY id DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []*entity.WebhookDelivery{}
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+func (r *WebhookRepo) GetDelivery(ctx context.Context, id int64) (*entity.WebhookDelivery, error) {
+	row := r.pool.QueryRow(ctx, `SELECT `+deliveryColumns+` FROM webhook_deliveries WHERE id = $1`, id)
+	d, err := scanDelivery(row)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, entity.ErrNotFound
+		}
+		return nil, err
+	}
+	return d, nil
+}
+
+func (r *WebhookRepo) Enqueue(ctx context.Context, delivery *entity.WebhookDelivery) (*entity.WebhookDelivery, error) {
+	row := r.pool.QueryRow(ctx, `
+		INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload)
+		VALUES ($1, $2, $3, $4)
+		RETURNING `+deliveryColumns,
+		delivery.WebhookID, delivery.EventID, delivery.EventType, delivery.Payload,
+	)
+	d, err := scanDelivery(row)
+	if err != nil {
+		return nil, fmt.Errorf("enqueue webhook delivery: %w", err)
+	}
+	return d, nil
+}
+
+func (r *WebhookRepo) Claim(ctx context.Context, limit int, lease time.Duration) ([]*entity.WebhookDelivery, error) {
+	rows, err := r.pool.Query(ctx, `
+		WITH due AS (
+			SELECT d.id FROM webhook_deliveries d
+			JOIN webhooks w ON w.id = d.webhook_id AND w.active
+			WHERE d.status = 'pending' AND d.next_attempt_at <= NOW()
+				AND (d.locked_until IS NULL OR d.locked_until < NOW())
+			ORDER BY d.next_attempt_at
+			LIMIT $1
+			FOR UPDATE OF d SKIP LOCKED
+		), claimed AS (
+			UPDATE webhook_deliveries d SET locked_until = NOW() + make_interval(secs => $2)
+			FROM due WHERE d.id = due.id
+			RETURNING d.*
+		)
+		SELECT c.id, c.webhook_id, c.event_id, c.event_type, c.payload, c.status, c.attempts, c.next_attempt_at,
+			c.last_status_code, c.last_error, c.created_at, c.delivered_at,
+			w.id, w.name, w.url, w.events, w.secret, w.active, w.created_by, w.created_at, w.updated_at
+		FROM claimed c
+		JOIN webhooks w ON w.id = c.webhook_id
+		ORDER BY c.next_attempt_at
+	`, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("claim webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []*entity.WebhookDelivery
+	for rows.Next() {
+		d := &entity.WebhookDelivery{Webhook: &entity.Webhook{}}
+		var events []string
+		err := rows.Scan(
+			&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &d.Payload, &d.Status, &d.Attempts, &d.NextAttemptAt,
+			&d.LastStatusCode, &d.LastError, &d.CreatedAt, &d.DeliveredAt,
+			&d.Webhook.ID, &d.Webhook.Name, &d.Webhook.URL, &events, &d.Webhook.Secret, &d.Webhook.Active,
+			&d.Webhook.CreatedBy, &d.Webhook.CreatedAt, &d.Webhook.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scan claimed delivery: %w", err)
+		}
+		d.Webhook.Events = stringsToEvents(events)
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+func (r *WebhookRepo) RecordAttempt(ctx context.Context, d *entity.WebhookDelivery, retryIn time.Duration) error {
+	err := r.pool.QueryRow(ctx, `
+		UPDATE webhook_deliveries SET
+			status = $1, attempts = $2, last_status_code = $3, last_error = $4,
+			next_attempt_at = NOW() + make_interval(secs => $5),
+			delivered_at = CASE WHEN $1 = 'succeeded' THEN NOW() END,
+			locked_until = NULL
+		WHERE id = $6
+		RETURNING next_attempt_at, delivered_at
+	`, d.Status, d.Attempts, d.LastStatusCode, d.LastError, retryIn.Seconds(), d.ID,
+	).Scan(&d.NextAttemptAt, &d.DeliveredAt)
+	if err != nil {
+		return fmt.Errorf("record webhook attempt: %w", err)
+	}
+	return nil
+}
+
+func scanWebhook(row pgx.Row) (*entity.Webhook, error) {
+	hook := &entity.Webhook{}
+	var events []string
+	err := row.Scan(
+		&hook.ID, &hook.Name, &hook.URL, &events, &hook.Secret, &hook.Active,
+		&hook.CreatedBy, &hook.CreatedAt, &hook.UpdatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("scan webhook: %w", err)
+	}
+	hook.Events = stringsToEvents(events)
+	return hook, nil
+}
+
+func scanDelivery(row pgx.Row) (*entity.WebhookDelivery, error) {
+	d := &entity.WebhookDelivery{}
+	err := row.Scan(
+		&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &d.Payload, &d.Status, &d.Attempts, &d.NextAttemptAt,
+		&d.LastStatusCode, &d.LastError, &d.CreatedAt, &d.DeliveredAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("scan webhook delivery: %w", err)
+	}
+	return d, nil
+}
+
+func eventsToStrings(events []entity.EventType) []string {
+	out := make([]string, 0, len(events))
+	for _, e := range events {
+		out = append(out, string(e))
+	}
+	return out
+}
+
+func stringsToEvents(events []string) []entity.EventType {
+	out := make([]entity.EventType, 0, len(events))
+	for _, e := range events {
+		out = append(out, entity.EventType(e))
+	}
+	return out
+}
@@ -0,0 +1,46 @@
+package entity
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrDuplicateAttachment = errors.New("the file is already attached to this object")
+
+// AttachmentKind groups the accepted content types
+type AttachmentKind string
+
+const (
+	AttachmentImage    AttachmentKind = "image"
+	AttachmentDocument AttachmentKind = "document"
+)
+
+// Attachment is a photo or document attached to an object version, such as
+// a field photo, a survey report or a scanned historical map. Files are
+// stored once by content hash, so versions attaching the same file share it.
+type Attachment struct {
+	ID           int64          `json:"id"`
+	ObjectID     int64          `json:"object_id"`
+	Filename     string         `json:"filename"`
+	ContentType  string         `json:"content_type"`
+	Kind         AttachmentKind `json:"kind"`
+	Size         int64          `json:"size"`
+	SHA256       string         `json:"sha256"`
+	HasThumbnail bool           `json:"has_thumbnail"`
+	Caption      *string        `json:"caption,omitempty"`
+	// Latitude and Longitude come from the photo's EXIF GPS data
+	Latitude   *float64  `json:"latitude,omitempty"`
+	Longitude  *float64  `json:"longitude,omitempty"`
+	UploadedBy *int64    `json:"uploaded_by,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// BlobKey is where the file is kept in the blob store
+func (a *Attachment) BlobKey() string {
+	return "attachments/" + a.SHA256[:2] + "/" + a.SHA256
+}
+
+// ThumbnailKey is where the JPEG preview of an image is kept
+func (a *Attachment) ThumbnailKey() string {
+	return "thumbnails/" + a.SHA256[:2] + "/" + a.SHA256 + ".jpg"
+}
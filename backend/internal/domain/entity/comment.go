@@ -0,0 +1,126 @@
+package entity
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidAnchor = errors.New("anchor needs one of a known field, a [lon, lat] coordinate or a vertex path into the geometry")
+	ErrNotThreadRoot = errors.New("only the first comment of a thread can be resolved")
+	ErrHasReplies    = errors.New("comment has replies")
+)
+
+// CommentableFields are the water object fields a comment can be anchored to
+var CommentableFields = []string{
+	"name_kz", "name_ru", "name_en", "object_type", "region", "geometry", "source_crs",
+	"length_km", "area_km2", "max_depth_m", "avg_depth_m", "water_volume_km3", "basin_area_km2", "avg_discharge_m3s",
+	"salinity_level", "pollution_index", "ecological_status",
+	"description_kz", "description_ru", "description_en", "historical_notes",
+}
+
+// CommentAnchor ties a comment to part of a submission. Vertex is an index
+// path into the GeoJSON coordinates, for example [0, 12] for the 13th
+// vertex of a polygon's outer ring.
+type CommentAnchor struct {
+	Field      string    `json:"field,omitempty"`
+	Coordinate []float64 `json:"coordinate,omitempty"`
+	Vertex     []int     `json:"vertex,omitempty"`
+}
+
+// Resolve validates the anchor against the object it is made on. A vertex
+// anchor also records the vertex's coordinate, so the comment can still be
+// placed after the expert edits the geometry.
+func (a *CommentAnchor) Resolve(obj *WaterObject) error {
+	switch {
+	case a.Field != "" && a.Coordinate == nil && a.Vertex == nil:
+		for _, f := range CommentableFields {
+			if a.Field == f {
+				return nil
+			}
+		}
+	case a.Field == "" && a.Vertex == nil && a.Coordinate != nil:
+		if validCoordinate(a.Coordinate) {
+			return nil
+		}
+	case a.Field == "" && a.Vertex != nil:
+		position, ok := vertexAt(obj.Geometry.Coordinates, a.Vertex)
+		if ok && validCoordinate(position) {
+			a.Coordinate = position[:2]
+			return nil
+		}
+	}
+	return ErrInvalidAnchor
+}
+
+func validCoordinate(c []float64) bool {
+	return len(c) >= 2 && c[0] >= -180 && c[0] <= 180 && c[1] >= -90 && c[1] <= 90
+}
+
+// vertexAt walks the index path through nested coordinate arrays and
+// returns the position it ends on
+func vertexAt(coordinates json.RawMessage, path []int) ([]float64, bool) {
+	if len(path) == 0 {
+		return nil, false
+	}
+
+	node := coordinates
+	for _, i := range path {
+		var items []json.RawMessage
+		if err := json.Unmarshal(node, &items); err != nil || i < 0 || i >= len(items) {
+			return nil, false
+		}
+		node = items[i]
+	}
+
+	var position []float64
+	if err := json.Unmarshal(node, &position); err != nil {
+		return nil, false
+	}
+	return position, true
+}
+
+// ReviewComment is a remark in the discussion of a submission. Comments
+// belong to the object's canonical ID, so they carry over to resubmissions
+// and later versions; ObjectVersion records which version was discussed.
+// Replies point at the first comment of their thread.
+type ReviewComment struct {
+	ID            int64            `json:"id"`
+	CanonicalID   uuid.UUID        `json:"canonical_id"`
+	ObjectID      *int64           `json:"object_id,omitempty"`
+	ObjectVersion int              `json:"object_version"`
+	ParentID      *int64           `json:"parent_id,omitempty"`
+	AuthorID      *int64           `json:"author_id,omitempty"`
+	AuthorName    string           `json:"author_name"`
+	Body          string           `json:"body"`
+	Anchor        *CommentAnchor   `json:"anchor,omitempty"`
+	ResolvedAt    *time.Time       `json:"resolved_at,omitempty"`
+	ResolvedBy    *int64           `json:"resolved_by,omitempty"`
+	CreatedAt     time.Time        `json:"created_at"`
+	UpdatedAt     time.Time        `json:"updated_at"`
+	Replies       []*ReviewComment `json:"replies,omitempty"`
+}
+
+// Threads nests replies under the first comment of their thread, keeping
+// the order of the input
+func Threads(comments []*ReviewComment) []*ReviewComment {
+	roots := []*ReviewComment{}
+	byID := map[int64]*ReviewComment{}
+	for _, c := range comments {
+		if c.ParentID == nil {
+			roots = append(roots, c)
+			byID[c.ID] = c
+		}
+	}
+	for _, c := range comments {
+		if c.ParentID != nil {
+			if root, ok := byID[*c.ParentID]; ok {
+				root.Replies = append(root.Replies, c)
+			}
+		}
+	}
+	return roots
+}
@@ -0,0 +1,69 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrSelfMerge = errors.New("cannot merge an object into itself")
+
+// DuplicateMethod names the spatial test that matched two objects
+type DuplicateMethod string
+
+const (
+	DuplicatePolygonOverlap DuplicateMethod = "polygon_iou"
+	DuplicateLineOverlap    DuplicateMethod = "line_overlap"
+	DuplicateProximity      DuplicateMethod = "point_proximity"
+)
+
+// DuplicateMatch is a published or pending object that likely describes the
+// same water body as a submission. Spatial and NameSimilarity range from 0
+// to 1; Score weighs them together.
+type DuplicateMatch struct {
+	ObjectID       int64           `json:"-"`
+	CandidateID    int64           `json:"object_id"`
+	CanonicalID    uuid.UUID       `json:"canonical_id"`
+	NameKZ         string          `json:"name_kz"`
+	ObjectType     ObjectType      `json:"object_type"`
+	Status         ObjectStatus    `json:"status"`
+	Method         DuplicateMethod `json:"method"`
+	Spatial        float64         `json:"spatial_score"`
+	NameSimilarity float64         `json:"name_similarity"`
+	Score          float64         `json:"score"`
+	DistanceM      *float64        `json:"distance_m,omitempty"`
+	DetectedAt     time.Time       `json:"detected_at"`
+}
+
+// Extent is a bounding box in degrees of longitude and latitude
+type Extent struct {
+	MinLon, MinLat float64
+	MaxLon, MaxLat float64
+}
+
+// ObjectMerge records that the history of one object was folded into
+// another, so that links to the old canonical id keep resolving
+type ObjectMerge struct {
+	SourceCanonicalID uuid.UUID `json:"source_canonical_id"`
+	TargetCanonicalID uuid.UUID `json:"target_canonical_id"`
+	MergedBy          int64     `json:"merged_by"`
+	MergedAt          time.Time `json:"merged_at"`
+}
+
+// ComparableTypes lists the object types drawn with the same kind of
+// geometry as t, which are the ones a duplicate of t may have been filed as
+func ComparableTypes(t ObjectType) []ObjectType {
+	allowed := AllowedGeometryTypes[t]
+	if len(allowed) == 0 {
+		return nil
+	}
+
+	var out []ObjectType
+	for other, geoms := range AllowedGeometryTypes {
+		if geoms[0] == allowed[0] {
+			out = append(out, other)
+		}
+	}
+	return out
+}
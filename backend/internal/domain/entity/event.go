@@ -0,0 +1,63 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EventType names a workflow event pushed to connected clients
+type EventType string
+
+const (
+	EventPendingAdded     EventType = "pending_added"
+	EventApproved         EventType = "approved"
+	EventRejected         EventType = "rejected"
+	EventChangesRequested EventType = "changes_requested"
+	EventObjectPublished  EventType = "object_published"
+	// EventObjectUnpublished reports a published version leaving the map,
+	// whether unpublished or archived
+	EventObjectUnpublished EventType = "object_unpublished"
+)
+
+// Event is a change in the review workflow. Events are stored briefly so
+// that clients can resume a stream after reconnecting.
+type Event struct {
+	ID          int64      `json:"id"`
+	Type        EventType  `json:"type"`
+	ObjectID    int64      `json:"object_id"`
+	CanonicalID uuid.UUID  `json:"canonical_id"`
+	Version     int        `json:"version"`
+	ObjectType  ObjectType `json:"object_type"`
+	Region      *Region    `json:"region,omitempty"`
+	Name        string     `json:"name"`
+	AuthorID    int64      `json:"author_id"`
+	ActorID     *int64     `json:"actor_id,omitempty"`
+	Reason      string     `json:"reason,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// NewObjectEvent describes an event about a water object
+func NewObjectEvent(eventType EventType, obj *WaterObject, actorID *int64) *Event {
+	return &Event{
+		Type:        eventType,
+		ObjectID:    obj.ID,
+		CanonicalID: obj.CanonicalID,
+		Version:     obj.Version,
+		ObjectType:  obj.ObjectType,
+		Region:      obj.Region,
+		Name:        obj.NameKZ,
+		AuthorID:    obj.CreatedBy,
+		ActorID:     actorID,
+	}
+}
+
+// VisibleTo reports whether a user may receive the event. Publications and
+// unpublications are public; the rest go to the object's author and to reviewers whose
+// permissions cover the object.
+func (e *Event) VisibleTo(userID int64, perms PermissionSet) bool {
+	if e.Type == EventObjectPublished || e.Type == EventObjectUnpublished || e.AuthorID == userID {
+		return true
+	}
+	return perms.AllowsScope(PermObjectsReview, e.Region, e.ObjectType)
+}
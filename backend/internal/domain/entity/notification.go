@@ -0,0 +1,58 @@
+package entity
+
+import "time"
+
+// NotificationEvent identifies what a notification is about. Users can
+// turn each event off in their preferences.
+type NotificationEvent string
+
+const (
+	NotifySubmissionApproved NotificationEvent = "submission_approved"
+	NotifySubmissionRejected NotificationEvent = "submission_rejected"
+	NotifyChangesRequested   NotificationEvent = "changes_requested"
+	NotifyReviewQueued       NotificationEvent = "review_queued"
+	NotifyRoleChanged        NotificationEvent = "role_changed"
+	NotifyReviewComment      NotificationEvent = "review_comment"
+)
+
+var AllNotificationEvents = []NotificationEvent{
+	NotifySubmissionApproved, NotifySubmissionRejected, NotifyChangesRequested, NotifyReviewQueued, NotifyRoleChanged, NotifyReviewComment,
+}
+
+func (e NotificationEvent) IsValid() bool {
+	for _, known := range AllNotificationEvents {
+		if e == known {
+			return true
+		}
+	}
+	return false
+}
+
+// NotificationLevel controls how the client presents a notification
+type NotificationLevel string
+
+const (
+	LevelSuccess NotificationLevel = "success"
+	LevelWarning NotificationLevel = "warning"
+	LevelInfo    NotificationLevel = "info"
+)
+
+type Notification struct {
+	ID        int64             `json:"id"`
+	UserID    int64             `json:"-"`
+	Event     NotificationEvent `json:"event"`
+	Level     NotificationLevel `json:"type"`
+	Title     string            `json:"title"`
+	Message   string            `json:"message"`
+	ObjectID  *int64            `json:"object_id,omitempty"`
+	Read      bool              `json:"read"`
+	ReadAt    *time.Time        `json:"read_at,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// NotificationPreference records whether a user receives an event. Events
+// without a stored preference are enabled.
+type NotificationPreference struct {
+	Event   NotificationEvent `json:"event"`
+	Enabled bool              `json:"enabled"`
+}
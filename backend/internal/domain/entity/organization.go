@@ -0,0 +1,46 @@
+package entity
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrOrganizationNameTaken = errors.New("organization name already taken")
+	ErrAlreadyMember         = errors.New("user is already a member")
+	ErrNotMember             = errors.New("not a member of the organization")
+)
+
+// OrganizationRole is a member's role inside one organization
+type OrganizationRole string
+
+const (
+	// OrgRoleOwner manages the organization's membership
+	OrgRoleOwner  OrganizationRole = "owner"
+	OrgRoleMember OrganizationRole = "member"
+)
+
+func (r OrganizationRole) IsValid() bool {
+	return r == OrgRoleOwner || r == OrgRoleMember
+}
+
+// Organization is an institute or team that contributes water objects.
+// Members see and co-edit each other's drafts.
+type Organization struct {
+	ID          int64     `json:"id"`
+	Name        string    `json:"name"`
+	Description *string   `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+
+	// Role is the requesting user's role, filled when listing their own organizations
+	Role *OrganizationRole `json:"role,omitempty"`
+}
+
+type OrganizationMember struct {
+	OrganizationID int64            `json:"organization_id"`
+	UserID         int64            `json:"user_id"`
+	Name           string           `json:"name"`
+	Email          string           `json:"email"`
+	Role           OrganizationRole `json:"role"`
+	JoinedAt       time.Time        `json:"joined_at"`
+}
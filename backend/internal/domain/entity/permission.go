@@ -0,0 +1,168 @@
+package entity
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidGrant   = errors.New("invalid permission grant")
+	ErrDuplicateGrant = errors.New("permission already granted")
+)
+
+// Permission names an action that can be granted to roles or users
+type Permission string
+
+const (
+	PermObjectsEdit       Permission = "objects.edit"
+	PermObjectsReview     Permission = "objects.review"
+	PermUsersManage       Permission = "users.manage"
+	PermPermissionsManage Permission = "permissions.manage"
+	PermAuditRead         Permission = "audit.read"
+	PermWebhooksManage    Permission = "webhooks.manage"
+	PermQuorumManage      Permission = "quorum.manage"
+	PermObjectsMerge      Permission = "objects.merge"
+	PermQualityManage     Permission = "quality.manage"
+
+	// PermObjectsReviewSenior marks a senior reviewer, whose approval can
+	// satisfy a review quorum rule on its own
+	PermObjectsReviewSenior Permission = "objects.review_senior"
+)
+
+// AllPermissions lists every permission, for the admin UI
+var AllPermissions = []Permission{
+	PermObjectsEdit,
+	PermObjectsReview,
+	PermObjectsReviewSenior,
+	PermUsersManage,
+	PermPermissionsManage,
+	PermAuditRead,
+	PermWebhooksManage,
+	PermQuorumManage,
+	PermObjectsMerge,
+	PermQualityManage,
+}
+
+func (p Permission) IsValid() bool {
+	for _, known := range AllPermissions {
+		if p == known {
+			return true
+		}
+	}
+	return false
+}
+
+// IsScopable reports whether grants of the permission may be limited to a
+// region or object type
+func (p Permission) IsScopable() bool {
+	return p == PermObjectsEdit || p == PermObjectsReview || p == PermObjectsReviewSenior
+}
+
+// PermissionGrant gives a permission to every user with a role or to a
+// single user. Region and ObjectType, when set, limit the grant to water
+// objects in that region or of that type.
+type PermissionGrant struct {
+	ID         int64       `json:"id"`
+	Permission Permission  `json:"permission"`
+	Role       *UserRole   `json:"role,omitempty"`
+	UserID     *int64      `json:"user_id,omitempty"`
+	Region     *Region     `json:"region,omitempty"`
+	ObjectType *ObjectType `json:"object_type,omitempty"`
+	CreatedBy  *int64      `json:"created_by,omitempty"`
+	CreatedAt  time.Time   `json:"created_at"`
+}
+
+// Validate checks that the grant has exactly one grantee and valid scopes
+func (g *PermissionGrant) Validate() error {
+	if !g.Permission.IsValid() {
+		return ErrInvalidGrant
+	}
+	if (g.Role == nil) == (g.UserID == nil) {
+		return ErrInvalidGrant
+	}
+	if g.Role != nil && !g.Role.IsValid() {
+		return ErrInvalidGrant
+	}
+	if (g.Region != nil || g.ObjectType != nil) && !g.Permission.IsScopable() {
+		return ErrInvalidGrant
+	}
+	if g.Region != nil && !g.Region.IsValid() {
+		return ErrInvalidGrant
+	}
+	if g.ObjectType != nil && !g.ObjectType.IsValid() {
+		return ErrInvalidGrant
+	}
+	return nil
+}
+
+// covers reports whether the grant's scopes include the object
+func (g *PermissionGrant) covers(region *Region, objectType ObjectType) bool {
+	if g.Region != nil && (region == nil || *region != *g.Region) {
+		return false
+	}
+	if g.ObjectType != nil && *g.ObjectType != objectType {
+		return false
+	}
+	return true
+}
+
+// PermissionSet is the effective list of grants for one user
+type PermissionSet []*PermissionGrant
+
+// Has reports whether any grant of the permission exists, whatever its scope
+func (s PermissionSet) Has(p Permission) bool {
+	for _, g := range s {
+		if g.Permission == p {
+			return true
+		}
+	}
+	return false
+}
+
+// Count returns the number of grants of the permission
+func (s PermissionSet) Count(p Permission) int {
+	n := 0
+	for _, g := range s {
+		if g.Permission == p {
+			n++
+		}
+	}
+	return n
+}
+
+// Allows reports whether the permission applies to a water object
+func (s PermissionSet) Allows(p Permission, obj *WaterObject) bool {
+	return s.AllowsScope(p, obj.Region, obj.ObjectType)
+}
+
+// AllowsScope reports whether the permission applies to objects of the
+// type in the region
+func (s PermissionSet) AllowsScope(p Permission, region *Region, objectType ObjectType) bool {
+	for _, g := range s {
+		if g.Permission == p && g.covers(region, objectType) {
+			return true
+		}
+	}
+	return false
+}
+
+// Covers reports whether the set holds the grant's permission over at
+// least the grant's scope: an unscoped grant needs an unscoped one, a
+// regional grant one for that region or wider
+func (s PermissionSet) Covers(grant *PermissionGrant) bool {
+	for _, g := range s {
+		if g.Permission == grant.Permission && g.covers(grant.Region, typeOrEmpty(grant.ObjectType)) {
+			return true
+		}
+	}
+	return false
+}
+
+// typeOrEmpty is the type of a grant, or none for an unscoped one, which
+// only an unscoped grant covers
+func typeOrEmpty(t *ObjectType) ObjectType {
+	if t == nil {
+		return ""
+	}
+	return *t
+}
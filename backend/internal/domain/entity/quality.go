@@ -0,0 +1,56 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Severity ranks data-quality rules. Errors keep an object from being
+// submitted for review; warnings are only reported.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+func (s Severity) IsValid() bool {
+	return s == SeverityError || s == SeverityWarning
+}
+
+// RuleViolation is a data-quality rule an object breaks
+type RuleViolation struct {
+	ObjectID  int64     `json:"object_id"`
+	RuleID    string    `json:"rule_id"`
+	Severity  Severity  `json:"severity"`
+	Field     string    `json:"field"`
+	Message   string    `json:"message"`
+	CheckedAt time.Time `json:"checked_at"`
+
+	// The object as it is now, filled in by reports
+	CanonicalID *uuid.UUID   `json:"canonical_id,omitempty"`
+	NameKZ      string       `json:"name_kz,omitempty"`
+	ObjectType  ObjectType   `json:"object_type,omitempty"`
+	Region      *Region      `json:"region,omitempty"`
+	Status      ObjectStatus `json:"status,omitempty"`
+}
+
+// HasErrors reports whether any of the violations is an error
+func HasErrors(violations []*RuleViolation) bool {
+	for _, v := range violations {
+		if v.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// QualitySweep summarises a run of the rules over the published objects
+type QualitySweep struct {
+	Objects    int       `json:"objects"`
+	Errors     int       `json:"errors"`
+	Warnings   int       `json:"warnings"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+}
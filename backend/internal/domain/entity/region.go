@@ -0,0 +1,45 @@
+package entity
+
+// Region is a first-level administrative division of Kazakhstan
+type Region string
+
+const (
+	RegionAbai            Region = "abai"
+	RegionAkmola          Region = "akmola"
+	RegionAktobe          Region = "aktobe"
+	RegionAlmaty          Region = "almaty"
+	RegionAtyrau          Region = "atyrau"
+	RegionEastKazakhstan  Region = "east_kazakhstan"
+	RegionJambyl          Region = "jambyl"
+	RegionJetisu          Region = "jetisu"
+	RegionKaraganda       Region = "karaganda"
+	RegionKostanay        Region = "kostanay"
+	RegionKyzylorda       Region = "kyzylorda"
+	RegionMangystau       Region = "mangystau"
+	RegionNorthKazakhstan Region = "north_kazakhstan"
+	RegionPavlodar        Region = "pavlodar"
+	RegionTurkistan       Region = "turkistan"
+	RegionUlytau          Region = "ulytau"
+	RegionWestKazakhstan  Region = "west_kazakhstan"
+	RegionAstanaCity      Region = "astana_city"
+	RegionAlmatyCity      Region = "almaty_city"
+	RegionShymkentCity    Region = "shymkent_city"
+)
+
+// AllRegions lists the oblasts followed by the cities of republican significance
+var AllRegions = []Region{
+	RegionAbai, RegionAkmola, RegionAktobe, RegionAlmaty, RegionAtyrau,
+	RegionEastKazakhstan, RegionJambyl, RegionJetisu, RegionKaraganda,
+	RegionKostanay, RegionKyzylorda, RegionMangystau, RegionNorthKazakhstan,
+	RegionPavlodar, RegionTurkistan, RegionUlytau, RegionWestKazakhstan,
+	RegionAstanaCity, RegionAlmatyCity, RegionShymkentCity,
+}
+
+func (r Region) IsValid() bool {
+	for _, known := range AllRegions {
+		if r == known {
+			return true
+		}
+	}
+	return false
+}
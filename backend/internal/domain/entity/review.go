@@ -0,0 +1,129 @@
+package entity
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrClaimed             = errors.New("object is claimed by another reviewer")
+	ErrAlreadyApproved     = errors.New("reviewer has already approved this submission")
+	ErrInvalidQuorumRule   = errors.New("invalid quorum rule")
+	ErrDuplicateQuorumRule = errors.New("a quorum rule for this scope already exists")
+)
+
+// ReviewClaim assigns a pending object to one reviewer so that others
+// leave it alone. Claims lapse at ExpiresAt if the reviewer goes quiet.
+type ReviewClaim struct {
+	ObjectID     int64     `json:"object_id"`
+	ReviewerID   int64     `json:"reviewer_id"`
+	ReviewerName string    `json:"reviewer_name"`
+	ClaimedAt    time.Time `json:"claimed_at"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// ReviewApproval is one reviewer's vote to publish a submission. Senior
+// records whether the reviewer held objects.review_senior for the object.
+type ReviewApproval struct {
+	ObjectID     int64     `json:"object_id"`
+	ReviewerID   int64     `json:"reviewer_id"`
+	ReviewerName string    `json:"reviewer_name"`
+	Senior       bool      `json:"senior"`
+	Note         *string   `json:"note,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// QuorumRule sets how many approvals publish a submission in its scope.
+// A submission needs Approvals approvals, of which at least SeniorApprovals
+// from senior reviewers; with SeniorOverride, one senior approval suffices.
+type QuorumRule struct {
+	ID              int64       `json:"id"`
+	ObjectType      *ObjectType `json:"object_type,omitempty"`
+	Region          *Region     `json:"region,omitempty"`
+	Approvals       int         `json:"approvals"`
+	SeniorApprovals int         `json:"senior_approvals"`
+	SeniorOverride  bool        `json:"senior_override"`
+	CreatedBy       *int64      `json:"created_by,omitempty"`
+	CreatedAt       time.Time   `json:"created_at"`
+}
+
+// DefaultQuorum applies to objects no rule matches: a single approval
+var DefaultQuorum = &QuorumRule{Approvals: 1}
+
+// Validate checks the counts and scopes
+func (r *QuorumRule) Validate() error {
+	if r.Approvals < 1 || r.SeniorApprovals < 0 || r.SeniorApprovals > r.Approvals {
+		return ErrInvalidQuorumRule
+	}
+	if r.ObjectType != nil && !r.ObjectType.IsValid() {
+		return ErrInvalidQuorumRule
+	}
+	if r.Region != nil && !r.Region.IsValid() {
+		return ErrInvalidQuorumRule
+	}
+	return nil
+}
+
+func (r *QuorumRule) matches(obj *WaterObject) bool {
+	if r.ObjectType != nil && *r.ObjectType != obj.ObjectType {
+		return false
+	}
+	if r.Region != nil && (obj.Region == nil || *obj.Region != *r.Region) {
+		return false
+	}
+	return true
+}
+
+// specificity ranks matching rules; a type and region rule beats a type
+// rule, which beats a region rule, which beats a catch-all
+func (r *QuorumRule) specificity() int {
+	n := 0
+	if r.ObjectType != nil {
+		n += 2
+	}
+	if r.Region != nil {
+		n++
+	}
+	return n
+}
+
+// QuorumFor returns the most specific rule matching the object, or
+// DefaultQuorum
+func QuorumFor(rules []*QuorumRule, obj *WaterObject) *QuorumRule {
+	best := DefaultQuorum
+	bestRank := -1
+	for _, r := range rules {
+		if r.matches(obj) && r.specificity() > bestRank {
+			best, bestRank = r, r.specificity()
+		}
+	}
+	return best
+}
+
+// QuorumProgress reports how far a submission is from publication
+type QuorumProgress struct {
+	Approvals       int  `json:"approvals"`
+	SeniorApprovals int  `json:"senior_approvals"`
+	Required        int  `json:"required"`
+	SeniorRequired  int  `json:"senior_required"`
+	SeniorOverride  bool `json:"senior_override"`
+	Met             bool `json:"met"`
+}
+
+// Progress counts the approvals against the rule
+func (r *QuorumRule) Progress(approvals []*ReviewApproval) QuorumProgress {
+	p := QuorumProgress{
+		Required:       r.Approvals,
+		SeniorRequired: r.SeniorApprovals,
+		SeniorOverride: r.SeniorOverride,
+	}
+	for _, a := range approvals {
+		p.Approvals++
+		if a.Senior {
+			p.SeniorApprovals++
+		}
+	}
+	p.Met = (p.Approvals >= r.Approvals && p.SeniorApprovals >= r.SeniorApprovals) ||
+		(r.SeniorOverride && p.SeniorApprovals > 0)
+	return p
+}
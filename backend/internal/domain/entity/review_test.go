@@ -0,0 +1,103 @@
+package entity
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestQuorumFor(t *testing.T) {
+	river, lake := ObjectTypeRiver, ObjectTypeLake
+	almaty, abai := RegionAlmaty, RegionAbai
+
+	catchAll := &QuorumRule{ID: 1, Approvals: 2}
+	inAlmaty := &QuorumRule{ID: 2, Region: &almaty, Approvals: 2}
+	rivers := &QuorumRule{ID: 3, ObjectType: &river, Approvals: 2}
+	riversInAlmaty := &QuorumRule{ID: 4, ObjectType: &river, Region: &almaty, Approvals: 3}
+	lakesInAbai := &QuorumRule{ID: 5, ObjectType: &lake, Region: &abai, Approvals: 3}
+	all := []*QuorumRule{catchAll, inAlmaty, rivers, riversInAlmaty, lakesInAbai}
+
+	tests := []struct {
+		name  string
+		rules []*QuorumRule
+		obj   *WaterObject
+		want  *QuorumRule
+	}{
+		{"no rules", nil, &WaterObject{ObjectType: ObjectTypeRiver, Region: &almaty}, DefaultQuorum},
+		{"type and region beat the rest", all, &WaterObject{ObjectType: ObjectTypeRiver, Region: &almaty}, riversInAlmaty},
+		{"type beats region", []*QuorumRule{catchAll, inAlmaty, rivers}, &WaterObject{ObjectType: ObjectTypeRiver, Region: &almaty}, rivers},
+		{"region beats catch-all", all, &WaterObject{ObjectType: ObjectTypeLake, Region: &almaty}, inAlmaty},
+		{"type in another region", all, &WaterObject{ObjectType: ObjectTypeRiver, Region: &abai}, rivers},
+		{"no region only matches unscoped regions", all, &WaterObject{ObjectType: ObjectTypeLake}, catchAll},
+		{"nothing matches", []*QuorumRule{inAlmaty, lakesInAbai}, &WaterObject{ObjectType: ObjectTypeRiver, Region: &abai}, DefaultQuorum},
+		{"order does not matter", []*QuorumRule{riversInAlmaty, rivers, inAlmaty, catchAll}, &WaterObject{ObjectType: ObjectTypeRiver, Region: &almaty}, riversInAlmaty},
+	}
+	for _, tt := range tests {
+		if got := QuorumFor(tt.rules, tt.obj); got != tt.want {
+			t.Errorf("%s: QuorumFor = rule %d, want rule %d", tt.name, got.ID, tt.want.ID)
+		}
+	}
+}
+
+func TestQuorumProgress(t *testing.T) {
+	regular := &ReviewApproval{}
+	senior := &ReviewApproval{Senior: true}
+
+	tests := []struct {
+		name      string
+		rule      QuorumRule
+		approvals []*ReviewApproval
+		want      QuorumProgress
+	}{
+		{"default, no approvals", *DefaultQuorum, nil,
+			QuorumProgress{Required: 1}},
+		{"default, one approval", *DefaultQuorum, []*ReviewApproval{regular},
+			QuorumProgress{Approvals: 1, Required: 1, Met: true}},
+		{"two of two", QuorumRule{Approvals: 2}, []*ReviewApproval{regular, regular},
+			QuorumProgress{Approvals: 2, Required: 2, Met: true}},
+		{"one of two", QuorumRule{Approvals: 2}, []*ReviewApproval{regular},
+			QuorumProgress{Approvals: 1, Required: 2}},
+		{"seniors count towards the total", QuorumRule{Approvals: 2, SeniorApprovals: 1}, []*ReviewApproval{regular, senior},
+			QuorumProgress{Approvals: 2, SeniorApprovals: 1, Required: 2, SeniorRequired: 1, Met: true}},
+		{"enough approvals, no senior", QuorumRule{Approvals: 2, SeniorApprovals: 1}, []*ReviewApproval{regular, regular, regular},
+			QuorumProgress{Approvals: 3, Required: 2, SeniorRequired: 1}},
+		{"senior alone without override", QuorumRule{Approvals: 2, SeniorApprovals: 1}, []*ReviewApproval{senior},
+			QuorumProgress{Approvals: 1, SeniorApprovals: 1, Required: 2, SeniorRequired: 1}},
+		{"senior override", QuorumRule{Approvals: 3, SeniorOverride: true}, []*ReviewApproval{senior},
+			QuorumProgress{Approvals: 1, SeniorApprovals: 1, Required: 3, SeniorOverride: true, Met: true}},
+		{"override needs a senior", QuorumRule{Approvals: 3, SeniorOverride: true}, []*ReviewApproval{regular, regular},
+			QuorumProgress{Approvals: 2, Required: 3, SeniorOverride: true}},
+	}
+	for _, tt := range tests {
+		if got := tt.rule.Progress(tt.approvals); got != tt.want {
+			t.Errorf("%s: Progress = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestQuorumRuleValidate(t *testing.T) {
+	river, almaty := ObjectTypeRiver, RegionAlmaty
+	badType, badRegion := ObjectType("ocean"), Region("atlantis")
+
+	tests := []struct {
+		name string
+		rule QuorumRule
+		ok   bool
+	}{
+		{"single approval", QuorumRule{Approvals: 1}, true},
+		{"scoped", QuorumRule{ObjectType: &river, Region: &almaty, Approvals: 2, SeniorApprovals: 2}, true},
+		{"no approvals", QuorumRule{Approvals: 0}, false},
+		{"negative seniors", QuorumRule{Approvals: 1, SeniorApprovals: -1}, false},
+		{"more seniors than approvals", QuorumRule{Approvals: 1, SeniorApprovals: 2}, false},
+		{"unknown type", QuorumRule{ObjectType: &badType, Approvals: 1}, false},
+		{"unknown region", QuorumRule{Region: &badRegion, Approvals: 1}, false},
+	}
+	for _, tt := range tests {
+		err := tt.rule.Validate()
+		if tt.ok && err != nil {
+			t.Errorf("%s: Validate = %v, want nil", tt.name, err)
+		}
+		if !tt.ok && !errors.Is(err, ErrInvalidQuorumRule) {
+			t.Errorf("%s: Validate = %v, want %v", tt.name, err, ErrInvalidQuorumRule)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrSessionRevoked = errors.New("session revoked or expired")
+	ErrTokenReused    = errors.New("refresh token reuse detected")
+)
+
+// Session is a login on one device. It outlives the short-lived access
+// tokens issued for it and is extended by rotating refresh tokens.
+type Session struct {
+	ID            uuid.UUID  `json:"id"`
+	UserID        int64      `json:"user_id"`
+	UserAgent     string     `json:"user_agent"`
+	IP            string     `json:"ip"`
+	CreatedAt     time.Time  `json:"created_at"`
+	LastSeenAt    time.Time  `json:"last_seen_at"`
+	ExpiresAt     time.Time  `json:"expires_at"`
+	RevokedAt     *time.Time `json:"revoked_at,omitempty"`
+	RevokedReason *string    `json:"revoked_reason,omitempty"`
+
+	// ImpersonatorID is the administrator acting as the user in an
+	// impersonation session; such sessions have no refresh token
+	ImpersonatorID *int64 `json:"impersonator_id,omitempty"`
+}
+
+// IsActive reports whether the session can still be used
+func (s *Session) IsActive(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// RefreshToken is a single-use token that belongs to a session. Only its
+// hash is stored; a second use of the same token revokes the session.
+type RefreshToken struct {
+	TokenHash string
+	SessionID uuid.UUID
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
+
+// Session revocation reasons
+const (
+	RevokeReasonLogout      = "logout"
+	RevokeReasonLogoutAll   = "logout_all"
+	RevokeReasonAdmin       = "admin"
+	RevokeReasonTokenReused = "token_reused"
+	RevokeReasonPassword    = "password_changed"
+	RevokeReasonDeactivate  = "account_deactivated"
+	RevokeReasonImpersonate = "impersonation_ended"
+)
+
+// Impersonation sessions last DefaultImpersonation unless the administrator
+// asks for less or more, up to MaxImpersonation
+const (
+	DefaultImpersonation = 15 * time.Minute
+	MaxImpersonation     = time.Hour
+)
+
+// JWT "typ" claim values
+const (
+	TokenTypeAccess = "access"
+)
@@ -0,0 +1,137 @@
+package entity
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidSource     = errors.New("a source needs a title, a publication year and a licence")
+	ErrInvalidCiteField  = errors.New("only measured fields can be cited")
+	ErrDuplicateCitation = errors.New("the source is already cited for this field")
+)
+
+// Source is a bibliographic entry that objects and their measurements can
+// cite. Sources are shared: one survey may back many objects.
+type Source struct {
+	ID        int64     `json:"id"`
+	Title     string    `json:"title"`
+	Authors   *string   `json:"authors,omitempty"`
+	Publisher *string   `json:"publisher,omitempty"`
+	Year      int       `json:"year"`
+	URL       *string   `json:"url,omitempty"`
+	DOI       *string   `json:"doi,omitempty"`
+	Licence   string    `json:"licence"`
+	CreatedBy *int64    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Validate checks the required bibliographic details
+func (s *Source) Validate() error {
+	s.Title = strings.TrimSpace(s.Title)
+	s.Licence = strings.TrimSpace(s.Licence)
+	if s.Title == "" || s.Licence == "" || s.Year < 1000 || s.Year > time.Now().Year()+1 {
+		return ErrInvalidSource
+	}
+	return nil
+}
+
+// Citation attaches a source to an object version, either as a whole or,
+// when Field is set, to one of its measurements. Locator points into the
+// source, such as a page or table.
+type Citation struct {
+	ID        int64     `json:"id"`
+	ObjectID  int64     `json:"object_id"`
+	SourceID  int64     `json:"source_id"`
+	Field     *string   `json:"field,omitempty"`
+	Locator   *string   `json:"locator,omitempty"`
+	CreatedBy *int64    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// The cited source
+	Title   string  `json:"title"`
+	Authors *string `json:"authors,omitempty"`
+	Year    int     `json:"year"`
+	URL     *string `json:"url,omitempty"`
+	Licence string  `json:"licence"`
+}
+
+// MeasurementFields are the measured attributes of an object, which need a
+// source when a published value changes
+var MeasurementFields = []string{
+	"length_km", "area_km2", "max_depth_m", "avg_depth_m",
+	"water_volume_km3", "basin_area_km2", "avg_discharge_m3s", "pollution_index",
+}
+
+// IsMeasurementField reports whether the field is a measured attribute
+func IsMeasurementField(field string) bool {
+	for _, f := range MeasurementFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *WaterObject) measurement(field string) *float64 {
+	switch field {
+	case "length_km":
+		return w.LengthKm
+	case "area_km2":
+		return w.AreaKm2
+	case "max_depth_m":
+		return w.MaxDepthM
+	case "avg_depth_m":
+		return w.AvgDepthM
+	case "water_volume_km3":
+		return w.WaterVolumeKm3
+	case "basin_area_km2":
+		return w.BasinAreaKm2
+	case "avg_discharge_m3s":
+		return w.AvgDischargeM3s
+	case "pollution_index":
+		return w.PollutionIndex
+	}
+	return nil
+}
+
+// ChangedMeasurements lists the measured fields in which the object differs
+// from the published version, or that it sets when nothing is published
+func (w *WaterObject) ChangedMeasurements(published *WaterObject) []string {
+	var changed []string
+	for _, field := range MeasurementFields {
+		value := w.measurement(field)
+		var before *float64
+		if published != nil {
+			before = published.measurement(field)
+		}
+		if value == nil && before == nil {
+			continue
+		}
+		if value == nil || before == nil || *value != *before {
+			changed = append(changed, field)
+		}
+	}
+	return changed
+}
+
+// UncitedFields returns the fields not backed by any of the citations; a
+// citation of the whole object backs every field
+func UncitedFields(fields []string, citations []*Citation) []string {
+	cited := make(map[string]bool)
+	for _, c := range citations {
+		if c.Field == nil {
+			return nil
+		}
+		cited[*c.Field] = true
+	}
+
+	var out []string
+	for _, f := range fields {
+		if !cited[f] {
+			out = append(out, f)
+		}
+	}
+	return out
+}
@@ -0,0 +1,73 @@
+package entity
+
+import "time"
+
+// Stats summarises the catalogue and the review workflow for the admin
+// dashboard
+type Stats struct {
+	PublishedCount int `json:"published_count"`
+	PendingCount   int `json:"pending_count"`
+	DraftCount     int `json:"draft_count"`
+	ExpertCount    int `json:"expert_count"`
+	AdminCount     int `json:"admin_count"`
+	TotalUsers     int `json:"total_users"`
+
+	ByStatus map[ObjectStatus]int                `json:"by_status"`
+	ByType   map[ObjectType]map[ObjectStatus]int `json:"by_type"`
+
+	PendingAge  QueueAge           `json:"pending_age"`
+	Reviewers   []DecisionStats    `json:"reviewers"`
+	Experts     []DecisionStats    `json:"experts"`
+	Submissions []SubmissionBucket `json:"submissions"`
+
+	// Totals over the published version of each object
+	RiverLengthKm float64 `json:"river_length_km"`
+	LakeAreaKm2   float64 `json:"lake_area_km2"`
+
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// QueueAge describes how long the pending objects have been waiting for
+// review, in seconds
+type QueueAge struct {
+	Count  int     `json:"count"`
+	P50    float64 `json:"p50_seconds"`
+	P90    float64 `json:"p90_seconds"`
+	P99    float64 `json:"p99_seconds"`
+	Oldest float64 `json:"oldest_seconds"`
+}
+
+// DecisionStats counts review decisions made by one reviewer, or made on
+// the submissions of one expert. A reviewer is credited with every approval
+// they gave, whether or not it completed the quorum; an expert with each
+// publication of their work.
+type DecisionStats struct {
+	UserID           int64   `json:"user_id"`
+	Name             string  `json:"name"`
+	Approved         int     `json:"approved"`
+	Rejected         int     `json:"rejected"`
+	ChangesRequested int     `json:"changes_requested"`
+	Pending          int     `json:"pending,omitempty"`
+	ApprovalRate     float64 `json:"approval_rate"`
+	RejectRate       float64 `json:"rejection_rate"`
+}
+
+// SetRates derives the approval and rejection rates from the decision
+// counts; requested changes count as decisions too
+func (d *DecisionStats) SetRates() {
+	decided := d.Approved + d.Rejected + d.ChangesRequested
+	if decided == 0 {
+		return
+	}
+	d.ApprovalRate = float64(d.Approved) / float64(decided)
+	d.RejectRate = float64(d.Rejected) / float64(decided)
+}
+
+// SubmissionBucket counts submissions and review decisions in one week
+type SubmissionBucket struct {
+	Week             time.Time `json:"week"`
+	Submitted        int       `json:"submitted"`
+	Approved         int       `json:"approved"`
+	Rejected         int       `json:"rejected"`
+	ChangesRequested int       `json:"changes_requested"`
+}
@@ -0,0 +1,37 @@
+package entity
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidOTP         = errors.New("invalid verification code")
+	ErrTwoFactorRequired  = errors.New("two-factor authentication is mandatory for this role")
+	ErrTwoFactorNotActive = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorEnrolled  = errors.New("two-factor authentication is already enabled")
+)
+
+// RequiresTwoFactor reports whether password logins for the role must be
+// completed with a one-time code
+func (r UserRole) RequiresTwoFactor() bool {
+	return r == RoleAdmin || r == RoleExpert
+}
+
+// TwoFactor is a user's TOTP enrolment. It is pending until the first code
+// generated from the secret has been verified.
+type TwoFactor struct {
+	UserID       int64      `json:"user_id"`
+	Secret       string     `json:"-"`
+	ConfirmedAt  *time.Time `json:"confirmed_at,omitempty"`
+	LastUsedStep int64      `json:"-"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// IsEnabled reports whether the enrolment has been confirmed
+func (t *TwoFactor) IsEnabled() bool {
+	return t != nil && t.ConfirmedAt != nil
+}
+
+// JWT "typ" claim for the short-lived token returned by the password step
+const TokenTypeMFAChallenge = "mfa_challenge"
@@ -22,21 +22,60 @@ func (r UserRole) IsValid() bool {
 	return false
 }
 
-func (r UserRole) CanEdit() bool {
-	return r == RoleExpert || r == RoleAdmin
+type User struct {
+	ID              int64      `json:"id"`
+	Name            string     `json:"name"`
+	Email           string     `json:"email"`
+	Password        string     `json:"-"` // Never serialize
+	Role            UserRole   `json:"role"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	DeactivatedAt   *time.Time `json:"deactivated_at,omitempty"`
+	DeletedAt       *time.Time `json:"deleted_at,omitempty"`
+	FailedLogins    int        `json:"-"`
+	LockedUntil     *time.Time `json:"locked_until,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
 }
 
-func (r UserRole) CanReview() bool {
-	return r == RoleAdmin
+// Accounts are locked after LockoutThreshold consecutive failed sign-ins.
+// The lock starts at lockoutBase and doubles with every further failure.
+const (
+	LockoutThreshold = 5
+	lockoutBase      = time.Minute
+	lockoutMax       = 24 * time.Hour
+)
+
+// LockoutDuration returns how long to lock an account after the given
+// number of consecutive failures
+func LockoutDuration(failures int) time.Duration {
+	if failures < LockoutThreshold {
+		return 0
+	}
+	d := lockoutBase
+	for i := LockoutThreshold; i < failures && d < lockoutMax; i++ {
+		d *= 2
+	}
+	if d > lockoutMax {
+		d = lockoutMax
+	}
+	return d
 }
 
-type User struct {
-	ID        int64     `json:"id"`
-	Name      string    `json:"name"`
-	Email     string    `json:"email"`
-	Password  string    `json:"-"` // Never serialize
-	Role      UserRole  `json:"role"`
-	CreatedAt time.Time `json:"created_at"`
+// LockedFor returns the remaining lockout, or zero if the account is not locked
+func (u *User) LockedFor(now time.Time) time.Duration {
+	if u.LockedUntil == nil || !u.LockedUntil.After(now) {
+		return 0
+	}
+	return u.LockedUntil.Sub(now)
+}
+
+// IsActive reports whether the account may sign in
+func (u *User) IsActive() bool {
+	return u.DeactivatedAt == nil && u.DeletedAt == nil
+}
+
+// IsEmailVerified reports whether the user confirmed their email address
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
 }
 
 type ChangeAction string
@@ -60,3 +99,25 @@ type ChangeLog struct {
 	PerformedBy   int64                  `json:"performed_by"`
 	PerformedAt   time.Time              `json:"performed_at"`
 }
+
+// UserIdentity links a local user to an account at an external OpenID
+// Connect identity provider
+type UserIdentity struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id"`
+	Issuer    string    `json:"issuer"`
+	Subject   string    `json:"subject"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Rank orders roles by privilege, for picking the strongest of several
+func (r UserRole) Rank() int {
+	switch r {
+	case RoleAdmin:
+		return 2
+	case RoleExpert:
+		return 1
+	}
+	return 0
+}
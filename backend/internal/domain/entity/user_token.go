@@ -0,0 +1,45 @@
+package entity
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidToken    = errors.New("invalid or expired token")
+	ErrAccountInactive = errors.New("account is deactivated")
+	ErrEmailUnverified = errors.New("email address is not verified")
+)
+
+// UserTokenPurpose separates single-use tokens sent by email
+type UserTokenPurpose string
+
+const (
+	TokenPurposeVerifyEmail   UserTokenPurpose = "verify_email"
+	TokenPurposePasswordReset UserTokenPurpose = "password_reset"
+	// TokenPurposeInvite lets an account created by an administrator set
+	// its first password
+	TokenPurposeInvite UserTokenPurpose = "invite"
+)
+
+// TTL is how long a token of this purpose stays valid
+func (p UserTokenPurpose) TTL() time.Duration {
+	switch p {
+	case TokenPurposePasswordReset:
+		return time.Hour
+	case TokenPurposeInvite:
+		return 7 * 24 * time.Hour
+	}
+	return 48 * time.Hour
+}
+
+// UserToken is an expiring single-use token; only its hash is stored
+type UserToken struct {
+	ID        int64
+	UserID    int64
+	Purpose   UserTokenPurpose
+	TokenHash string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
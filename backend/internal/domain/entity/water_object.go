@@ -12,6 +12,7 @@ import (
 var (
 	ErrNameRequired         = errors.New("name_kz is required")
 	ErrInvalidObjectType    = errors.New("invalid object type")
+	ErrInvalidRegion        = errors.New("invalid region")
 	ErrGeometryTypeMismatch = errors.New("geometry type does not match object type")
 	ErrNotFound             = errors.New("object not found")
 	ErrUnauthorized         = errors.New("unauthorized")
@@ -45,6 +46,10 @@ const (
 	StatusPublished ObjectStatus = "published"
 	StatusArchived  ObjectStatus = "archived"
 	StatusRejected  ObjectStatus = "rejected"
+
+	// StatusChangesRequested sends a submission back to its authors for
+	// fixes; unlike a rejection it can be edited and resubmitted
+	StatusChangesRequested ObjectStatus = "changes_requested"
 )
 
 // Geometry represents a GeoJSON geometry
@@ -65,8 +70,13 @@ type WaterObject struct {
 
 	// Classification
 	ObjectType ObjectType `json:"object_type"`
+	Region     *Region    `json:"region,omitempty"`
 	Geometry   Geometry   `json:"geometry"`
 
+	// SourceCRS records the CRS the geometry was submitted in; Geometry
+	// itself is always stored as EPSG:4326
+	SourceCRS *string `json:"source_crs,omitempty"`
+
 	// Measurements
 	LengthKm        *float64 `json:"length_km,omitempty"`
 	AreaKm2         *float64 `json:"area_km2,omitempty"`
@@ -87,10 +97,23 @@ type WaterObject struct {
 	DescriptionEN   *string `json:"description_en,omitempty"`
 	HistoricalNotes *string `json:"historical_notes,omitempty"`
 
+	// Sources cite the object and its measurements; filled in where needed
+	Sources []*Citation `json:"sources,omitempty"`
+
+	// Attachments are the photos and documents of this version; filled in
+	// where needed
+	Attachments []*Attachment `json:"attachments,omitempty"`
+
 	// Status
 	Status          ObjectStatus `json:"status"`
+	// RejectionReason holds the reviewer's note on a rejection or a
+	// request for changes
 	RejectionReason *string      `json:"rejection_reason,omitempty"`
 
+	// OrganizationID is the contributing organization, whose members share
+	// the draft and are credited once it is published
+	OrganizationID *int64 `json:"organization_id,omitempty"`
+
 	// Audit
 	CreatedBy   int64      `json:"created_by"`
 	UpdatedBy   *int64     `json:"updated_by,omitempty"`
@@ -108,6 +131,9 @@ func (w *WaterObject) Validate() error {
 	if !w.ObjectType.IsValid() {
 		return ErrInvalidObjectType
 	}
+	if w.Region != nil && !w.Region.IsValid() {
+		return ErrInvalidRegion
+	}
 	return nil
 }
 
@@ -0,0 +1,122 @@
+package entity
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var ErrInvalidWebhook = errors.New("webhook needs a name, an http(s) url and known event types")
+
+// EventPing is sent by the test endpoint to check that a receiver works
+const EventPing EventType = "ping"
+
+// WebhookEvents lists the event types webhooks can subscribe to
+var WebhookEvents = []EventType{EventPendingAdded, EventApproved, EventRejected, EventChangesRequested, EventObjectPublished, EventObjectUnpublished}
+
+// Webhook delivers workflow events to a partner system. An empty Events
+// list subscribes to every event type.
+type Webhook struct {
+	ID        int64       `json:"id"`
+	Name      string      `json:"name"`
+	URL       string      `json:"url"`
+	Events    []EventType `json:"events"`
+	Secret    string      `json:"-"`
+	Active    bool        `json:"active"`
+	CreatedBy *int64      `json:"created_by,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+func (w *Webhook) Validate() error {
+	if strings.TrimSpace(w.Name) == "" {
+		return ErrInvalidWebhook
+	}
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidWebhook
+	}
+	for _, e := range w.Events {
+		if !e.isWebhookEvent() {
+			return ErrInvalidWebhook
+		}
+	}
+	return nil
+}
+
+func (e EventType) isWebhookEvent() bool {
+	for _, known := range WebhookEvents {
+		if e == known {
+			return true
+		}
+	}
+	return false
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	DeliveryFailed    DeliveryStatus = "failed"
+)
+
+// MaxDeliveryAttempts is how often a delivery is tried before it is
+// marked failed; with DeliveryBackoff that spans about four hours
+const MaxDeliveryAttempts = 10
+
+// DeliveryBackoff returns the wait before the next attempt after the given
+// number of failed attempts: 30 seconds, doubling each time
+func DeliveryBackoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	return 30 * time.Second << min(attempts-1, 10)
+}
+
+// WebhookDelivery is one event queued for one webhook
+type WebhookDelivery struct {
+	ID             int64           `json:"id"`
+	WebhookID      int64           `json:"webhook_id"`
+	EventID        *int64          `json:"event_id,omitempty"`
+	EventType      EventType       `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         DeliveryStatus  `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at"`
+	LastStatusCode *int            `json:"last_status_code,omitempty"`
+	LastError      *string         `json:"last_error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+
+	// Webhook is loaded with deliveries claimed for sending
+	Webhook *Webhook `json:"-"`
+}
+
+// RecordAttempt updates the delivery with the outcome of an attempt and
+// returns how long to wait before retrying
+func (d *WebhookDelivery) RecordAttempt(statusCode int, err error) time.Duration {
+	d.Attempts++
+	d.LastStatusCode = nil
+	d.LastError = nil
+	if statusCode != 0 {
+		d.LastStatusCode = &statusCode
+	}
+	if err != nil {
+		msg := err.Error()
+		d.LastError = &msg
+	}
+
+	switch {
+	case err == nil && statusCode >= 200 && statusCode < 300:
+		d.Status = DeliverySucceeded
+	case d.Attempts >= MaxDeliveryAttempts:
+		d.Status = DeliveryFailed
+	default:
+		d.Status = DeliveryPending
+		return DeliveryBackoff(d.Attempts)
+	}
+	return 0
+}
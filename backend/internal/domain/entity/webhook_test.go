@@ -0,0 +1,63 @@
+package entity
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDeliveryBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, 30 * time.Second},
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{9, 128 * time.Minute},
+		{11, 512 * time.Minute},
+		// The doubling stops after ten steps
+		{12, 512 * time.Minute},
+		{100, 512 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := DeliveryBackoff(tt.attempts); got != tt.want {
+			t.Errorf("DeliveryBackoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestRecordAttempt(t *testing.T) {
+	failure := errors.New("receiver responded 503")
+
+	tests := []struct {
+		name       string
+		attempts   int
+		statusCode int
+		err        error
+		wantStatus DeliveryStatus
+		wantRetry  time.Duration
+	}{
+		{"delivered", 0, 204, nil, DeliverySucceeded, 0},
+		{"first failure", 0, 503, failure, DeliveryPending, 30 * time.Second},
+		{"network error", 2, 0, failure, DeliveryPending, 2 * time.Minute},
+		{"last attempt", MaxDeliveryAttempts - 1, 500, failure, DeliveryFailed, 0},
+		{"delivered on the last attempt", MaxDeliveryAttempts - 1, 200, nil, DeliverySucceeded, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &WebhookDelivery{Attempts: tt.attempts}
+			retry := d.RecordAttempt(tt.statusCode, tt.err)
+			if d.Status != tt.wantStatus || retry != tt.wantRetry {
+				t.Errorf("RecordAttempt = %s, %v; want %s, %v", d.Status, retry, tt.wantStatus, tt.wantRetry)
+			}
+			if d.Attempts != tt.attempts+1 {
+				t.Errorf("Attempts = %d, want %d", d.Attempts, tt.attempts+1)
+			}
+			if (d.LastStatusCode != nil) != (tt.statusCode != 0) || (d.LastError != nil) != (tt.err != nil) {
+				t.Errorf("last outcome not recorded: status %v, error %v", d.LastStatusCode, d.LastError)
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package entity
+
+import (
+	"context"
+	"fmt"
+)
+
+// Transition names a step in the lifecycle of a water object version:
+//
+//	draft, changes_requested --submit--> pending
+//	pending --approve--> published
+//	pending --reject--> rejected
+//	pending --request_changes--> changes_requested
+//	published --unpublish--> draft
+//	published --archive--> archived
+//	archived --restore--> published
+//
+// Approving or restoring a version archives the published one it replaces.
+// Versions never change once published: unpublishing archives the version
+// and returns a copy of it to the authors as the next version's draft.
+type Transition string
+
+const (
+	TransitionSubmit         Transition = "submit"
+	TransitionApprove        Transition = "approve"
+	TransitionReject         Transition = "reject"
+	TransitionRequestChanges Transition = "request_changes"
+	TransitionUnpublish      Transition = "unpublish"
+	TransitionArchive        Transition = "archive"
+	TransitionRestore        Transition = "restore"
+)
+
+// Objects can be edited and deleted by their authors only in these statuses
+var (
+	EditableStatuses  = []ObjectStatus{StatusDraft, StatusChangesRequested}
+	DeletableStatuses = []ObjectStatus{StatusDraft, StatusRejected}
+)
+
+// transitionRule describes where a transition may start and who may take it
+type transitionRule struct {
+	from []ObjectStatus
+	to   ObjectStatus
+	// permission must cover the object's region and type
+	permission Permission
+	// editorOnly limits the transition to the object's authors
+	editorOnly bool
+	// notAuthor keeps authors from deciding on their own submissions
+	notAuthor bool
+}
+
+var transitionRules = map[Transition]transitionRule{
+	TransitionSubmit: {
+		from: []ObjectStatus{StatusDraft, StatusChangesRequested}, to: StatusPending,
+		permission: PermObjectsEdit, editorOnly: true,
+	},
+	TransitionApprove: {
+		from: []ObjectStatus{StatusPending}, to: StatusPublished,
+		permission: PermObjectsReview, notAuthor: true,
+	},
+	TransitionReject: {
+		from: []ObjectStatus{StatusPending}, to: StatusRejected,
+		permission: PermObjectsReview, notAuthor: true,
+	},
+	TransitionRequestChanges: {
+		from: []ObjectStatus{StatusPending}, to: StatusChangesRequested,
+		permission: PermObjectsReview, notAuthor: true,
+	},
+	TransitionUnpublish: {
+		from: []ObjectStatus{StatusPublished}, to: StatusDraft,
+		permission: PermObjectsReview,
+	},
+	TransitionArchive: {
+		from: []ObjectStatus{StatusPublished}, to: StatusArchived,
+		permission: PermObjectsReview,
+	},
+	TransitionRestore: {
+		from: []ObjectStatus{StatusArchived}, to: StatusPublished,
+		permission: PermObjectsReview,
+	},
+}
+
+// Sources returns the statuses the transition may start from
+func (t Transition) Sources() []ObjectStatus {
+	return transitionRules[t].from
+}
+
+// Target returns the status the transition leads to
+func (t Transition) Target() ObjectStatus {
+	return transitionRules[t].to
+}
+
+// TransitionsFrom lists the transitions that may start from a status
+func TransitionsFrom(status ObjectStatus) []Transition {
+	var out []Transition
+	for _, t := range []Transition{
+		TransitionSubmit, TransitionApprove, TransitionReject, TransitionRequestChanges,
+		TransitionUnpublish, TransitionArchive, TransitionRestore,
+	} {
+		for _, from := range transitionRules[t].from {
+			if from == status {
+				out = append(out, t)
+			}
+		}
+	}
+	return out
+}
+
+// Guard failures, carried by TransitionError.Reason
+const (
+	GuardInvalidState = "invalid_transition"
+	GuardPermission   = "forbidden"
+	GuardNotEditor    = "not_editor"
+	GuardSelfReview   = "self_review"
+)
+
+// TransitionError explains why a transition was refused
+type TransitionError struct {
+	Transition Transition
+	Status     ObjectStatus
+	Reason     string
+}
+
+func (e *TransitionError) Error() string {
+	switch e.Reason {
+	case GuardInvalidState:
+		return fmt.Sprintf("cannot %s an object that is %s", e.Transition, e.Status)
+	case GuardNotEditor:
+		return fmt.Sprintf("only the object's authors can %s it", e.Transition)
+	case GuardSelfReview:
+		return fmt.Sprintf("authors cannot %s their own submission", e.Transition)
+	}
+	return fmt.Sprintf("your permissions do not allow you to %s this object", e.Transition)
+}
+
+// Actor is the user attempting a transition
+type Actor struct {
+	UserID      int64
+	Permissions PermissionSet
+	// Editor is set when the user may edit the object, as its creator or a
+	// member of its organization. For review transitions it is set for any
+	// author, including the last user to change the object.
+	Editor bool
+}
+
+// StatusChange is a completed transition, handed to the workflow hooks
+type StatusChange struct {
+	Transition Transition
+	Object     *WaterObject
+	From       ObjectStatus
+	To         ObjectStatus
+	ActorID    int64
+	Reason     string
+}
+
+// TransitionHook runs after a transition has been stored
+type TransitionHook func(ctx context.Context, change *StatusChange)
+
+// Workflow guards the status transitions of water objects and runs hooks
+// once they are stored
+type Workflow struct {
+	hooks []TransitionHook
+}
+
+func NewWorkflow() *Workflow {
+	return &Workflow{}
+}
+
+// OnTransition registers a hook for every completed transition
+func (w *Workflow) OnTransition(hook TransitionHook) {
+	w.hooks = append(w.hooks, hook)
+}
+
+// Check reports whether the actor may take the transition from the object's
+// current status, returning a *TransitionError when not
+func (w *Workflow) Check(t Transition, obj *WaterObject, actor Actor) error {
+	rule, ok := transitionRules[t]
+	if !ok {
+		return &TransitionError{Transition: t, Status: obj.Status, Reason: GuardInvalidState}
+	}
+
+	if rule.permission != "" && !actor.Permissions.Allows(rule.permission, obj) {
+		return &TransitionError{Transition: t, Status: obj.Status, Reason: GuardPermission}
+	}
+	if rule.editorOnly && !actor.Editor {
+		return &TransitionError{Transition: t, Status: obj.Status, Reason: GuardNotEditor}
+	}
+	if rule.notAuthor && (actor.Editor || obj.CreatedBy == actor.UserID) {
+		return &TransitionError{Transition: t, Status: obj.Status, Reason: GuardSelfReview}
+	}
+
+	for _, from := range rule.from {
+		if obj.Status == from {
+			return nil
+		}
+	}
+	return &TransitionError{Transition: t, Status: obj.Status, Reason: GuardInvalidState}
+}
+
+// Completed runs the hooks for a stored transition of the object, which
+// still carries its previous status
+func (w *Workflow) Completed(ctx context.Context, t Transition, obj *WaterObject, actorID int64, reason string) {
+	change := &StatusChange{
+		Transition: t,
+		Object:     obj,
+		From:       obj.Status,
+		To:         t.Target(),
+		ActorID:    actorID,
+		Reason:     reason,
+	}
+	for _, hook := range w.hooks {
+		hook(ctx, change)
+	}
+}
@@ -0,0 +1,175 @@
+package entity
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+var allStatuses = []ObjectStatus{
+	StatusDraft, StatusPending, StatusPublished, StatusArchived, StatusRejected, StatusChangesRequested,
+}
+
+// guard returns the reason of a refused transition, or "" when allowed
+func guard(t *testing.T, err error) string {
+	t.Helper()
+	if err == nil {
+		return ""
+	}
+	var te *TransitionError
+	if !errors.As(err, &te) {
+		t.Fatalf("Check returned %T, want *TransitionError", err)
+	}
+	return te.Reason
+}
+
+func grants(perms ...Permission) PermissionSet {
+	var set PermissionSet
+	for _, p := range perms {
+		set = append(set, &PermissionGrant{Permission: p})
+	}
+	return set
+}
+
+func TestCheckStatuses(t *testing.T) {
+	w := NewWorkflow()
+	author := Actor{UserID: 1, Permissions: grants(PermObjectsEdit), Editor: true}
+	reviewer := Actor{UserID: 2, Permissions: grants(PermObjectsReview)}
+
+	tests := []struct {
+		transition Transition
+		actor      Actor
+		from       []ObjectStatus
+		to         ObjectStatus
+	}{
+		{TransitionSubmit, author, []ObjectStatus{StatusDraft, StatusChangesRequested}, StatusPending},
+		{TransitionApprove, reviewer, []ObjectStatus{StatusPending}, StatusPublished},
+		{TransitionReject, reviewer, []ObjectStatus{StatusPending}, StatusRejected},
+		{TransitionRequestChanges, reviewer, []ObjectStatus{StatusPending}, StatusChangesRequested},
+		{TransitionUnpublish, reviewer, []ObjectStatus{StatusPublished}, StatusDraft},
+		{TransitionArchive, reviewer, []ObjectStatus{StatusPublished}, StatusArchived},
+		{TransitionRestore, reviewer, []ObjectStatus{StatusArchived}, StatusPublished},
+	}
+	for _, tt := range tests {
+		if got := tt.transition.Target(); got != tt.to {
+			t.Errorf("%s: Target = %s, want %s", tt.transition, got, tt.to)
+		}
+		for _, status := range allStatuses {
+			want := GuardInvalidState
+			for _, from := range tt.from {
+				if status == from {
+					want = ""
+				}
+			}
+			obj := &WaterObject{Status: status, CreatedBy: 1}
+			if got := guard(t, w.Check(tt.transition, obj, tt.actor)); got != want {
+				t.Errorf("%s from %s: guard %q, want %q", tt.transition, status, got, want)
+			}
+		}
+	}
+
+	obj := &WaterObject{Status: StatusPending}
+	if got := guard(t, w.Check("delete", obj, reviewer)); got != GuardInvalidState {
+		t.Errorf("unknown transition: guard %q, want %q", got, GuardInvalidState)
+	}
+}
+
+func TestCheckSelfReview(t *testing.T) {
+	w := NewWorkflow()
+	perms := grants(PermObjectsEdit, PermObjectsReview)
+
+	// Editor is how the handlers report an organisation member or the last
+	// user to change the object
+	tests := []struct {
+		name  string
+		actor Actor
+		want  string
+	}{
+		{"creator", Actor{UserID: 1, Permissions: perms}, GuardSelfReview},
+		{"organisation member", Actor{UserID: 2, Permissions: perms, Editor: true}, GuardSelfReview},
+		{"last editor", Actor{UserID: 3, Permissions: perms, Editor: true}, GuardSelfReview},
+		{"other reviewer", Actor{UserID: 4, Permissions: perms}, ""},
+	}
+	for _, tt := range tests {
+		for _, tr := range []Transition{TransitionApprove, TransitionReject, TransitionRequestChanges} {
+			obj := &WaterObject{Status: StatusPending, CreatedBy: 1}
+			if got := guard(t, w.Check(tr, obj, tt.actor)); got != tt.want {
+				t.Errorf("%s, %s: guard %q, want %q", tt.name, tr, got, tt.want)
+			}
+		}
+	}
+
+	// Taking a published version down is not a review of one's own work
+	creator := Actor{UserID: 1, Permissions: perms, Editor: true}
+	for _, tr := range []Transition{TransitionUnpublish, TransitionArchive} {
+		obj := &WaterObject{Status: StatusPublished, CreatedBy: 1}
+		if got := guard(t, w.Check(tr, obj, creator)); got != "" {
+			t.Errorf("creator, %s: guard %q, want none", tr, got)
+		}
+	}
+}
+
+func TestCheckPermissions(t *testing.T) {
+	w := NewWorkflow()
+	almaty, abai := RegionAlmaty, RegionAbai
+	lake := ObjectTypeLake
+	obj := func(status ObjectStatus) *WaterObject {
+		return &WaterObject{Status: status, CreatedBy: 1, Region: &almaty, ObjectType: ObjectTypeRiver}
+	}
+
+	tests := []struct {
+		name       string
+		transition Transition
+		status     ObjectStatus
+		actor      Actor
+		want       string
+	}{
+		{"reviewer", TransitionApprove, StatusPending,
+			Actor{UserID: 2, Permissions: grants(PermObjectsReview)}, ""},
+		{"editor cannot approve", TransitionApprove, StatusPending,
+			Actor{UserID: 2, Permissions: grants(PermObjectsEdit)}, GuardPermission},
+		{"senior review alone", TransitionApprove, StatusPending,
+			Actor{UserID: 2, Permissions: grants(PermObjectsReviewSenior)}, GuardPermission},
+		{"review in the region", TransitionReject, StatusPending,
+			Actor{UserID: 2, Permissions: PermissionSet{{Permission: PermObjectsReview, Region: &almaty}}}, ""},
+		{"review in another region", TransitionReject, StatusPending,
+			Actor{UserID: 2, Permissions: PermissionSet{{Permission: PermObjectsReview, Region: &abai}}}, GuardPermission},
+		{"review of another type", TransitionArchive, StatusPublished,
+			Actor{UserID: 2, Permissions: PermissionSet{{Permission: PermObjectsReview, ObjectType: &lake}}}, GuardPermission},
+		{"no permissions", TransitionRestore, StatusArchived,
+			Actor{UserID: 2}, GuardPermission},
+		{"author submits", TransitionSubmit, StatusDraft,
+			Actor{UserID: 1, Permissions: grants(PermObjectsEdit), Editor: true}, ""},
+		{"author without edit", TransitionSubmit, StatusDraft,
+			Actor{UserID: 1, Permissions: grants(PermObjectsReview), Editor: true}, GuardPermission},
+		{"editor of someone else's draft", TransitionSubmit, StatusDraft,
+			Actor{UserID: 2, Permissions: grants(PermObjectsEdit)}, GuardNotEditor},
+		// Guards come before the status, so a refusal does not reveal it
+		{"no permission in the wrong status", TransitionApprove, StatusDraft,
+			Actor{UserID: 2}, GuardPermission},
+	}
+	for _, tt := range tests {
+		if got := guard(t, w.Check(tt.transition, obj(tt.status), tt.actor)); got != tt.want {
+			t.Errorf("%s: guard %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTransitionsFrom(t *testing.T) {
+	tests := []struct {
+		status ObjectStatus
+		want   []Transition
+	}{
+		{StatusDraft, []Transition{TransitionSubmit}},
+		{StatusChangesRequested, []Transition{TransitionSubmit}},
+		{StatusPending, []Transition{TransitionApprove, TransitionReject, TransitionRequestChanges}},
+		{StatusPublished, []Transition{TransitionUnpublish, TransitionArchive}},
+		{StatusArchived, []Transition{TransitionRestore}},
+		{StatusRejected, nil},
+	}
+	for _, tt := range tests {
+		if got := TransitionsFrom(tt.status); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("TransitionsFrom(%s) = %v, want %v", tt.status, got, tt.want)
+		}
+	}
+}
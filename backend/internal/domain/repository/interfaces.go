@@ -2,6 +2,9 @@ package repository
 
 import (
 	"context"
+	"time"
+
+	"github.com/google/uuid"
 
 	"watermap/internal/domain/entity"
 )
@@ -17,12 +20,18 @@ type WaterObjectFilter struct {
 type WaterObjectRepository interface {
 	// Public queries
 	GetPublished(ctx context.Context, filter *WaterObjectFilter) ([]*entity.WaterObject, error)
+	StreamPublished(ctx context.Context, filter *WaterObjectFilter, fn func(*entity.WaterObject) error) error
 	GetByCanonicalID(ctx context.Context, canonicalID string, status entity.ObjectStatus) (*entity.WaterObject, error)
 	GetByID(ctx context.Context, id int64) (*entity.WaterObject, error)
 	GetVersionHistory(ctx context.Context, canonicalID string) ([]*entity.WaterObject, error)
 
 	// Expert operations
+	// GetDraftsByUser returns the unpublished work of the user and of the
+	// organizations they belong to
 	GetDraftsByUser(ctx context.Context, userID int64) ([]*entity.WaterObject, error)
+	// GetSubmissionsByOrganization returns an organization's pending, changes
+	// requested and rejected objects
+	GetSubmissionsByOrganization(ctx context.Context, orgID int64) ([]*entity.WaterObject, error)
 	Create(ctx context.Context, obj *entity.WaterObject) (*entity.WaterObject, error)
 	Update(ctx context.Context, obj *entity.WaterObject) (*entity.WaterObject, error)
 	Delete(ctx context.Context, id int64, userID int64) error
@@ -30,8 +39,137 @@ type WaterObjectRepository interface {
 
 	// Admin operations
 	GetPending(ctx context.Context) ([]*entity.WaterObject, error)
-	Approve(ctx context.Context, id int64, reviewerID int64) error
+	// Workflow transitions, guarded by entity.Workflow. Each returns
+	// entity.ErrNotFound if the object is no longer in a source status of
+	// the transition. Review decisions release any claim on the object;
+	// approving is ReviewRepository.AddApproval.
 	Reject(ctx context.Context, id int64, reviewerID int64, reason string) error
+	// RequestChanges returns the object to its authors and discards the
+	// approvals given so far
+	RequestChanges(ctx context.Context, id int64, reviewerID int64, reason string) error
+	// Unpublish archives a published version and opens a copy of it, with
+	// its sources and attachments, as the next version's draft for its
+	// authors. It returns the draft's id.
+	Unpublish(ctx context.Context, id int64, actorID int64) (int64, error)
+	// Archive takes a published version off the map
+	Archive(ctx context.Context, id int64, actorID int64) error
+	// Restore publishes an archived version again, archiving the current one
+	Restore(ctx context.Context, id int64, actorID int64) error
+
+	// GetDuplicateCandidates returns the published and pending objects of
+	// the given types whose bounds meet the extent, which a new submission
+	// may duplicate
+	GetDuplicateCandidates(ctx context.Context, types []entity.ObjectType, within entity.Extent) ([]*entity.WaterObject, error)
+	// Merge moves the versions and review comments of source under target,
+	// numbered after target's own. If both have a published version, the
+	// source's is archived. It returns entity.ErrNotFound if either is missing.
+	Merge(ctx context.Context, source, target uuid.UUID, actorID int64) error
+	// MergedInto returns the canonical id a merged object now lives under,
+	// or entity.ErrNotFound if it was never merged
+	MergedInto(ctx context.Context, canonicalID string) (uuid.UUID, error)
+}
+
+type DuplicateRepository interface {
+	// Replace stores the matches found for an object in place of earlier ones
+	Replace(ctx context.Context, objectID int64, matches []*entity.DuplicateMatch) error
+	// ListForObject returns the object's matches that are still published
+	// or pending and have not been merged into it, best first
+	ListForObject(ctx context.Context, objectID int64) ([]*entity.DuplicateMatch, error)
+	// ListPending returns the matches of every pending object
+	ListPending(ctx context.Context) ([]*entity.DuplicateMatch, error)
+}
+
+type SourceRepository interface {
+	// Search returns sources whose title, authors or DOI contain the query,
+	// newest publication first; all sources when the query is empty
+	Search(ctx context.Context, query string, limit, offset int) ([]*entity.Source, error)
+	GetByID(ctx context.Context, id int64) (*entity.Source, error)
+	Create(ctx context.Context, source *entity.Source) (*entity.Source, error)
+	Update(ctx context.Context, source *entity.Source) error
+
+	// Citations returns the citations of the objects with their sources
+	Citations(ctx context.Context, objectIDs ...int64) ([]*entity.Citation, error)
+	// Cite attaches a source to an object; entity.ErrDuplicateCitation if
+	// it is already cited for the same field
+	Cite(ctx context.Context, citation *entity.Citation) (*entity.Citation, error)
+	// Uncite removes a citation of the object; entity.ErrNotFound if missing
+	Uncite(ctx context.Context, objectID, citationID int64) error
+}
+
+type AttachmentRepository interface {
+	// ListForObjects returns the attachments of the objects, oldest first
+	ListForObjects(ctx context.Context, objectIDs ...int64) ([]*entity.Attachment, error)
+	GetByID(ctx context.Context, id int64) (*entity.Attachment, error)
+	// Create records an attachment; entity.ErrDuplicateAttachment if the
+	// object already has the same file
+	Create(ctx context.Context, attachment *entity.Attachment) (*entity.Attachment, error)
+	// Delete removes an attachment of the object and returns it
+	Delete(ctx context.Context, objectID, id int64) (*entity.Attachment, error)
+	// Referenced reports whether any object still attaches the file
+	Referenced(ctx context.Context, sha256 string) (bool, error)
+	// LockContent serialises work on the stored file with the given hash
+	// across servers, until unlock is called
+	LockContent(ctx context.Context, sha256 string) (unlock func(), err error)
+}
+
+type ViolationFilter struct {
+	Severity   entity.Severity
+	RuleID     string
+	ObjectType entity.ObjectType
+	Status     entity.ObjectStatus
+}
+
+type QualityRepository interface {
+	// ReplaceForObject stores the object's violations in place of earlier ones
+	ReplaceForObject(ctx context.Context, objectID int64, violations []*entity.RuleViolation) error
+	// ReplacePublished replaces the violations of every published object
+	ReplacePublished(ctx context.Context, violations []*entity.RuleViolation) error
+	// List returns matching violations with their objects, errors first
+	List(ctx context.Context, filter *ViolationFilter) ([]*entity.RuleViolation, error)
+}
+
+type ReviewRepository interface {
+	// Claim assigns a pending object to the reviewer until expiresAt, renewing
+	// their own claim. It returns entity.ErrClaimed while another reviewer's
+	// claim is active and entity.ErrNotFound if the object is not pending.
+	Claim(ctx context.Context, objectID, reviewerID int64, expiresAt time.Time) (*entity.ReviewClaim, error)
+	// Unclaim releases the reviewer's claim; entity.ErrNotFound if they hold none
+	Unclaim(ctx context.Context, objectID, reviewerID int64) error
+	// GetClaim returns the active claim on the object or entity.ErrNotFound
+	GetClaim(ctx context.Context, objectID int64) (*entity.ReviewClaim, error)
+	// ActiveClaims returns the unexpired claims on pending objects
+	ActiveClaims(ctx context.Context) ([]*entity.ReviewClaim, error)
+
+	// AddApproval records an approval of a pending object and returns all of
+	// its approvals. When quorumMet finds them sufficient, the object is
+	// published in the same transaction, which holds the object's row lock,
+	// and published is true. It returns entity.ErrAlreadyApproved on a
+	// second vote.
+	AddApproval(ctx context.Context, approval *entity.ReviewApproval, quorumMet func([]*entity.ReviewApproval) bool) (approvals []*entity.ReviewApproval, published bool, err error)
+	ListApprovals(ctx context.Context, objectID int64) ([]*entity.ReviewApproval, error)
+	// PendingApprovals returns the approvals of every pending object
+	PendingApprovals(ctx context.Context) ([]*entity.ReviewApproval, error)
+
+	ListQuorumRules(ctx context.Context) ([]*entity.QuorumRule, error)
+	CreateQuorumRule(ctx context.Context, rule *entity.QuorumRule) (*entity.QuorumRule, error)
+	DeleteQuorumRule(ctx context.Context, id int64) error
+}
+
+// User statuses accepted by UserFilter.Status
+const (
+	UserStatusActive      = "active"
+	UserStatusDeactivated = "deactivated"
+	UserStatusDeleted     = "deleted"
+	UserStatusInvited     = "invited"
+)
+
+type UserFilter struct {
+	// Query matches a substring of the name or email, case-insensitively
+	Query  string
+	Role   entity.UserRole
+	Status string
+	Limit  int
+	Offset int
 }
 
 type UserRepository interface {
@@ -39,10 +177,203 @@ type UserRepository interface {
 	GetByEmail(ctx context.Context, email string) (*entity.User, error)
 	Create(ctx context.Context, user *entity.User) (*entity.User, error)
 	UpdateRole(ctx context.Context, id int64, role entity.UserRole) error
-	GetAll(ctx context.Context) ([]*entity.User, error)
+	// Search returns a page of matching users and the total number of matches
+	Search(ctx context.Context, filter *UserFilter) ([]*entity.User, int, error)
+	// UpdatePassword sets the password and, in the same update, clears the
+	// failed sign-ins and any lockout, so a reset unlocks the account
+	UpdatePassword(ctx context.Context, id int64, passwordHash string) error
+	MarkEmailVerified(ctx context.Context, id int64) error
+	Deactivate(ctx context.Context, id int64) error
+	Reactivate(ctx context.Context, id int64) error
+	// SoftDelete closes the account but keeps the name, so authored water
+	// objects stay attributed
+	SoftDelete(ctx context.Context, id int64) error
+	// Anonymize scrubs personal data and marks the account deleted; the row
+	// stays so authored water objects and change logs keep their references
+	Anonymize(ctx context.Context, id int64) error
+	// RecordLoginFailure increments and returns the consecutive failure count
+	RecordLoginFailure(ctx context.Context, id int64) (int, error)
+	LockUntil(ctx context.Context, id int64, until time.Time) error
+	ResetLoginFailures(ctx context.Context, id int64) error
+}
+
+type UserTokenRepository interface {
+	Create(ctx context.Context, token *entity.UserToken) error
+	// Consume marks an unused, unexpired token as used and returns it
+	Consume(ctx context.Context, purpose entity.UserTokenPurpose, tokenHash string) (*entity.UserToken, error)
+	DeleteForUser(ctx context.Context, userID int64, purpose entity.UserTokenPurpose) error
+}
+
+type PermissionRepository interface {
+	List(ctx context.Context) ([]*entity.PermissionGrant, error)
+	GetByID(ctx context.Context, id int64) (*entity.PermissionGrant, error)
+	Create(ctx context.Context, grant *entity.PermissionGrant) (*entity.PermissionGrant, error)
+	Delete(ctx context.Context, id int64) error
+	// ForUser returns the grants made to the user directly or to their role
+	ForUser(ctx context.Context, userID int64, role entity.UserRole) (entity.PermissionSet, error)
+	// UsersAllowed returns the active users holding the permission for the
+	// object, matching PermissionSet.Allows
+	UsersAllowed(ctx context.Context, perm entity.Permission, obj *entity.WaterObject) ([]int64, error)
+}
+
+type OrganizationRepository interface {
+	List(ctx context.Context) ([]*entity.Organization, error)
+	// ListForUser returns the user's organizations with their role filled in
+	ListForUser(ctx context.Context, userID int64) ([]*entity.Organization, error)
+	GetByID(ctx context.Context, id int64) (*entity.Organization, error)
+	Create(ctx context.Context, org *entity.Organization) (*entity.Organization, error)
+	Update(ctx context.Context, org *entity.Organization) error
+	ListMembers(ctx context.Context, orgID int64) ([]*entity.OrganizationMember, error)
+	// GetMembership returns entity.ErrNotMember if the user is not a member
+	GetMembership(ctx context.Context, orgID, userID int64) (*entity.OrganizationMember, error)
+	AddMember(ctx context.Context, orgID, userID int64, role entity.OrganizationRole) error
+	UpdateMemberRole(ctx context.Context, orgID, userID int64, role entity.OrganizationRole) error
+	RemoveMember(ctx context.Context, orgID, userID int64) error
+}
+
+type UserIdentityRepository interface {
+	GetByIssuerSubject(ctx context.Context, issuer, subject string) (*entity.UserIdentity, error)
+	Create(ctx context.Context, identity *entity.UserIdentity) (*entity.UserIdentity, error)
+}
+
+type TwoFactorRepository interface {
+	Get(ctx context.Context, userID int64) (*entity.TwoFactor, error)
+	// SavePending stores a new unconfirmed secret, replacing any pending one.
+	// It returns entity.ErrTwoFactorEnrolled when a secret is confirmed.
+	SavePending(ctx context.Context, userID int64, secret string) error
+	Confirm(ctx context.Context, userID int64, step int64) error
+	// UseStep records a successful verification; it returns false when the
+	// step is not newer than the last one used, i.e. a replayed code.
+	UseStep(ctx context.Context, userID int64, step int64) (bool, error)
+	Delete(ctx context.Context, userID int64) error
+	ReplaceRecoveryCodes(ctx context.Context, userID int64, codeHashes []string) error
+	// UseRecoveryCode consumes a recovery code; it returns false if unknown or used.
+	UseRecoveryCode(ctx context.Context, userID int64, codeHash string) (bool, error)
+	CountRecoveryCodes(ctx context.Context, userID int64) (int, error)
+}
+
+type SessionRepository interface {
+	Create(ctx context.Context, session *entity.Session, refresh *entity.RefreshToken) (*entity.Session, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*entity.Session, error)
+	GetActiveByUser(ctx context.Context, userID int64) ([]*entity.Session, error)
+	// Rotate consumes the presented refresh token and stores its replacement.
+	// The session's expiry slides to the replacement's, but never past
+	// maxLifetime after the session began; the replacement is capped alike.
+	// It returns entity.ErrTokenReused if the token was already consumed.
+	Rotate(ctx context.Context, tokenHash string, next *entity.RefreshToken, lastSeen time.Time, maxLifetime time.Duration) (*entity.Session, error)
+	GetSessionIDByTokenHash(ctx context.Context, tokenHash string) (uuid.UUID, error)
+	Revoke(ctx context.Context, id uuid.UUID, reason string) error
+	RevokeAllForUser(ctx context.Context, userID int64, reason string) (int64, error)
+}
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *entity.APIKey) (*entity.APIKey, error)
+	GetByHash(ctx context.Context, keyHash string) (*entity.APIKey, error)
+	GetByID(ctx context.Context, id int64, userID int64) (*entity.APIKey, error)
+	ListByUser(ctx context.Context, userID int64) ([]*entity.APIKey, error)
+	Revoke(ctx context.Context, id int64, userID int64) error
+	TouchLastUsed(ctx context.Context, id int64, ip string, at time.Time) error
 }
 
 type ChangeLogRepository interface {
 	Create(ctx context.Context, log *entity.ChangeLog) error
 	GetByCanonicalID(ctx context.Context, canonicalID string) ([]*entity.ChangeLog, error)
 }
+
+type AuditFilter struct {
+	Action     entity.AuditAction
+	ActorID    *int64
+	TargetType string
+	TargetID   string
+	From       *time.Time
+	To         *time.Time
+	Limit      int
+	Offset     int
+}
+
+type AuditLogRepository interface {
+	// Append seals the event onto the end of the hash chain and stores it
+	Append(ctx context.Context, event *entity.AuditEvent) error
+	// List returns matching events, newest first
+	List(ctx context.Context, filter *AuditFilter) ([]*entity.AuditEvent, error)
+	// Stream calls fn for each matching event in chain order
+	Stream(ctx context.Context, filter *AuditFilter, fn func(*entity.AuditEvent) error) error
+}
+
+type NotificationFilter struct {
+	UnreadOnly bool
+	Limit      int
+	Offset     int
+}
+
+type NotificationRepository interface {
+	// Create stores a copy of the notification for each recipient that has
+	// not turned its event off
+	Create(ctx context.Context, n *entity.Notification, userIDs []int64) error
+	List(ctx context.Context, userID int64, filter *NotificationFilter) ([]*entity.Notification, error)
+	UnreadCount(ctx context.Context, userID int64) (int, error)
+	MarkRead(ctx context.Context, userID, id int64) error
+	MarkAllRead(ctx context.Context, userID int64) (int64, error)
+	Delete(ctx context.Context, userID, id int64) error
+	// Preferences returns a preference for every event, defaults included
+	Preferences(ctx context.Context, userID int64) ([]entity.NotificationPreference, error)
+	SetPreferences(ctx context.Context, userID int64, prefs []entity.NotificationPreference) error
+}
+
+type EventRepository interface {
+	// Append stores the event, queues it for the webhooks subscribed to
+	// its type and announces it to every listening instance
+	Append(ctx context.Context, event *entity.Event) error
+	// ListSince returns up to limit events with an ID above afterID, oldest first
+	ListSince(ctx context.Context, afterID int64, limit int) ([]*entity.Event, error)
+	// FirstID returns the oldest retained event ID, or 0 when there are none
+	FirstID(ctx context.Context) (int64, error)
+	DeleteBefore(ctx context.Context, before time.Time) (int64, error)
+	// Listen calls fn for every appended event until ctx is done or the
+	// connection fails
+	Listen(ctx context.Context, fn func(*entity.Event)) error
+}
+
+type DeliveryFilter struct {
+	Status entity.DeliveryStatus
+	Limit  int
+	Offset int
+}
+
+type WebhookRepository interface {
+	List(ctx context.Context) ([]*entity.Webhook, error)
+	GetByID(ctx context.Context, id int64) (*entity.Webhook, error)
+	Create(ctx context.Context, hook *entity.Webhook) (*entity.Webhook, error)
+	Update(ctx context.Context, hook *entity.Webhook) error
+	Delete(ctx context.Context, id int64) error
+
+	ListDeliveries(ctx context.Context, webhookID int64, filter *DeliveryFilter) ([]*entity.WebhookDelivery, error)
+	GetDelivery(ctx context.Context, id int64) (*entity.WebhookDelivery, error)
+	// Enqueue queues a delivery outside the event stream, for pings and
+	// manual redelivery
+	Enqueue(ctx context.Context, delivery *entity.WebhookDelivery) (*entity.WebhookDelivery, error)
+	// Claim leases up to limit due deliveries of active webhooks, with
+	// their webhook loaded, so that other instances skip them
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]*entity.WebhookDelivery, error)
+	// RecordAttempt stores the outcome of an attempt and releases the lease
+	RecordAttempt(ctx context.Context, delivery *entity.WebhookDelivery, retryIn time.Duration) error
+}
+
+type CommentRepository interface {
+	// ListByCanonicalID returns the comments on every version of an object,
+	// oldest first
+	ListByCanonicalID(ctx context.Context, canonicalID uuid.UUID) ([]*entity.ReviewComment, error)
+	GetByID(ctx context.Context, id int64) (*entity.ReviewComment, error)
+	Create(ctx context.Context, comment *entity.ReviewComment) (*entity.ReviewComment, error)
+	UpdateBody(ctx context.Context, id int64, body string) error
+	// Delete removes a comment; ErrHasReplies when replies depend on it
+	Delete(ctx context.Context, id int64) error
+	// SetResolved resolves the thread, or reopens it when resolvedBy is nil
+	SetResolved(ctx context.Context, id int64, resolvedBy *int64) error
+}
+
+type StatsRepository interface {
+	// Collect aggregates the dashboard statistics, with submissions bucketed
+	// by week over the given number of weeks
+	Collect(ctx context.Context, weeks int) (*entity.Stats, error)
+}
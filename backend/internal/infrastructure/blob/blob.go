@@ -0,0 +1,67 @@
+// Package blob stores uploaded files on the local filesystem or in an
+// S3-compatible object store.
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var (
+	ErrNotFound   = errors.New("blob: not found")
+	ErrInvalidKey = errors.New("blob: invalid key")
+)
+
+// Store keeps blobs under slash-separated keys
+type Store interface {
+	// Put writes size bytes from r under key, replacing any earlier blob
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get opens the blob under key, or fails with ErrNotFound
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blob under key; missing blobs are not an error
+	Delete(ctx context.Context, key string) error
+}
+
+// Config selects and configures the store implementation
+type Config struct {
+	Driver string // local or s3
+	Dir    string // root directory for the local driver
+
+	// S3-compatible endpoint, such as https://s3.eu-central-1.amazonaws.com
+	// or a MinIO server. Buckets are addressed path-style.
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+}
+
+// New returns the store for cfg.Driver
+func New(cfg Config) (Store, error) {
+	switch strings.ToLower(cfg.Driver) {
+	case "", "local":
+		return NewLocalStore(cfg.Dir)
+	case "s3":
+		if cfg.Endpoint == "" || cfg.Bucket == "" {
+			return nil, fmt.Errorf("blob: S3 endpoint and bucket are required")
+		}
+		return NewS3Store(cfg.Endpoint, cfg.Region, cfg.Bucket, cfg.AccessKey, cfg.SecretKey)
+	}
+	return nil, fmt.Errorf("blob: unknown driver %q", cfg.Driver)
+}
+
+// validKey rejects keys that could escape the store's root
+func validKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+	return true
+}
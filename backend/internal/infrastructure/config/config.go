@@ -3,6 +3,8 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
@@ -14,10 +16,105 @@ type Config struct {
 	DBPassword string
 	JWTSecret  string
 	ClientURL  string
+
+	// AccessTokenTTL is the lifetime of the JWT sent with every request;
+	// RefreshTokenTTL is how long a session survives without being refreshed.
+	// Each refresh extends it, up to SessionMaxLifetime after sign-in.
+	AccessTokenTTL     time.Duration
+	RefreshTokenTTL    time.Duration
+	SessionMaxLifetime time.Duration
+
+	// OpenID Connect single sign-on; disabled when OIDCIssuerURL is empty.
+	// OIDCRoleMapping is a comma-separated list of claim value=role pairs
+	// matched against the OIDCRoleClaim claim (a string or string array).
+	OIDCIssuerURL    string
+	OIDCClientID     string
+	OIDCClientSecret string
+	OIDCRedirectURL  string
+	OIDCRoleClaim    string
+	OIDCRoleMapping  string
+	OIDCSyncRoles    bool
+
+	// SimplifyCacheSize bounds the number of cached simplified geometries
+	SimplifyCacheSize int
+
+	// StatsCacheTTL is how long admin dashboard statistics are reused
+	// before being recomputed
+	StatsCacheTTL time.Duration
+
+	// ReviewClaimTTL is how long a reviewer's claim on a pending object
+	// keeps others away before it lapses
+	ReviewClaimTTL time.Duration
+
+	// Duplicate detection on submit: lines within DuplicateLineBuffer metres
+	// of each other overlap, springs closer than DuplicateSpringRadius
+	// metres are near each other
+	DuplicateLineBuffer   float64
+	DuplicateSpringRadius float64
+
+	// QualityRules is a YAML or JSON file of data-quality rules replacing
+	// the built-in ones; QualitySweepHour is the hour (UTC) of the nightly
+	// check of published objects
+	QualityRules     string
+	QualitySweepHour int
+
+	// Attachment storage. BlobDriver is local (files below BlobDir) or s3,
+	// for any S3-compatible store addressed path-style at S3Endpoint.
+	// AttachmentMaxSize is the upload limit in bytes; image thumbnails fit
+	// in ThumbnailSize pixels.
+	BlobDriver        string
+	BlobDir           string
+	S3Endpoint        string
+	S3Region          string
+	S3Bucket          string
+	S3AccessKey       string
+	S3SecretKey       string
+	AttachmentMaxSize int64
+	ThumbnailSize     int
+
+	// EventRetention is how long workflow events are kept for clients
+	// resuming a live stream
+	EventRetention time.Duration
+
+	// WebhookPollInterval is how often the webhook queue is checked for
+	// due deliveries
+	WebhookPollInterval time.Duration
+
+	// Outgoing mail. MailDriver is smtp, log (default) or file; the file
+	// driver writes one message per file into MailDir.
+	MailDriver   string
+	MailFrom     string
+	MailDir      string
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+
+	// Rate limiting. RateLimitBackend is memory (per instance) or postgres
+	// (shared). Limits are "requests/duration" token buckets per route group;
+	// RateLimitAuthAccount applies per account on the credential endpoints.
+	RateLimitBackend     string
+	RateLimitAuth        string
+	RateLimitAuthAccount string
+	RateLimitPublic      string
+	RateLimitAPI         string
+
+	// TrustedProxies lists the addresses or CIDR ranges of reverse proxies
+	// whose X-Forwarded-For and X-Real-IP headers are believed, as a
+	// comma-separated TRUSTED_PROXIES. Empty trusts none: the client IP used
+	// for rate limits, audit records and sessions is then the socket peer.
+	TrustedProxies []string
 }
 
 func Load() *Config {
 	dbPort, _ := strconv.Atoi(getEnv("DB_PORT", "5432"))
+	simplifyCacheSize, _ := strconv.Atoi(getEnv("SIMPLIFY_CACHE_SIZE", "20000"))
+	smtpPort, _ := strconv.Atoi(getEnv("SMTP_PORT", "587"))
+	lineBuffer, _ := strconv.ParseFloat(getEnv("DUPLICATE_LINE_BUFFER", "200"), 64)
+	springRadius, _ := strconv.ParseFloat(getEnv("DUPLICATE_SPRING_RADIUS", "250"), 64)
+	sweepHour, _ := strconv.Atoi(getEnv("QUALITY_SWEEP_HOUR", "2"))
+	attachmentMaxSize, _ := strconv.ParseInt(getEnv("ATTACHMENT_MAX_SIZE", "26214400"), 10, 64)
+	thumbnailSize, _ := strconv.Atoi(getEnv("THUMBNAIL_SIZE", "320"))
 
 	return &Config{
 		Port:       getEnv("PORT", "5000"),
@@ -28,6 +125,57 @@ func Load() *Config {
 		DBPassword: getEnv("DB_PASSWORD", "mypassword"),
 		JWTSecret:  getEnv("JWT_SECRET", "your-secret-key"),
 		ClientURL:  getEnv("CLIENT_URL", "http://localhost:5173"),
+
+		AccessTokenTTL:     getDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL:    getDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+		SessionMaxLifetime: getDuration("SESSION_MAX_LIFETIME", 90*24*time.Hour),
+
+		OIDCIssuerURL:    getEnv("OIDC_ISSUER_URL", ""),
+		OIDCClientID:     getEnv("OIDC_CLIENT_ID", "watermap"),
+		OIDCClientSecret: getEnv("OIDC_CLIENT_SECRET", ""),
+		OIDCRedirectURL:  getEnv("OIDC_REDIRECT_URL", "http://localhost:5000/api/auth/oidc/callback"),
+		OIDCRoleClaim:    getEnv("OIDC_ROLE_CLAIM", "groups"),
+		OIDCRoleMapping:  getEnv("OIDC_ROLE_MAPPING", ""),
+		OIDCSyncRoles:    getEnv("OIDC_SYNC_ROLES", "false") == "true",
+
+		SimplifyCacheSize: simplifyCacheSize,
+		StatsCacheTTL:     getDuration("STATS_CACHE_TTL", 30*time.Second),
+		EventRetention:    getDuration("EVENT_RETENTION", 24*time.Hour),
+		ReviewClaimTTL:    getDuration("REVIEW_CLAIM_TTL", 2*time.Hour),
+
+		DuplicateLineBuffer:   lineBuffer,
+		DuplicateSpringRadius: springRadius,
+
+		QualityRules:     getEnv("QUALITY_RULES", ""),
+		QualitySweepHour: sweepHour,
+
+		BlobDriver:        getEnv("BLOB_DRIVER", "local"),
+		BlobDir:           getEnv("BLOB_DIR", "uploads"),
+		S3Endpoint:        getEnv("S3_ENDPOINT", ""),
+		S3Region:          getEnv("S3_REGION", "us-east-1"),
+		S3Bucket:          getEnv("S3_BUCKET", ""),
+		S3AccessKey:       getEnv("S3_ACCESS_KEY", ""),
+		S3SecretKey:       getEnv("S3_SECRET_KEY", ""),
+		AttachmentMaxSize: attachmentMaxSize,
+		ThumbnailSize:     thumbnailSize,
+
+		WebhookPollInterval: getDuration("WEBHOOK_POLL_INTERVAL", 5*time.Second),
+
+		MailDriver:   getEnv("MAIL_DRIVER", "log"),
+		MailFrom:     getEnv("MAIL_FROM", "WaterMap <no-reply@watermap.local>"),
+		MailDir:      getEnv("MAIL_DIR", "mail"),
+		SMTPHost:     getEnv("SMTP_HOST", ""),
+		SMTPPort:     smtpPort,
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+
+		RateLimitBackend:     getEnv("RATE_LIMIT_BACKEND", "memory"),
+		RateLimitAuth:        getEnv("RATE_LIMIT_AUTH", "20/1m"),
+		RateLimitAuthAccount: getEnv("RATE_LIMIT_AUTH_ACCOUNT", "10/15m"),
+		RateLimitPublic:      getEnv("RATE_LIMIT_PUBLIC", "600/1m"),
+		RateLimitAPI:         getEnv("RATE_LIMIT_API", "300/1m"),
+
+		TrustedProxies: getList("TRUSTED_PROXIES"),
 	}
 }
 
@@ -41,3 +189,23 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+// getList splits a comma-separated variable, nil when it is unset
+func getList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func getDuration(key string, fallback time.Duration) time.Duration {
+	if val := os.Getenv(key); val != "" {
+		if d, err := time.ParseDuration(val); err == nil {
+			return d
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,176 @@
+// Package crs reprojects geometries between the coordinate reference systems
+// used by Kazakh survey data and the EPSG:4326 lon/lat used for storage.
+package crs
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/project"
+)
+
+var ErrUnsupportedCRS = errors.New("unsupported coordinate reference system")
+
+// WGS84 is the storage CRS
+const WGS84 = "EPSG:4326"
+
+// CRS is a supported coordinate reference system with projections to and
+// from WGS84 lon/lat
+type CRS struct {
+	Code      string
+	Name      string
+	toWGS84   orb.Projection
+	fromWGS84 orb.Projection
+}
+
+// IsWGS84 reports whether the CRS is the storage CRS
+func (c *CRS) IsWGS84() bool {
+	return c.Code == WGS84
+}
+
+var registry = map[string]*CRS{}
+
+func register(c *CRS) {
+	registry[c.Code] = c
+}
+
+func init() {
+	identity := func(p orb.Point) orb.Point { return p }
+
+	register(&CRS{
+		Code:      WGS84,
+		Name:      "WGS 84",
+		toWGS84:   identity,
+		fromWGS84: identity,
+	})
+
+	register(&CRS{
+		Code:      "EPSG:3857",
+		Name:      "WGS 84 / Pseudo-Mercator",
+		toWGS84:   project.Mercator.ToWGS84,
+		fromWGS84: project.WGS84.ToMercator,
+	})
+
+	register(&CRS{
+		Code: "EPSG:4284",
+		Name: "Pulkovo 1942",
+		toWGS84: func(p orb.Point) orb.Point {
+			lon, lat := shiftDatum(p[0], p[1], krassovskyEllipsoid, wgs84Ellipsoid, pulkovoToWGS84)
+			return orb.Point{lon, lat}
+		},
+		fromWGS84: func(p orb.Point) orb.Point {
+			lon, lat := shiftDatum(p[0], p[1], wgs84Ellipsoid, krassovskyEllipsoid, pulkovoToWGS84.inverse())
+			return orb.Point{lon, lat}
+		},
+	})
+
+	// UTM zones 39N-45N cover Kazakhstan from the Caspian to the Altai
+	for zone := 39; zone <= 45; zone++ {
+		tm := newTransverseMercator(wgs84Ellipsoid, float64(zone*6-183), 0.9996, 500000, 0)
+		register(&CRS{
+			Code:      "EPSG:" + strconv.Itoa(32600+zone),
+			Name:      fmt.Sprintf("WGS 84 / UTM zone %dN", zone),
+			toWGS84:   tmToWGS84(tm, nil),
+			fromWGS84: tmFromWGS84(tm, nil),
+		})
+	}
+
+	// Pulkovo 1942 / Gauss-Kruger zones 8-15 (6° zones, zone number prefixed
+	// to the false easting)
+	for zone := 8; zone <= 15; zone++ {
+		tm := newTransverseMercator(krassovskyEllipsoid, float64(zone*6-3), 1, float64(zone)*1e6+500000, 0)
+		datum := pulkovoToWGS84
+		register(&CRS{
+			Code:      "EPSG:" + strconv.Itoa(28400+zone),
+			Name:      fmt.Sprintf("Pulkovo 1942 / Gauss-Kruger zone %d", zone),
+			toWGS84:   tmToWGS84(tm, &datum),
+			fromWGS84: tmFromWGS84(tm, &datum),
+		})
+	}
+}
+
+func tmToWGS84(tm *transverseMercator, datum *helmert) orb.Projection {
+	return func(p orb.Point) orb.Point {
+		lon, lat := tm.inverse(p[0], p[1])
+		if datum != nil {
+			lon, lat = shiftDatum(lon, lat, tm.ell, wgs84Ellipsoid, *datum)
+		}
+		return orb.Point{lon, lat}
+	}
+}
+
+func tmFromWGS84(tm *transverseMercator, datum *helmert) orb.Projection {
+	return func(p orb.Point) orb.Point {
+		lon, lat := p[0], p[1]
+		if datum != nil {
+			lon, lat = shiftDatum(lon, lat, wgs84Ellipsoid, tm.ell, datum.inverse())
+		}
+		x, y := tm.forward(lon, lat)
+		return orb.Point{x, y}
+	}
+}
+
+// Normalize turns the accepted spellings of a CRS identifier
+// ("EPSG:32642", "epsg:32642", "32642", "urn:ogc:def:crs:EPSG::32642",
+// "http://www.opengis.net/def/crs/EPSG/0/32642", "CRS84") into "EPSG:<code>"
+func Normalize(code string) string {
+	c := strings.TrimSpace(code)
+	upper := strings.ToUpper(c)
+
+	switch {
+	case upper == "CRS84" || strings.HasSuffix(upper, "OGC:1.3:CRS84") || strings.HasSuffix(upper, "/OGC/1.3/CRS84"):
+		return WGS84
+	case strings.HasPrefix(upper, "URN:OGC:DEF:CRS:EPSG:"):
+		parts := strings.Split(c, ":")
+		return "EPSG:" + parts[len(parts)-1]
+	case strings.Contains(upper, "/DEF/CRS/EPSG/"):
+		parts := strings.Split(c, "/")
+		return "EPSG:" + parts[len(parts)-1]
+	case strings.HasPrefix(upper, "EPSG:"):
+		return "EPSG:" + c[len("EPSG:"):]
+	}
+
+	if _, err := strconv.Atoi(c); err == nil {
+		return "EPSG:" + c
+	}
+	return upper
+}
+
+// Lookup returns a supported CRS by any of the spellings accepted by Normalize
+func Lookup(code string) (*CRS, error) {
+	c, ok := registry[Normalize(code)]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedCRS, code)
+	}
+	return c, nil
+}
+
+// Supported lists the codes of all supported coordinate reference systems
+func Supported() []string {
+	codes := make([]string, 0, len(registry))
+	for code := range registry {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// ToWGS84 returns a copy of g reprojected from c to EPSG:4326
+func ToWGS84(g orb.Geometry, c *CRS) orb.Geometry {
+	if c.IsWGS84() {
+		return g
+	}
+	return project.Geometry(orb.Clone(g), c.toWGS84)
+}
+
+// FromWGS84 returns a copy of g reprojected from EPSG:4326 to c
+func FromWGS84(g orb.Geometry, c *CRS) orb.Geometry {
+	if c.IsWGS84() {
+		return g
+	}
+	return project.Geometry(orb.Clone(g), c.fromWGS84)
+}
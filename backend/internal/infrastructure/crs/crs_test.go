@@ -0,0 +1,127 @@
+package crs
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/paulmach/orb"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"EPSG:32642", "EPSG:32642"},
+		{"epsg:32642", "EPSG:32642"},
+		{" 32642 ", "EPSG:32642"},
+		{"urn:ogc:def:crs:EPSG::32642", "EPSG:32642"},
+		{"urn:ogc:def:crs:EPSG:9.9.1:28413", "EPSG:28413"},
+		{"http://www.opengis.net/def/crs/EPSG/0/4284", "EPSG:4284"},
+		{"CRS84", WGS84},
+		{"urn:ogc:def:crs:OGC:1.3:CRS84", WGS84},
+		{"http://www.opengis.net/def/crs/OGC/1.3/CRS84", WGS84},
+		{"local", "LOCAL"},
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.in); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	for _, code := range []string{"EPSG:4326", "EPSG:3857", "EPSG:4284", "EPSG:32639", "EPSG:32645", "EPSG:28408", "EPSG:28415"} {
+		if _, err := Lookup(code); err != nil {
+			t.Errorf("Lookup(%s): %v", code, err)
+		}
+	}
+	for _, code := range []string{"EPSG:32646", "EPSG:28407", "EPSG:2154", ""} {
+		if _, err := Lookup(code); !errors.Is(err, ErrUnsupportedCRS) {
+			t.Errorf("Lookup(%q) = %v, want ErrUnsupportedCRS", code, err)
+		}
+	}
+}
+
+// TestKnownPoints checks projections against published values
+func TestKnownPoints(t *testing.T) {
+	tests := []struct {
+		code string
+		in   orb.Point
+		want orb.Point
+		tol  float64
+	}{
+		// The origin of a UTM zone is its central meridian on the equator
+		{"EPSG:32643", orb.Point{75, 0}, orb.Point{500000, 0}, 1e-6},
+		// 0.9996 × the WGS 84 meridian arc to 45°N, 4 984 944.378 m
+		{"EPSG:32643", orb.Point{75, 45}, orb.Point{500000, 4982950.400}, 1e-3},
+		{"EPSG:3857", orb.Point{180, 0}, orb.Point{20037508.342789244, 0}, 1e-6},
+		{"EPSG:4326", orb.Point{76.95, 43.25}, orb.Point{76.95, 43.25}, 0},
+	}
+	for _, tt := range tests {
+		c, err := Lookup(tt.code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := FromWGS84(tt.in, c).(orb.Point)
+		if math.Abs(got[0]-tt.want[0]) > tt.tol || math.Abs(got[1]-tt.want[1]) > tt.tol {
+			t.Errorf("%s: FromWGS84(%v) = %v, want %v", tt.code, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	// Places across Kazakhstan, each in the zone that covers it
+	places := []struct {
+		name        string
+		lonLat      orb.Point
+		utm, kruger string
+	}{
+		{"Aktau", orb.Point{51.17, 43.65}, "EPSG:32639", "EPSG:28409"},
+		{"Aral", orb.Point{61.67, 46.8}, "EPSG:32641", "EPSG:28411"},
+		{"Astana", orb.Point{71.43, 51.13}, "EPSG:32642", "EPSG:28412"},
+		{"Lake Balkhash", orb.Point{74.98, 46.85}, "EPSG:32643", "EPSG:28413"},
+		{"Almaty", orb.Point{76.95, 43.25}, "EPSG:32643", "EPSG:28413"},
+		{"Zaysan", orb.Point{84.0, 47.5}, "EPSG:32644", "EPSG:28414"},
+	}
+	// Within a millimetre on the ground
+	const tolDegrees = 1e-8
+
+	for _, p := range places {
+		for _, code := range []string{WGS84, "EPSG:3857", "EPSG:4284", p.utm, p.kruger} {
+			c, err := Lookup(code)
+			if err != nil {
+				t.Fatal(err)
+			}
+			back := ToWGS84(FromWGS84(p.lonLat, c), c).(orb.Point)
+			if math.Abs(back[0]-p.lonLat[0]) > tolDegrees || math.Abs(back[1]-p.lonLat[1]) > tolDegrees {
+				t.Errorf("%s via %s: %v came back as %v", p.name, code, p.lonLat, back)
+			}
+		}
+	}
+}
+
+func TestRoundTripGeometry(t *testing.T) {
+	c, err := Lookup("EPSG:28413")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ring := orb.Ring{{76.9, 43.2}, {77.0, 43.2}, {77.0, 43.3}, {76.9, 43.3}, {76.9, 43.2}}
+	polygon := orb.Polygon{ring}
+
+	projected := FromWGS84(polygon, c).(orb.Polygon)
+	if polygon[0][1] != (orb.Point{77.0, 43.2}) {
+		t.Fatal("FromWGS84 changed its input")
+	}
+	// Gauss-Kruger eastings carry the zone number
+	if x := projected[0][0][0]; x < 13_000_000 || x > 14_000_000 {
+		t.Errorf("easting %f lacks the zone prefix", x)
+	}
+
+	back := ToWGS84(projected, c).(orb.Polygon)
+	for i, p := range back[0] {
+		if math.Abs(p[0]-ring[i][0]) > 1e-8 || math.Abs(p[1]-ring[i][1]) > 1e-8 {
+			t.Errorf("vertex %d: %v came back as %v", i, ring[i], p)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package crs
+
+import "math"
+
+// ellipsoid describes a reference ellipsoid by semi-major axis and flattening
+type ellipsoid struct {
+	a float64
+	f float64
+}
+
+var (
+	wgs84Ellipsoid      = ellipsoid{a: 6378137.0, f: 1 / 298.257223563}
+	krassovskyEllipsoid = ellipsoid{a: 6378245.0, f: 1 / 298.3}
+)
+
+func (e ellipsoid) e2() float64 {
+	return e.f * (2 - e.f)
+}
+
+// helmert holds the seven parameters of a position vector transformation to
+// WGS84: translations in metres, rotations in arc seconds, scale in ppm
+type helmert struct {
+	tx, ty, tz float64
+	rx, ry, rz float64
+	ds         float64
+}
+
+// pulkovoToWGS84 is the EPSG:1267-style parameter set used by PROJ for
+// Pulkovo 1942 (towgs84=23.92,-141.27,-80.9,0,0.35,0.82,-0.12)
+var pulkovoToWGS84 = helmert{
+	tx: 23.92, ty: -141.27, tz: -80.9,
+	rx: 0, ry: 0.35, rz: 0.82,
+	ds: -0.12,
+}
+
+const arcSecond = math.Pi / (180 * 3600)
+
+func (h helmert) inverse() helmert {
+	return helmert{
+		tx: -h.tx, ty: -h.ty, tz: -h.tz,
+		rx: -h.rx, ry: -h.ry, rz: -h.rz,
+		ds: -h.ds,
+	}
+}
+
+func (h helmert) apply(x, y, z float64) (float64, float64, float64) {
+	s := 1 + h.ds*1e-6
+	rx, ry, rz := h.rx*arcSecond, h.ry*arcSecond, h.rz*arcSecond
+
+	return h.tx + s*(x-rz*y+ry*z),
+		h.ty + s*(rz*x+y-rx*z),
+		h.tz + s*(-ry*x+rx*y+z)
+}
+
+// toGeocentric converts geographic degrees (height 0) to ECEF metres
+func (e ellipsoid) toGeocentric(lon, lat float64) (float64, float64, float64) {
+	phi, lam := lat*math.Pi/180, lon*math.Pi/180
+	e2 := e.e2()
+	n := e.a / math.Sqrt(1-e2*math.Sin(phi)*math.Sin(phi))
+
+	return n * math.Cos(phi) * math.Cos(lam),
+		n * math.Cos(phi) * math.Sin(lam),
+		n * (1 - e2) * math.Sin(phi)
+}
+
+// toGeographic converts ECEF metres back to geographic degrees
+func (e ellipsoid) toGeographic(x, y, z float64) (float64, float64) {
+	e2 := e.e2()
+	p := math.Hypot(x, y)
+	lon := math.Atan2(y, x)
+
+	// Iterate on latitude; converges to sub-millimetre in a few steps
+	lat := math.Atan2(z, p*(1-e2))
+	for i := 0; i < 5; i++ {
+		n := e.a / math.Sqrt(1-e2*math.Sin(lat)*math.Sin(lat))
+		h := p/math.Cos(lat) - n
+		lat = math.Atan2(z, p*(1-e2*n/(n+h)))
+	}
+
+	return lon * 180 / math.Pi, lat * 180 / math.Pi
+}
+
+// shiftDatum moves a geographic coordinate from one ellipsoid to another
+func shiftDatum(lon, lat float64, from, to ellipsoid, h helmert) (float64, float64) {
+	x, y, z := from.toGeocentric(lon, lat)
+	x, y, z = h.apply(x, y, z)
+	return to.toGeographic(x, y, z)
+}
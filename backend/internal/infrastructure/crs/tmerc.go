@@ -0,0 +1,90 @@
+package crs
+
+import "math"
+
+// transverseMercator implements the Krüger series formulation of the
+// transverse Mercator projection, accurate to well under a millimetre
+// within a 6° zone.
+type transverseMercator struct {
+	ell    ellipsoid
+	lon0   float64 // central meridian, degrees
+	k0     float64
+	falseE float64
+	falseN float64
+	aHat   float64
+	e      float64
+	alpha  [3]float64
+	beta   [3]float64
+	delta  [3]float64
+}
+
+func newTransverseMercator(ell ellipsoid, lon0, k0, falseE, falseN float64) *transverseMercator {
+	n := ell.f / (2 - ell.f)
+	n2, n3 := n*n, n*n*n
+
+	return &transverseMercator{
+		ell:    ell,
+		lon0:   lon0,
+		k0:     k0,
+		falseE: falseE,
+		falseN: falseN,
+		aHat:   ell.a / (1 + n) * (1 + n2/4 + n2*n2/64),
+		e:      math.Sqrt(ell.e2()),
+		alpha: [3]float64{
+			n/2 - 2*n2/3 + 5*n3/16,
+			13*n2/48 - 3*n3/5,
+			61 * n3 / 240,
+		},
+		beta: [3]float64{
+			n/2 - 2*n2/3 + 37*n3/96,
+			n2/48 + n3/15,
+			17 * n3 / 480,
+		},
+		delta: [3]float64{
+			2*n - 2*n2/3 - 2*n3,
+			7*n2/3 - 8*n3/5,
+			56 * n3 / 15,
+		},
+	}
+}
+
+// forward projects geographic degrees to easting/northing metres
+func (tm *transverseMercator) forward(lon, lat float64) (float64, float64) {
+	phi := lat * math.Pi / 180
+	dLam := (lon - tm.lon0) * math.Pi / 180
+
+	t := math.Sinh(math.Atanh(math.Sin(phi)) - tm.e*math.Atanh(tm.e*math.Sin(phi)))
+	xiP := math.Atan2(t, math.Cos(dLam))
+	etaP := math.Atanh(math.Sin(dLam) / math.Sqrt(1+t*t))
+
+	xi, eta := xiP, etaP
+	for j := 0; j < 3; j++ {
+		k := float64(2 * (j + 1))
+		xi += tm.alpha[j] * math.Sin(k*xiP) * math.Cosh(k*etaP)
+		eta += tm.alpha[j] * math.Cos(k*xiP) * math.Sinh(k*etaP)
+	}
+
+	return tm.falseE + tm.k0*tm.aHat*eta, tm.falseN + tm.k0*tm.aHat*xi
+}
+
+// inverse converts easting/northing metres back to geographic degrees
+func (tm *transverseMercator) inverse(x, y float64) (float64, float64) {
+	xi := (y - tm.falseN) / (tm.k0 * tm.aHat)
+	eta := (x - tm.falseE) / (tm.k0 * tm.aHat)
+
+	xiP, etaP := xi, eta
+	for j := 0; j < 3; j++ {
+		k := float64(2 * (j + 1))
+		xiP -= tm.beta[j] * math.Sin(k*xi) * math.Cosh(k*eta)
+		etaP -= tm.beta[j] * math.Cos(k*xi) * math.Sinh(k*eta)
+	}
+
+	chi := math.Asin(math.Sin(xiP) / math.Cosh(etaP))
+	phi := chi
+	for j := 0; j < 3; j++ {
+		phi += tm.delta[j] * math.Sin(float64(2*(j+1))*chi)
+	}
+
+	lon := tm.lon0 + math.Atan2(math.Sinh(etaP), math.Cos(xiP))*180/math.Pi
+	return lon, phi * 180 / math.Pi
+}
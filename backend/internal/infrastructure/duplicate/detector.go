@@ -0,0 +1,196 @@
+package duplicate
+
+import (
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geo"
+
+	"watermap/internal/domain/entity"
+	"watermap/internal/infrastructure/geometry"
+)
+
+const (
+	// spatialWeight is the share of the score given to geometry; names
+	// make up the rest
+	spatialWeight = 0.7
+
+	// A candidate is reported when its geometry alone is convincing, or
+	// when geometry and name together reach minScore
+	strongSpatial = 0.6
+	minScore      = 0.4
+
+	// metresPerDegree is the length of a degree of latitude on the mean
+	// Earth radius, the smaller of the two that springs and lines are
+	// measured on, so that search areas err on the wide side
+	metresPerDegree = 6371008.8 * math.Pi / 180
+)
+
+// Detector finds objects that likely describe the same water body as a
+// submission, comparing polygons by intersection over union, lines by the
+// share running within LineBuffer metres of each other and springs by
+// distance, each combined with the similarity of their names
+type Detector struct {
+	lineBuffer   float64
+	springRadius float64
+}
+
+func NewDetector(lineBuffer, springRadius float64) *Detector {
+	return &Detector{lineBuffer: lineBuffer, springRadius: springRadius}
+}
+
+// SearchArea returns the bounds of the object padded by the distance at
+// which Find still reports a match: the spring radius around a point and
+// the line buffer around anything else. Candidates outside it cannot match.
+func (d *Detector) SearchArea(obj *entity.WaterObject) (entity.Extent, error) {
+	geom, err := geometry.ToOrb(obj.Geometry)
+	if err != nil {
+		return entity.Extent{}, err
+	}
+
+	pad := d.lineBuffer
+	if _, ok := geom.(orb.Point); ok {
+		pad = d.springRadius
+	}
+
+	// A degree of longitude shrinks towards the poles; take its length at
+	// the latitude furthest from the equator
+	b := geom.Bound()
+	lat := math.Max(math.Abs(b.Min[1]), math.Abs(b.Max[1]))
+	padLat := pad / metresPerDegree
+	padLon := math.Min(padLat/math.Cos(lat*math.Pi/180), 180)
+	return entity.Extent{
+		MinLon: b.Min[0] - padLon,
+		MinLat: b.Min[1] - padLat,
+		MaxLon: b.Max[0] + padLon,
+		MaxLat: b.Max[1] + padLat,
+	}, nil
+}
+
+// Find compares the object with the candidates and returns the likely
+// duplicates, best first. Versions of the object itself are skipped.
+func (d *Detector) Find(obj *entity.WaterObject, candidates []*entity.WaterObject) []*entity.DuplicateMatch {
+	geom, err := geometry.ToOrb(obj.Geometry)
+	if err != nil {
+		return nil
+	}
+
+	var matches []*entity.DuplicateMatch
+	for _, candidate := range candidates {
+		if candidate.CanonicalID == obj.CanonicalID {
+			continue
+		}
+		other, err := geometry.ToOrb(candidate.Geometry)
+		if err != nil {
+			log.Printf("duplicate check: object %d: %v", candidate.ID, err)
+			continue
+		}
+		if m := d.compare(obj, geom, candidate, other); m != nil {
+			matches = append(matches, m)
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	return matches
+}
+
+func (d *Detector) compare(obj *entity.WaterObject, geom orb.Geometry, candidate *entity.WaterObject, other orb.Geometry) *entity.DuplicateMatch {
+	m := &entity.DuplicateMatch{
+		ObjectID:    obj.ID,
+		CandidateID: candidate.ID,
+		CanonicalID: candidate.CanonicalID,
+		NameKZ:      candidate.NameKZ,
+		ObjectType:  candidate.ObjectType,
+		Status:      candidate.Status,
+	}
+
+	switch g := geom.(type) {
+	case orb.Polygon, orb.MultiPolygon:
+		m.Method = entity.DuplicatePolygonOverlap
+		m.Spatial = geometry.PolygonIoU(g, other)
+	case orb.LineString, orb.MultiLineString:
+		m.Method = entity.DuplicateLineOverlap
+		m.Spatial = geometry.LineOverlap(g, other, d.lineBuffer)
+	case orb.Point:
+		p, ok := other.(orb.Point)
+		if !ok {
+			return nil
+		}
+		m.Method = entity.DuplicateProximity
+		distance := geo.DistanceHaversine(g, p)
+		if distance < d.springRadius {
+			m.Spatial = 1 - distance/d.springRadius
+		}
+		m.DistanceM = &distance
+	default:
+		return nil
+	}
+
+	m.NameSimilarity = nameSimilarity(obj, candidate)
+	m.Score = spatialWeight*m.Spatial + (1-spatialWeight)*m.NameSimilarity
+
+	if m.Spatial >= strongSpatial || (m.Spatial > 0 && m.Score >= minScore) {
+		return m
+	}
+	return nil
+}
+
+// nameSimilarity returns the closest match between any names of the two
+// objects, as one minus the edit distance relative to the longer name
+func nameSimilarity(a, b *entity.WaterObject) float64 {
+	best := 0.0
+	for _, x := range names(a) {
+		for _, y := range names(b) {
+			longer := len(x)
+			if len(y) > longer {
+				longer = len(y)
+			}
+			if s := 1 - float64(levenshtein(x, y))/float64(longer); s > best {
+				best = s
+			}
+		}
+	}
+	return best
+}
+
+// names returns the object's names lower-cased, with punctuation dropped
+// and spaces collapsed
+func names(obj *entity.WaterObject) [][]rune {
+	var out [][]rune
+	for _, name := range []*string{&obj.NameKZ, obj.NameRU, obj.NameEN} {
+		if name == nil {
+			continue
+		}
+		normalized := strings.Join(strings.FieldsFunc(strings.ToLower(*name), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}), " ")
+		if normalized != "" {
+			out = append(out, []rune(normalized))
+		}
+	}
+	return out
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,204 @@
+package duplicate
+
+import (
+	"math"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geo"
+
+	"watermap/internal/domain/entity"
+	"watermap/internal/infrastructure/geometry"
+)
+
+func object(t *testing.T, id int64, name string, g orb.Geometry) *entity.WaterObject {
+	t.Helper()
+	geom, err := geometry.FromOrb(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &entity.WaterObject{ID: id, CanonicalID: uuid.New(), NameKZ: name, Geometry: geom}
+}
+
+func square(x, y, size float64) orb.Polygon {
+	return orb.Polygon{{{x, y}, {x + size, y}, {x + size, y + size}, {x, y + size}, {x, y}}}
+}
+
+// north moves a point by metres along its meridian
+func north(p orb.Point, metres float64) orb.Point {
+	return orb.Point{p[0], p[1] + metres/(orb.EarthRadius*math.Pi/180)}
+}
+
+func TestFindPolygons(t *testing.T) {
+	d := NewDetector(50, 200)
+	lake := object(t, 1, "Улкен Алматы колі", square(76.98, 43.05, 0.01))
+
+	sameLake := object(t, 2, "Үлкен Алматы көлі", square(76.98, 43.05, 0.01))
+	version := object(t, 3, "Улкен Алматы колі", square(76.98, 43.05, 0.01))
+	version.CanonicalID = lake.CanonicalID
+	neighbour := object(t, 4, "Улкен Алматы колі", square(77.0, 43.05, 0.01))
+	overlapping := object(t, 5, "Бозсай", square(76.985, 43.05, 0.01))
+
+	got := d.Find(lake, []*entity.WaterObject{overlapping, neighbour, version, sameLake})
+	if len(got) != 1 {
+		t.Fatalf("Find returned %d matches, want the same lake only", len(got))
+	}
+	m := got[0]
+	if m.CandidateID != 2 || m.Method != entity.DuplicatePolygonOverlap {
+		t.Errorf("match = object %d by %s, want object 2 by %s", m.CandidateID, m.Method, entity.DuplicatePolygonOverlap)
+	}
+	if m.Spatial < 0.99 {
+		t.Errorf("spatial = %.3f, want about 1", m.Spatial)
+	}
+	if m.NameSimilarity >= 1 || m.NameSimilarity < 0.7 {
+		t.Errorf("name similarity = %.3f, want close but not equal", m.NameSimilarity)
+	}
+}
+
+func TestFindLines(t *testing.T) {
+	d := NewDetector(50, 200)
+	river := object(t, 1, "Каскелен", orb.LineString{{76.6, 43.2}, {76.65, 43.2}, {76.7, 43.2}})
+
+	retraced := object(t, 2, "Qaskelen", orb.LineString{north(orb.Point{76.6, 43.2}, 15), north(orb.Point{76.7, 43.2}, 15)})
+	tributary := object(t, 3, "Кескелен", orb.LineString{{76.65, 43.25}, {76.65, 43.2}})
+
+	got := d.Find(river, []*entity.WaterObject{tributary, retraced})
+	if len(got) != 1 || got[0].CandidateID != 2 {
+		t.Fatalf("Find = %v, want the retraced river only", ids(got))
+	}
+	if got[0].Method != entity.DuplicateLineOverlap {
+		t.Errorf("method = %s, want %s", got[0].Method, entity.DuplicateLineOverlap)
+	}
+
+	// A tributary does not become a duplicate of its river either way round
+	if got := d.Find(tributary, []*entity.WaterObject{river}); len(got) != 0 {
+		t.Errorf("tributary against its river: Find = %v, want none", ids(got))
+	}
+}
+
+func TestFindSprings(t *testing.T) {
+	d := NewDetector(50, 200)
+	at := orb.Point{77.1, 43.3}
+	spring := object(t, 1, "Тұма", at)
+
+	tests := []struct {
+		name     string
+		distance float64
+		found    bool
+	}{
+		{"same spot", 0, true},
+		{"inside the radius", 50, true},
+		{"at the edge of the radius", 190, false},
+		{"outside the radius", 300, false},
+	}
+	for _, tt := range tests {
+		candidate := object(t, 2, "Бұлақ", north(at, tt.distance))
+		got := d.Find(spring, []*entity.WaterObject{candidate})
+		if (len(got) == 1) != tt.found {
+			t.Errorf("%s: Find = %v, want found %v", tt.name, ids(got), tt.found)
+			continue
+		}
+		if !tt.found {
+			continue
+		}
+		m := got[0]
+		if m.Method != entity.DuplicateProximity || m.DistanceM == nil {
+			t.Fatalf("%s: match = %+v, want a proximity match with a distance", tt.name, m)
+		}
+		if math.Abs(*m.DistanceM-tt.distance) > 0.5 {
+			t.Errorf("%s: distance = %.1f m, want %.0f", tt.name, *m.DistanceM, tt.distance)
+		}
+		if want := 1 - tt.distance/200; math.Abs(m.Spatial-want) > 0.01 {
+			t.Errorf("%s: spatial = %.3f, want %.3f", tt.name, m.Spatial, want)
+		}
+	}
+
+	// A spring is only compared with other points
+	pond := object(t, 3, "Тұма", square(77.1, 43.3, 0.001))
+	if got := d.Find(spring, []*entity.WaterObject{pond}); len(got) != 0 {
+		t.Errorf("spring against a polygon: Find = %v, want none", ids(got))
+	}
+}
+
+func TestFindOrdersByScore(t *testing.T) {
+	d := NewDetector(50, 200)
+	at := orb.Point{77.1, 43.3}
+	spring := object(t, 1, "Тұма", at)
+	far := object(t, 2, "Тұма", north(at, 100))
+	near := object(t, 3, "Тұма", north(at, 10))
+
+	got := d.Find(spring, []*entity.WaterObject{far, near})
+	if len(got) != 2 || got[0].CandidateID != 3 || got[1].CandidateID != 2 {
+		t.Errorf("Find = %v, want [3 2]", ids(got))
+	}
+}
+
+func TestSearchArea(t *testing.T) {
+	d := NewDetector(50, 200)
+	at := orb.Point{77.1, 43.3}
+
+	tests := []struct {
+		name    string
+		obj     *entity.WaterObject
+		padding float64
+	}{
+		{"spring", object(t, 1, "Тұма", at), 200},
+		{"river", object(t, 2, "Іле", orb.LineString{at, {77.2, 43.35}}), 50},
+		{"lake", object(t, 3, "Сорбұлақ", square(77.1, 43.3, 0.05)), 50},
+	}
+	for _, tt := range tests {
+		area, err := d.SearchArea(tt.obj)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		g, _ := geometry.ToOrb(tt.obj.Geometry)
+		b := g.Bound()
+		corner := orb.Point{area.MaxLon, area.MaxLat}
+		if got := geo.DistanceHaversine(orb.Point{b.Max[0], area.MaxLat}, corner); math.Abs(got-tt.padding) > tt.padding/100 {
+			t.Errorf("%s: pads %.1f m east, want %.0f", tt.name, got, tt.padding)
+		}
+		if got := geo.DistanceHaversine(orb.Point{area.MinLon, b.Min[1]}, orb.Point{area.MinLon, area.MinLat}); math.Abs(got-tt.padding) > tt.padding/100 {
+			t.Errorf("%s: pads %.1f m south, want %.0f", tt.name, got, tt.padding)
+		}
+	}
+
+	// Anything Find would report lies inside the area
+	spring := object(t, 1, "Тұма", at)
+	area, _ := d.SearchArea(spring)
+	for _, bearing := range []float64{0, 90, 180, 270} {
+		p := geo.PointAtBearingAndDistance(at, bearing, 199)
+		if p[0] < area.MinLon || p[0] > area.MaxLon || p[1] < area.MinLat || p[1] > area.MaxLat {
+			t.Errorf("point 199 m at bearing %.0f lies outside the search area", bearing)
+		}
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"", "абай", 4},
+		{"абай", "", 4},
+		{"kitten", "sitting", 3},
+		{"көл", "кол", 1},
+		{"есіл", "есил", 1},
+		{"іле", "іле", 0},
+		{"balkhash", "balqash", 2},
+	}
+	for _, tt := range tests {
+		if got := levenshtein([]rune(tt.a), []rune(tt.b)); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func ids(matches []*entity.DuplicateMatch) []int64 {
+	var out []int64
+	for _, m := range matches {
+		out = append(out, m.CandidateID)
+	}
+	return out
+}
@@ -0,0 +1,138 @@
+// Package eventbus fans workflow events out to the streams connected to
+// this instance. Events travel through Postgres LISTEN/NOTIFY, so every
+// instance delivers every event whichever instance published it.
+package eventbus
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"watermap/internal/domain/entity"
+	"watermap/internal/domain/repository"
+)
+
+// reconnectDelay is how long Run waits before listening again after the
+// listener connection fails
+const reconnectDelay = 5 * time.Second
+
+// Subscription receives events until it is closed. The bus closes C when
+// the subscriber falls too far behind or when events may have been missed;
+// the client then resumes from its last event ID.
+type Subscription struct {
+	C <-chan *entity.Event
+	c chan *entity.Event
+}
+
+type Bus struct {
+	repo repository.EventRepository
+
+	mu   sync.Mutex
+	subs map[*Subscription]struct{}
+}
+
+func New(repo repository.EventRepository) *Bus {
+	return &Bus{repo: repo, subs: map[*Subscription]struct{}{}}
+}
+
+// Publish stores the event; it reaches subscribers through the listener
+func (b *Bus) Publish(ctx context.Context, event *entity.Event) error {
+	return b.repo.Append(ctx, event)
+}
+
+// Since returns stored events after the ID, for resuming a stream. ok is
+// false when events after the ID have already been pruned.
+func (b *Bus) Since(ctx context.Context, afterID int64, limit int) (events []*entity.Event, ok bool, err error) {
+	first, err := b.repo.FirstID(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	if first > afterID+1 {
+		return nil, false, nil
+	}
+
+	events, err = b.repo.ListSince(ctx, afterID, limit)
+	return events, true, err
+}
+
+func (b *Bus) Subscribe(buffer int) *Subscription {
+	c := make(chan *entity.Event, buffer)
+	sub := &Subscription{C: c, c: c}
+
+	b.mu.Lock()
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+	return sub
+}
+
+func (b *Bus) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subs[sub]; ok {
+		delete(b.subs, sub)
+		close(sub.c)
+	}
+}
+
+func (b *Bus) broadcast(event *entity.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subs {
+		select {
+		case sub.c <- event:
+		default:
+			delete(b.subs, sub)
+			close(sub.c)
+		}
+	}
+}
+
+// disconnectAll closes every subscription, so clients replay what the bus
+// may have missed
+func (b *Bus) disconnectAll() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subs {
+		delete(b.subs, sub)
+		close(sub.c)
+	}
+}
+
+// Run listens for events until ctx is done, reconnecting after failures
+func (b *Bus) Run(ctx context.Context) {
+	for {
+		err := b.repo.Listen(ctx, b.broadcast)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("event listener: %v", err)
+		b.disconnectAll()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(reconnectDelay):
+		}
+	}
+}
+
+// Prune deletes stored events older than retention every interval
+func (b *Bus) Prune(ctx context.Context, interval, retention time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := b.repo.DeleteBefore(ctx, time.Now().Add(-retention)); err != nil {
+				log.Printf("prune events: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package geometry
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+
+	"watermap/internal/domain/entity"
+)
+
+// ToOrb decodes an entity geometry into an orb geometry
+func ToOrb(geom entity.Geometry) (orb.Geometry, error) {
+	data, err := json.Marshal(geom)
+	if err != nil {
+		return nil, fmt.Errorf("marshal geometry: %w", err)
+	}
+
+	g, err := geojson.UnmarshalGeometry(data)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal geometry: %w", err)
+	}
+	if g.Geometry() == nil {
+		return nil, fmt.Errorf("empty geometry")
+	}
+	return g.Geometry(), nil
+}
+
+// FromOrb encodes an orb geometry back into an entity geometry
+func FromOrb(g orb.Geometry) (entity.Geometry, error) {
+	data, err := json.Marshal(geojson.NewGeometry(g))
+	if err != nil {
+		return entity.Geometry{}, fmt.Errorf("marshal geometry: %w", err)
+	}
+
+	var geom entity.Geometry
+	if err := json.Unmarshal(data, &geom); err != nil {
+		return entity.Geometry{}, fmt.Errorf("unmarshal geometry: %w", err)
+	}
+	return geom, nil
+}
@@ -0,0 +1,154 @@
+package geometry
+
+import (
+	"math"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/planar"
+	"github.com/paulmach/orb/project"
+)
+
+const (
+	// iouGrid is the number of samples per side used to estimate the area
+	// two polygons share
+	iouGrid = 64
+
+	// lineSamples is the number of points taken along a line when measuring
+	// how much of it runs inside another line's buffer
+	lineSamples = 200
+
+	earthRadiusM = 6371008.8
+)
+
+// PolygonIoU estimates the intersection over union of two polygonal
+// geometries. The shared area is sampled on a grid over the overlap of their
+// bounds, which is accurate to a few percent and needs no polygon clipping.
+func PolygonIoU(a, b orb.Geometry) float64 {
+	ab, bb := a.Bound(), b.Bound()
+	if !ab.Intersects(bb) {
+		return 0
+	}
+
+	overlap := orb.Bound{
+		Min: orb.Point{math.Max(ab.Min[0], bb.Min[0]), math.Max(ab.Min[1], bb.Min[1])},
+		Max: orb.Point{math.Min(ab.Max[0], bb.Max[0]), math.Min(ab.Max[1], bb.Max[1])},
+	}
+	w, h := overlap.Max[0]-overlap.Min[0], overlap.Max[1]-overlap.Min[1]
+	if w <= 0 || h <= 0 {
+		return 0
+	}
+
+	inside := 0
+	for i := 0; i < iouGrid; i++ {
+		for j := 0; j < iouGrid; j++ {
+			p := orb.Point{
+				overlap.Min[0] + w*(float64(i)+0.5)/iouGrid,
+				overlap.Min[1] + h*(float64(j)+0.5)/iouGrid,
+			}
+			if polygonContains(a, p) && polygonContains(b, p) {
+				inside++
+			}
+		}
+	}
+
+	intersection := w * h * float64(inside) / (iouGrid * iouGrid)
+	union := math.Abs(planar.Area(a)) + math.Abs(planar.Area(b)) - intersection
+	if union <= 0 {
+		return 0
+	}
+	return math.Min(intersection/union, 1)
+}
+
+func polygonContains(g orb.Geometry, p orb.Point) bool {
+	switch g := g.(type) {
+	case orb.Polygon:
+		return planar.PolygonContains(g, p)
+	case orb.MultiPolygon:
+		return planar.MultiPolygonContains(g, p)
+	}
+	return false
+}
+
+// LineOverlap returns the share of the lines that run within bufferM metres
+// of each other, taking the smaller of the two directions so that a short
+// tributary is not reported as a duplicate of the river it joins
+func LineOverlap(a, b orb.Geometry, bufferM float64) float64 {
+	if !a.Bound().Intersects(b.Bound().Pad(bufferM / metresPerDegree)) {
+		return 0
+	}
+
+	proj := localProjection(a.Bound().Union(b.Bound()).Center())
+	pa := project.Geometry(orb.Clone(a), proj)
+	pb := project.Geometry(orb.Clone(b), proj)
+
+	return math.Min(within(pa, pb, bufferM), within(pb, pa, bufferM))
+}
+
+// metresPerDegree is the length of a degree of latitude
+const metresPerDegree = earthRadiusM * math.Pi / 180
+
+// localProjection maps degrees to metres on a plane tangent at the origin,
+// which is precise enough over the extent of a single water body
+func localProjection(origin orb.Point) orb.Projection {
+	scale := math.Cos(origin[1] * math.Pi / 180)
+	return func(p orb.Point) orb.Point {
+		return orb.Point{
+			(p[0] - origin[0]) * metresPerDegree * scale,
+			(p[1] - origin[1]) * metresPerDegree,
+		}
+	}
+}
+
+// within returns the share of samples along a that lie within buffer of b
+func within(a, b orb.Geometry, buffer float64) float64 {
+	samples := sampleLine(a, lineSamples)
+	if len(samples) == 0 {
+		return 0
+	}
+
+	near := 0
+	for _, p := range samples {
+		if planar.DistanceFrom(b, p) <= buffer {
+			near++
+		}
+	}
+	return float64(near) / float64(len(samples))
+}
+
+// sampleLine returns about n points spaced evenly along a line geometry
+func sampleLine(g orb.Geometry, n int) []orb.Point {
+	var lines []orb.LineString
+	switch g := g.(type) {
+	case orb.LineString:
+		lines = []orb.LineString{g}
+	case orb.MultiLineString:
+		lines = g
+	default:
+		return nil
+	}
+
+	total := planar.Length(g)
+	if total == 0 {
+		return nil
+	}
+	step := total / float64(n)
+
+	var points []orb.Point
+	for _, ls := range lines {
+		// offset carries the distance to the next sample across segments
+		offset := step / 2
+		for i := 1; i < len(ls); i++ {
+			from, to := ls[i-1], ls[i]
+			length := planar.Distance(from, to)
+			for ; offset <= length; offset += step {
+				t := offset / length
+				points = append(points, orb.Point{
+					from[0] + (to[0]-from[0])*t,
+					from[1] + (to[1]-from[1])*t,
+				})
+			}
+			offset -= length
+		}
+	}
+	return points
+}
@@ -0,0 +1,64 @@
+package geometry
+
+import (
+	"math"
+	"testing"
+
+	"github.com/paulmach/orb"
+)
+
+func square(x, y, size float64) orb.Polygon {
+	return orb.Polygon{{{x, y}, {x + size, y}, {x + size, y + size}, {x, y + size}, {x, y}}}
+}
+
+func TestPolygonIoU(t *testing.T) {
+	a := square(76.9, 43.2, 0.02)
+	tests := []struct {
+		name string
+		b    orb.Geometry
+		want float64
+	}{
+		{"identical", square(76.9, 43.2, 0.02), 1},
+		{"disjoint", square(77.0, 43.2, 0.02), 0},
+		{"touching bounds", square(76.92, 43.2, 0.02), 0},
+		{"half shifted", square(76.91, 43.2, 0.02), 1.0 / 3},
+		{"contained quarter", square(76.9, 43.2, 0.01), 0.25},
+		{"multipolygon", orb.MultiPolygon{a, square(77.0, 43.2, 0.02)}, 0.5},
+	}
+	for _, tt := range tests {
+		if got := PolygonIoU(a, tt.b); math.Abs(got-tt.want) > 0.01 {
+			t.Errorf("%s: PolygonIoU = %.3f, want %.3f", tt.name, got, tt.want)
+		}
+		if got, back := PolygonIoU(a, tt.b), PolygonIoU(tt.b, a); math.Abs(got-back) > 1e-9 {
+			t.Errorf("%s: PolygonIoU not symmetric: %.3f and %.3f", tt.name, got, back)
+		}
+	}
+}
+
+func TestLineOverlap(t *testing.T) {
+	// A river running east for about 8 km, and 20 m north of it a second
+	// trace of the same river
+	river := orb.LineString{{76.0, 43.0}, {76.05, 43.0}, {76.1, 43.0}}
+	metre := 1 / metresPerDegree
+
+	tests := []struct {
+		name     string
+		b        orb.Geometry
+		min, max float64
+	}{
+		{"identical", river, 1, 1},
+		{"traced 20 m apart", orb.LineString{{76.0, 43.0 + 20*metre}, {76.1, 43.0 + 20*metre}}, 0.99, 1},
+		{"traced 100 m apart", orb.LineString{{76.0, 43.0 + 100*metre}, {76.1, 43.0 + 100*metre}}, 0, 0},
+		{"reversed", orb.LineString{{76.1, 43.0}, {76.0, 43.0}}, 1, 1},
+		{"upper half", orb.LineString{{76.05, 43.0}, {76.1, 43.0}}, 0.45, 0.55},
+		{"tributary joining it", orb.LineString{{76.05, 43.05}, {76.05, 43.0}}, 0, 0.02},
+		{"far away", orb.LineString{{77.0, 44.0}, {77.1, 44.0}}, 0, 0},
+		{"multilinestring", orb.MultiLineString{{{76.0, 43.0}, {76.05, 43.0}}, {{76.05, 43.0}, {76.1, 43.0}}}, 0.99, 1},
+	}
+	for _, tt := range tests {
+		got := LineOverlap(river, tt.b, 50)
+		if got < tt.min || got > tt.max {
+			t.Errorf("%s: LineOverlap = %.3f, want %.2f to %.2f", tt.name, got, tt.min, tt.max)
+		}
+	}
+}
@@ -0,0 +1,284 @@
+package geometry
+
+import (
+	"container/list"
+	"errors"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/planar"
+	"github.com/paulmach/orb/simplify"
+
+	"watermap/internal/domain/entity"
+)
+
+var ErrInvalidTolerance = errors.New("invalid simplification tolerance")
+
+type Method string
+
+const (
+	MethodDouglasPeucker Method = "dp"
+	MethodVisvalingam    Method = "vw"
+)
+
+func (m Method) IsValid() bool {
+	return m == MethodDouglasPeucker || m == MethodVisvalingam
+}
+
+const (
+	// MaxZoom is the deepest zoom level accepted by ToleranceForZoom
+	MaxZoom = 22
+
+	// retries is how many times a ring is re-simplified with a halved
+	// tolerance before falling back to the original ring
+	retries = 3
+)
+
+// ToleranceForZoom returns the size of one 256px web map tile pixel in degrees
+// at the given zoom level, which is the coarsest detail still visible
+func ToleranceForZoom(zoom int) float64 {
+	return 360 / (256 * math.Exp2(float64(zoom)))
+}
+
+type cacheKey struct {
+	id        int64
+	version   int
+	tolerance float64
+	method    Method
+}
+
+// Simplifier reduces geometries for overview maps. Simplified variants are
+// cached per object version; published versions are immutable, so entries
+// never need to be invalidated, only evicted.
+type Simplifier struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[cacheKey]*list.Element
+	order    *list.List
+}
+
+type cacheEntry struct {
+	key  cacheKey
+	geom entity.Geometry
+}
+
+func NewSimplifier(capacity int) *Simplifier {
+	return &Simplifier{
+		capacity: capacity,
+		entries:  make(map[cacheKey]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// SimplifyObject returns the object's geometry simplified with the given
+// tolerance (in degrees), using the cache keyed on the object's id and version
+func (s *Simplifier) SimplifyObject(obj *entity.WaterObject, tolerance float64, method Method) (entity.Geometry, error) {
+	if tolerance <= 0 || math.IsNaN(tolerance) || math.IsInf(tolerance, 0) {
+		return entity.Geometry{}, ErrInvalidTolerance
+	}
+
+	key := cacheKey{id: obj.ID, version: obj.Version, tolerance: tolerance, method: method}
+	if geom, ok := s.get(key); ok {
+		return geom, nil
+	}
+
+	geom, err := s.Simplify(obj.Geometry, tolerance, method)
+	if err != nil {
+		return entity.Geometry{}, err
+	}
+
+	s.put(key, geom)
+	return geom, nil
+}
+
+// Simplify reduces the geometry without touching the cache. Points are
+// returned unchanged. Rings that would degenerate or self-intersect are
+// retried with a smaller tolerance and finally kept at full resolution, and
+// holes that would escape their shell are kept as is, so polygon topology is
+// preserved.
+func (s *Simplifier) Simplify(geom entity.Geometry, tolerance float64, method Method) (entity.Geometry, error) {
+	if tolerance <= 0 || math.IsNaN(tolerance) || math.IsInf(tolerance, 0) {
+		return entity.Geometry{}, ErrInvalidTolerance
+	}
+
+	g, err := ToOrb(geom)
+	if err != nil {
+		return entity.Geometry{}, err
+	}
+
+	switch v := g.(type) {
+	case orb.Point, orb.MultiPoint:
+		return geom, nil
+	case orb.LineString:
+		g = simplifyLine(v, tolerance, method)
+	case orb.MultiLineString:
+		out := make(orb.MultiLineString, 0, len(v))
+		for _, ls := range v {
+			out = append(out, simplifyLine(ls, tolerance, method))
+		}
+		g = out
+	case orb.Polygon:
+		g = simplifyPolygon(v, tolerance, method)
+	case orb.MultiPolygon:
+		out := make(orb.MultiPolygon, 0, len(v))
+		for _, p := range v {
+			out = append(out, simplifyPolygon(p, tolerance, method))
+		}
+		g = out
+	default:
+		return geom, nil
+	}
+
+	return FromOrb(g)
+}
+
+func newSimplifier(tolerance float64, method Method) interface {
+	LineString(orb.LineString) orb.LineString
+	Ring(orb.Ring) orb.Ring
+} {
+	if method == MethodVisvalingam {
+		// Visvalingam works on triangle areas, so square the linear tolerance
+		return simplify.VisvalingamThreshold(tolerance * tolerance)
+	}
+	return simplify.DouglasPeucker(tolerance)
+}
+
+func simplifyLine(ls orb.LineString, tolerance float64, method Method) orb.LineString {
+	out := newSimplifier(tolerance, method).LineString(ls.Clone())
+	if len(out) < 2 {
+		return ls
+	}
+	return out
+}
+
+func simplifyPolygon(p orb.Polygon, tolerance float64, method Method) orb.Polygon {
+	if len(p) == 0 {
+		return p
+	}
+
+	shell := simplifyRing(p[0], tolerance, method)
+	out := orb.Polygon{shell}
+
+	for _, hole := range p[1:] {
+		h := simplifyRing(hole, tolerance, method)
+		if !ringInside(h, shell) {
+			h = hole
+		}
+		out = append(out, h)
+	}
+	return out
+}
+
+func simplifyRing(r orb.Ring, tolerance float64, method Method) orb.Ring {
+	for i := 0; i < retries; i++ {
+		out := newSimplifier(tolerance, method).Ring(r.Clone())
+		if len(out) >= 4 && !ringSelfIntersects(out) {
+			return out
+		}
+		tolerance /= 2
+	}
+	return r
+}
+
+func ringInside(inner, outer orb.Ring) bool {
+	for _, p := range inner {
+		if !planar.RingContains(outer, p) {
+			return false
+		}
+	}
+	return true
+}
+
+// ringSelfIntersects checks whether any two non-adjacent edges cross. Edges
+// are swept from west to east, and each is only tested against the edges
+// whose longitudes it overlaps, so rings with many thousand vertices stay
+// cheap to check.
+func ringSelfIntersects(ring orb.Ring) bool {
+	edges := len(ring) - 1
+	if edges < 4 {
+		return false
+	}
+
+	order := make([]int, edges)
+	for i := range order {
+		order[i] = i
+	}
+	west := func(e int) float64 { return math.Min(ring[e][0], ring[e+1][0]) }
+	east := func(e int) float64 { return math.Max(ring[e][0], ring[e+1][0]) }
+	sort.Slice(order, func(i, j int) bool { return west(order[i]) < west(order[j]) })
+
+	var active []int
+	for _, e := range order {
+		// Drop the edges that end before this one starts
+		kept := active[:0]
+		for _, a := range active {
+			if east(a) >= west(e) {
+				kept = append(kept, a)
+			}
+		}
+		active = kept
+
+		for _, a := range active {
+			// Adjacent edges share a vertex, including the last and first
+			if d := a - e; d == 1 || d == -1 || d == edges-1 || d == 1-edges {
+				continue
+			}
+			if segmentsCross(ring[a], ring[a+1], ring[e], ring[e+1]) {
+				return true
+			}
+		}
+		active = append(active, e)
+	}
+	return false
+}
+
+func segmentsCross(a, b, c, d orb.Point) bool {
+	d1 := orientation(c, d, a)
+	d2 := orientation(c, d, b)
+	d3 := orientation(a, b, c)
+	d4 := orientation(a, b, d)
+
+	return ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) &&
+		((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0))
+}
+
+func orientation(a, b, c orb.Point) float64 {
+	return (c[0]-a[0])*(b[1]-a[1]) - (b[0]-a[0])*(c[1]-a[1])
+}
+
+func (s *Simplifier) get(key cacheKey) (entity.Geometry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	el, ok := s.entries[key]
+	if !ok {
+		return entity.Geometry{}, false
+	}
+	s.order.MoveToFront(el)
+	return el.Value.(*cacheEntry).geom, true
+}
+
+func (s *Simplifier) put(key cacheKey, geom entity.Geometry) {
+	if s.capacity <= 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, ok := s.entries[key]; ok {
+		el.Value.(*cacheEntry).geom = geom
+		s.order.MoveToFront(el)
+		return
+	}
+
+	s.entries[key] = s.order.PushFront(&cacheEntry{key: key, geom: geom})
+
+	for s.order.Len() > s.capacity {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
@@ -0,0 +1,88 @@
+package geometry
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/paulmach/orb"
+)
+
+// circle returns a closed ring of n vertices around the origin
+func circle(n int) orb.Ring {
+	r := make(orb.Ring, n+1)
+	for i := range n {
+		a := 2 * math.Pi * float64(i) / float64(n)
+		r[i] = orb.Point{math.Cos(a), math.Sin(a)}
+	}
+	r[n] = r[0]
+	return r
+}
+
+func TestRingSelfIntersects(t *testing.T) {
+	// A comb whose last tooth is pushed back across the first
+	comb := orb.Ring{{0, 0}}
+	for x := 1.0; x < 2000; x += 2 {
+		comb = append(comb, orb.Point{x, 1}, orb.Point{x + 1, 0})
+	}
+	comb = append(comb, orb.Point{2000, -1}, orb.Point{0.5, 2}, orb.Point{0, 0})
+
+	tests := []struct {
+		name string
+		ring orb.Ring
+		want bool
+	}{
+		{"triangle", orb.Ring{{0, 0}, {1, 0}, {0, 1}, {0, 0}}, false},
+		{"square", orb.Ring{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}}, false},
+		{"bowtie", orb.Ring{{0, 0}, {1, 1}, {1, 0}, {0, 1}, {0, 0}}, true},
+		{"concave", orb.Ring{{0, 0}, {4, 0}, {4, 4}, {2, 1}, {0, 4}, {0, 0}}, false},
+		{"last edge crosses the second", orb.Ring{{0, 0}, {4, 0}, {4, 4}, {0, 4}, {2, -1}, {0, 0}}, true},
+		{"vertical edges", orb.Ring{{0, 0}, {0, 4}, {1, 4}, {1, -1}, {-1, 2}, {0, 0}}, true},
+		{"large circle", circle(20000), false},
+		{"large comb crossing itself", comb, true},
+	}
+	for _, tt := range tests {
+		start := time.Now()
+		if got := ringSelfIntersects(tt.ring); got != tt.want {
+			t.Errorf("%s: ringSelfIntersects = %v, want %v", tt.name, got, tt.want)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("%s: took %v", tt.name, elapsed)
+		}
+	}
+}
+
+func TestSimplifyRingRetries(t *testing.T) {
+	// The bottom edge bends 0.3 down, and a spike from the top reaches
+	// below its straightened line. Removing the bend crosses the spike, so
+	// the ring only simplifies once the tolerance drops under 0.3. The
+	// kink on the right edge goes at any tolerance used here.
+	ring := orb.Ring{{0, 0}, {5, -0.3}, {10, 0}, {10.01, 2.5}, {10, 5}, {5.1, 5}, {5, -0.1}, {4.9, 5}, {0, 5}, {0, 0}}
+	simplified := orb.Ring{{0, 0}, {5, -0.3}, {10, 0}, {10, 5}, {5.1, 5}, {5, -0.1}, {4.9, 5}, {0, 5}, {0, 0}}
+
+	tests := []struct {
+		name      string
+		tolerance float64
+		want      orb.Ring
+	}{
+		{"fine tolerance", 0.2, simplified},
+		{"first retry", 0.5, simplified},
+		{"last retry", 1, simplified},
+		{"no retry keeps the bend", 4, ring},
+	}
+	for _, tt := range tests {
+		got := simplifyRing(ring, tt.tolerance, MethodDouglasPeucker)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: simplifyRing = %v, want %v", tt.name, got, tt.want)
+		}
+		if ringSelfIntersects(got) {
+			t.Errorf("%s: simplified ring crosses itself", tt.name)
+		}
+	}
+
+	// Plain simplification does cross itself
+	if out := newSimplifier(0.5, MethodDouglasPeucker).Ring(ring.Clone()); !ringSelfIntersects(out) {
+		t.Fatalf("simplification at 0.5 = %v, expected it to cross itself", out)
+	}
+}
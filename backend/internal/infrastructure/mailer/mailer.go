@@ -0,0 +1,48 @@
+// Package mailer sends transactional email through SMTP or, in
+// development, writes it to the log or to files.
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Message is a plain-text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers messages
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Config selects and configures the mailer implementation
+type Config struct {
+	Driver   string // smtp, log or file
+	From     string
+	Host     string
+	Port     int
+	Username string
+	Password string
+	Dir      string // output directory for the file driver
+}
+
+// New returns the mailer for cfg.Driver
+func New(cfg Config) (Mailer, error) {
+	switch strings.ToLower(cfg.Driver) {
+	case "", "log":
+		return NewLogMailer(), nil
+	case "file":
+		return NewFileMailer(cfg.Dir)
+	case "smtp":
+		if cfg.Host == "" {
+			return nil, fmt.Errorf("mailer: SMTP host is required")
+		}
+		return NewSMTPMailer(cfg.Host, cfg.Port, cfg.Username, cfg.Password, cfg.From), nil
+	}
+	return nil, fmt.Errorf("mailer: unknown driver %q", cfg.Driver)
+}
@@ -0,0 +1,181 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// EXIF tags read from photos
+const (
+	tagOrientation  = 0x0112
+	tagGPSIFD       = 0x8825
+	tagGPSLatRef    = 0x0001
+	tagGPSLatitude  = 0x0002
+	tagGPSLonRef    = 0x0003
+	tagGPSLongitude = 0x0004
+
+	typeASCII    = 2
+	typeShort    = 3
+	typeLong     = 4
+	typeRational = 5
+)
+
+type exifData struct {
+	orientation int
+	hasGPS      bool
+	latitude    float64
+	longitude   float64
+}
+
+// readExif reads the orientation and GPS position from the EXIF block of
+// a JPEG or the header of a TIFF. Missing or malformed data is ignored.
+func readExif(data []byte) exifData {
+	var meta exifData
+	tiff := exifBlock(data)
+	if tiff == nil {
+		return meta
+	}
+
+	var order binary.ByteOrder
+	switch {
+	case bytes.HasPrefix(tiff, []byte("II*\x00")):
+		order = binary.LittleEndian
+	case bytes.HasPrefix(tiff, []byte("MM\x00*")):
+		order = binary.BigEndian
+	default:
+		return meta
+	}
+	r := &tiffReader{data: tiff, order: order}
+
+	ifd0 := r.ifd(r.u32(4))
+	if e, ok := ifd0[tagOrientation]; ok && e.typ == typeShort {
+		meta.orientation = int(r.u16(e.pos + 8))
+	}
+
+	e, ok := ifd0[tagGPSIFD]
+	if !ok || e.typ != typeLong {
+		return meta
+	}
+	gps := r.ifd(r.u32(e.pos + 8))
+	lat, latOK := r.degrees(gps[tagGPSLatitude])
+	lon, lonOK := r.degrees(gps[tagGPSLongitude])
+	if !latOK || !lonOK {
+		return meta
+	}
+	if r.ref(gps[tagGPSLatRef]) == 'S' {
+		lat = -lat
+	}
+	if r.ref(gps[tagGPSLonRef]) == 'W' {
+		lon = -lon
+	}
+	// Cameras without a fix often write zeroes
+	if lat < -90 || lat > 90 || lon < -180 || lon > 180 || (lat == 0 && lon == 0) {
+		return meta
+	}
+
+	meta.hasGPS = true
+	meta.latitude, meta.longitude = lat, lon
+	return meta
+}
+
+// exifBlock returns the TIFF structure holding the EXIF data: the APP1
+// segment of a JPEG or a TIFF file itself
+func exifBlock(data []byte) []byte {
+	if !bytes.HasPrefix(data, []byte{0xFF, 0xD8}) {
+		return data
+	}
+
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return nil
+		}
+		marker := data[i+1]
+		// Image data follows the start-of-scan marker, no metadata after it
+		if marker == 0xDA || marker == 0xD9 {
+			return nil
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		end := i + 2 + length
+		if length < 2 || end > len(data) {
+			return nil
+		}
+		segment := data[i+4 : end]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return segment[6:]
+		}
+		i = end
+	}
+	return nil
+}
+
+type tiffReader struct {
+	data  []byte
+	order binary.ByteOrder
+}
+
+type ifdEntry struct {
+	typ   uint16
+	count uint32
+	// pos is the offset of the entry; its value or value offset is at pos+8
+	pos uint32
+}
+
+func (r *tiffReader) u16(off uint32) uint16 {
+	if uint64(off)+2 > uint64(len(r.data)) {
+		return 0
+	}
+	return r.order.Uint16(r.data[off:])
+}
+
+func (r *tiffReader) u32(off uint32) uint32 {
+	if uint64(off)+4 > uint64(len(r.data)) {
+		return 0
+	}
+	return r.order.Uint32(r.data[off:])
+}
+
+// ifd reads the entries of the image file directory at off by tag
+func (r *tiffReader) ifd(off uint32) map[uint16]ifdEntry {
+	entries := make(map[uint16]ifdEntry)
+	if off == 0 {
+		return entries
+	}
+	n := uint32(r.u16(off))
+	for i := uint32(0); i < n; i++ {
+		pos := off + 2 + i*12
+		if uint64(pos)+12 > uint64(len(r.data)) {
+			break
+		}
+		entries[r.u16(pos)] = ifdEntry{typ: r.u16(pos + 2), count: r.u32(pos + 4), pos: pos}
+	}
+	return entries
+}
+
+// degrees reads a degrees, minutes, seconds triple of rationals
+func (r *tiffReader) degrees(e ifdEntry) (float64, bool) {
+	if e.typ != typeRational || e.count != 3 {
+		return 0, false
+	}
+	off := r.u32(e.pos + 8)
+	if uint64(off)+24 > uint64(len(r.data)) {
+		return 0, false
+	}
+
+	var parts [3]float64
+	for i := range parts {
+		num, den := r.u32(off+uint32(i)*8), r.u32(off+uint32(i)*8+4)
+		if den == 0 {
+			return 0, false
+		}
+		parts[i] = float64(num) / float64(den)
+	}
+	return parts[0] + parts[1]/60 + parts[2]/3600, true
+}
+
+// ref reads the single letter of a latitude or longitude reference
+func (r *tiffReader) ref(e ifdEntry) byte {
+	if e.typ != typeASCII || e.count == 0 || uint64(e.pos)+9 > uint64(len(r.data)) {
+		return 0
+	}
+	return r.data[e.pos+8]
+}
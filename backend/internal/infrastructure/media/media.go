@@ -0,0 +1,58 @@
+// Package media inspects uploaded files: it detects their type from the
+// content, reads the GPS position of photos and renders thumbnails.
+package media
+
+import (
+	"errors"
+
+	"github.com/gabriel-vasile/mimetype"
+
+	"watermap/internal/domain/entity"
+)
+
+var ErrUnsupportedType = errors.New("unsupported file type; upload JPEG, PNG, GIF, WebP or TIFF images or PDF documents")
+
+// accepted maps the accepted content types to their kind
+var accepted = map[string]entity.AttachmentKind{
+	"image/jpeg":      entity.AttachmentImage,
+	"image/png":       entity.AttachmentImage,
+	"image/gif":       entity.AttachmentImage,
+	"image/webp":      entity.AttachmentImage,
+	"image/tiff":      entity.AttachmentImage,
+	"application/pdf": entity.AttachmentDocument,
+}
+
+// Info is what Inspect found out about a file
+type Info struct {
+	ContentType string
+	Kind        entity.AttachmentKind
+	// Latitude and Longitude are the photo's EXIF GPS position, if any
+	Latitude  *float64
+	Longitude *float64
+	// Thumbnail is a JPEG preview of images the server can decode
+	Thumbnail []byte
+}
+
+// Inspect sniffs the content type of data, ignoring whatever the client
+// claimed, and extracts the position and a thumbnail of at most
+// thumbSize pixels per side from images
+func Inspect(data []byte, thumbSize int) (*Info, error) {
+	contentType := mimetype.Detect(data).String()
+	kind, ok := accepted[contentType]
+	if !ok {
+		return nil, ErrUnsupportedType
+	}
+
+	info := &Info{ContentType: contentType, Kind: kind}
+	if kind != entity.AttachmentImage {
+		return info, nil
+	}
+
+	meta := readExif(data)
+	if meta.hasGPS {
+		info.Latitude, info.Longitude = &meta.latitude, &meta.longitude
+	}
+	// Images in formats the standard library can't decode go without
+	info.Thumbnail, _ = thumbnail(data, thumbSize, meta.orientation)
+	return info, nil
+}
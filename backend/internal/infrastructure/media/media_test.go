@@ -0,0 +1,312 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"testing"
+	"time"
+
+	"watermap/internal/domain/entity"
+)
+
+// exifTIFF builds a little-endian TIFF block with an orientation and, when
+// latRef is set, a GPS position given in whole degrees, minutes and seconds
+func exifTIFF(orientation uint16, latRef byte, lat [3]uint32, lonRef byte, lon [3]uint32) []byte {
+	var b bytes.Buffer
+	le := binary.LittleEndian
+	w := func(v interface{}) { binary.Write(&b, le, v) }
+
+	const ifd0, gpsIFD, ratOff = 8, 38, 92
+	b.WriteString("II*\x00")
+	w(uint32(ifd0))
+
+	entries := uint16(1)
+	if latRef != 0 {
+		entries = 2
+	}
+	w(entries)
+	w([]uint16{tagOrientation, typeShort})
+	w(uint32(1))
+	w([]uint16{orientation, 0})
+	if latRef != 0 {
+		w([]uint16{tagGPSIFD, typeLong})
+		w([]uint32{1, gpsIFD})
+	}
+	w(uint32(0))
+	if latRef == 0 {
+		return b.Bytes()
+	}
+	b.Write(make([]byte, gpsIFD-b.Len()))
+
+	w(uint16(4))
+	w([]uint16{tagGPSLatRef, typeASCII})
+	w(uint32(2))
+	b.Write([]byte{latRef, 0, 0, 0})
+	w([]uint16{tagGPSLatitude, typeRational})
+	w([]uint32{3, ratOff})
+	w([]uint16{tagGPSLonRef, typeASCII})
+	w(uint32(2))
+	b.Write([]byte{lonRef, 0, 0, 0})
+	w([]uint16{tagGPSLongitude, typeRational})
+	w([]uint32{3, ratOff + 24})
+	w(uint32(0))
+
+	for _, v := range append(lat[:], lon[:]...) {
+		w([]uint32{v, 1})
+	}
+	return b.Bytes()
+}
+
+// withExif inserts the TIFF block as an APP1 segment after the JPEG's SOI
+func withExif(jpg, tiff []byte) []byte {
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	out := append([]byte{}, jpg[:2]...)
+	out = append(out, segment...)
+	out = append(out, payload...)
+	return append(out, jpg[2:]...)
+}
+
+func encodeJPEG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), 128, 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// hugePNG is a valid PNG header claiming w×h pixels
+func hugePNG(t *testing.T, w, h uint32) []byte {
+	t.Helper()
+	data := encodePNG(t, image.NewGray(image.Rect(0, 0, 1, 1)))
+	// IHDR data starts after the signature, length and type
+	binary.BigEndian.PutUint32(data[16:], w)
+	binary.BigEndian.PutUint32(data[20:], h)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+	return data
+}
+
+func TestInspect(t *testing.T) {
+	almaty := [3]uint32{43, 15, 0}
+	almatyLon := [3]uint32{76, 57, 36}
+	photo := encodeJPEG(t, 640, 320)
+	transparent := image.NewNRGBA(image.Rect(0, 0, 40, 20))
+
+	tests := []struct {
+		name        string
+		data        []byte
+		contentType string
+		kind        entity.AttachmentKind
+		lat, lon    float64
+		hasGPS      bool
+		thumbW      int
+		thumbH      int
+		err         error
+	}{
+		{
+			name:        "upright photo",
+			data:        photo,
+			contentType: "image/jpeg", kind: entity.AttachmentImage,
+			thumbW: 320, thumbH: 160,
+		},
+		{
+			name:        "rotated photo with a position",
+			data:        withExif(photo, exifTIFF(6, 'N', almaty, 'E', almatyLon)),
+			contentType: "image/jpeg", kind: entity.AttachmentImage,
+			lat: 43.25, lon: 76.96, hasGPS: true,
+			thumbW: 160, thumbH: 320,
+		},
+		{
+			name:        "southern and western hemispheres",
+			data:        withExif(photo, exifTIFF(1, 'S', [3]uint32{33, 52, 12}, 'W', [3]uint32{70, 30, 0})),
+			contentType: "image/jpeg", kind: entity.AttachmentImage,
+			lat: -(33 + 52.0/60 + 12.0/3600), lon: -70.5, hasGPS: true,
+			thumbW: 320, thumbH: 160,
+		},
+		{
+			name:        "no fix written as zeroes",
+			data:        withExif(photo, exifTIFF(1, 'N', [3]uint32{}, 'E', [3]uint32{})),
+			contentType: "image/jpeg", kind: entity.AttachmentImage,
+			thumbW: 320, thumbH: 160,
+		},
+		{
+			name:        "mirrored orientation keeps the size",
+			data:        withExif(photo, exifTIFF(2, 0, [3]uint32{}, 0, [3]uint32{})),
+			contentType: "image/jpeg", kind: entity.AttachmentImage,
+			thumbW: 320, thumbH: 160,
+		},
+		{
+			name:        "small image keeps its size",
+			data:        encodePNG(t, transparent),
+			contentType: "image/png", kind: entity.AttachmentImage,
+			thumbW: 40, thumbH: 20,
+		},
+		{
+			name:        "TIFF with a position but no decoder",
+			data:        exifTIFF(1, 'N', almaty, 'E', almatyLon),
+			contentType: "image/tiff", kind: entity.AttachmentImage,
+			lat: 43.25, lon: 76.96, hasGPS: true,
+		},
+		{
+			name:        "image too large to decode",
+			data:        hugePNG(t, 20000, 20000),
+			contentType: "image/png", kind: entity.AttachmentImage,
+		},
+		{
+			name:        "PDF document",
+			data:        []byte("%PDF-1.7\n1 0 obj\n<<>>\nendobj\ntrailer\n<<>>\n%%EOF\n"),
+			contentType: "application/pdf", kind: entity.AttachmentDocument,
+		},
+		{name: "HTML", data: []byte("<!DOCTYPE html><html><script>alert(1)</script></html>"), err: ErrUnsupportedType},
+		{name: "plain text", data: []byte("just some notes"), err: ErrUnsupportedType},
+		{name: "SVG", data: []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`), err: ErrUnsupportedType},
+		{name: "empty", data: nil, err: ErrUnsupportedType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := Inspect(tt.data, 320)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("Inspect = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Inspect: %v", err)
+			}
+			if info.ContentType != tt.contentType || info.Kind != tt.kind {
+				t.Errorf("type = %s %s, want %s %s", info.ContentType, info.Kind, tt.contentType, tt.kind)
+			}
+
+			if (info.Latitude != nil) != tt.hasGPS {
+				t.Fatalf("position found = %v, want %v", info.Latitude != nil, tt.hasGPS)
+			}
+			if tt.hasGPS && (math.Abs(*info.Latitude-tt.lat) > 1e-9 || math.Abs(*info.Longitude-tt.lon) > 1e-9) {
+				t.Errorf("position = %v, %v; want %v, %v", *info.Latitude, *info.Longitude, tt.lat, tt.lon)
+			}
+
+			if tt.thumbW == 0 {
+				if info.Thumbnail != nil {
+					t.Error("unexpected thumbnail")
+				}
+				return
+			}
+			cfg, format, err := image.DecodeConfig(bytes.NewReader(info.Thumbnail))
+			if err != nil || format != "jpeg" {
+				t.Fatalf("thumbnail is not a JPEG: %v %s", err, format)
+			}
+			if cfg.Width != tt.thumbW || cfg.Height != tt.thumbH {
+				t.Errorf("thumbnail is %d×%d, want %d×%d", cfg.Width, cfg.Height, tt.thumbW, tt.thumbH)
+			}
+		})
+	}
+}
+
+func TestOrient(t *testing.T) {
+	// A 3×2 image with one marked pixel in the top left corner
+	src := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	mark := color.RGBA{255, 0, 0, 255}
+	src.SetRGBA(0, 0, mark)
+
+	tests := []struct {
+		orientation int
+		w, h        int
+		x, y        int
+	}{
+		{0, 3, 2, 0, 0},
+		{1, 3, 2, 0, 0},
+		{2, 3, 2, 2, 0},
+		{3, 3, 2, 2, 1},
+		{4, 3, 2, 0, 1},
+		{5, 2, 3, 0, 0},
+		{6, 2, 3, 1, 0},
+		{7, 2, 3, 1, 2},
+		{8, 2, 3, 0, 2},
+		{9, 3, 2, 0, 0},
+	}
+	for _, tt := range tests {
+		got := orient(src, tt.orientation)
+		if b := got.Bounds(); b.Dx() != tt.w || b.Dy() != tt.h {
+			t.Errorf("orientation %d: size %d×%d, want %d×%d", tt.orientation, b.Dx(), b.Dy(), tt.w, tt.h)
+			continue
+		}
+		if got.RGBAAt(tt.x, tt.y) != mark {
+			t.Errorf("orientation %d: corner pixel not at (%d, %d)", tt.orientation, tt.x, tt.y)
+		}
+	}
+}
+
+func TestScaleFlattensTransparency(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	got := scale(src, 2)
+	if b := got.Bounds(); b.Dx() != 2 || b.Dy() != 2 {
+		t.Fatalf("size %d×%d, want 2×2", b.Dx(), b.Dy())
+	}
+	if c := got.RGBAAt(0, 0); c != (color.RGBA{255, 255, 255, 255}) {
+		t.Errorf("transparent pixel became %v, want white", c)
+	}
+}
+
+func TestThumbnailRejectsHugeImages(t *testing.T) {
+	for _, size := range [][2]uint32{{20000, 20000}, {8000, 5001}} {
+		if _, err := thumbnail(hugePNG(t, size[0], size[1]), 320, 1); err != errTooLarge {
+			t.Errorf("%d×%d: thumbnail = %v, want errTooLarge", size[0], size[1], err)
+		}
+	}
+}
+
+func TestThumbnailWaitsForADecodeSlot(t *testing.T) {
+	photo := encodeJPEG(t, 64, 32)
+	for range cap(decodeSlots) {
+		decodeSlots <- struct{}{}
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := thumbnail(photo, 32, 1)
+		done <- err
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("thumbnail decoded while every slot was taken")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	for range cap(decodeSlots) {
+		<-decodeSlots
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("thumbnail = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("thumbnail still waiting after the slots were freed")
+	}
+}
@@ -0,0 +1,143 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+
+	// Decoders for the formats thumbnails are made of
+	_ "image/gif"
+	_ "image/png"
+)
+
+// maxPixels bounds the size of images decoded for thumbnails, so a small
+// file can't claim enormous dimensions and exhaust memory. Decoded at four
+// bytes a pixel, the largest image takes 160 MB.
+const maxPixels = 40_000_000
+
+// decodeSlots bounds how many images are decoded at once, so that
+// concurrent uploads hold at most a few of the largest images in memory
+var decodeSlots = make(chan struct{}, 2)
+
+var errTooLarge = errors.New("image too large for a thumbnail")
+
+// thumbnail renders a JPEG of the image fitting in size×size, turned
+// upright according to the EXIF orientation
+func thumbnail(data []byte, size, orientation int) ([]byte, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return nil, errTooLarge
+	}
+
+	decodeSlots <- struct{}{}
+	small, err := decodeScaled(data, size)
+	<-decodeSlots
+	if err != nil {
+		return nil, err
+	}
+
+	img := orient(small, orientation)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeScaled decodes the image and shrinks it, so that the full-size
+// image can be collected before the thumbnail is encoded
+func decodeScaled(data []byte, size int) (*image.RGBA, error) {
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return scale(src, size), nil
+}
+
+// scale shrinks the image to fit in size×size by averaging the source
+// pixels behind each target pixel; smaller images keep their size
+func scale(src image.Image, size int) *image.RGBA {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > size || h > size {
+		if w >= h {
+			w, h = size, max(1, h*size/w)
+		} else {
+			w, h = max(1, w*size/h), size
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0 := b.Min.Y + y*b.Dy()/h
+		y1 := max(y0+1, b.Min.Y+(y+1)*b.Dy()/h)
+		for x := 0; x < w; x++ {
+			x0 := b.Min.X + x*b.Dx()/w
+			x1 := max(x0+1, b.Min.X+(x+1)*b.Dx()/w)
+
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := src.At(sx, sy).RGBA()
+					r, g, bl, a = r+uint64(pr), g+uint64(pg), bl+uint64(pb), a+uint64(pa)
+					n++
+				}
+			}
+			// Colours are premultiplied; transparency turns white, as JPEG
+			// has no alpha
+			white := 0xFF - uint8(a/n>>8)
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8(r/n>>8) + white,
+				G: uint8(g/n>>8) + white,
+				B: uint8(bl/n>>8) + white,
+				A: 0xFF,
+			})
+		}
+	}
+	return dst
+}
+
+// orient applies an EXIF orientation (1-8) so the image displays upright
+func orient(src *image.RGBA, orientation int) *image.RGBA {
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	// Orientations 5-8 swap the axes
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = w-1-x, y
+			case 3:
+				dx, dy = w-1-x, h-1-y
+			case 4:
+				dx, dy = x, h-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = h-1-y, x
+			case 7:
+				dx, dy = h-1-y, w-1-x
+			case 8:
+				dx, dy = y, w-1-x
+			}
+			dst.SetRGBA(dx, dy, src.RGBAAt(x, y))
+		}
+	}
+	return dst
+}
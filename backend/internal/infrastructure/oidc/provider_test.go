@@ -0,0 +1,147 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testClientID = "watermap"
+
+// testIdP serves discovery and a JWKS holding one RSA and one EC key
+type testIdP struct {
+	server *httptest.Server
+	rsa    *rsa.PrivateKey
+	ec     *ecdsa.PrivateKey
+}
+
+func newTestIdP(t *testing.T) *testIdP {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &testIdP{rsa: rsaKey, ec: ecKey}
+
+	b64 := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(discovery{
+			Issuer:                idp.server.URL,
+			AuthorizationEndpoint: idp.server.URL + "/authorize",
+			TokenEndpoint:         idp.server.URL + "/token",
+			JWKSURI:               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jwksDocument{Keys: []jwk{
+			{Kty: "RSA", Kid: "rsa-1", Use: "sig", N: b64(rsaKey.N.Bytes()), E: b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+			{Kty: "EC", Kid: "ec-1", Use: "sig", Crv: "P-256", X: b64(ecKey.X.FillBytes(make([]byte, 32))), Y: b64(ecKey.Y.FillBytes(make([]byte, 32)))},
+			// Encryption keys are never used to verify
+			{Kty: "RSA", Kid: "enc-1", Use: "enc", N: b64(rsaKey.N.Bytes()), E: b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+		}})
+	})
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+func (idp *testIdP) claims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":   idp.server.URL,
+		"aud":   testClientID,
+		"sub":   "user-1",
+		"nonce": "n-0S6",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"iat":   time.Now().Unix(),
+	}
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key interface{}, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	raw, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	idp := newTestIdP(t)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	with := func(key string, value interface{}) jwt.MapClaims {
+		c := idp.claims()
+		if value == nil {
+			delete(c, key)
+		} else {
+			c[key] = value
+		}
+		return c
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{"RS256", sign(t, jwt.SigningMethodRS256, "rsa-1", idp.rsa, idp.claims()), true},
+		{"ES256", sign(t, jwt.SigningMethodES256, "ec-1", idp.ec, idp.claims()), true},
+		{"audience list", sign(t, jwt.SigningMethodRS256, "rsa-1", idp.rsa, with("aud", []string{"other", testClientID})), true},
+		{"expired within leeway", sign(t, jwt.SigningMethodRS256, "rsa-1", idp.rsa, with("exp", time.Now().Add(-30*time.Second).Unix())), true},
+		{"expired", sign(t, jwt.SigningMethodRS256, "rsa-1", idp.rsa, with("exp", time.Now().Add(-time.Hour).Unix())), false},
+		{"no expiry", sign(t, jwt.SigningMethodRS256, "rsa-1", idp.rsa, with("exp", nil)), false},
+		{"wrong issuer", sign(t, jwt.SigningMethodRS256, "rsa-1", idp.rsa, with("iss", "https://evil.example")), false},
+		{"wrong audience", sign(t, jwt.SigningMethodRS256, "rsa-1", idp.rsa, with("aud", "another-client")), false},
+		{"wrong nonce", sign(t, jwt.SigningMethodRS256, "rsa-1", idp.rsa, with("nonce", "replayed")), false},
+		{"no subject", sign(t, jwt.SigningMethodRS256, "rsa-1", idp.rsa, with("sub", nil)), false},
+		{"signed by another key", sign(t, jwt.SigningMethodRS256, "rsa-1", otherKey, idp.claims()), false},
+		{"unknown key id", sign(t, jwt.SigningMethodRS256, "rsa-2", idp.rsa, idp.claims()), false},
+		{"encryption key", sign(t, jwt.SigningMethodRS256, "enc-1", idp.rsa, idp.claims()), false},
+		{"EC key id with RSA algorithm", sign(t, jwt.SigningMethodRS256, "ec-1", idp.rsa, idp.claims()), false},
+		{"HMAC with the public key", sign(t, jwt.SigningMethodHS256, "rsa-1", idp.rsa.N.Bytes(), idp.claims()), false},
+		{"unsigned", sign(t, jwt.SigningMethodNone, "rsa-1", jwt.UnsafeAllowNoneSignatureType, idp.claims()), false},
+	}
+
+	p := NewProvider(Config{IssuerURL: idp.server.URL, ClientID: testClientID}, nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := p.VerifyIDToken(context.Background(), tt.token, "n-0S6")
+			if tt.valid {
+				if err != nil {
+					t.Fatalf("VerifyIDToken: %v", err)
+				}
+				if claims["sub"] != "user-1" {
+					t.Errorf("sub = %v, want user-1", claims["sub"])
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("VerifyIDToken = %v, want ErrInvalidToken", err)
+			}
+		})
+	}
+}
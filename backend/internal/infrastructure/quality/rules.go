@@ -0,0 +1,254 @@
+package quality
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+
+	"watermap/internal/domain/entity"
+)
+
+var ErrInvalidRule = errors.New("invalid quality rule")
+
+//go:embed rules.yaml
+var defaultRules []byte
+
+// Rule tests one field of the object types it lists, or of every type when
+// ObjectTypes is empty. A rule sets one or more conditions:
+//
+//	required, forbidden  the field must, or must not, be set
+//	min, max             inclusive bounds of a numeric field
+//	lte, gte             another numeric field the value must not exceed,
+//	                     or fall below
+//	one_of               the values allowed in a text field
+//
+// Bounds and comparisons are skipped while the fields they read are unset.
+type Rule struct {
+	ID          string              `yaml:"id" json:"id"`
+	ObjectTypes []entity.ObjectType `yaml:"object_types" json:"object_types,omitempty"`
+	Field       string              `yaml:"field" json:"field"`
+	Severity    entity.Severity     `yaml:"severity" json:"severity"`
+	Message     string              `yaml:"message" json:"message,omitempty"`
+
+	Required  bool     `yaml:"required" json:"required,omitempty"`
+	Forbidden bool     `yaml:"forbidden" json:"forbidden,omitempty"`
+	Min       *float64 `yaml:"min" json:"min,omitempty"`
+	Max       *float64 `yaml:"max" json:"max,omitempty"`
+	LTE       string   `yaml:"lte" json:"lte,omitempty"`
+	GTE       string   `yaml:"gte" json:"gte,omitempty"`
+	OneOf     []string `yaml:"one_of" json:"one_of,omitempty"`
+}
+
+// RuleSet is a validated list of rules
+type RuleSet struct {
+	rules []*Rule
+}
+
+// Load reads rules from a YAML or JSON file, or the built-in rules when
+// path is empty
+func Load(path string) (*RuleSet, error) {
+	data := defaultRules
+	if path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("read quality rules: %w", err)
+		}
+	}
+	return Parse(data)
+}
+
+// Parse decodes and validates a rule document. JSON documents are valid
+// YAML, so both formats are accepted.
+func Parse(data []byte) (*RuleSet, error) {
+	var doc struct {
+		Rules []*Rule `yaml:"rules"`
+	}
+	if err := yaml.UnmarshalWithOptions(data, &doc, yaml.DisallowUnknownField()); err != nil {
+		return nil, fmt.Errorf("parse quality rules: %w", err)
+	}
+
+	seen := make(map[string]bool, len(doc.Rules))
+	for _, r := range doc.Rules {
+		if err := r.validate(); err != nil {
+			return nil, err
+		}
+		if seen[r.ID] {
+			return nil, fmt.Errorf("%w: duplicate id %q", ErrInvalidRule, r.ID)
+		}
+		seen[r.ID] = true
+	}
+	return &RuleSet{rules: doc.Rules}, nil
+}
+
+func (r *Rule) validate() error {
+	fail := func(reason string) error {
+		return fmt.Errorf("%w %q: %s", ErrInvalidRule, r.ID, reason)
+	}
+
+	if r.ID == "" {
+		return fmt.Errorf("%w: missing id", ErrInvalidRule)
+	}
+	if !r.Severity.IsValid() {
+		return fail("severity must be error or warning")
+	}
+	for _, t := range r.ObjectTypes {
+		if !t.IsValid() {
+			return fail(fmt.Sprintf("unknown object type %q", t))
+		}
+	}
+
+	_, numeric := numericFields[r.Field]
+	_, text := textFields[r.Field]
+	if !numeric && !text {
+		return fail(fmt.Sprintf("unknown field %q", r.Field))
+	}
+
+	if !r.Required && !r.Forbidden && r.Min == nil && r.Max == nil && r.LTE == "" && r.GTE == "" && len(r.OneOf) == 0 {
+		return fail("no condition set")
+	}
+	if r.Required && r.Forbidden {
+		return fail("a field cannot be both required and forbidden")
+	}
+	if !numeric && (r.Min != nil || r.Max != nil || r.LTE != "" || r.GTE != "") {
+		return fail("min, max, lte and gte need a numeric field")
+	}
+	if !text && len(r.OneOf) > 0 {
+		return fail("one_of needs a text field")
+	}
+	for _, other := range []string{r.LTE, r.GTE} {
+		if _, ok := numericFields[other]; other != "" && !ok {
+			return fail(fmt.Sprintf("unknown numeric field %q", other))
+		}
+	}
+	return nil
+}
+
+// Rules returns the rules in the set
+func (s *RuleSet) Rules() []*Rule {
+	return s.rules
+}
+
+// Check returns the rules the object breaks, errors first
+func (s *RuleSet) Check(obj *entity.WaterObject) []*entity.RuleViolation {
+	var errs, warnings []*entity.RuleViolation
+	for _, r := range s.rules {
+		if !r.appliesTo(obj.ObjectType) {
+			continue
+		}
+		if reason := r.check(obj); reason != "" {
+			message := r.Message
+			if message == "" {
+				message = reason
+			}
+			v := &entity.RuleViolation{
+				ObjectID: obj.ID,
+				RuleID:   r.ID,
+				Severity: r.Severity,
+				Field:    r.Field,
+				Message:  message,
+			}
+			if r.Severity == entity.SeverityError {
+				errs = append(errs, v)
+			} else {
+				warnings = append(warnings, v)
+			}
+		}
+	}
+	return append(errs, warnings...)
+}
+
+func (r *Rule) appliesTo(t entity.ObjectType) bool {
+	if len(r.ObjectTypes) == 0 {
+		return true
+	}
+	for _, rt := range r.ObjectTypes {
+		if rt == t {
+			return true
+		}
+	}
+	return false
+}
+
+// check returns why the object breaks the rule, or "" when it does not
+func (r *Rule) check(obj *entity.WaterObject) string {
+	if get, ok := textFields[r.Field]; ok {
+		value := get(obj)
+		set := value != nil && strings.TrimSpace(*value) != ""
+		switch {
+		case r.Required && !set:
+			return fmt.Sprintf("%s is required for %s objects", r.Field, obj.ObjectType)
+		case r.Forbidden && set:
+			return fmt.Sprintf("%s does not apply to %s objects", r.Field, obj.ObjectType)
+		case set && len(r.OneOf) > 0 && !contains(r.OneOf, *value):
+			return fmt.Sprintf("%s must be one of %s", r.Field, strings.Join(r.OneOf, ", "))
+		}
+		return ""
+	}
+
+	value := numericFields[r.Field](obj)
+	switch {
+	case r.Required && value == nil:
+		return fmt.Sprintf("%s is required for %s objects", r.Field, obj.ObjectType)
+	case r.Forbidden && value != nil:
+		return fmt.Sprintf("%s does not apply to %s objects", r.Field, obj.ObjectType)
+	case value == nil:
+		return ""
+	case r.Min != nil && *value < *r.Min:
+		return fmt.Sprintf("%s must be at least %g", r.Field, *r.Min)
+	case r.Max != nil && *value > *r.Max:
+		return fmt.Sprintf("%s must be at most %g", r.Field, *r.Max)
+	}
+	if r.LTE != "" {
+		if other := numericFields[r.LTE](obj); other != nil && *value > *other {
+			return fmt.Sprintf("%s must not exceed %s", r.Field, r.LTE)
+		}
+	}
+	if r.GTE != "" {
+		if other := numericFields[r.GTE](obj); other != nil && *value < *other {
+			return fmt.Sprintf("%s must not be below %s", r.Field, r.GTE)
+		}
+	}
+	return ""
+}
+
+func contains(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Fields rules may test, by their JSON names
+var numericFields = map[string]func(*entity.WaterObject) *float64{
+	"length_km":         func(o *entity.WaterObject) *float64 { return o.LengthKm },
+	"area_km2":          func(o *entity.WaterObject) *float64 { return o.AreaKm2 },
+	"max_depth_m":       func(o *entity.WaterObject) *float64 { return o.MaxDepthM },
+	"avg_depth_m":       func(o *entity.WaterObject) *float64 { return o.AvgDepthM },
+	"water_volume_km3":  func(o *entity.WaterObject) *float64 { return o.WaterVolumeKm3 },
+	"basin_area_km2":    func(o *entity.WaterObject) *float64 { return o.BasinAreaKm2 },
+	"avg_discharge_m3s": func(o *entity.WaterObject) *float64 { return o.AvgDischargeM3s },
+	"pollution_index":   func(o *entity.WaterObject) *float64 { return o.PollutionIndex },
+}
+
+var textFields = map[string]func(*entity.WaterObject) *string{
+	"name_ru":           func(o *entity.WaterObject) *string { return o.NameRU },
+	"name_en":           func(o *entity.WaterObject) *string { return o.NameEN },
+	"salinity_level":    func(o *entity.WaterObject) *string { return o.SalinityLevel },
+	"ecological_status": func(o *entity.WaterObject) *string { return o.EcologicalStatus },
+	"description_kz":    func(o *entity.WaterObject) *string { return o.DescriptionKZ },
+	"description_ru":    func(o *entity.WaterObject) *string { return o.DescriptionRU },
+	"description_en":    func(o *entity.WaterObject) *string { return o.DescriptionEN },
+	"region": func(o *entity.WaterObject) *string {
+		if o.Region == nil {
+			return nil
+		}
+		region := string(*o.Region)
+		return &region
+	},
+}
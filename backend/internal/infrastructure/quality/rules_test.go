@@ -0,0 +1,212 @@
+package quality
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"watermap/internal/domain/entity"
+)
+
+func num(v float64) *float64 { return &v }
+func text(v string) *string  { return &v }
+
+func TestDefaultRules(t *testing.T) {
+	set, err := Load("")
+	if err != nil {
+		t.Fatalf("built-in rules: %v", err)
+	}
+	var ids []string
+	for _, r := range set.Rules() {
+		ids = append(ids, r.ID)
+	}
+	want := []string{"avg_depth_within_max", "spring_without_area", "reservoir_volume", "pollution_index_range"}
+	if !reflect.DeepEqual(ids, want) {
+		t.Errorf("built-in rules = %v, want %v", ids, want)
+	}
+
+	tests := []struct {
+		name string
+		obj  *entity.WaterObject
+		want []string
+	}{
+		{"clean lake", &entity.WaterObject{ObjectType: entity.ObjectTypeLake, MaxDepthM: num(20), AvgDepthM: num(8)}, nil},
+		{"deeper on average", &entity.WaterObject{ObjectType: entity.ObjectTypeLake, MaxDepthM: num(5), AvgDepthM: num(8)},
+			[]string{"avg_depth_within_max"}},
+		{"spring with an area", &entity.WaterObject{ObjectType: entity.ObjectTypeSpring, AreaKm2: num(0.1)},
+			[]string{"spring_without_area"}},
+		{"reservoir without volume", &entity.WaterObject{ObjectType: entity.ObjectTypeReservoir},
+			[]string{"reservoir_volume"}},
+		{"errors before warnings", &entity.WaterObject{ObjectType: entity.ObjectTypeSpring, AreaKm2: num(0.1), PollutionIndex: num(12)},
+			[]string{"pollution_index_range", "spring_without_area"}},
+	}
+	for _, tt := range tests {
+		if got := ruleIDs(set.Check(tt.obj)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Check = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		ok   bool
+	}{
+		{"yaml", `
+rules:
+  - id: depth
+    field: max_depth_m
+    min: 0
+    severity: error
+`, true},
+		{"json", `{"rules": [{"id": "depth", "field": "max_depth_m", "min": 0, "severity": "warning"}]}`, true},
+		{"empty", `rules: []`, true},
+		{"unknown key", `
+rules:
+  - id: depth
+    field: max_depth_m
+    minimum: 0
+    severity: error
+`, false},
+		{"duplicate id", `
+rules:
+  - {id: depth, field: max_depth_m, min: 0, severity: error}
+  - {id: depth, field: avg_depth_m, min: 0, severity: error}
+`, false},
+		{"invalid rule", `
+rules:
+  - {id: depth, field: depth, min: 0, severity: error}
+`, false},
+		{"not a document", `rules: [`, false},
+	}
+	for _, tt := range tests {
+		_, err := Parse([]byte(tt.doc))
+		if tt.ok && err != nil {
+			t.Errorf("%s: Parse = %v, want nil", tt.name, err)
+		}
+		if !tt.ok && err == nil {
+			t.Errorf("%s: Parse accepted the document", tt.name)
+		}
+	}
+
+	_, err := Parse([]byte("rules:\n  - {id: depth, field: depth, min: 0, severity: error}\n"))
+	if !errors.Is(err, ErrInvalidRule) {
+		t.Errorf("invalid rule: err = %v, want %v", err, ErrInvalidRule)
+	}
+}
+
+func TestRuleValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+		ok   bool
+	}{
+		{"required text", Rule{ID: "r", Field: "name_ru", Severity: entity.SeverityWarning, Required: true}, true},
+		{"bounds", Rule{ID: "r", Field: "length_km", Severity: entity.SeverityError, Min: num(0), Max: num(5000)}, true},
+		{"comparison", Rule{ID: "r", Field: "avg_depth_m", Severity: entity.SeverityError, LTE: "max_depth_m"}, true},
+		{"choices", Rule{ID: "r", Field: "ecological_status", Severity: entity.SeverityError, OneOf: []string{"good", "poor"}}, true},
+		{"scoped", Rule{ID: "r", ObjectTypes: []entity.ObjectType{entity.ObjectTypeLake}, Field: "area_km2", Severity: entity.SeverityError, Required: true}, true},
+		{"missing id", Rule{Field: "length_km", Severity: entity.SeverityError, Required: true}, false},
+		{"unknown severity", Rule{ID: "r", Field: "length_km", Severity: "fatal", Required: true}, false},
+		{"unknown object type", Rule{ID: "r", ObjectTypes: []entity.ObjectType{"ocean"}, Field: "length_km", Severity: entity.SeverityError, Required: true}, false},
+		{"unknown field", Rule{ID: "r", Field: "depth", Severity: entity.SeverityError, Required: true}, false},
+		{"no condition", Rule{ID: "r", Field: "length_km", Severity: entity.SeverityError}, false},
+		{"required and forbidden", Rule{ID: "r", Field: "length_km", Severity: entity.SeverityError, Required: true, Forbidden: true}, false},
+		{"bounds on text", Rule{ID: "r", Field: "name_ru", Severity: entity.SeverityError, Min: num(1)}, false},
+		{"comparison on text", Rule{ID: "r", Field: "name_ru", Severity: entity.SeverityError, GTE: "length_km"}, false},
+		{"choices on a number", Rule{ID: "r", Field: "length_km", Severity: entity.SeverityError, OneOf: []string{"1"}}, false},
+		{"compared with an unknown field", Rule{ID: "r", Field: "avg_depth_m", Severity: entity.SeverityError, LTE: "depth"}, false},
+		{"compared with a text field", Rule{ID: "r", Field: "avg_depth_m", Severity: entity.SeverityError, GTE: "name_ru"}, false},
+	}
+	for _, tt := range tests {
+		err := tt.rule.validate()
+		if tt.ok && err != nil {
+			t.Errorf("%s: validate = %v, want nil", tt.name, err)
+		}
+		if !tt.ok && !errors.Is(err, ErrInvalidRule) {
+			t.Errorf("%s: validate = %v, want %v", tt.name, err, ErrInvalidRule)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	region := entity.RegionAlmaty
+	tests := []struct {
+		name   string
+		rule   Rule
+		obj    entity.WaterObject
+		breaks bool
+	}{
+		{"required and set", Rule{Field: "name_ru", Required: true}, entity.WaterObject{NameRU: text("Иле")}, false},
+		{"required and unset", Rule{Field: "name_ru", Required: true}, entity.WaterObject{}, true},
+		{"required and blank", Rule{Field: "name_ru", Required: true}, entity.WaterObject{NameRU: text("  ")}, true},
+		{"required number unset", Rule{Field: "length_km", Required: true}, entity.WaterObject{}, true},
+		{"required number zero", Rule{Field: "length_km", Required: true}, entity.WaterObject{LengthKm: num(0)}, false},
+		{"forbidden and unset", Rule{Field: "area_km2", Forbidden: true}, entity.WaterObject{}, false},
+		{"forbidden and set", Rule{Field: "area_km2", Forbidden: true}, entity.WaterObject{AreaKm2: num(1)}, true},
+		{"forbidden text set", Rule{Field: "salinity_level", Forbidden: true}, entity.WaterObject{SalinityLevel: text("fresh")}, true},
+		{"min met", Rule{Field: "max_depth_m", Min: num(0)}, entity.WaterObject{MaxDepthM: num(0)}, false},
+		{"below min", Rule{Field: "max_depth_m", Min: num(0)}, entity.WaterObject{MaxDepthM: num(-1)}, true},
+		{"min skipped while unset", Rule{Field: "max_depth_m", Min: num(0)}, entity.WaterObject{}, false},
+		{"max met", Rule{Field: "pollution_index", Max: num(10)}, entity.WaterObject{PollutionIndex: num(10)}, false},
+		{"above max", Rule{Field: "pollution_index", Max: num(10)}, entity.WaterObject{PollutionIndex: num(10.5)}, true},
+		{"lte met", Rule{Field: "avg_depth_m", LTE: "max_depth_m"}, entity.WaterObject{AvgDepthM: num(4), MaxDepthM: num(4)}, false},
+		{"lte broken", Rule{Field: "avg_depth_m", LTE: "max_depth_m"}, entity.WaterObject{AvgDepthM: num(5), MaxDepthM: num(4)}, true},
+		{"lte against unset", Rule{Field: "avg_depth_m", LTE: "max_depth_m"}, entity.WaterObject{AvgDepthM: num(5)}, false},
+		{"gte met", Rule{Field: "basin_area_km2", GTE: "area_km2"}, entity.WaterObject{BasinAreaKm2: num(100), AreaKm2: num(10)}, false},
+		{"gte broken", Rule{Field: "basin_area_km2", GTE: "area_km2"}, entity.WaterObject{BasinAreaKm2: num(5), AreaKm2: num(10)}, true},
+		{"one_of met", Rule{Field: "ecological_status", OneOf: []string{"good", "poor"}}, entity.WaterObject{EcologicalStatus: text("good")}, false},
+		{"not one_of", Rule{Field: "ecological_status", OneOf: []string{"good", "poor"}}, entity.WaterObject{EcologicalStatus: text("fine")}, true},
+		{"one_of skipped while unset", Rule{Field: "ecological_status", OneOf: []string{"good", "poor"}}, entity.WaterObject{}, false},
+		{"region", Rule{Field: "region", OneOf: []string{string(entity.RegionAbai)}}, entity.WaterObject{Region: &region}, true},
+	}
+	for _, tt := range tests {
+		tt.rule.ID, tt.rule.Severity = "rule", entity.SeverityError
+		tt.obj.ID, tt.obj.ObjectType = 9, entity.ObjectTypeLake
+		set := &RuleSet{rules: []*Rule{&tt.rule}}
+
+		got := set.Check(&tt.obj)
+		if (len(got) > 0) != tt.breaks {
+			t.Errorf("%s: Check = %v, want broken %v", tt.name, ruleIDs(got), tt.breaks)
+			continue
+		}
+		if tt.breaks {
+			v := got[0]
+			if v.ObjectID != 9 || v.RuleID != "rule" || v.Field != tt.rule.Field || v.Message == "" {
+				t.Errorf("%s: violation = %+v", tt.name, v)
+			}
+		}
+	}
+}
+
+func TestCheckScopeAndMessage(t *testing.T) {
+	set := &RuleSet{rules: []*Rule{
+		{ID: "lake_area", ObjectTypes: []entity.ObjectType{entity.ObjectTypeLake}, Field: "area_km2",
+			Required: true, Severity: entity.SeverityWarning, Message: "lakes need an area"},
+		{ID: "length", Field: "length_km", Required: true, Severity: entity.SeverityError},
+	}}
+
+	got := set.Check(&entity.WaterObject{ObjectType: entity.ObjectTypeLake})
+	if ids := ruleIDs(got); !reflect.DeepEqual(ids, []string{"length", "lake_area"}) {
+		t.Fatalf("lake: Check = %v, want [length lake_area]", ids)
+	}
+	if got[1].Message != "lakes need an area" {
+		t.Errorf("message = %q, want the rule's own", got[1].Message)
+	}
+	if got[0].Message != "length_km is required for lake objects" {
+		t.Errorf("message = %q, want the generated one", got[0].Message)
+	}
+
+	if ids := ruleIDs(set.Check(&entity.WaterObject{ObjectType: entity.ObjectTypeRiver})); !reflect.DeepEqual(ids, []string{"length"}) {
+		t.Errorf("river: Check = %v, want [length]", ids)
+	}
+}
+
+func ruleIDs(violations []*entity.RuleViolation) []string {
+	var ids []string
+	for _, v := range violations {
+		ids = append(ids, v.RuleID)
+	}
+	return ids
+}
@@ -0,0 +1,82 @@
+package quality
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"watermap/internal/domain/entity"
+	"watermap/internal/domain/repository"
+)
+
+// Sweeper checks the published objects against the rules, catching data
+// published before a rule was added or changed
+type Sweeper struct {
+	objects repository.WaterObjectRepository
+	repo    repository.QualityRepository
+	rules   *RuleSet
+}
+
+func NewSweeper(objects repository.WaterObjectRepository, repo repository.QualityRepository, rules *RuleSet) *Sweeper {
+	return &Sweeper{objects: objects, repo: repo, rules: rules}
+}
+
+// Sweep checks every published object and replaces their stored violations
+func (s *Sweeper) Sweep(ctx context.Context) (*entity.QualitySweep, error) {
+	sweep := &entity.QualitySweep{StartedAt: time.Now()}
+
+	var violations []*entity.RuleViolation
+	err := s.objects.StreamPublished(ctx, nil, func(obj *entity.WaterObject) error {
+		sweep.Objects++
+		for _, v := range s.rules.Check(obj) {
+			v.CheckedAt = sweep.StartedAt
+			if v.Severity == entity.SeverityError {
+				sweep.Errors++
+			} else {
+				sweep.Warnings++
+			}
+			violations = append(violations, v)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("check published objects: %w", err)
+	}
+
+	if err := s.repo.ReplacePublished(ctx, violations); err != nil {
+		return nil, err
+	}
+
+	sweep.FinishedAt = time.Now()
+	return sweep, nil
+}
+
+// RunNightly sweeps once a day at the given hour (UTC) until ctx is done
+func (s *Sweeper) RunNightly(ctx context.Context, hour int) {
+	for {
+		timer := time.NewTimer(untilHour(time.Now().UTC(), hour))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		sweep, err := s.Sweep(ctx)
+		if err != nil {
+			log.Printf("quality sweep: %v", err)
+			continue
+		}
+		log.Printf("quality sweep: %d objects, %d errors, %d warnings", sweep.Objects, sweep.Errors, sweep.Warnings)
+	}
+}
+
+// untilHour returns the time from now to the next occurrence of the hour
+func untilHour(now time.Time, hour int) time.Duration {
+	next := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next.Sub(now)
+}
@@ -0,0 +1,67 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresStore keeps buckets in the rate_limit_buckets table so that all
+// instances behind a load balancer share the same limits
+type PostgresStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresStore(pool *pgxpool.Pool) *PostgresStore {
+	return &PostgresStore{pool: pool}
+}
+
+// Take refills and decrements the bucket in one statement. The row lock
+// taken by the upsert serialises concurrent requests for the same key.
+func (s *PostgresStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	query := `
+		INSERT INTO rate_limit_buckets AS b (key, tokens, allowed, updated_at)
+		VALUES ($1, $2::DOUBLE PRECISION - 1, TRUE, $3::TIMESTAMPTZ)
+		ON CONFLICT (key) DO UPDATE SET
+			allowed = LEAST($2, b.tokens + GREATEST(EXTRACT(EPOCH FROM ($3 - b.updated_at)), 0) * $4::DOUBLE PRECISION) >= 1,
+			tokens = LEAST($2, b.tokens + GREATEST(EXTRACT(EPOCH FROM ($3 - b.updated_at)), 0) * $4)
+				- CASE WHEN LEAST($2, b.tokens + GREATEST(EXTRACT(EPOCH FROM ($3 - b.updated_at)), 0) * $4) >= 1 THEN 1 ELSE 0 END,
+			updated_at = $3
+		RETURNING tokens, allowed
+	`
+
+	var tokens float64
+	var allowed bool
+	err := s.pool.QueryRow(ctx, query, key, float64(limit.Requests), now, limit.rate()).Scan(&tokens, &allowed)
+	if err != nil {
+		return Result{}, fmt.Errorf("take rate limit token: %w", err)
+	}
+
+	if allowed {
+		return Result{Allowed: true, Remaining: int(tokens)}, nil
+	}
+	return result(tokens, limit), nil
+}
+
+// Prune periodically deletes buckets untouched for longer than idle,
+// until ctx is cancelled
+func (s *PostgresStore) Prune(ctx context.Context, interval, idle time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.pool.Exec(ctx,
+				"DELETE FROM rate_limit_buckets WHERE updated_at < $1", time.Now().Add(-idle),
+			); err != nil {
+				log.Printf("prune rate limit buckets: %v", err)
+			}
+		}
+	}
+}
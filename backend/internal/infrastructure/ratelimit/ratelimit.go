@@ -0,0 +1,76 @@
+// Package ratelimit implements token bucket rate limiting with an
+// in-memory store for single instances and a Postgres store shared by
+// several instances.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit allows Requests per Per on average, with bursts up to Requests
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// rate is the refill rate in tokens per second
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+func (l Limit) String() string {
+	return strconv.Itoa(l.Requests) + "/" + l.Per.String()
+}
+
+// ParseLimit parses "requests/duration", for example "10/1m" or "300/1m"
+func ParseLimit(s string) (Limit, error) {
+	n, per, ok := strings.Cut(strings.TrimSpace(s), "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("ratelimit: invalid limit %q, want requests/duration", s)
+	}
+
+	requests, err := strconv.Atoi(n)
+	if err != nil || requests <= 0 {
+		return Limit{}, fmt.Errorf("ratelimit: invalid request count in %q", s)
+	}
+	d, err := time.ParseDuration(per)
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("ratelimit: invalid duration in %q", s)
+	}
+	return Limit{Requests: requests, Per: d}, nil
+}
+
+// Result describes the outcome of taking a token
+type Result struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration
+}
+
+// Store keeps token buckets. Take removes one token from the bucket at
+// key if one is available.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+}
+
+// refill returns the tokens in a bucket after elapsed time, capped at the burst size
+func refill(tokens float64, elapsed time.Duration, limit Limit) float64 {
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	return math.Min(float64(limit.Requests), tokens+elapsed.Seconds()*limit.rate())
+}
+
+// result builds the outcome for a bucket holding tokens before the take
+func result(tokens float64, limit Limit) Result {
+	if tokens >= 1 {
+		return Result{Allowed: true, Remaining: int(tokens - 1)}
+	}
+	wait := time.Duration((1 - tokens) / limit.rate() * float64(time.Second))
+	return Result{Allowed: false, RetryAfter: wait}
+}
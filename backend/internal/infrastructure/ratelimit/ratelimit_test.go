@@ -0,0 +1,134 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Limit
+		wantErr bool
+	}{
+		{"10/1m", Limit{10, time.Minute}, false},
+		{" 300/1s ", Limit{300, time.Second}, false},
+		{"5/90s", Limit{5, 90 * time.Second}, false},
+		{"10", Limit{}, true},
+		{"0/1m", Limit{}, true},
+		{"-1/1m", Limit{}, true},
+		{"ten/1m", Limit{}, true},
+		{"10/minute", Limit{}, true},
+		{"10/0s", Limit{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseLimit(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseLimit(%q) = %v, %v; want %v, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestRefill(t *testing.T) {
+	limit := Limit{Requests: 10, Per: time.Minute}
+
+	tests := []struct {
+		name    string
+		tokens  float64
+		elapsed time.Duration
+		want    float64
+	}{
+		{"no time passed", 3, 0, 3},
+		{"one token per six seconds", 0, 6 * time.Second, 1},
+		{"partial token", 0, 3 * time.Second, 0.5},
+		{"capped at the burst", 8, time.Minute, 10},
+		{"empty bucket refills fully", 0, time.Minute, 10},
+		{"clock going back adds nothing", 4, -time.Minute, 4},
+	}
+	for _, tt := range tests {
+		if got := refill(tt.tokens, tt.elapsed, limit); got != tt.want {
+			t.Errorf("%s: refill = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestResult(t *testing.T) {
+	limit := Limit{Requests: 10, Per: time.Minute}
+
+	tests := []struct {
+		tokens float64
+		want   Result
+	}{
+		{10, Result{Allowed: true, Remaining: 9}},
+		{1, Result{Allowed: true, Remaining: 0}},
+		{1.5, Result{Allowed: true, Remaining: 0}},
+		{0, Result{RetryAfter: 6 * time.Second}},
+		{0.5, Result{RetryAfter: 3 * time.Second}},
+	}
+	for _, tt := range tests {
+		if got := result(tt.tokens, limit); got != tt.want {
+			t.Errorf("result(%v) = %+v, want %+v", tt.tokens, got, tt.want)
+		}
+	}
+}
+
+func TestMemoryStoreTake(t *testing.T) {
+	ctx := context.Background()
+	limit := Limit{Requests: 3, Per: 3 * time.Second}
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// The steps run in order against one store
+	steps := []struct {
+		name    string
+		key     string
+		at      time.Duration
+		allowed bool
+		retry   time.Duration
+	}{
+		{"burst 1", "a", 0, true, 0},
+		{"burst 2", "a", 0, true, 0},
+		{"burst 3", "a", 0, true, 0},
+		{"burst spent", "a", 0, false, time.Second},
+		{"other keys are separate", "b", 0, true, 0},
+		{"half a token later", "a", 500 * time.Millisecond, false, 500 * time.Millisecond},
+		{"one token later", "a", time.Second, true, 0},
+		{"spent again", "a", time.Second, false, time.Second},
+		{"refilled after a long pause", "a", time.Hour, true, 0},
+		{"but only up to the burst", "a", time.Hour, true, 0},
+		{"so the third is the last", "a", time.Hour, true, 0},
+		{"and the fourth waits", "a", time.Hour, false, time.Second},
+	}
+
+	s := NewMemoryStore()
+	for _, st := range steps {
+		res, err := s.Take(ctx, st.key, limit, start.Add(st.at))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Allowed != st.allowed || res.RetryAfter != st.retry {
+			t.Errorf("%s: Take = %+v, want allowed %v, retry after %v", st.name, res, st.allowed, st.retry)
+		}
+	}
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	ctx := context.Background()
+	fast := Limit{Requests: 2, Per: time.Second}
+	slow := Limit{Requests: 2, Per: time.Hour}
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	s := NewMemoryStore()
+	s.Take(ctx, "idle", fast, start)
+	s.Take(ctx, "busy", slow, start)
+	s.Take(ctx, "busy", slow, start)
+
+	// The first take after the interval drops the buckets that refilled
+	s.Take(ctx, "other", fast, start.Add(sweepInterval+time.Millisecond))
+	if _, ok := s.buckets["idle"]; ok {
+		t.Error("refilled bucket was kept")
+	}
+	if _, ok := s.buckets["busy"]; !ok {
+		t.Error("bucket still refilling was dropped")
+	}
+}
@@ -0,0 +1,150 @@
+// Package webhook sends queued workflow events to partner systems. Every
+// request carries an HMAC-SHA256 signature over the timestamp and body so
+// receivers can check that it came from WaterMap and is not a replay.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"watermap/internal/domain/entity"
+	"watermap/internal/domain/repository"
+)
+
+const (
+	HeaderEvent     = "X-Watermap-Event"
+	HeaderDelivery  = "X-Watermap-Delivery"
+	HeaderTimestamp = "X-Watermap-Timestamp"
+	HeaderSignature = "X-Watermap-Signature"
+)
+
+const (
+	// batchSize is how many deliveries one claim leases
+	batchSize = 20
+	// lease must outlast a batch of attempts, after which another instance
+	// may pick up deliveries this one failed to record
+	lease = 5 * time.Minute
+	// requestTimeout bounds a single attempt
+	requestTimeout = 10 * time.Second
+)
+
+// Sign returns the signature header value for a body sent at timestamp
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a signature in constant time and rejects timestamps further
+// than tolerance from now
+func Verify(secret, timestamp string, body []byte, signature string, tolerance time.Duration) bool {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	age := time.Since(time.Unix(ts, 0))
+	if age > tolerance || age < -tolerance {
+		return false
+	}
+	return hmac.Equal([]byte(Sign(secret, ts, body)), []byte(signature))
+}
+
+// Dispatcher works through the delivery queue. Several instances may run
+// one each; claims keep them from sending the same delivery twice.
+type Dispatcher struct {
+	repo   repository.WebhookRepository
+	client *http.Client
+}
+
+func NewDispatcher(repo repository.WebhookRepository) *Dispatcher {
+	return &Dispatcher{
+		repo: repo,
+		client: &http.Client{
+			Timeout: requestTimeout,
+			// A redirect is reported as a failed attempt rather than followed
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// Run polls the queue every interval until ctx is done
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.drain(ctx)
+		}
+	}
+}
+
+// drain sends claimed batches until nothing is due
+func (d *Dispatcher) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		deliveries, err := d.repo.Claim(ctx, batchSize, lease)
+		if err != nil {
+			log.Printf("claim webhook deliveries: %v", err)
+			return
+		}
+		for _, delivery := range deliveries {
+			d.attempt(ctx, delivery)
+		}
+		if len(deliveries) < batchSize {
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) attempt(ctx context.Context, delivery *entity.WebhookDelivery) {
+	statusCode, err := d.send(ctx, delivery)
+	retryIn := delivery.RecordAttempt(statusCode, err)
+
+	if err := d.repo.RecordAttempt(ctx, delivery, retryIn); err != nil {
+		log.Printf("record webhook delivery %d: %v", delivery.ID, err)
+	}
+}
+
+func (d *Dispatcher) send(ctx context.Context, delivery *entity.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "WaterMap-Webhooks/1.0")
+	req.Header.Set(HeaderEvent, string(delivery.EventType))
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(delivery.Webhook.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return resp.StatusCode, fmt.Errorf("receiver responded %s: %s", resp.Status, bytes.TrimSpace(snippet))
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,111 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"watermap/internal/domain/entity"
+)
+
+func TestSign(t *testing.T) {
+	// Expected values computed with another HMAC-SHA256 implementation over
+	// "<timestamp>.<body>"
+	tests := []struct {
+		secret    string
+		timestamp int64
+		body      string
+		want      string
+	}{
+		{"secret", 1700000000, `{}`, "sha256=b8569b78799ff9e3cbff0fc2d63a33a2b57f3282abd07c37ae5e8e7d79a5f163"},
+		{"key", 1700000000, `{"id":1}`, "sha256=1d542af0cd7355fefa5c19021ad89d3e2afd77ebff5448db280f180e013d2651"},
+	}
+	for _, tt := range tests {
+		if got := Sign(tt.secret, tt.timestamp, []byte(tt.body)); got != tt.want {
+			t.Errorf("Sign(%q, %d, %s) = %s, want %s", tt.secret, tt.timestamp, tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"type":"pending_added"}`)
+	now := time.Now().Unix()
+	ts := strconv.FormatInt(now, 10)
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		body      []byte
+		signature string
+		want      bool
+	}{
+		{"valid", "secret", ts, body, Sign("secret", now, body), true},
+		{"wrong secret", "other", ts, body, Sign("secret", now, body), false},
+		{"tampered body", "secret", ts, []byte(`{"type":"pending_removed"}`), Sign("secret", now, body), false},
+		{"timestamp not signed", "secret", strconv.FormatInt(now+1, 10), body, Sign("secret", now, body), false},
+		{"too old", "secret", strconv.FormatInt(now-600, 10), body, Sign("secret", now-600, body), false},
+		{"too far ahead", "secret", strconv.FormatInt(now+600, 10), body, Sign("secret", now+600, body), false},
+		{"malformed timestamp", "secret", "yesterday", body, Sign("secret", now, body), false},
+		{"missing signature", "secret", ts, body, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Verify(tt.secret, tt.timestamp, tt.body, tt.signature, 5*time.Minute); got != tt.want {
+				t.Errorf("Verify = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSendSignsRequests(t *testing.T) {
+	var verified bool
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		verified = Verify("secret", r.Header.Get(HeaderTimestamp), body, r.Header.Get(HeaderSignature), time.Minute) &&
+			r.Header.Get(HeaderEvent) == string(entity.EventPendingAdded) &&
+			r.Header.Get(HeaderDelivery) == "7"
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	d := NewDispatcher(nil)
+	status, err := d.send(context.Background(), &entity.WebhookDelivery{
+		ID:        7,
+		EventType: entity.EventPendingAdded,
+		Payload:   []byte(`{"id":7}`),
+		Webhook:   &entity.Webhook{URL: receiver.URL, Secret: "secret"},
+	})
+	if err != nil || status != http.StatusNoContent {
+		t.Fatalf("send = %d, %v", status, err)
+	}
+	if !verified {
+		t.Error("receiver could not verify the request")
+	}
+}
+
+func TestSendReportsFailures(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/moved" {
+			http.Redirect(w, r, "/", http.StatusFound)
+			return
+		}
+		http.Error(w, "busy", http.StatusServiceUnavailable)
+	}))
+	defer receiver.Close()
+
+	d := NewDispatcher(nil)
+	for _, path := range []string{"/", "/moved"} {
+		status, err := d.send(context.Background(), &entity.WebhookDelivery{
+			Payload: []byte(`{}`),
+			Webhook: &entity.Webhook{URL: receiver.URL + path, Secret: "secret"},
+		})
+		if err == nil {
+			t.Errorf("%s: send succeeded with status %d", path, status)
+		}
+	}
+}